@@ -105,8 +105,16 @@ func (p *AnthropicProvider) buildRequest(req *ChatRequest) map[string]interface{
 		result["max_tokens"] = req.MaxTokens
 	}
 
+	// Cache breakpoints on the tools, the system prompt and the end of the history let each
+	// iteration of an agent loop reuse the prompt prefix the previous one wrote
 	if req.System != "" {
-		result["system"] = req.System
+		result["system"] = []interface{}{
+			map[string]interface{}{
+				"type":          "text",
+				"text":          req.System,
+				"cache_control": anthropicCacheControl,
+			},
+		}
 	}
 
 	if len(req.Tools) > 0 {
@@ -118,9 +126,12 @@ func (p *AnthropicProvider) buildRequest(req *ChatRequest) map[string]interface{
 				"input_schema": json.RawMessage(tool.InputSchema),
 			})
 		}
+		tools[len(tools)-1]["cache_control"] = anthropicCacheControl
 		result["tools"] = tools
 	}
 
+	markHistoryBreakpoints(messages)
+
 	// Enable extended thinking mode for reasoning tasks
 	if req.EnableThinking {
 		result["thinking"] = map[string]interface{}{
@@ -136,10 +147,58 @@ func (p *AnthropicProvider) buildRequest(req *ChatRequest) map[string]interface{
 	return result
 }
 
+// anthropicCacheControl marks the end of a prompt prefix to cache
+var anthropicCacheControl = map[string]interface{}{"type": "ephemeral"}
+
+// markHistoryBreakpoints puts cache breakpoints on the last message and on the user message
+// before it, where the previous request of an agent loop ended. The previous request's cache
+// entry is read back and the new turn is written after it.
+func markHistoryBreakpoints(messages []map[string]interface{}) {
+	if len(messages) == 0 {
+		return
+	}
+	markCacheBreakpoint(messages[len(messages)-1])
+	for i := len(messages) - 2; i >= 0; i-- {
+		if messages[i]["role"] == "user" {
+			markCacheBreakpoint(messages[i])
+			return
+		}
+	}
+}
+
+// markCacheBreakpoint puts a cache breakpoint on the last content block of a message,
+// turning plain text content into a text block
+func markCacheBreakpoint(msg map[string]interface{}) {
+	switch content := msg["content"].(type) {
+	case string:
+		if content != "" {
+			msg["content"] = []interface{}{
+				map[string]interface{}{
+					"type":          "text",
+					"text":          content,
+					"cache_control": anthropicCacheControl,
+				},
+			}
+		}
+	case []interface{}:
+		if len(content) > 0 {
+			if block, ok := content[len(content)-1].(map[string]interface{}); ok {
+				block["cache_control"] = anthropicCacheControl
+			}
+		}
+	}
+}
+
 // convertMessage converts a session message to Anthropic format
 func (p *AnthropicProvider) convertMessage(msg session.Message) map[string]interface{} {
 	switch msg.Role {
 	case "user":
+		if len(msg.Parts) > 0 {
+			return map[string]interface{}{
+				"role":    "user",
+				"content": anthropicContent(msg.ContentParts()),
+			}
+		}
 		return map[string]interface{}{
 			"role":    "user",
 			"content": msg.Content,
@@ -206,6 +265,61 @@ func (p *AnthropicProvider) convertMessage(msg session.Message) map[string]inter
 	return nil
 }
 
+// anthropicContent converts message parts to content blocks. Images and PDFs are sent
+// inline, text files as plain-text documents; audio isn't supported.
+func anthropicContent(parts []session.ContentPart) []interface{} {
+	content := make([]interface{}, 0, len(parts))
+	for _, part := range parts {
+		switch {
+		case part.Type == session.PartText:
+			content = append(content, map[string]interface{}{
+				"type": "text",
+				"text": part.Text,
+			})
+		case len(part.Data) == 0:
+			content = append(content, map[string]interface{}{
+				"type": "text",
+				"text": part.AsText(),
+			})
+		case part.Type == session.PartImage:
+			content = append(content, map[string]interface{}{
+				"type": "image",
+				"source": map[string]interface{}{
+					"type":       "base64",
+					"media_type": part.MediaType,
+					"data":       part.Data,
+				},
+			})
+		case part.Type == session.PartDocument && part.MediaType == "application/pdf":
+			content = append(content, map[string]interface{}{
+				"type":  "document",
+				"title": part.Name,
+				"source": map[string]interface{}{
+					"type":       "base64",
+					"media_type": part.MediaType,
+					"data":       part.Data,
+				},
+			})
+		case part.Type == session.PartDocument && session.IsTextMedia(part.MediaType):
+			content = append(content, map[string]interface{}{
+				"type":  "document",
+				"title": part.Name,
+				"source": map[string]interface{}{
+					"type":       "text",
+					"media_type": "text/plain",
+					"data":       string(part.Data),
+				},
+			})
+		default:
+			content = append(content, map[string]interface{}{
+				"type": "text",
+				"text": part.AsText(),
+			})
+		}
+	}
+	return content
+}
+
 // streamResponse reads SSE events and sends them to the channel
 func (p *AnthropicProvider) streamResponse(ctx context.Context, resp *http.Response, events chan<- StreamEvent) {
 	defer close(events)
@@ -214,6 +328,7 @@ func (p *AnthropicProvider) streamResponse(ctx context.Context, resp *http.Respo
 	reader := bufio.NewReader(resp.Body)
 	var currentToolCall *ToolCall
 	var inputBuffer strings.Builder
+	var usage Usage
 
 	for {
 		select {
@@ -248,6 +363,21 @@ func (p *AnthropicProvider) streamResponse(ctx context.Context, resp *http.Respo
 		}
 
 		switch event.Type {
+		case "message_start":
+			usage.Model = event.Message.Model
+			// input_tokens only counts the tokens after the last cache breakpoint
+			u := event.Message.Usage
+			usage.InputTokens = u.InputTokens + u.CacheReadInputTokens + u.CacheCreationInputTokens
+			usage.CacheReadTokens = u.CacheReadInputTokens
+			usage.CacheWriteTokens = u.CacheCreationInputTokens
+			usage.OutputTokens = u.OutputTokens
+
+		case "message_delta":
+			// Output token count in message_delta is cumulative
+			if event.Usage.OutputTokens > 0 {
+				usage.OutputTokens = event.Usage.OutputTokens
+			}
+
 		case "content_block_start":
 			if event.ContentBlock.Type == "tool_use" {
 				currentToolCall = &ToolCall{
@@ -283,6 +413,7 @@ func (p *AnthropicProvider) streamResponse(ctx context.Context, resp *http.Respo
 			}
 
 		case "message_stop":
+			events <- StreamEvent{Type: EventTypeUsage, Usage: &usage}
 			events <- StreamEvent{Type: EventTypeDone}
 			return
 
@@ -314,12 +445,25 @@ type anthropicStreamEvent struct {
 		PartialJSON string `json:"partial_json,omitempty"`
 		Thinking    string `json:"thinking,omitempty"`
 	} `json:"delta,omitempty"`
+	Message struct {
+		Model string         `json:"model,omitempty"`
+		Usage anthropicUsage `json:"usage,omitempty"`
+	} `json:"message,omitempty"`
+	Usage anthropicUsage `json:"usage,omitempty"`
 	Error struct {
 		Type    string `json:"type"`
 		Message string `json:"message"`
 	} `json:"error,omitempty"`
 }
 
+// anthropicUsage represents token usage in message_start and message_delta events
+type anthropicUsage struct {
+	InputTokens              int `json:"input_tokens,omitempty"`
+	OutputTokens             int `json:"output_tokens,omitempty"`
+	CacheCreationInputTokens int `json:"cache_creation_input_tokens,omitempty"`
+	CacheReadInputTokens     int `json:"cache_read_input_tokens,omitempty"`
+}
+
 // parseAnthropicError parses an error response from the Anthropic API
 func parseAnthropicError(statusCode int, body []byte) error {
 	var errResp struct {
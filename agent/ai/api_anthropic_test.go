@@ -0,0 +1,46 @@
+package ai
+
+import (
+	"encoding/json"
+	"testing"
+
+	"gobot/agent/session"
+)
+
+func TestAnthropicCacheBreakpoints(t *testing.T) {
+	req := NewAnthropicProvider("key", "claude").buildRequest(&ChatRequest{
+		System: "be brief",
+		Tools:  append(ollamaTools, ToolDefinition{Name: "ls", InputSchema: json.RawMessage(`{"type":"object"}`)}),
+		Messages: []session.Message{
+			{Role: "user", Content: "what module is this?"},
+			{Role: "assistant", ToolCalls: json.RawMessage(`[{"id":"call_1","name":"read","input":{"path":"go.mod"}}]`)},
+			{Role: "tool", ToolResults: json.RawMessage(`[{"tool_call_id":"call_1","content":"module gobot"}]`)},
+		},
+	})
+
+	data, _ := json.Marshal(req)
+	var body struct {
+		System   []map[string]any `json:"system"`
+		Tools    []map[string]any `json:"tools"`
+		Messages []struct {
+			Content []map[string]any `json:"content"`
+		} `json:"messages"`
+	}
+	if err := json.Unmarshal(data, &body); err != nil {
+		t.Fatalf("unexpected request %s: %v", data, err)
+	}
+
+	cached := func(block map[string]any) bool { return block["cache_control"] != nil }
+	if len(body.System) != 1 || body.System[0]["text"] != "be brief" || !cached(body.System[0]) {
+		t.Errorf("expected a cached system block, got %+v", body.System)
+	}
+	if cached(body.Tools[0]) || !cached(body.Tools[1]) {
+		t.Errorf("expected a breakpoint on the last tool only, got %+v", body.Tools)
+	}
+
+	// The previous user turn and the last message end cached prefixes; the rest don't
+	history := body.Messages
+	if len(history) != 3 || !cached(history[0].Content[0]) || cached(history[1].Content[0]) || !cached(history[2].Content[0]) {
+		t.Errorf("unexpected history breakpoints %s", data)
+	}
+}
@@ -29,7 +29,14 @@ type GeminiContent struct {
 
 // GeminiPart represents a part of content
 type GeminiPart struct {
-	Text string `json:"text,omitempty"`
+	Text       string      `json:"text,omitempty"`
+	InlineData *GeminiBlob `json:"inlineData,omitempty"`
+}
+
+// GeminiBlob is inline media (images, PDFs, audio) in a content part
+type GeminiBlob struct {
+	MimeType string `json:"mimeType"`
+	Data     []byte `json:"data"` // Base64 in JSON
 }
 
 // GeminiRequest represents a request to Gemini
@@ -72,7 +79,12 @@ type GeminiStreamResponse struct {
 		} `json:"content"`
 		FinishReason string `json:"finishReason"`
 	} `json:"candidates"`
-	Error *struct {
+	UsageMetadata *struct {
+		PromptTokenCount     int `json:"promptTokenCount"`
+		CandidatesTokenCount int `json:"candidatesTokenCount"`
+	} `json:"usageMetadata,omitempty"`
+	ModelVersion string `json:"modelVersion,omitempty"`
+	Error        *struct {
 		Code    int    `json:"code"`
 		Message string `json:"message"`
 	} `json:"error,omitempty"`
@@ -134,6 +146,14 @@ func (p *GeminiProvider) Stream(ctx context.Context, req *ChatRequest) (<-chan S
 				continue
 			}
 
+			if len(msg.Parts) > 0 {
+				contents = append(contents, GeminiContent{
+					Role:  role,
+					Parts: geminiParts(msg.ContentParts()),
+				})
+				continue
+			}
+
 			content := msg.Content
 			if content == "" && len(msg.ToolCalls) > 0 {
 				// Include tool calls in assistant message
@@ -246,7 +266,7 @@ func (p *GeminiProvider) Stream(ctx context.Context, req *ChatRequest) (<-chan S
 			body, _ := io.ReadAll(resp.Body)
 			resultCh <- StreamEvent{
 				Type:  EventTypeError,
-				Error: fmt.Errorf("Gemini error (%d): %s", resp.StatusCode, string(body)),
+				Error: parseGeminiError(resp.StatusCode, body),
 			}
 			return
 		}
@@ -256,6 +276,7 @@ func (p *GeminiProvider) Stream(ctx context.Context, req *ChatRequest) (<-chan S
 		scanner.Buffer(make([]byte, 1024*1024), 1024*1024)
 
 		toolCallCounter := 0
+		var usage *Usage
 
 		for scanner.Scan() {
 			select {
@@ -289,6 +310,15 @@ func (p *GeminiProvider) Stream(ctx context.Context, req *ChatRequest) (<-chan S
 				return
 			}
 
+			// Usage metadata is cumulative; keep the latest
+			if chunk.UsageMetadata != nil {
+				usage = &Usage{
+					Model:        chunk.ModelVersion,
+					InputTokens:  chunk.UsageMetadata.PromptTokenCount,
+					OutputTokens: chunk.UsageMetadata.CandidatesTokenCount,
+				}
+			}
+
 			for _, candidate := range chunk.Candidates {
 				for _, part := range candidate.Content.Parts {
 					if part.Text != "" {
@@ -312,6 +342,9 @@ func (p *GeminiProvider) Stream(ctx context.Context, req *ChatRequest) (<-chan S
 				}
 
 				if candidate.FinishReason == "STOP" || candidate.FinishReason == "MAX_TOKENS" {
+					if usage != nil {
+						resultCh <- StreamEvent{Type: EventTypeUsage, Usage: usage}
+					}
 					resultCh <- StreamEvent{Type: EventTypeDone}
 					return
 				}
@@ -329,6 +362,19 @@ func (p *GeminiProvider) Stream(ctx context.Context, req *ChatRequest) (<-chan S
 	return resultCh, nil
 }
 
+// geminiParts converts message parts to Gemini parts: media inline, text files as text
+func geminiParts(parts []session.ContentPart) []GeminiPart {
+	out := make([]GeminiPart, 0, len(parts))
+	for _, part := range parts {
+		if part.Type == session.PartText || len(part.Data) == 0 || session.IsTextMedia(part.MediaType) {
+			out = append(out, GeminiPart{Text: part.AsText()})
+			continue
+		}
+		out = append(out, GeminiPart{InlineData: &GeminiBlob{MimeType: part.MediaType, Data: part.Data}})
+	}
+	return out
+}
+
 // normalizeContents ensures proper alternating turns for Gemini
 func (p *GeminiProvider) normalizeContents(contents []GeminiContent) []GeminiContent {
 	if len(contents) == 0 {
@@ -367,3 +413,31 @@ func (p *GeminiProvider) normalizeContents(contents []GeminiContent) []GeminiCon
 
 	return normalized
 }
+
+// parseGeminiError parses an error response from the Gemini API
+func parseGeminiError(statusCode int, body []byte) error {
+	var errResp struct {
+		Error struct {
+			Message string `json:"message"`
+			Status  string `json:"status"`
+		} `json:"error"`
+	}
+	message := string(body)
+	if err := json.Unmarshal(body, &errResp); err == nil && errResp.Error.Message != "" {
+		message = errResp.Error.Message
+	}
+
+	code := errResp.Error.Status
+	switch statusCode {
+	case http.StatusTooManyRequests:
+		code = "rate_limit_exceeded"
+	case http.StatusUnauthorized, http.StatusForbidden:
+		code = "authentication_error"
+	}
+
+	return &ProviderError{
+		Code:    code,
+		Type:    errResp.Error.Status,
+		Message: fmt.Sprintf("Gemini error (%d): %s", statusCode, message),
+	}
+}
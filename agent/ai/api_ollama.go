@@ -5,31 +5,66 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"strings"
+	"sync"
 	"time"
 
 	"gobot/agent/session"
 )
 
+// errOllamaNoTools is returned when a model rejects the tools field
+var errOllamaNoTools = errors.New("model does not support tools")
+
 // OllamaProvider implements the Provider interface for Ollama (local models)
 type OllamaProvider struct {
 	baseURL string
 	model   string
 	client  *http.Client
+
+	// Models that rejected native tools and use the text tool protocol instead
+	mu        sync.Mutex
+	textTools map[string]bool
 }
 
 // OllamaMessage represents a message in Ollama format
 type OllamaMessage struct {
-	Role    string `json:"role"`
-	Content string `json:"content"`
+	Role      string           `json:"role"`
+	Content   string           `json:"content"`
+	Thinking  string           `json:"thinking,omitempty"`
+	Images    [][]byte         `json:"images,omitempty"` // Base64 in JSON, for vision models
+	ToolCalls []OllamaToolCall `json:"tool_calls,omitempty"`
+	ToolName  string           `json:"tool_name,omitempty"` // Tool results only
+}
+
+// OllamaToolCall represents a tool call made by the model
+type OllamaToolCall struct {
+	ID       string `json:"id,omitempty"` // Only set by newer servers
+	Function struct {
+		Index     int             `json:"index,omitempty"`
+		Name      string          `json:"name"`
+		Arguments json.RawMessage `json:"arguments"` // An object, not a string
+	} `json:"function"`
+}
+
+// OllamaTool describes a tool in a chat request
+type OllamaTool struct {
+	Type     string `json:"type"`
+	Function struct {
+		Name        string          `json:"name"`
+		Description string          `json:"description"`
+		Parameters  json.RawMessage `json:"parameters"`
+	} `json:"function"`
 }
 
 // OllamaRequest represents a chat request to Ollama
 type OllamaRequest struct {
 	Model    string          `json:"model"`
 	Messages []OllamaMessage `json:"messages"`
+	Tools    []OllamaTool    `json:"tools,omitempty"`
 	Stream   bool            `json:"stream"`
 	Options  *OllamaOptions  `json:"options,omitempty"`
 }
@@ -46,6 +81,10 @@ type OllamaStreamResponse struct {
 	CreatedAt string        `json:"created_at"`
 	Message   OllamaMessage `json:"message"`
 	Done      bool          `json:"done"`
+
+	// Token counts, only present on the final chunk
+	PromptEvalCount int `json:"prompt_eval_count,omitempty"`
+	EvalCount       int `json:"eval_count,omitempty"`
 }
 
 // NewOllamaProvider creates a new Ollama provider
@@ -70,123 +109,41 @@ func (p *OllamaProvider) ID() string {
 	return fmt.Sprintf("ollama-%s", p.model)
 }
 
-// Stream sends a request to Ollama and streams the response
+// Stream sends a request to Ollama and streams the response. Models that reject native
+// tools are retried with the text tool protocol, and remembered so later calls skip the retry.
 func (p *OllamaProvider) Stream(ctx context.Context, req *ChatRequest) (<-chan StreamEvent, error) {
 	resultCh := make(chan StreamEvent, 100)
 
 	go func() {
 		defer close(resultCh)
 
-		// Convert messages to Ollama format
-		messages := make([]OllamaMessage, 0, len(req.Messages)+1)
-
-		// Add system message if present
-		if req.System != "" {
-			messages = append(messages, OllamaMessage{
-				Role:    "system",
-				Content: req.System,
-			})
-		}
-
-		// Convert session messages
-		for _, msg := range req.Messages {
-			switch msg.Role {
-			case "user":
-				messages = append(messages, OllamaMessage{
-					Role:    "user",
-					Content: msg.Content,
-				})
-			case "assistant":
-				messages = append(messages, OllamaMessage{
-					Role:    "assistant",
-					Content: msg.Content,
-				})
-			case "system":
-				messages = append(messages, OllamaMessage{
-					Role:    "system",
-					Content: msg.Content,
-				})
-			case "tool":
-				// Ollama doesn't have native tool support, include as user message
-				if len(msg.ToolResults) > 0 {
-					var results []session.ToolResult
-					json.Unmarshal(msg.ToolResults, &results)
-					for _, r := range results {
-						content := fmt.Sprintf("[Tool Result]\n%s", r.Content)
-						messages = append(messages, OllamaMessage{
-							Role:    "user",
-							Content: content,
-						})
-					}
-				}
-			}
-		}
-
 		// Use request model override if provided, otherwise use provider default
 		model := p.model
 		if req.Model != "" {
 			model = req.Model
 		}
 
-		// Build request
-		ollamaReq := OllamaRequest{
-			Model:    model,
-			Messages: messages,
-			Stream:   true,
-		}
-
-		if req.Temperature > 0 {
-			ollamaReq.Options = &OllamaOptions{
-				Temperature: req.Temperature,
-			}
+		textTools := len(req.Tools) > 0 && p.usesTextTools(model)
+		resp, err := p.send(ctx, p.buildRequest(req, model, textTools))
+		if errors.Is(err, errOllamaNoTools) && !textTools {
+			p.setTextTools(model)
+			textTools = true
+			resp, err = p.send(ctx, p.buildRequest(req, model, textTools))
 		}
-		if req.MaxTokens > 0 {
-			if ollamaReq.Options == nil {
-				ollamaReq.Options = &OllamaOptions{}
-			}
-			ollamaReq.Options.NumPredict = req.MaxTokens
-		}
-
-		body, err := json.Marshal(ollamaReq)
 		if err != nil {
 			resultCh <- StreamEvent{
 				Type:  EventTypeError,
-				Error: fmt.Errorf("failed to marshal request: %w", err),
-			}
-			return
-		}
-
-		// Make HTTP request
-		httpReq, err := http.NewRequestWithContext(ctx, "POST", p.baseURL+"/api/chat", bytes.NewReader(body))
-		if err != nil {
-			resultCh <- StreamEvent{
-				Type:  EventTypeError,
-				Error: fmt.Errorf("failed to create request: %w", err),
-			}
-			return
-		}
-		httpReq.Header.Set("Content-Type", "application/json")
-
-		resp, err := p.client.Do(httpReq)
-		if err != nil {
-			resultCh <- StreamEvent{
-				Type:  EventTypeError,
-				Error: fmt.Errorf("request failed: %w", err),
+				Error: err,
 			}
 			return
 		}
 		defer resp.Body.Close()
 
-		if resp.StatusCode != http.StatusOK {
-			body, _ := io.ReadAll(resp.Body)
-			resultCh <- StreamEvent{
-				Type:  EventTypeError,
-				Error: fmt.Errorf("Ollama error (%d): %s", resp.StatusCode, string(body)),
-			}
-			return
-		}
-
 		// Stream response
+		var parser *textToolParser
+		if textTools {
+			parser = &textToolParser{}
+		}
 		scanner := bufio.NewScanner(resp.Body)
 		scanner.Buffer(make([]byte, 1024*1024), 1024*1024)
 
@@ -207,14 +164,41 @@ func (p *OllamaProvider) Stream(ctx context.Context, req *ChatRequest) (<-chan S
 				continue
 			}
 
+			if chunk.Message.Thinking != "" {
+				resultCh <- StreamEvent{
+					Type: EventTypeThinking,
+					Text: chunk.Message.Thinking,
+				}
+			}
+
 			if chunk.Message.Content != "" {
+				text, calls := chunk.Message.Content, []*ToolCall(nil)
+				if parser != nil {
+					text, calls = parser.Feed(text)
+				}
+				emitOllamaOutput(resultCh, text, calls)
+			}
+
+			for _, tc := range chunk.Message.ToolCalls {
 				resultCh <- StreamEvent{
-					Type: EventTypeText,
-					Text: chunk.Message.Content,
+					Type:     EventTypeToolCall,
+					ToolCall: convertOllamaToolCall(tc),
 				}
 			}
 
 			if chunk.Done {
+				if parser != nil {
+					text, calls := parser.Flush()
+					emitOllamaOutput(resultCh, text, calls)
+				}
+				resultCh <- StreamEvent{
+					Type: EventTypeUsage,
+					Usage: &Usage{
+						Model:        chunk.Model,
+						InputTokens:  chunk.PromptEvalCount,
+						OutputTokens: chunk.EvalCount,
+					},
+				}
 				resultCh <- StreamEvent{Type: EventTypeDone}
 				return
 			}
@@ -231,6 +215,198 @@ func (p *OllamaProvider) Stream(ctx context.Context, req *ChatRequest) (<-chan S
 	return resultCh, nil
 }
 
+// send posts a chat request and returns the streaming response
+func (p *OllamaProvider) send(ctx context.Context, ollamaReq OllamaRequest) (*http.Response, error) {
+	body, err := json.Marshal(ollamaReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", p.baseURL+"/api/chat", bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		if resp.StatusCode == http.StatusBadRequest && len(ollamaReq.Tools) > 0 && bytes.Contains(body, []byte("does not support tools")) {
+			return nil, errOllamaNoTools
+		}
+		return nil, fmt.Errorf("Ollama error (%d): %s", resp.StatusCode, string(body))
+	}
+	return resp, nil
+}
+
+// buildRequest converts a ChatRequest to Ollama format, describing tools in the system
+// prompt instead of the tools field when textTools is set
+func (p *OllamaProvider) buildRequest(req *ChatRequest, model string, textTools bool) OllamaRequest {
+	messages := make([]OllamaMessage, 0, len(req.Messages)+1)
+
+	system := req.System
+	if textTools {
+		system = strings.TrimSpace(system + "\n\n" + textToolPrompt(req.Tools))
+	}
+	if system != "" {
+		messages = append(messages, OllamaMessage{
+			Role:    "system",
+			Content: system,
+		})
+	}
+
+	// Tool results carry the tool's name rather than the call ID
+	toolNames := make(map[string]string)
+
+	for _, msg := range req.Messages {
+		switch msg.Role {
+		case "user", "system":
+			out := OllamaMessage{
+				Role:    msg.Role,
+				Content: msg.Content,
+			}
+			// Images go alongside the text; other attachments are inlined as text
+			for _, part := range msg.Parts {
+				if part.Type == session.PartImage && len(part.Data) > 0 {
+					out.Images = append(out.Images, part.Data)
+				} else {
+					out.Content = strings.TrimSpace(out.Content + "\n\n" + part.AsText())
+				}
+			}
+			messages = append(messages, out)
+		case "assistant":
+			out := OllamaMessage{
+				Role:    "assistant",
+				Content: msg.Content,
+			}
+			var calls []session.ToolCall
+			if len(msg.ToolCalls) > 0 {
+				json.Unmarshal(msg.ToolCalls, &calls)
+			}
+			for _, call := range calls {
+				toolNames[call.ID] = call.Name
+				if textTools {
+					out.Content = strings.TrimSpace(out.Content + "\n" + textToolCallText(call))
+					continue
+				}
+				var tc OllamaToolCall
+				tc.Function.Name = call.Name
+				tc.Function.Arguments = call.Input
+				if len(tc.Function.Arguments) == 0 {
+					tc.Function.Arguments = json.RawMessage("{}")
+				}
+				out.ToolCalls = append(out.ToolCalls, tc)
+			}
+			messages = append(messages, out)
+		case "tool":
+			var results []session.ToolResult
+			if len(msg.ToolResults) > 0 {
+				json.Unmarshal(msg.ToolResults, &results)
+			}
+			if textTools {
+				parts := make([]string, 0, len(results))
+				for _, r := range results {
+					parts = append(parts, textToolResultText(toolNames[r.ToolCallID], r))
+				}
+				messages = append(messages, OllamaMessage{
+					Role:    "user",
+					Content: strings.Join(parts, "\n\n"),
+				})
+				continue
+			}
+			for _, r := range results {
+				messages = append(messages, OllamaMessage{
+					Role:     "tool",
+					Content:  r.Content,
+					ToolName: toolNames[r.ToolCallID],
+				})
+			}
+		}
+	}
+
+	ollamaReq := OllamaRequest{
+		Model:    model,
+		Messages: messages,
+		Stream:   true,
+	}
+
+	if !textTools {
+		for _, tool := range req.Tools {
+			var t OllamaTool
+			t.Type = "function"
+			t.Function.Name = tool.Name
+			t.Function.Description = tool.Description
+			t.Function.Parameters = tool.InputSchema
+			ollamaReq.Tools = append(ollamaReq.Tools, t)
+		}
+	}
+
+	if req.Temperature > 0 {
+		ollamaReq.Options = &OllamaOptions{
+			Temperature: req.Temperature,
+		}
+	}
+	if req.MaxTokens > 0 {
+		if ollamaReq.Options == nil {
+			ollamaReq.Options = &OllamaOptions{}
+		}
+		ollamaReq.Options.NumPredict = req.MaxTokens
+	}
+
+	return ollamaReq
+}
+
+// usesTextTools reports whether a model is known to lack native tool support
+func (p *OllamaProvider) usesTextTools(model string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.textTools[model]
+}
+
+// setTextTools remembers that a model lacks native tool support
+func (p *OllamaProvider) setTextTools(model string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.textTools == nil {
+		p.textTools = make(map[string]bool)
+	}
+	p.textTools[model] = true
+}
+
+// convertOllamaToolCall converts a tool call from a response, assigning an ID if the server didn't
+func convertOllamaToolCall(tc OllamaToolCall) *ToolCall {
+	id := tc.ID
+	if id == "" {
+		id = newToolCallID()
+	}
+	input := tc.Function.Arguments
+	if len(input) == 0 || string(input) == "null" {
+		input = json.RawMessage("{}")
+	}
+	return &ToolCall{ID: id, Name: tc.Function.Name, Input: input}
+}
+
+// emitOllamaOutput sends streamed text and tool calls parsed from it
+func emitOllamaOutput(ch chan<- StreamEvent, text string, calls []*ToolCall) {
+	if text != "" {
+		ch <- StreamEvent{
+			Type: EventTypeText,
+			Text: text,
+		}
+	}
+	for _, call := range calls {
+		ch <- StreamEvent{
+			Type:     EventTypeToolCall,
+			ToolCall: call,
+		}
+	}
+}
+
 // CheckOllamaAvailable checks if Ollama is running
 func CheckOllamaAvailable(baseURL string) bool {
 	if baseURL == "" {
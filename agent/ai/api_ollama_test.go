@@ -0,0 +1,192 @@
+package ai
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"gobot/agent/session"
+)
+
+// ollamaServer serves /api/chat, replying to each request with the next list of NDJSON chunks.
+// A model named "no-tools" rejects requests with tools, as Ollama does for such models.
+func ollamaServer(t *testing.T, replies [][]string, requests chan<- OllamaRequest) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req OllamaRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		requests <- req
+
+		if req.Model == "no-tools" && len(req.Tools) > 0 {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"error":"registry.ollama.ai/library/no-tools:latest does not support tools"}`)
+			return
+		}
+		if len(replies) == 0 {
+			t.Errorf("unexpected request %+v", req)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		for _, chunk := range replies[0] {
+			fmt.Fprintln(w, chunk)
+		}
+		replies = replies[1:]
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// ollamaHistory is a conversation with one finished tool call
+var ollamaHistory = []session.Message{
+	{Role: "user", Content: "what module is this?"},
+	{Role: "assistant", Content: "Let me look.", ToolCalls: json.RawMessage(`[{"id":"call_1","name":"read","input":{"path":"go.mod"}}]`)},
+	{Role: "tool", ToolResults: json.RawMessage(`[{"tool_call_id":"call_1","content":"module gobot"}]`)},
+}
+
+var ollamaTools = []ToolDefinition{{
+	Name:        "read",
+	Description: "Read a file",
+	InputSchema: json.RawMessage(`{"type": "object", "properties": {"path": {"type": "string"}}}`),
+}}
+
+func TestOllamaNativeTools(t *testing.T) {
+	requests := make(chan OllamaRequest, 1)
+	srv := ollamaServer(t, [][]string{{
+		`{"model":"qwen3","message":{"role":"assistant","content":"","thinking":"Need the file."},"done":false}`,
+		`{"model":"qwen3","message":{"role":"assistant","content":"","tool_calls":[{"function":{"name":"read","arguments":{"path":"main.go"}}}]},"done":false}`,
+		`{"model":"qwen3","message":{"role":"assistant","content":"Done."},"done":false}`,
+		`{"model":"qwen3","message":{"role":"assistant","content":""},"done":true,"prompt_eval_count":30,"eval_count":5}`,
+	}}, requests)
+
+	p := NewOllamaProvider(srv.URL, "qwen3")
+	events, err := collect(t, p, &ChatRequest{System: "be brief", Messages: ollamaHistory, Tools: ollamaTools})
+	if err != nil {
+		t.Fatalf("Stream failed: %v", err)
+	}
+
+	req := <-requests
+	if len(req.Tools) != 1 || req.Tools[0].Type != "function" || req.Tools[0].Function.Name != "read" {
+		t.Errorf("expected the read tool in the request, got %+v", req.Tools)
+	}
+	if len(req.Messages) != 4 {
+		t.Fatalf("expected 4 messages, got %+v", req.Messages)
+	}
+	assistant, tool := req.Messages[2], req.Messages[3]
+	if len(assistant.ToolCalls) != 1 || assistant.ToolCalls[0].Function.Name != "read" || string(assistant.ToolCalls[0].Function.Arguments) != `{"path":"go.mod"}` {
+		t.Errorf("unexpected assistant message %+v", assistant)
+	}
+	if tool.Role != "tool" || tool.ToolName != "read" || tool.Content != "module gobot" {
+		t.Errorf("unexpected tool message %+v", tool)
+	}
+
+	var thinking, text string
+	var calls []*ToolCall
+	for _, e := range events {
+		switch e.Type {
+		case EventTypeThinking:
+			thinking += e.Text
+		case EventTypeText:
+			text += e.Text
+		case EventTypeToolCall:
+			calls = append(calls, e.ToolCall)
+		}
+	}
+	if thinking != "Need the file." || text != "Done." {
+		t.Errorf("unexpected thinking %q and text %q", thinking, text)
+	}
+	if len(calls) != 1 || calls[0].Name != "read" || string(calls[0].Input) != `{"path":"main.go"}` || !strings.HasPrefix(calls[0].ID, "call_") {
+		t.Errorf("unexpected tool calls %+v", calls)
+	}
+	if last := events[len(events)-1]; last.Type != EventTypeDone {
+		t.Errorf("expected the stream to end with done, got %s", last.Type)
+	}
+}
+
+func TestOllamaTextToolFallback(t *testing.T) {
+	requests := make(chan OllamaRequest, 3)
+	reply := []string{
+		`{"model":"no-tools","message":{"role":"assistant","content":"Checking. <tool"},"done":false}`,
+		`{"model":"no-tools","message":{"role":"assistant","content":"_call>\n{\"name\": \"read\", \"arguments\": {\"path\": "},"done":false}`,
+		`{"model":"no-tools","message":{"role":"assistant","content":"\"main.go\"}}\n</tool_call>"},"done":false}`,
+		`{"model":"no-tools","message":{"role":"assistant","content":""},"done":true,"prompt_eval_count":80,"eval_count":20}`,
+	}
+	srv := ollamaServer(t, [][]string{reply, reply}, requests)
+
+	p := NewOllamaProvider(srv.URL, "no-tools")
+	req := &ChatRequest{System: "be brief", Messages: ollamaHistory, Tools: ollamaTools}
+	events, err := collect(t, p, req)
+	if err != nil {
+		t.Fatalf("Stream failed: %v", err)
+	}
+
+	// The first request is rejected and retried with the tools in the system prompt
+	if first := <-requests; len(first.Tools) != 1 {
+		t.Fatalf("expected the first request to offer native tools, got %+v", first)
+	}
+	retry := <-requests
+	if len(retry.Tools) != 0 {
+		t.Errorf("expected no native tools in the retry, got %+v", retry.Tools)
+	}
+	system := retry.Messages[0].Content
+	if !strings.HasPrefix(system, "be brief") || !strings.Contains(system, "### read") || !strings.Contains(system, "<tool_call>") {
+		t.Errorf("expected the tools in the system prompt, got %q", system)
+	}
+	if got := retry.Messages[2].Content; !strings.Contains(got, "Let me look.\n<tool_call>") || !strings.Contains(got, `"path":"go.mod"`) {
+		t.Errorf("expected the tool call as text, got %q", got)
+	}
+	if got := retry.Messages[3]; got.Role != "user" || got.Content != "<tool_result name=\"read\">\nmodule gobot\n</tool_result>" {
+		t.Errorf("expected the tool result as a user message, got %+v", got)
+	}
+
+	var text string
+	var calls []*ToolCall
+	for _, e := range events {
+		switch e.Type {
+		case EventTypeText:
+			text += e.Text
+		case EventTypeToolCall:
+			calls = append(calls, e.ToolCall)
+		case EventTypeError:
+			t.Fatalf("unexpected error event: %v", e.Error)
+		}
+	}
+	if text != "Checking. " {
+		t.Errorf("expected the tool call to be removed from the text, got %q", text)
+	}
+	if len(calls) != 1 || calls[0].Name != "read" || string(calls[0].Input) != `{"path": "main.go"}` {
+		t.Errorf("unexpected tool calls %+v", calls)
+	}
+
+	// The model is remembered, so the next request goes straight to the text protocol
+	if _, err := collect(t, p, req); err != nil {
+		t.Fatalf("second Stream failed: %v", err)
+	}
+	if next := <-requests; len(next.Tools) != 0 {
+		t.Errorf("expected the text protocol without a retry, got %+v", next.Tools)
+	}
+}
+
+func TestTextToolParser(t *testing.T) {
+	var p textToolParser
+	var text string
+	var calls []*ToolCall
+	for _, chunk := range []string{"a < b, ", "<tool_call>not json</tool_call> ", "<tool_call>{\"name\":\"ls\"}"} {
+		out, c := p.Feed(chunk)
+		text += out
+		calls = append(calls, c...)
+	}
+	rest, c := p.Flush()
+	text += rest
+	calls = append(calls, c...)
+
+	// Malformed calls stay in the text; an unclosed call at the end still counts
+	if text != "a < b, <tool_call>not json</tool_call> " {
+		t.Errorf("unexpected text %q", text)
+	}
+	if len(calls) != 1 || calls[0].Name != "ls" || string(calls[0].Input) != "{}" {
+		t.Errorf("unexpected calls %+v", calls)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"bufio"
 	"bytes"
 	"context"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -14,29 +15,105 @@ import (
 )
 
 const (
-	openaiAPIURL = "https://api.openai.com/v1/chat/completions"
+	openaiBaseURL = "https://api.openai.com/v1"
 )
 
-// OpenAIProvider implements the OpenAI API
+// OpenAIProvider implements the OpenAI API, and any OpenAI-compatible chat completions API
+// (vLLM, LM Studio, OpenRouter, Groq, DeepSeek, gateways) at another base URL
 type OpenAIProvider struct {
-	apiKey string
-	model  string
-	client *http.Client
+	id         string
+	baseURL    string // API root including the version, e.g. https://api.openai.com/v1
+	apiKey     string // Optional for local servers
+	model      string
+	headers    map[string]string // Extra headers sent with every request
+	compatible bool              // Not api.openai.com: send max_tokens rather than max_completion_tokens
+	client     *http.Client
 }
 
 // NewOpenAIProvider creates a new OpenAI provider
 // Model should be provided from models.yaml config - do NOT hardcode model IDs
 func NewOpenAIProvider(apiKey, model string) *OpenAIProvider {
 	return &OpenAIProvider{
-		apiKey: apiKey,
-		model:  model,
-		client: &http.Client{},
+		id:      "openai",
+		baseURL: openaiBaseURL,
+		apiKey:  apiKey,
+		model:   model,
+		client:  &http.Client{},
+	}
+}
+
+// NewOpenAICompatibleProvider creates a provider for an OpenAI-compatible API. id is the
+// provider name used in "provider/model" IDs; baseURL is the API root, usually ending in /v1.
+func NewOpenAICompatibleProvider(id, baseURL, apiKey, model string, headers map[string]string) *OpenAIProvider {
+	return &OpenAIProvider{
+		id:         id,
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		apiKey:     apiKey,
+		model:      model,
+		headers:    headers,
+		compatible: true,
+		client:     &http.Client{},
 	}
 }
 
 // ID returns the provider identifier
 func (p *OpenAIProvider) ID() string {
-	return "openai"
+	return p.id
+}
+
+// Model returns the default model
+func (p *OpenAIProvider) Model() string {
+	return p.model
+}
+
+// SetModel sets the default model, e.g. to one found with ListModels
+func (p *OpenAIProvider) SetModel(model string) {
+	p.model = model
+}
+
+// setHeaders adds authentication and the configured extra headers to a request
+func (p *OpenAIProvider) setHeaders(req *http.Request) {
+	if p.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+p.apiKey)
+	}
+	for name, value := range p.headers {
+		req.Header.Set(name, value)
+	}
+}
+
+// ListModels returns the IDs of the models the API serves, from GET /models
+func (p *OpenAIProvider) ListModels(ctx context.Context) ([]string, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", p.baseURL+"/models", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	p.setHeaders(req)
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, parseOpenAIError(resp.StatusCode, body)
+	}
+
+	var data struct {
+		Data []struct {
+			ID string `json:"id"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
+		return nil, fmt.Errorf("failed to decode model list: %w", err)
+	}
+
+	models := make([]string, 0, len(data.Data))
+	for _, m := range data.Data {
+		models = append(models, m.ID)
+	}
+	return models, nil
 }
 
 // Stream sends a request and returns streaming events
@@ -49,13 +126,13 @@ func (p *OpenAIProvider) Stream(ctx context.Context, req *ChatRequest) (<-chan S
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	httpReq, err := http.NewRequestWithContext(ctx, "POST", openaiAPIURL, bytes.NewReader(body))
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", p.baseURL+"/chat/completions", bytes.NewReader(body))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
 	httpReq.Header.Set("Content-Type", "application/json")
-	httpReq.Header.Set("Authorization", "Bearer "+p.apiKey)
+	p.setHeaders(httpReq)
 
 	resp, err := p.client.Do(httpReq)
 	if err != nil {
@@ -103,10 +180,17 @@ func (p *OpenAIProvider) buildRequest(req *ChatRequest) map[string]any {
 		"model":    model,
 		"messages": messages,
 		"stream":   true,
+		// Ask for a final chunk with token usage
+		"stream_options": map[string]any{"include_usage": true},
 	}
 
 	if req.MaxTokens > 0 {
-		result["max_completion_tokens"] = req.MaxTokens
+		// Most compatible servers only know the older parameter name
+		if p.compatible {
+			result["max_tokens"] = req.MaxTokens
+		} else {
+			result["max_completion_tokens"] = req.MaxTokens
+		}
 	}
 
 	if len(req.Tools) > 0 {
@@ -131,6 +215,12 @@ func (p *OpenAIProvider) buildRequest(req *ChatRequest) map[string]any {
 func (p *OpenAIProvider) convertMessage(msg session.Message) map[string]any {
 	switch msg.Role {
 	case "user":
+		if len(msg.Parts) > 0 {
+			return map[string]any{
+				"role":    "user",
+				"content": openaiContent(msg.ContentParts()),
+			}
+		}
 		return map[string]any{
 			"role":    "user",
 			"content": msg.Content,
@@ -189,6 +279,52 @@ func (p *OpenAIProvider) convertMessage(msg session.Message) map[string]any {
 	return nil
 }
 
+// openaiContent converts message parts to content parts: images and PDFs as data URLs,
+// WAV and MP3 audio as input_audio, text files inline
+func openaiContent(parts []session.ContentPart) []map[string]any {
+	content := make([]map[string]any, 0, len(parts))
+	for _, part := range parts {
+		encoded := base64.StdEncoding.EncodeToString(part.Data)
+		dataURL := "data:" + part.MediaType + ";base64," + encoded
+		switch {
+		case len(part.Data) == 0 || part.Type == session.PartText:
+			content = append(content, map[string]any{"type": "text", "text": part.AsText()})
+		case part.Type == session.PartImage:
+			content = append(content, map[string]any{
+				"type":      "image_url",
+				"image_url": map[string]any{"url": dataURL},
+			})
+		case part.Type == session.PartDocument && part.MediaType == "application/pdf":
+			content = append(content, map[string]any{
+				"type": "file",
+				"file": map[string]any{"filename": part.Name, "file_data": dataURL},
+			})
+		case part.Type == session.PartAudio && openaiAudioFormat(part.MediaType) != "":
+			content = append(content, map[string]any{
+				"type": "input_audio",
+				"input_audio": map[string]any{
+					"data":   encoded,
+					"format": openaiAudioFormat(part.MediaType),
+				},
+			})
+		default:
+			content = append(content, map[string]any{"type": "text", "text": part.AsText()})
+		}
+	}
+	return content
+}
+
+// openaiAudioFormat returns the input_audio format for a media type, or "" if unsupported
+func openaiAudioFormat(mediaType string) string {
+	switch mediaType {
+	case "audio/wav", "audio/x-wav", "audio/wave":
+		return "wav"
+	case "audio/mpeg", "audio/mp3":
+		return "mp3"
+	}
+	return ""
+}
+
 // streamResponse reads SSE events and sends them to the channel
 func (p *OpenAIProvider) streamResponse(ctx context.Context, resp *http.Response, events chan<- StreamEvent) {
 	defer close(events)
@@ -196,7 +332,9 @@ func (p *OpenAIProvider) streamResponse(ctx context.Context, resp *http.Response
 
 	reader := bufio.NewReader(resp.Body)
 	var currentToolCall *ToolCall
+	var currentIndex int
 	var argsBuffer strings.Builder
+	var usage *Usage
 
 	for {
 		select {
@@ -230,6 +368,16 @@ func (p *OpenAIProvider) streamResponse(ctx context.Context, resp *http.Response
 			continue
 		}
 
+		// Usage arrives in a final chunk with no choices
+		if chunk.Usage != nil {
+			usage = &Usage{
+				Model:           chunk.Model,
+				InputTokens:     chunk.Usage.PromptTokens,
+				OutputTokens:    chunk.Usage.CompletionTokens,
+				CacheReadTokens: chunk.Usage.PromptTokensDetails.CachedTokens,
+			}
+		}
+
 		if len(chunk.Choices) == 0 {
 			continue
 		}
@@ -237,6 +385,14 @@ func (p *OpenAIProvider) streamResponse(ctx context.Context, resp *http.Response
 		choice := chunk.Choices[0]
 		delta := choice.Delta
 
+		// Reasoning models on compatible APIs (e.g. deepseek-reasoner) stream their thinking separately
+		if delta.ReasoningContent != "" {
+			events <- StreamEvent{
+				Type: EventTypeThinking,
+				Text: delta.ReasoningContent,
+			}
+		}
+
 		// Handle content
 		if delta.Content != "" {
 			events <- StreamEvent{
@@ -248,21 +404,26 @@ func (p *OpenAIProvider) streamResponse(ctx context.Context, resp *http.Response
 		// Handle tool calls
 		if len(delta.ToolCalls) > 0 {
 			for _, tc := range delta.ToolCalls {
-				if tc.ID != "" {
+				// A new call has a new index; some servers repeat the ID on every chunk
+				if currentToolCall == nil || (tc.ID != "" && tc.ID != currentToolCall.ID) || (tc.Index != nil && *tc.Index != currentIndex) {
+					if tc.Index != nil {
+						currentIndex = *tc.Index
+					}
 					// New tool call starting
 					if currentToolCall != nil {
 						// Finish previous tool call
-						currentToolCall.Input = json.RawMessage(argsBuffer.String())
-						events <- StreamEvent{
-							Type:     EventTypeToolCall,
-							ToolCall: currentToolCall,
-						}
+						events <- finishOpenAIToolCall(currentToolCall, &argsBuffer)
 					}
 					currentToolCall = &ToolCall{
 						ID:   tc.ID,
 						Name: tc.Function.Name,
 					}
+					if currentToolCall.ID == "" {
+						currentToolCall.ID = fmt.Sprintf("call_%d", currentIndex)
+					}
 					argsBuffer.Reset()
+				} else if currentToolCall.Name == "" {
+					currentToolCall.Name = tc.Function.Name
 				}
 				if tc.Function.Arguments != "" {
 					argsBuffer.WriteString(tc.Function.Arguments)
@@ -270,26 +431,43 @@ func (p *OpenAIProvider) streamResponse(ctx context.Context, resp *http.Response
 			}
 		}
 
-		// Handle finish reason
-		if choice.FinishReason == "tool_calls" && currentToolCall != nil {
-			currentToolCall.Input = json.RawMessage(argsBuffer.String())
-			events <- StreamEvent{
-				Type:     EventTypeToolCall,
-				ToolCall: currentToolCall,
-			}
+		// Handle finish reason; some servers finish tool calls with "stop"
+		if choice.FinishReason != "" && currentToolCall != nil {
+			events <- finishOpenAIToolCall(currentToolCall, &argsBuffer)
 			currentToolCall = nil
 		}
 	}
 
+	// Some servers end the stream without a finish reason
+	if currentToolCall != nil {
+		events <- finishOpenAIToolCall(currentToolCall, &argsBuffer)
+	}
+
+	if usage != nil {
+		events <- StreamEvent{Type: EventTypeUsage, Usage: usage}
+	}
 	events <- StreamEvent{Type: EventTypeDone}
 }
 
+// finishOpenAIToolCall completes a streamed tool call with its accumulated arguments
+func finishOpenAIToolCall(call *ToolCall, args *strings.Builder) StreamEvent {
+	call.Input = json.RawMessage(args.String())
+	if args.Len() == 0 {
+		call.Input = json.RawMessage("{}")
+	}
+	return StreamEvent{Type: EventTypeToolCall, ToolCall: call}
+}
+
 // openaiStreamChunk represents a streaming chunk from OpenAI
 type openaiStreamChunk struct {
+	Model   string       `json:"model,omitempty"`
+	Usage   *openaiUsage `json:"usage,omitempty"`
 	Choices []struct {
 		Delta struct {
-			Content   string `json:"content,omitempty"`
-			ToolCalls []struct {
+			Content          string `json:"content,omitempty"`
+			ReasoningContent string `json:"reasoning_content,omitempty"`
+			ToolCalls        []struct {
+				Index    *int   `json:"index,omitempty"`
 				ID       string `json:"id,omitempty"`
 				Function struct {
 					Name      string `json:"name,omitempty"`
@@ -301,6 +479,15 @@ type openaiStreamChunk struct {
 	} `json:"choices"`
 }
 
+// openaiUsage represents token usage reported at the end of a stream
+type openaiUsage struct {
+	PromptTokens        int `json:"prompt_tokens"`
+	CompletionTokens    int `json:"completion_tokens"`
+	PromptTokensDetails struct {
+		CachedTokens int `json:"cached_tokens"` // Included in PromptTokens; caching is automatic
+	} `json:"prompt_tokens_details"`
+}
+
 // parseOpenAIError parses an error response from the OpenAI API
 func parseOpenAIError(statusCode int, body []byte) error {
 	var errResp struct {
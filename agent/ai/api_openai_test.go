@@ -0,0 +1,137 @@
+package ai
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"gobot/agent/session"
+)
+
+// openaiSSEServer serves a chat completions stream of chunks and a model list under /v1
+func openaiSSEServer(t *testing.T, chunks []string, requests chan<- map[string]any) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer sk-test" || r.Header.Get("X-Team") != "agents" {
+			w.WriteHeader(http.StatusUnauthorized)
+			fmt.Fprint(w, `{"error":{"type":"invalid_request_error","message":"bad credentials"}}`)
+			return
+		}
+
+		switch r.URL.Path {
+		case "/v1/models":
+			fmt.Fprint(w, `{"object":"list","data":[{"id":"llama-3.3-70b","object":"model"},{"id":"qwen3-coder","object":"model"}]}`)
+		case "/v1/chat/completions":
+			var body map[string]any
+			json.NewDecoder(r.Body).Decode(&body)
+			requests <- body
+
+			w.Header().Set("Content-Type", "text/event-stream")
+			for _, chunk := range chunks {
+				fmt.Fprintf(w, "data: %s\n\n", chunk)
+				w.(http.Flusher).Flush()
+			}
+			fmt.Fprint(w, "data: [DONE]\n\n")
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestOpenAICompatibleStream(t *testing.T) {
+	requests := make(chan map[string]any, 1)
+	srv := openaiSSEServer(t, []string{
+		`{"model":"llama-3.3-70b","choices":[{"delta":{"reasoning_content":"Checking the files."}}]}`,
+		`{"model":"llama-3.3-70b","choices":[{"delta":{"content":"Let me "}}]}`,
+		`{"model":"llama-3.3-70b","choices":[{"delta":{"content":"look."}}]}`,
+		// The ID is repeated on every chunk of a call, as some servers do
+		`{"model":"llama-3.3-70b","choices":[{"delta":{"tool_calls":[{"index":0,"id":"call_a","function":{"name":"read","arguments":"{\"path\":"}}]}}]}`,
+		`{"model":"llama-3.3-70b","choices":[{"delta":{"tool_calls":[{"index":0,"id":"call_a","function":{"arguments":"\"go.mod\"}"}}]}}]}`,
+		// A second call identified only by its index
+		`{"model":"llama-3.3-70b","choices":[{"delta":{"tool_calls":[{"index":1,"function":{"name":"glob","arguments":"{\"pattern\":\"*.go\"}"}}]}}]}`,
+		`{"model":"llama-3.3-70b","choices":[{"delta":{},"finish_reason":"stop"}]}`,
+		`{"model":"llama-3.3-70b","choices":[],"usage":{"prompt_tokens":42,"completion_tokens":7}}`,
+	}, requests)
+
+	p := NewOpenAICompatibleProvider("local", srv.URL+"/v1/", "sk-test", "llama-3.3-70b", map[string]string{"X-Team": "agents"})
+	if p.ID() != "local" {
+		t.Errorf("expected provider ID local, got %s", p.ID())
+	}
+
+	events, err := collect(t, p, &ChatRequest{
+		System:    "be brief",
+		MaxTokens: 256,
+		Messages:  []session.Message{{Role: "user", Content: "what module is this?"}},
+	})
+	if err != nil {
+		t.Fatalf("Stream failed: %v", err)
+	}
+
+	body := <-requests
+	if body["model"] != "llama-3.3-70b" || body["max_tokens"] != float64(256) || body["max_completion_tokens"] != nil {
+		t.Errorf("unexpected request body %v", body)
+	}
+
+	var thinking, text strings.Builder
+	var calls []*ToolCall
+	var usage *Usage
+	for _, e := range events {
+		switch e.Type {
+		case EventTypeThinking:
+			thinking.WriteString(e.Text)
+		case EventTypeText:
+			text.WriteString(e.Text)
+		case EventTypeToolCall:
+			calls = append(calls, e.ToolCall)
+		case EventTypeUsage:
+			usage = e.Usage
+		case EventTypeError:
+			t.Fatalf("unexpected error event: %v", e.Error)
+		}
+	}
+	if thinking.String() != "Checking the files." || text.String() != "Let me look." {
+		t.Errorf("unexpected thinking %q and text %q", thinking.String(), text.String())
+	}
+	if len(calls) != 2 {
+		t.Fatalf("expected 2 tool calls, got %d", len(calls))
+	}
+	if calls[0].ID != "call_a" || calls[0].Name != "read" || string(calls[0].Input) != `{"path":"go.mod"}` {
+		t.Errorf("unexpected first call %+v (input %s)", calls[0], calls[0].Input)
+	}
+	if calls[1].ID != "call_1" || calls[1].Name != "glob" || string(calls[1].Input) != `{"pattern":"*.go"}` {
+		t.Errorf("unexpected second call %+v (input %s)", calls[1], calls[1].Input)
+	}
+	if usage == nil || usage.InputTokens != 42 || usage.OutputTokens != 7 || usage.Model != "llama-3.3-70b" {
+		t.Errorf("unexpected usage %+v", usage)
+	}
+	if last := events[len(events)-1]; last.Type != EventTypeDone {
+		t.Errorf("expected the stream to end with done, got %s", last.Type)
+	}
+}
+
+func TestOpenAICompatibleListModels(t *testing.T) {
+	srv := openaiSSEServer(t, nil, nil)
+
+	p := NewOpenAICompatibleProvider("local", srv.URL+"/v1", "sk-test", "", map[string]string{"X-Team": "agents"})
+	models, err := p.ListModels(t.Context())
+	if err != nil {
+		t.Fatalf("ListModels failed: %v", err)
+	}
+	if strings.Join(models, ",") != "llama-3.3-70b,qwen3-coder" {
+		t.Errorf("unexpected models %v", models)
+	}
+
+	// Missing extra headers are rejected by the server
+	p = NewOpenAICompatibleProvider("local", srv.URL+"/v1", "sk-test", "", nil)
+	if _, err := p.ListModels(t.Context()); err == nil || !strings.Contains(err.Error(), "bad credentials") {
+		t.Errorf("expected an authentication error, got %v", err)
+	}
+	if _, err := p.Stream(t.Context(), &ChatRequest{Messages: []session.Message{{Role: "user", Content: "hi"}}}); err == nil {
+		t.Error("expected Stream to fail without the extra header")
+	}
+}
@@ -317,7 +317,11 @@ func buildPromptFromMessages(messages []session.Message) string {
 		case "system":
 			parts = append(parts, fmt.Sprintf("[System]\n%s", msg.Content))
 		case "user":
-			parts = append(parts, fmt.Sprintf("[User]\n%s", msg.Content))
+			content := msg.Content
+			for _, part := range msg.Parts {
+				content = strings.TrimSpace(content + "\n\n" + part.AsText())
+			}
+			parts = append(parts, fmt.Sprintf("[User]\n%s", content))
 		case "assistant":
 			parts = append(parts, fmt.Sprintf("[Assistant]\n%s", msg.Content))
 		case "tool":
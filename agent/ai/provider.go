@@ -17,6 +17,7 @@ const (
 	EventTypeError      StreamEventType = "error"
 	EventTypeDone       StreamEventType = "done"
 	EventTypeThinking   StreamEventType = "thinking"
+	EventTypeUsage      StreamEventType = "usage"
 )
 
 // StreamEvent represents a streaming response event
@@ -24,6 +25,7 @@ type StreamEvent struct {
 	Type     StreamEventType `json:"type"`
 	Text     string          `json:"text,omitempty"`
 	ToolCall *ToolCall       `json:"tool_call,omitempty"`
+	Usage    *Usage          `json:"usage,omitempty"`
 	Error    error           `json:"error,omitempty"`
 }
 
@@ -92,6 +94,14 @@ func IsRateLimitOrAuth(err error) bool {
 	return false
 }
 
+// IsAuthError checks if an error is due to a rejected API key
+func IsAuthError(err error) bool {
+	if pe, ok := err.(*ProviderError); ok {
+		return pe.Code == "authentication_error" || pe.Type == "authentication_error"
+	}
+	return false
+}
+
 // containsContextError checks if error message indicates context overflow
 func containsContextError(msg string) bool {
 	keywords := []string{"context", "token", "length", "exceeded", "too long"}
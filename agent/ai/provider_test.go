@@ -1,7 +1,11 @@
 package ai
 
 import (
+	"encoding/json"
+	"strings"
 	"testing"
+
+	"gobot/agent/session"
 )
 
 func TestStreamEventTypes(t *testing.T) {
@@ -169,6 +173,21 @@ func TestIsRateLimitOrAuth(t *testing.T) {
 	}
 }
 
+func TestGeminiErrorClassification(t *testing.T) {
+	limited := parseGeminiError(429, []byte(`{"error":{"code":429,"message":"Quota exceeded","status":"RESOURCE_EXHAUSTED"}}`))
+	if !IsRateLimitOrAuth(limited) || IsAuthError(limited) {
+		t.Errorf("expected a rate limit error, got %#v", limited)
+	}
+	if limited.Error() != "Gemini error (429): Quota exceeded" {
+		t.Errorf("unexpected message %q", limited.Error())
+	}
+
+	rejected := parseGeminiError(403, []byte("forbidden"))
+	if !IsAuthError(rejected) || rejected.Error() != "Gemini error (403): forbidden" {
+		t.Errorf("expected an auth error, got %#v", rejected)
+	}
+}
+
 func TestContainsContextError(t *testing.T) {
 	tests := []struct {
 		msg      string
@@ -268,3 +287,62 @@ func TestChatRequest(t *testing.T) {
 		t.Errorf("unexpected system prompt: %s", req.System)
 	}
 }
+
+func TestMultimodalContent(t *testing.T) {
+	msg := session.Message{
+		Role:    "user",
+		Content: "compare these",
+		Parts: []session.ContentPart{
+			{Type: session.PartImage, MediaType: "image/png", Name: "a.png", Data: []byte("png")},
+			{Type: session.PartDocument, MediaType: "application/pdf", Name: "spec.pdf", Data: []byte("pdf")},
+			{Type: session.PartDocument, MediaType: "text/plain", Name: "notes.txt", Data: []byte("notes")},
+			{Type: session.PartAudio, MediaType: "audio/wav", Name: "clip.wav", Data: []byte("wav")},
+		},
+	}
+	encode := func(v any) string {
+		data, _ := json.Marshal(v)
+		return string(data)
+	}
+
+	anthropic := encode(NewAnthropicProvider("key", "claude").buildRequest(&ChatRequest{Messages: []session.Message{msg}}))
+	for _, want := range []string{
+		`{"text":"compare these","type":"text"}`,
+		`{"source":{"data":"cG5n","media_type":"image/png","type":"base64"},"type":"image"}`,
+		`{"source":{"data":"cGRm","media_type":"application/pdf","type":"base64"},"title":"spec.pdf","type":"document"}`,
+		`{"source":{"data":"notes","media_type":"text/plain","type":"text"},"title":"notes.txt","type":"document"}`,
+		`audio attachment clip.wav (audio/wav) not supported`,
+	} {
+		if !strings.Contains(anthropic, want) {
+			t.Errorf("Anthropic request missing %s in %s", want, anthropic)
+		}
+	}
+
+	openai := encode(NewOpenAIProvider("key", "gpt").buildRequest(&ChatRequest{Messages: []session.Message{msg}}))
+	for _, want := range []string{
+		`{"image_url":{"url":"data:image/png;base64,cG5n"},"type":"image_url"}`,
+		`{"file":{"file_data":"data:application/pdf;base64,cGRm","filename":"spec.pdf"},"type":"file"}`,
+		`\u003cfile name=\"notes.txt\"\u003e\nnotes\n\u003c/file\u003e`,
+		`{"input_audio":{"data":"d2F2","format":"wav"},"type":"input_audio"}`,
+	} {
+		if !strings.Contains(openai, want) {
+			t.Errorf("OpenAI request missing %s in %s", want, openai)
+		}
+	}
+
+	gemini := encode(geminiParts(msg.ContentParts()))
+	for _, want := range []string{
+		`{"text":"compare these"}`,
+		`{"inlineData":{"mimeType":"image/png","data":"cG5n"}}`,
+		`{"inlineData":{"mimeType":"application/pdf","data":"cGRm"}}`,
+		`{"inlineData":{"mimeType":"audio/wav","data":"d2F2"}}`,
+	} {
+		if !strings.Contains(gemini, want) {
+			t.Errorf("Gemini parts missing %s in %s", want, gemini)
+		}
+	}
+
+	ollama := NewOllamaProvider("", "llava").buildRequest(&ChatRequest{Messages: []session.Message{msg}}, "llava", false)
+	if got := ollama.Messages[0]; len(got.Images) != 1 || !strings.HasPrefix(got.Content, "compare these") || !strings.Contains(got.Content, "notes.txt") {
+		t.Errorf("unexpected Ollama message %+v", got)
+	}
+}
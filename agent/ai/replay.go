@@ -0,0 +1,323 @@
+package ai
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"gobot/agent/session"
+)
+
+// ErrCassetteMiss is returned when a replayed request has no matching recording
+var ErrCassetteMiss = errors.New("no recorded response for request")
+
+// cassetteVersion is stored in cassette files so future formats can be detected
+const cassetteVersion = 1
+
+// Cassette stores recorded provider interactions for deterministic replay.
+// Requests are matched by RequestKey; when the same request is made several times,
+// its recordings are replayed in the order they were captured.
+type Cassette struct {
+	Version      int            `json:"version"`
+	Interactions []*Interaction `json:"interactions"`
+
+	mu   sync.Mutex
+	path string
+	used map[string]int // Replays served per provider/key
+}
+
+// Interaction is one recorded request and the events the provider streamed back
+type Interaction struct {
+	Provider string          `json:"provider"`
+	Key      string          `json:"key"`
+	Request  *ChatRequest    `json:"request"` // Kept for reviewing cassettes; not used for matching
+	Events   []RecordedEvent `json:"events"`
+	Err      *RecordedError  `json:"error,omitempty"` // Stream() itself failed
+}
+
+// RecordedEvent is a StreamEvent in serializable form
+type RecordedEvent struct {
+	Type     StreamEventType `json:"type"`
+	Text     string          `json:"text,omitempty"`
+	ToolCall *ToolCall       `json:"tool_call,omitempty"`
+	Usage    *Usage          `json:"usage,omitempty"`
+	Error    *RecordedError  `json:"error,omitempty"`
+}
+
+// RecordedError preserves an error message and, for provider errors, its code and type
+// so IsContextOverflow and IsRateLimitOrAuth behave the same on replay
+type RecordedError struct {
+	Message string `json:"message"`
+	Code    string `json:"code,omitempty"`
+	Type    string `json:"type,omitempty"`
+}
+
+// OpenCassette loads a cassette file, or returns an empty cassette if it does not exist yet
+func OpenCassette(path string) (*Cassette, error) {
+	c := &Cassette{Version: cassetteVersion, path: path, used: make(map[string]int)}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, c); err != nil {
+		return nil, fmt.Errorf("failed to parse cassette %s: %w", path, err)
+	}
+	if c.Version > cassetteVersion {
+		return nil, fmt.Errorf("cassette %s has version %d, newer than supported (%d)", path, c.Version, cassetteVersion)
+	}
+	return c, nil
+}
+
+// Record wraps a live provider so every request and streamed response is saved to the cassette
+func (c *Cassette) Record(inner Provider) Provider {
+	return &ReplayProvider{id: inner.ID(), inner: inner, cassette: c}
+}
+
+// Replay returns a provider that serves recorded responses for providerID without network access
+func (c *Cassette) Replay(providerID string) Provider {
+	return &ReplayProvider{id: providerID, cassette: c}
+}
+
+// ProviderIDs returns the recorded provider IDs in order of first use
+func (c *Cassette) ProviderIDs() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var ids []string
+	seen := make(map[string]bool)
+	for _, in := range c.Interactions {
+		if !seen[in.Provider] {
+			seen[in.Provider] = true
+			ids = append(ids, in.Provider)
+		}
+	}
+	return ids
+}
+
+// next returns the next unplayed recording for a request
+func (c *Cassette) next(providerID, key string) *Interaction {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	usedKey := providerID + "/" + key
+	skip := c.used[usedKey]
+	var last *Interaction
+	for _, in := range c.Interactions {
+		if in.Provider != providerID || in.Key != key {
+			continue
+		}
+		last = in
+		if skip == 0 {
+			c.used[usedKey]++
+			return in
+		}
+		skip--
+	}
+	return last // Repeated more often than recorded: keep serving the final response
+}
+
+// add appends an interaction and writes the cassette to disk
+func (c *Cassette) add(in *Interaction) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.Interactions = append(c.Interactions, in)
+	if c.path == "" {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), 0755); err != nil {
+		return err
+	}
+	tmp := c.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, c.path)
+}
+
+// ReplayProvider records a live provider's responses to a cassette, or replays them from one
+type ReplayProvider struct {
+	id       string
+	inner    Provider // nil when replaying
+	cassette *Cassette
+}
+
+// ID returns the ID of the recorded provider
+func (p *ReplayProvider) ID() string {
+	return p.id
+}
+
+// Stream records the live response, or replays the recording matching the request
+func (p *ReplayProvider) Stream(ctx context.Context, req *ChatRequest) (<-chan StreamEvent, error) {
+	key := RequestKey(req)
+	if p.inner == nil {
+		return p.replay(ctx, key)
+	}
+
+	events, err := p.inner.Stream(ctx, req)
+	if err != nil {
+		if saveErr := p.cassette.add(&Interaction{Provider: p.id, Key: key, Request: req, Err: recordError(err)}); saveErr != nil {
+			fmt.Printf("[replay] Warning: failed to save cassette: %v\n", saveErr)
+		}
+		return nil, err
+	}
+
+	out := make(chan StreamEvent)
+	go func() {
+		defer close(out)
+		in := &Interaction{Provider: p.id, Key: key, Request: req}
+		for event := range events {
+			in.Events = append(in.Events, RecordedEvent{
+				Type:     event.Type,
+				Text:     event.Text,
+				ToolCall: event.ToolCall,
+				Usage:    event.Usage,
+				Error:    recordError(event.Error),
+			})
+			select {
+			case <-ctx.Done():
+				return
+			case out <- event:
+			}
+		}
+		if ctx.Err() != nil {
+			return // Cancelled streams are incomplete; don't record them
+		}
+		if err := p.cassette.add(in); err != nil {
+			fmt.Printf("[replay] Warning: failed to save cassette: %v\n", err)
+		}
+	}()
+	return out, nil
+}
+
+// replay streams a recorded interaction
+func (p *ReplayProvider) replay(ctx context.Context, key string) (<-chan StreamEvent, error) {
+	in := p.cassette.next(p.id, key)
+	if in == nil {
+		return nil, fmt.Errorf("%w (provider %s, key %s)", ErrCassetteMiss, p.id, key)
+	}
+	if in.Err != nil {
+		return nil, in.Err.toError()
+	}
+
+	ch := make(chan StreamEvent)
+	go func() {
+		defer close(ch)
+		for _, e := range in.Events {
+			event := StreamEvent{Type: e.Type, Text: e.Text, ToolCall: e.ToolCall, Usage: e.Usage}
+			if e.Error != nil {
+				event.Error = e.Error.toError()
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case ch <- event:
+			}
+		}
+	}()
+	return ch, nil
+}
+
+// recordError converts an error to its recorded form
+func recordError(err error) *RecordedError {
+	if err == nil {
+		return nil
+	}
+	var pe *ProviderError
+	if errors.As(err, &pe) {
+		return &RecordedError{Message: pe.Message, Code: pe.Code, Type: pe.Type}
+	}
+	return &RecordedError{Message: err.Error()}
+}
+
+// toError restores a recorded error, as a *ProviderError when it had a code or type
+func (e *RecordedError) toError() error {
+	if e.Code != "" || e.Type != "" {
+		return &ProviderError{Message: e.Message, Code: e.Code, Type: e.Type}
+	}
+	return errors.New(e.Message)
+}
+
+// RequestKey hashes the parts of a request that determine the response: model, system prompt,
+// tool names and message roles, content, attachments, tool calls and tool results.
+// Message IDs, timestamps and usage are ignored so replays match across runs.
+func RequestKey(req *ChatRequest) string {
+	type message struct {
+		Role        string          `json:"role"`
+		Content     string          `json:"content,omitempty"`
+		ToolCalls   json.RawMessage `json:"tool_calls,omitempty"`
+		ToolResults json.RawMessage `json:"tool_results,omitempty"`
+		Parts       []string        `json:"parts,omitempty"`
+	}
+	fingerprint := struct {
+		Model    string    `json:"model,omitempty"`
+		System   string    `json:"system,omitempty"`
+		Tools    []string  `json:"tools,omitempty"`
+		Thinking bool      `json:"thinking,omitempty"`
+		Messages []message `json:"messages"`
+	}{
+		Model:    req.Model,
+		System:   req.System,
+		Thinking: req.EnableThinking,
+	}
+	for _, t := range req.Tools {
+		fingerprint.Tools = append(fingerprint.Tools, t.Name)
+	}
+	sort.Strings(fingerprint.Tools)
+	for _, m := range req.Messages {
+		fingerprint.Messages = append(fingerprint.Messages, message{
+			Role:        m.Role,
+			Content:     m.Content,
+			ToolCalls:   compactJSON(m.ToolCalls),
+			ToolResults: compactJSON(m.ToolResults),
+			Parts:       partKeys(m.Parts),
+		})
+	}
+
+	data, _ := json.Marshal(fingerprint)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// partKeys identifies attachments by type and content hash, so inline and stored copies match
+func partKeys(parts []session.ContentPart) []string {
+	keys := make([]string, 0, len(parts))
+	for _, p := range parts {
+		if p.Type == session.PartText {
+			keys = append(keys, "text:"+p.Text)
+			continue
+		}
+		sum := sha256.Sum256(p.Data)
+		keys = append(keys, p.Type+":"+hex.EncodeToString(sum[:]))
+	}
+	return keys
+}
+
+// compactJSON normalizes whitespace so formatting differences don't change the hash
+func compactJSON(raw json.RawMessage) json.RawMessage {
+	if len(raw) == 0 {
+		return nil
+	}
+	var v any
+	if err := json.Unmarshal(raw, &v); err != nil {
+		return raw
+	}
+	data, _ := json.Marshal(v)
+	return data
+}
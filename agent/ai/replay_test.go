@@ -0,0 +1,116 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"gobot/agent/session"
+)
+
+func collect(t *testing.T, p Provider, req *ChatRequest) ([]StreamEvent, error) {
+	t.Helper()
+	events, err := p.Stream(context.Background(), req)
+	if err != nil {
+		return nil, err
+	}
+	var out []StreamEvent
+	for e := range events {
+		out = append(out, e)
+	}
+	return out, nil
+}
+
+func TestCassetteRecordAndReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cassettes", "flow.json")
+	hello := &ChatRequest{Model: "m", Messages: []session.Message{{Role: "user", Content: "hello"}}}
+	overflow := &ChatRequest{Model: "m", Messages: []session.Message{{Role: "user", Content: "huge"}}}
+
+	live := NewScriptedProvider("anthropic",
+		TextTurn("hi there").WithUsage(10, 3),
+		TextTurn("hi again"),
+		ErrorTurn(&ProviderError{Code: "context_length_exceeded", Message: "too long"}),
+	)
+
+	recorder, err := OpenCassette(path)
+	if err != nil {
+		t.Fatalf("OpenCassette failed: %v", err)
+	}
+	rec := recorder.Record(live)
+	collect(t, rec, hello)
+	collect(t, rec, hello)
+	if _, err := collect(t, rec, overflow); err == nil {
+		t.Fatal("expected recorded error to be returned while recording")
+	}
+
+	// Replay from disk without the live provider; timestamps and IDs don't affect matching
+	cassette, err := OpenCassette(path)
+	if err != nil {
+		t.Fatalf("reopen failed: %v", err)
+	}
+	if ids := cassette.ProviderIDs(); len(ids) != 1 || ids[0] != "anthropic" {
+		t.Fatalf("ProviderIDs = %v", ids)
+	}
+	replay := cassette.Replay("anthropic")
+
+	again := &ChatRequest{Model: "m", Messages: []session.Message{{ID: 42, Role: "user", Content: "hello"}}}
+	events, err := collect(t, replay, again)
+	if err != nil {
+		t.Fatalf("replay failed: %v", err)
+	}
+	if len(events) != 2 || events[0].Text != "hi there" || events[1].Usage == nil || events[1].Usage.InputTokens != 10 {
+		t.Errorf("unexpected first replay: %+v", events)
+	}
+	events, _ = collect(t, replay, again)
+	if len(events) != 1 || events[0].Text != "hi again" {
+		t.Errorf("repeated request should replay the second recording, got %+v", events)
+	}
+
+	if _, err := collect(t, replay, overflow); !IsContextOverflow(err) {
+		t.Errorf("expected replayed context overflow error, got %v", err)
+	}
+
+	miss := &ChatRequest{Model: "m", Messages: []session.Message{{Role: "user", Content: "unrecorded"}}}
+	if _, err := collect(t, replay, miss); !errors.Is(err, ErrCassetteMiss) {
+		t.Errorf("expected ErrCassetteMiss, got %v", err)
+	}
+}
+
+func TestRequestKeyIgnoresFormatting(t *testing.T) {
+	a := &ChatRequest{Messages: []session.Message{{Role: "assistant", ToolCalls: []byte(`[{"id":"c1","name":"bash","input":{"command":"ls"}}]`)}}}
+	b := &ChatRequest{Messages: []session.Message{{Role: "assistant", ToolCalls: []byte("[ {\"id\": \"c1\", \"name\": \"bash\",\n \"input\": {\"command\": \"ls\"}} ]")}}}
+	if RequestKey(a) != RequestKey(b) {
+		t.Error("whitespace in tool calls should not change the key")
+	}
+
+	c := &ChatRequest{Messages: []session.Message{{Role: "assistant", ToolCalls: []byte(`[{"id":"c1","name":"bash","input":{"command":"pwd"}}]`)}}}
+	if RequestKey(a) == RequestKey(c) {
+		t.Error("different tool input should change the key")
+	}
+}
+
+func TestScriptedProvider(t *testing.T) {
+	p := NewScriptedProvider("fake",
+		ToolCallTurn(ScriptedCall{Name: "read", Input: map[string]string{"path": "a.go"}}),
+		TextTurn("done"),
+	)
+
+	req := &ChatRequest{Messages: []session.Message{{Role: "user", Content: "read a.go"}}}
+	events, err := collect(t, p, req)
+	if err != nil {
+		t.Fatalf("Stream failed: %v", err)
+	}
+	if len(events) != 1 || events[0].ToolCall == nil || events[0].ToolCall.ID != "call_read_1" ||
+		string(events[0].ToolCall.Input) != `{"path":"a.go"}` {
+		t.Errorf("unexpected tool call: %+v", events)
+	}
+
+	collect(t, p, req)
+	if p.Remaining() != 0 || len(p.Requests()) != 2 {
+		t.Errorf("remaining=%d requests=%d", p.Remaining(), len(p.Requests()))
+	}
+	if _, err := collect(t, p, req); err == nil {
+		t.Error("expected error once the script is exhausted")
+	}
+}
@@ -0,0 +1,121 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+)
+
+// ScriptedProvider is a fake Provider that answers each request with the next scripted turn.
+// It records the requests it receives so tests can assert on what the runner sent.
+type ScriptedProvider struct {
+	id string
+
+	mu       sync.Mutex
+	turns    []ScriptedTurn
+	requests []*ChatRequest
+}
+
+// ScriptedTurn is one scripted response: the events to stream, or an error from Stream itself
+type ScriptedTurn struct {
+	Events []StreamEvent
+	Err    error
+}
+
+// NewScriptedProvider creates a fake provider that plays turns in order
+func NewScriptedProvider(id string, turns ...ScriptedTurn) *ScriptedProvider {
+	return &ScriptedProvider{id: id, turns: turns}
+}
+
+// TextTurn responds with text
+func TextTurn(text string) ScriptedTurn {
+	return ScriptedTurn{Events: []StreamEvent{{Type: EventTypeText, Text: text}}}
+}
+
+// ToolCallTurn responds with tool calls; input is marshalled to JSON
+func ToolCallTurn(calls ...ScriptedCall) ScriptedTurn {
+	var turn ScriptedTurn
+	for i, c := range calls {
+		id := c.ID
+		if id == "" {
+			id = fmt.Sprintf("call_%s_%d", c.Name, i+1)
+		}
+		input, _ := json.Marshal(c.Input)
+		turn.Events = append(turn.Events, StreamEvent{
+			Type:     EventTypeToolCall,
+			ToolCall: &ToolCall{ID: id, Name: c.Name, Input: input},
+		})
+	}
+	return turn
+}
+
+// ScriptedCall describes a tool call for ToolCallTurn
+type ScriptedCall struct {
+	ID    string // Generated from the name when empty
+	Name  string
+	Input any
+}
+
+// ErrorTurn makes Stream fail with err
+func ErrorTurn(err error) ScriptedTurn {
+	return ScriptedTurn{Err: err}
+}
+
+// WithUsage appends a usage event to the turn
+func (t ScriptedTurn) WithUsage(inputTokens, outputTokens int) ScriptedTurn {
+	t.Events = append(t.Events, StreamEvent{
+		Type:  EventTypeUsage,
+		Usage: &Usage{InputTokens: inputTokens, OutputTokens: outputTokens},
+	})
+	return t
+}
+
+// ID returns the provider identifier
+func (p *ScriptedProvider) ID() string {
+	return p.id
+}
+
+// Stream records the request and streams the next scripted turn
+func (p *ScriptedProvider) Stream(ctx context.Context, req *ChatRequest) (<-chan StreamEvent, error) {
+	p.mu.Lock()
+	p.requests = append(p.requests, req)
+	if len(p.turns) == 0 {
+		p.mu.Unlock()
+		return nil, fmt.Errorf("scripted provider %s: no turns left for request %d", p.id, len(p.requests))
+	}
+	turn := p.turns[0]
+	p.turns = p.turns[1:]
+	p.mu.Unlock()
+
+	if turn.Err != nil {
+		return nil, turn.Err
+	}
+
+	ch := make(chan StreamEvent)
+	go func() {
+		defer close(ch)
+		for _, event := range turn.Events {
+			select {
+			case <-ctx.Done():
+				return
+			case ch <- event:
+			}
+		}
+	}()
+	return ch, nil
+}
+
+// Requests returns the requests received so far
+func (p *ScriptedProvider) Requests() []*ChatRequest {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]*ChatRequest(nil), p.requests...)
+}
+
+// Remaining returns the number of unplayed turns
+func (p *ScriptedProvider) Remaining() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.turns)
+}
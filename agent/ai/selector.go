@@ -60,6 +60,11 @@ func (s *ModelSelector) SelectWithExclusions(messages []session.Message, exclude
 	return s.selectForTaskWithExclusions(taskType, excludeModels)
 }
 
+// SelectForTask returns the best model routed for a task type, skipping failed models
+func (s *ModelSelector) SelectForTask(taskType TaskType) string {
+	return s.selectForTask(taskType)
+}
+
 // MarkFailed marks a model as failed with exponential backoff cooldown
 func (s *ModelSelector) MarkFailed(modelID string) {
 	s.excludedMu.Lock()
@@ -174,8 +179,11 @@ func (s *ModelSelector) hasImageContent(messages []session.Message) bool {
 		if msg.Role != "user" {
 			continue
 		}
+		if msg.HasPart(session.PartImage) {
+			return true
+		}
 
-		// Check if content is a JSON array (multimodal content)
+		// Check if content is a JSON array (multimodal content embedded in text)
 		content := strings.TrimSpace(msg.Content)
 		if strings.HasPrefix(content, "[") {
 			var parts []map[string]interface{}
@@ -233,10 +241,13 @@ func (s *ModelSelector) hasAudioContent(messages []session.Message) bool {
 		if msg.Role != "user" {
 			continue
 		}
+		if msg.HasPart(session.PartAudio) {
+			return true
+		}
 
 		content := strings.TrimSpace(msg.Content)
 
-		// Check if content is a JSON array (multimodal content)
+		// Check if content is a JSON array (multimodal content embedded in text)
 		if strings.HasPrefix(content, "[") {
 			var parts []map[string]any
 			if err := json.Unmarshal([]byte(content), &parts); err == nil {
@@ -438,6 +449,56 @@ func (s *ModelSelector) selectForTaskWithExclusions(taskType TaskType, excludeMo
 	return s.getDefaultModelFiltered(isUsable)
 }
 
+// SelectCheapest returns the usable model with the lowest configured price.
+// canServe reports whether the caller has a provider for a model ID (nil = any).
+func (s *ModelSelector) SelectCheapest(canServe func(modelID string) bool) string {
+	return s.SelectCheaper("", canServe)
+}
+
+// SelectCheaper returns the cheapest usable model priced below currentModel,
+// or the cheapest overall if currentModel has no pricing. Returns "" if none qualifies.
+func (s *ModelSelector) SelectCheaper(currentModel string, canServe func(modelID string) bool) string {
+	maxPrice := -1.0
+	if info := s.GetModelInfo(currentModel); info != nil && info.Pricing != nil {
+		maxPrice = blendedPrice(info.Pricing)
+	}
+
+	s.excludedMu.RLock()
+	defer s.excludedMu.RUnlock()
+
+	best := ""
+	bestPrice := 0.0
+	for providerID, models := range s.config.Providers {
+		for _, m := range models {
+			if !m.IsActive() || m.Pricing == nil {
+				continue
+			}
+			modelID := providerID + "/" + m.ID
+			if modelID == currentModel || s.excluded[modelID] || s.isInCooldown(modelID) {
+				continue
+			}
+			if canServe != nil && !canServe(modelID) {
+				continue
+			}
+			price := blendedPrice(m.Pricing)
+			if maxPrice >= 0 && price >= maxPrice {
+				continue
+			}
+			if best == "" || price < bestPrice || (price == bestPrice && modelID < best) {
+				best = modelID
+				bestPrice = price
+			}
+		}
+	}
+
+	return best
+}
+
+// blendedPrice ranks models by combined input and output price per million tokens
+func blendedPrice(p *provider.ModelPricing) float64 {
+	return p.Input + p.Output
+}
+
 // getDefaultModel returns the default model, respecting exclusions (legacy)
 func (s *ModelSelector) getDefaultModel(excluded map[string]bool) string {
 	isUsable := func(modelID string) bool {
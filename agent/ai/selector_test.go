@@ -1,6 +1,7 @@
 package ai
 
 import (
+	"strings"
 	"testing"
 	"time"
 
@@ -54,6 +55,14 @@ func TestClassifyTask_Vision(t *testing.T) {
 	if taskType != TaskTypeVision {
 		t.Errorf("expected vision task type, got %s", taskType)
 	}
+
+	// Test with an attached image part
+	messages = []session.Message{
+		{Role: "user", Content: "what is this?", Parts: []session.ContentPart{{Type: session.PartImage, MediaType: "image/png"}}},
+	}
+	if taskType := selector.classifyTask(messages); taskType != TaskTypeVision {
+		t.Errorf("expected vision task type for an image part, got %s", taskType)
+	}
 }
 
 func TestClassifyTask_Reasoning(t *testing.T) {
@@ -533,3 +542,36 @@ func TestSelectSkipsCooldownModels(t *testing.T) {
 		t.Errorf("expected fallback model when primary in cooldown, got %s", model)
 	}
 }
+
+func TestSelectCheaper(t *testing.T) {
+	config := &provider.ModelsConfig{
+		Providers: map[string][]provider.ModelInfo{
+			"anthropic": {
+				{ID: "big", Pricing: &provider.ModelPricing{Input: 15, Output: 75}},
+				{ID: "mid", Pricing: &provider.ModelPricing{Input: 3, Output: 15}},
+			},
+			"openai": {
+				{ID: "small", Pricing: &provider.ModelPricing{Input: 0.1, Output: 0.4}},
+				{ID: "unpriced"},
+			},
+		},
+	}
+	selector := NewModelSelector(config)
+
+	if got := selector.SelectCheaper("anthropic/big", nil); got != "openai/small" {
+		t.Errorf("expected openai/small, got %q", got)
+	}
+
+	onlyAnthropic := func(id string) bool { return strings.HasPrefix(id, "anthropic/") }
+	if got := selector.SelectCheaper("anthropic/big", onlyAnthropic); got != "anthropic/mid" {
+		t.Errorf("expected anthropic/mid, got %q", got)
+	}
+
+	if got := selector.SelectCheaper("openai/small", nil); got != "" {
+		t.Errorf("expected nothing cheaper than the cheapest model, got %q", got)
+	}
+
+	if got := selector.SelectCheapest(nil); got != "openai/small" {
+		t.Errorf("expected cheapest openai/small, got %q", got)
+	}
+}
@@ -0,0 +1,150 @@
+package ai
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"gobot/agent/session"
+)
+
+// Text tool protocol, for models without native tool calling: tools are described in the
+// system prompt, the model writes calls as JSON in <tool_call> tags, and results are sent
+// back as user messages in <tool_result> tags.
+const (
+	textToolCallOpen    = "<tool_call>"
+	textToolCallClose   = "</tool_call>"
+	textToolResultOpen  = "<tool_result"
+	textToolResultClose = "</tool_result>"
+)
+
+// textToolCall is a tool call as the model writes it
+type textToolCall struct {
+	Name      string          `json:"name"`
+	Arguments json.RawMessage `json:"arguments"`
+}
+
+// textToolPrompt describes the tools and how to call them, for appending to the system prompt
+func textToolPrompt(tools []ToolDefinition) string {
+	var b strings.Builder
+	b.WriteString("# Tools\n\n")
+	b.WriteString("You can call the tools below. To call one, write a JSON object in tool_call tags and stop; ")
+	b.WriteString("you may make several calls in one reply:\n\n")
+	b.WriteString(textToolCallOpen + "\n{\"name\": \"tool_name\", \"arguments\": {\"param\": \"value\"}}\n" + textToolCallClose + "\n\n")
+	b.WriteString("Each result comes back in a <tool_result name=\"tool_name\"> block. Only call tools listed here, ")
+	b.WriteString("and don't write tool_call tags for any other purpose.\n\n## Available tools\n")
+	for _, tool := range tools {
+		fmt.Fprintf(&b, "\n### %s\n%s\nParameters (JSON schema): %s\n", tool.Name, strings.TrimSpace(tool.Description), string(compactJSON(tool.InputSchema)))
+	}
+	return b.String()
+}
+
+// textToolCallText renders a tool call from the history the way the model writes it
+func textToolCallText(call session.ToolCall) string {
+	args := call.Input
+	if len(args) == 0 {
+		args = json.RawMessage("{}")
+	}
+	data, _ := json.Marshal(textToolCall{Name: call.Name, Arguments: args})
+	return textToolCallOpen + "\n" + string(data) + "\n" + textToolCallClose
+}
+
+// textToolResultText renders a tool result for the model
+func textToolResultText(name string, result session.ToolResult) string {
+	attrs := fmt.Sprintf(" name=%q", name)
+	if result.IsError {
+		attrs += ` error="true"`
+	}
+	return textToolResultOpen + attrs + ">\n" + result.Content + "\n" + textToolResultClose
+}
+
+// textToolParser pulls tool calls out of streamed text, passing the rest through. Text that
+// might be the start of a tag is held back until the next chunk shows whether it is one.
+type textToolParser struct {
+	buf    string
+	inCall bool
+}
+
+// Feed adds streamed text and returns the text to show and any completed tool calls
+func (p *textToolParser) Feed(chunk string) (text string, calls []*ToolCall) {
+	p.buf += chunk
+	var out strings.Builder
+	for {
+		if !p.inCall {
+			if i := strings.Index(p.buf, textToolCallOpen); i >= 0 {
+				out.WriteString(p.buf[:i])
+				p.buf = p.buf[i+len(textToolCallOpen):]
+				p.inCall = true
+				continue
+			}
+			keep := partialSuffix(p.buf, textToolCallOpen)
+			out.WriteString(p.buf[:len(p.buf)-keep])
+			p.buf = p.buf[len(p.buf)-keep:]
+			return out.String(), calls
+		}
+
+		j := strings.Index(p.buf, textToolCallClose)
+		if j < 0 {
+			return out.String(), calls
+		}
+		raw := p.buf[:j]
+		p.buf = p.buf[j+len(textToolCallClose):]
+		p.inCall = false
+		if call := parseTextToolCall(raw); call != nil {
+			calls = append(calls, call)
+		} else {
+			// Not a valid call; show it as written
+			out.WriteString(textToolCallOpen + raw + textToolCallClose)
+		}
+	}
+}
+
+// Flush returns whatever is held back at the end of the stream. A call missing its closing
+// tag still counts if its JSON is complete.
+func (p *textToolParser) Flush() (text string, calls []*ToolCall) {
+	buf, inCall := p.buf, p.inCall
+	p.buf, p.inCall = "", false
+	if !inCall {
+		return buf, nil
+	}
+	if call := parseTextToolCall(buf); call != nil {
+		return "", []*ToolCall{call}
+	}
+	return textToolCallOpen + buf, nil
+}
+
+// parseTextToolCall parses the JSON inside tool_call tags, tolerating a code fence
+func parseTextToolCall(raw string) *ToolCall {
+	raw = strings.TrimSpace(raw)
+	raw = strings.TrimPrefix(raw, "```json")
+	raw = strings.Trim(raw, "`\n ")
+
+	var tc textToolCall
+	if err := json.Unmarshal([]byte(raw), &tc); err != nil || tc.Name == "" {
+		return nil
+	}
+	input := tc.Arguments
+	if len(input) == 0 || string(input) == "null" {
+		input = json.RawMessage("{}")
+	}
+	return &ToolCall{ID: newToolCallID(), Name: tc.Name, Input: input}
+}
+
+// partialSuffix returns the length of the longest end of s that begins tag
+func partialSuffix(s, tag string) int {
+	for k := min(len(tag)-1, len(s)); k > 0; k-- {
+		if strings.HasSuffix(s, tag[:k]) {
+			return k
+		}
+	}
+	return 0
+}
+
+// newToolCallID returns an ID for a tool call from a provider that doesn't assign them
+func newToolCallID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return "call_" + hex.EncodeToString(b)
+}
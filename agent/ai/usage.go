@@ -0,0 +1,95 @@
+package ai
+
+import (
+	"gobot/internal/provider"
+)
+
+// Usage reports token consumption for a single provider call
+type Usage struct {
+	Model            string  `json:"model,omitempty"` // Model that served the request (provider/model when known)
+	InputTokens      int     `json:"input_tokens"`    // All prompt tokens, including cache reads and writes
+	OutputTokens     int     `json:"output_tokens"`
+	CacheReadTokens  int     `json:"cache_read_tokens,omitempty"`  // Prompt tokens served from the provider's cache
+	CacheWriteTokens int     `json:"cache_write_tokens,omitempty"` // Prompt tokens written to the cache
+	CostUSD          float64 `json:"cost_usd,omitempty"`           // Filled in by the runner from models.yaml pricing
+}
+
+// TotalTokens returns input plus output tokens
+func (u *Usage) TotalTokens() int {
+	return u.InputTokens + u.OutputTokens
+}
+
+// Add accumulates another usage record into this one
+func (u *Usage) Add(other *Usage) {
+	if other == nil {
+		return
+	}
+	u.InputTokens += other.InputTokens
+	u.OutputTokens += other.OutputTokens
+	u.CacheReadTokens += other.CacheReadTokens
+	u.CacheWriteTokens += other.CacheWriteTokens
+	u.CostUSD += other.CostUSD
+}
+
+// Cost prices the usage with the given per-million-token pricing. Cache reads and writes
+// fall back to the input price when the model has no cache pricing.
+func (u *Usage) Cost(pricing *provider.ModelPricing) float64 {
+	if pricing == nil {
+		return 0
+	}
+	cachedInput, cacheWrite := pricing.CachedInput, pricing.CacheWrite
+	if cachedInput == 0 {
+		cachedInput = pricing.Input
+	}
+	if cacheWrite == 0 {
+		cacheWrite = pricing.Input
+	}
+	uncached := u.InputTokens - u.CacheReadTokens - u.CacheWriteTokens
+	return float64(uncached)*pricing.Input/1_000_000 +
+		float64(u.CacheReadTokens)*cachedInput/1_000_000 +
+		float64(u.CacheWriteTokens)*cacheWrite/1_000_000 +
+		float64(u.OutputTokens)*pricing.Output/1_000_000
+}
+
+// EstimateCost prices usage for a model using pricing from models.yaml
+func EstimateCost(modelID string, u *Usage) float64 {
+	if u == nil {
+		return 0
+	}
+	return u.Cost(LookupPricing(provider.GetModelsConfig(), modelID))
+}
+
+// LookupModelInfo finds a model in models.yaml by "provider/model" or bare model ID
+func LookupModelInfo(config *provider.ModelsConfig, modelID string) *provider.ModelInfo {
+	if config == nil || modelID == "" {
+		return nil
+	}
+
+	providerID, modelName := ParseModelID(modelID)
+	if providerID != "" {
+		for _, m := range config.Providers[providerID] {
+			if m.ID == modelName {
+				return &m
+			}
+		}
+	}
+
+	// Bare model name (or unknown provider prefix): search all providers
+	for _, models := range config.Providers {
+		for _, m := range models {
+			if m.ID == modelName || m.ID == modelID {
+				return &m
+			}
+		}
+	}
+
+	return nil
+}
+
+// LookupPricing returns the configured pricing for a model, or nil if unknown
+func LookupPricing(config *provider.ModelsConfig, modelID string) *provider.ModelPricing {
+	if info := LookupModelInfo(config, modelID); info != nil {
+		return info.Pricing
+	}
+	return nil
+}
@@ -0,0 +1,127 @@
+package ai
+
+import (
+	"context"
+	"io"
+	"math"
+	"net/http"
+	"strings"
+	"testing"
+
+	"gobot/internal/provider"
+)
+
+// sseResponse builds an HTTP response whose body is the given SSE lines
+func sseResponse(lines ...string) *http.Response {
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Body:       io.NopCloser(strings.NewReader(strings.Join(lines, "\n") + "\n")),
+	}
+}
+
+// collectUsage drains events and returns the last usage event
+func collectUsage(events <-chan StreamEvent) *Usage {
+	var usage *Usage
+	for event := range events {
+		if event.Type == EventTypeUsage {
+			usage = event.Usage
+		}
+	}
+	return usage
+}
+
+func TestAnthropicStreamUsage(t *testing.T) {
+	resp := sseResponse(
+		`data: {"type":"message_start","message":{"model":"claude-test","usage":{"input_tokens":120,"output_tokens":1}}}`,
+		`data: {"type":"content_block_delta","delta":{"type":"text_delta","text":"hi"}}`,
+		`data: {"type":"message_delta","delta":{},"usage":{"output_tokens":42}}`,
+		`data: {"type":"message_stop"}`,
+	)
+
+	events := make(chan StreamEvent, 100)
+	go NewAnthropicProvider("key", "claude-test").streamResponse(context.Background(), resp, events)
+
+	usage := collectUsage(events)
+	if usage == nil {
+		t.Fatal("expected usage event")
+	}
+	if usage.Model != "claude-test" || usage.InputTokens != 120 || usage.OutputTokens != 42 {
+		t.Errorf("unexpected usage: %+v", usage)
+	}
+}
+
+func TestAnthropicStreamCacheUsage(t *testing.T) {
+	resp := sseResponse(
+		`data: {"type":"message_start","message":{"model":"claude-test","usage":{"input_tokens":20,"cache_read_input_tokens":3000,"cache_creation_input_tokens":500,"output_tokens":1}}}`,
+		`data: {"type":"message_delta","delta":{},"usage":{"output_tokens":42}}`,
+		`data: {"type":"message_stop"}`,
+	)
+
+	events := make(chan StreamEvent, 100)
+	go NewAnthropicProvider("key", "claude-test").streamResponse(context.Background(), resp, events)
+
+	// Input tokens cover the whole prompt, cached or not
+	usage := collectUsage(events)
+	if usage == nil || usage.InputTokens != 3520 || usage.CacheReadTokens != 3000 || usage.CacheWriteTokens != 500 {
+		t.Errorf("unexpected usage: %+v", usage)
+	}
+}
+
+func TestOpenAIStreamUsage(t *testing.T) {
+	resp := sseResponse(
+		`data: {"model":"gpt-test","choices":[{"delta":{"content":"hi"}}]}`,
+		`data: {"model":"gpt-test","choices":[{"delta":{},"finish_reason":"stop"}]}`,
+		`data: {"model":"gpt-test","choices":[],"usage":{"prompt_tokens":10,"completion_tokens":5,"prompt_tokens_details":{"cached_tokens":8}}}`,
+		`data: [DONE]`,
+	)
+
+	events := make(chan StreamEvent, 100)
+	go NewOpenAIProvider("key", "gpt-test").streamResponse(context.Background(), resp, events)
+
+	usage := collectUsage(events)
+	if usage == nil {
+		t.Fatal("expected usage event")
+	}
+	if usage.Model != "gpt-test" || usage.InputTokens != 10 || usage.OutputTokens != 5 || usage.CacheReadTokens != 8 {
+		t.Errorf("unexpected usage: %+v", usage)
+	}
+}
+
+func TestUsageCost(t *testing.T) {
+	u := &Usage{InputTokens: 1_000_000, OutputTokens: 500_000}
+	cost := u.Cost(&provider.ModelPricing{Input: 3, Output: 15})
+	if math.Abs(cost-10.5) > 1e-9 {
+		t.Errorf("expected cost 10.5, got %f", cost)
+	}
+
+	if u.Cost(nil) != 0 {
+		t.Error("expected zero cost without pricing")
+	}
+
+	// Cache reads and writes use their own prices, or the input price when unset
+	cached := &Usage{InputTokens: 1_000_000, CacheReadTokens: 600_000, CacheWriteTokens: 100_000}
+	if cost := cached.Cost(&provider.ModelPricing{Input: 3, CachedInput: 0.3, CacheWrite: 3.75}); math.Abs(cost-1.455) > 1e-9 {
+		t.Errorf("expected cost 1.455 with cache pricing, got %f", cost)
+	}
+	if cost := cached.Cost(&provider.ModelPricing{Input: 3}); math.Abs(cost-3) > 1e-9 {
+		t.Errorf("expected cost 3 without cache pricing, got %f", cost)
+	}
+}
+
+func TestLookupPricing(t *testing.T) {
+	config := &provider.ModelsConfig{
+		Providers: map[string][]provider.ModelInfo{
+			"anthropic": {{ID: "claude-test", Pricing: &provider.ModelPricing{Input: 3, Output: 15}}},
+		},
+	}
+
+	if p := LookupPricing(config, "anthropic/claude-test"); p == nil || p.Input != 3 {
+		t.Errorf("expected pricing for provider/model, got %+v", p)
+	}
+	if p := LookupPricing(config, "claude-test"); p == nil || p.Output != 15 {
+		t.Errorf("expected pricing for bare model, got %+v", p)
+	}
+	if p := LookupPricing(config, "openai/unknown"); p != nil {
+		t.Errorf("expected nil pricing for unknown model, got %+v", p)
+	}
+}
@@ -0,0 +1,245 @@
+package audit
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+// Approval channels recorded with each entry
+const (
+	ChannelAuto = "auto" // Allowed by policy without asking
+	ChannelCLI  = "cli"  // Approved or denied at a terminal prompt
+	ChannelWeb  = "web"  // Approved or denied in the web UI
+)
+
+// MaxOutput is the number of bytes of tool output kept per entry
+const MaxOutput = 4096
+
+// Entry is one tool execution or approval decision
+type Entry struct {
+	ID         int64     `json:"id"`
+	CreatedAt  time.Time `json:"created_at"`
+	SessionKey string    `json:"session_key,omitempty"`
+	ToolCallID string    `json:"tool_call_id,omitempty"`
+	Tool       string    `json:"tool"`
+	Input      string    `json:"input"`
+	Output     string    `json:"output"` // Truncated to MaxOutput bytes
+	IsError    bool      `json:"is_error"`
+	Approved   bool      `json:"approved"` // False when the call was denied and never ran
+	Channel    string    `json:"channel,omitempty"`
+	Approver   string    `json:"approver,omitempty"`
+	DurationMS int64     `json:"duration_ms"`
+}
+
+// Filter narrows a Query; zero values match everything
+type Filter struct {
+	SessionKey string
+	Tool       string
+	Channel    string
+	Approver   string
+	ErrorsOnly bool
+	DeniedOnly bool
+	Since      time.Time
+	Until      time.Time
+	Limit      int // Default 100
+	Offset     int
+}
+
+// Log is an append-only audit table in the agent database
+type Log struct {
+	db *sql.DB
+}
+
+// Open opens the audit log in the agent database, creating the table if needed
+func Open(dbPath string) (*Log, error) {
+	// Tool calls run concurrently and the agent, server and CLI share the file, so wait on locks
+	db, err := sql.Open("sqlite", dbPath+"?_pragma=busy_timeout(5000)")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+
+	l := &Log{db: db}
+	if err := l.ensureSchema(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to ensure audit schema: %w", err)
+	}
+
+	return l, nil
+}
+
+// ensureSchema creates the audit table; triggers reject updates and deletes so rows can't be rewritten
+func (l *Log) ensureSchema() error {
+	schema := `
+	CREATE TABLE IF NOT EXISTS tool_audit (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		created_at INTEGER NOT NULL,
+		session_key TEXT,
+		tool_call_id TEXT,
+		tool TEXT NOT NULL,
+		input TEXT,
+		output TEXT,
+		is_error INTEGER NOT NULL DEFAULT 0,
+		approved INTEGER NOT NULL DEFAULT 1,
+		channel TEXT,
+		approver TEXT,
+		duration_ms INTEGER NOT NULL DEFAULT 0
+	);
+	CREATE INDEX IF NOT EXISTS idx_tool_audit_created ON tool_audit(created_at);
+	CREATE INDEX IF NOT EXISTS idx_tool_audit_session ON tool_audit(session_key, created_at);
+
+	CREATE TRIGGER IF NOT EXISTS tool_audit_no_update BEFORE UPDATE ON tool_audit
+	BEGIN
+		SELECT RAISE(ABORT, 'tool_audit is append-only');
+	END;
+	CREATE TRIGGER IF NOT EXISTS tool_audit_no_delete BEFORE DELETE ON tool_audit
+	BEGIN
+		SELECT RAISE(ABORT, 'tool_audit is append-only');
+	END;
+	`
+	_, err := l.db.Exec(schema)
+	return err
+}
+
+// Close closes the database connection (a nil log is a no-op)
+func (l *Log) Close() error {
+	if l == nil {
+		return nil
+	}
+	return l.db.Close()
+}
+
+// Record appends an entry, truncating its output and filling in the timestamp if unset
+func (l *Log) Record(e *Entry) error {
+	if e.CreatedAt.IsZero() {
+		e.CreatedAt = time.Now()
+	}
+	e.Output = truncate(e.Output, MaxOutput)
+
+	res, err := l.db.Exec(
+		`INSERT INTO tool_audit (created_at, session_key, tool_call_id, tool, input, output,
+			is_error, approved, channel, approver, duration_ms)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		e.CreatedAt.UnixMilli(), e.SessionKey, e.ToolCallID, e.Tool, e.Input, e.Output,
+		e.IsError, e.Approved, e.Channel, e.Approver, e.DurationMS,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record audit entry: %w", err)
+	}
+	e.ID, _ = res.LastInsertId()
+	return nil
+}
+
+// Query returns entries matching the filter, newest first
+func (l *Log) Query(f Filter) ([]Entry, error) {
+	var where []string
+	var args []any
+	add := func(clause string, arg any) {
+		where = append(where, clause)
+		args = append(args, arg)
+	}
+	if f.SessionKey != "" {
+		add("session_key = ?", f.SessionKey)
+	}
+	if f.Tool != "" {
+		add("tool = ?", f.Tool)
+	}
+	if f.Channel != "" {
+		add("channel = ?", f.Channel)
+	}
+	if f.Approver != "" {
+		add("approver = ?", f.Approver)
+	}
+	if f.ErrorsOnly {
+		where = append(where, "is_error = 1")
+	}
+	if f.DeniedOnly {
+		where = append(where, "approved = 0")
+	}
+	if !f.Since.IsZero() {
+		add("created_at >= ?", f.Since.UnixMilli())
+	}
+	if !f.Until.IsZero() {
+		add("created_at < ?", f.Until.UnixMilli())
+	}
+
+	query := `SELECT id, created_at, session_key, tool_call_id, tool, input, output,
+		is_error, approved, channel, approver, duration_ms FROM tool_audit`
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	limit := f.Limit
+	if limit <= 0 {
+		limit = 100
+	}
+	query += " ORDER BY created_at DESC, id DESC LIMIT ? OFFSET ?"
+	args = append(args, limit, f.Offset)
+
+	rows, err := l.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []Entry
+	for rows.Next() {
+		var e Entry
+		var createdAt int64
+		var sessionKey, toolCallID, input, output, channel, approver sql.NullString
+		if err := rows.Scan(&e.ID, &createdAt, &sessionKey, &toolCallID, &e.Tool, &input, &output,
+			&e.IsError, &e.Approved, &channel, &approver, &e.DurationMS); err != nil {
+			return nil, err
+		}
+		e.CreatedAt = time.UnixMilli(createdAt)
+		e.SessionKey = sessionKey.String
+		e.ToolCallID = toolCallID.String
+		e.Input = input.String
+		e.Output = output.String
+		e.Channel = channel.String
+		e.Approver = approver.String
+		entries = append(entries, e)
+	}
+
+	return entries, rows.Err()
+}
+
+// truncate shortens s to at most max bytes, marking the cut
+func truncate(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	return s[:max] + "... [truncated]"
+}
+
+// sessionKeyCtx is the context key for the session a tool call belongs to
+type sessionKeyCtx struct{}
+
+// WithSession returns a context that attributes tool calls to a session
+func WithSession(ctx context.Context, sessionKey string) context.Context {
+	return context.WithValue(ctx, sessionKeyCtx{}, sessionKey)
+}
+
+// SessionFrom returns the session key set by WithSession, or ""
+func SessionFrom(ctx context.Context) string {
+	key, _ := ctx.Value(sessionKeyCtx{}).(string)
+	return key
+}
+
+// ParseTime parses a filter bound: a duration before now ("24h", "30m"), a date ("2006-01-02")
+// or an RFC 3339 timestamp
+func ParseTime(s string) (time.Time, error) {
+	if d, err := time.ParseDuration(s); err == nil {
+		return time.Now().Add(-d), nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", s, time.Local); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q (use a duration like 24h, a date like 2006-01-02, or RFC 3339)", s)
+}
@@ -0,0 +1,89 @@
+package audit
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRecordAndQuery(t *testing.T) {
+	l, err := Open(filepath.Join(t.TempDir(), "audit.db"))
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer l.Close()
+
+	old := time.Now().Add(-48 * time.Hour)
+	entries := []*Entry{
+		{CreatedAt: old, SessionKey: "a", Tool: "bash", Input: `{"command":"ls"}`, Output: "ok", Approved: true, Channel: ChannelAuto},
+		{SessionKey: "a", Tool: "write", Output: strings.Repeat("x", MaxOutput+10), Approved: true, Channel: ChannelCLI, Approver: "bob"},
+		{SessionKey: "b", Tool: "bash", Output: "denied", IsError: true, Channel: ChannelWeb, Approver: "alice"},
+	}
+	for _, e := range entries {
+		if err := l.Record(e); err != nil {
+			t.Fatalf("Record failed: %v", err)
+		}
+	}
+
+	all, _ := l.Query(Filter{})
+	if len(all) != 3 || all[0].Tool != "bash" || all[0].SessionKey != "b" {
+		t.Fatalf("expected newest first, got %+v", all)
+	}
+	if len(all[1].Output) > MaxOutput+len("... [truncated]") {
+		t.Errorf("output not truncated: %d bytes", len(all[1].Output))
+	}
+
+	tests := []struct {
+		name   string
+		filter Filter
+		want   int
+	}{
+		{"session", Filter{SessionKey: "a"}, 2},
+		{"tool", Filter{Tool: "bash"}, 2},
+		{"channel", Filter{Channel: ChannelCLI}, 1},
+		{"approver", Filter{Approver: "alice"}, 1},
+		{"errors", Filter{ErrorsOnly: true}, 1},
+		{"denied", Filter{DeniedOnly: true}, 1},
+		{"since", Filter{Since: time.Now().Add(-time.Hour)}, 2},
+		{"until", Filter{Until: time.Now().Add(-time.Hour)}, 1},
+		{"limit", Filter{Limit: 1}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := l.Query(tt.filter)
+			if err != nil {
+				t.Fatalf("Query failed: %v", err)
+			}
+			if len(got) != tt.want {
+				t.Errorf("got %d entries, want %d", len(got), tt.want)
+			}
+		})
+	}
+}
+
+func TestAppendOnly(t *testing.T) {
+	l, err := Open(filepath.Join(t.TempDir(), "audit.db"))
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer l.Close()
+
+	l.Record(&Entry{Tool: "bash"})
+	if _, err := l.db.Exec("UPDATE tool_audit SET tool = 'read'"); err == nil {
+		t.Error("expected update to be rejected")
+	}
+	if _, err := l.db.Exec("DELETE FROM tool_audit"); err == nil {
+		t.Error("expected delete to be rejected")
+	}
+}
+
+func TestWithSession(t *testing.T) {
+	if got := SessionFrom(context.Background()); got != "" {
+		t.Errorf("expected empty session, got %q", got)
+	}
+	if got := SessionFrom(WithSession(context.Background(), "ops")); got != "ops" {
+		t.Errorf("expected ops, got %q", got)
+	}
+}
@@ -0,0 +1,78 @@
+package config
+
+import (
+	"path"
+)
+
+// BudgetConfig holds spend limits checked by the runner before every provider call.
+// Zero values mean "no limit".
+type BudgetConfig struct {
+	PerRun     BudgetLimit `yaml:"per_run,omitempty"`     // Spend within a single Run
+	PerSession BudgetLimit `yaml:"per_session,omitempty"` // Lifetime spend of a session key
+	PerDay     BudgetLimit `yaml:"per_day,omitempty"`     // Spend across all sessions today
+
+	// Overrides apply to session keys matching a glob (e.g. "cron-*"), checked in order.
+	// Set limits replace the defaults above; an override's per_day applies to the matching session only.
+	Overrides []BudgetOverride `yaml:"overrides,omitempty"`
+}
+
+// BudgetLimit defines soft and hard limits in dollars and tokens
+type BudgetLimit struct {
+	SoftUSD    float64 `yaml:"soft_usd,omitempty"`    // Downgrade to a cheaper model
+	HardUSD    float64 `yaml:"hard_usd,omitempty"`    // Stop the run
+	SoftTokens int64   `yaml:"soft_tokens,omitempty"` // Downgrade to a cheaper model
+	HardTokens int64   `yaml:"hard_tokens,omitempty"` // Stop the run
+}
+
+// BudgetOverride replaces the default limits for matching session keys
+type BudgetOverride struct {
+	Match      string       `yaml:"match"` // Glob on the session key (path.Match syntax)
+	PerRun     *BudgetLimit `yaml:"per_run,omitempty"`
+	PerSession *BudgetLimit `yaml:"per_session,omitempty"`
+	PerDay     *BudgetLimit `yaml:"per_day,omitempty"` // Per matching session, per day
+}
+
+// BudgetLimits is the resolved set of limits for one session key
+type BudgetLimits struct {
+	PerRun        BudgetLimit
+	PerSession    BudgetLimit
+	PerDay        BudgetLimit // All sessions combined
+	SessionPerDay BudgetLimit // This session only (from overrides)
+}
+
+// IsZero returns true if no limit is set
+func (l BudgetLimit) IsZero() bool {
+	return l.SoftUSD == 0 && l.HardUSD == 0 && l.SoftTokens == 0 && l.HardTokens == 0
+}
+
+// IsZero returns true if no budgets are configured
+func (b *BudgetConfig) IsZero() bool {
+	return b.PerRun.IsZero() && b.PerSession.IsZero() && b.PerDay.IsZero() && len(b.Overrides) == 0
+}
+
+// LimitsFor resolves the limits that apply to a session key
+func (b *BudgetConfig) LimitsFor(sessionKey string) BudgetLimits {
+	limits := BudgetLimits{
+		PerRun:     b.PerRun,
+		PerSession: b.PerSession,
+		PerDay:     b.PerDay,
+	}
+
+	for _, o := range b.Overrides {
+		if matched, _ := path.Match(o.Match, sessionKey); !matched {
+			continue
+		}
+		if o.PerRun != nil {
+			limits.PerRun = *o.PerRun
+		}
+		if o.PerSession != nil {
+			limits.PerSession = *o.PerSession
+		}
+		if o.PerDay != nil {
+			limits.SessionPerDay = *o.PerDay
+		}
+		break
+	}
+
+	return limits
+}
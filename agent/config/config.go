@@ -4,6 +4,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"gobot/internal/provider"
 
@@ -17,13 +18,28 @@ type Config struct {
 
 	// Session settings
 	DataDir    string `yaml:"data_dir"`    // ~/.gobot
-	MaxContext int    `yaml:"max_context"` // Max messages before compaction
+	MaxContext int    `yaml:"max_context"` // Max messages sent when the model's context window is unknown
+
+	// Summarize older turns as the prompt approaches the model's context window
+	Compaction CompactionConfig `yaml:"compaction"`
 
 	// Execution settings
-	MaxIterations int `yaml:"max_iterations"` // Safety limit (default: 100)
+	MaxIterations    int      `yaml:"max_iterations"`         // Safety limit (default: 100)
+	MaxParallelTools int      `yaml:"max_parallel_tools"`     // Concurrent tool calls per turn (default: 4, 1 = sequential)
+	SerialTools      []string `yaml:"serial_tools,omitempty"` // Extra tools that must never run concurrently
 
 	// Tool settings
 	Policy PolicyConfig `yaml:"policy"`
+	Web    WebConfig    `yaml:"web,omitempty"` // What the web tool may fetch
+
+	// Where bash commands run at each policy level
+	Sandbox SandboxConfig `yaml:"sandbox,omitempty"`
+
+	// Spend limits enforced before each provider call
+	Budgets BudgetConfig `yaml:"budgets,omitempty"`
+
+	// External MCP servers whose tools are mounted into the registry
+	MCPServers []MCPServerConfig `yaml:"mcp_servers,omitempty"`
 
 	// SaaS connection settings
 	ServerURL string `yaml:"server_url"` // SaaS server URL
@@ -32,13 +48,14 @@ type Config struct {
 
 // ProviderConfig holds configuration for a single provider
 type ProviderConfig struct {
-	Name    string   `yaml:"name"`               // Identifier for this provider
-	Type    string   `yaml:"type"`               // "api", "cli", or "ollama"
-	APIKey  string   `yaml:"api_key,omitempty"`  // For API providers
-	Model   string   `yaml:"model,omitempty"`    // Model to use
-	Command string   `yaml:"command,omitempty"`  // For CLI providers (binary path)
-	Args    []string `yaml:"args,omitempty"`     // Default CLI arguments
-	BaseURL string   `yaml:"base_url,omitempty"` // For Ollama (default: http://localhost:11434)
+	Name    string            `yaml:"name"`               // Identifier for this provider
+	Type    string            `yaml:"type"`               // "api", "cli", "ollama" or "openai-compatible"
+	APIKey  string            `yaml:"api_key,omitempty"`  // For API providers
+	Model   string            `yaml:"model,omitempty"`    // Model to use
+	Command string            `yaml:"command,omitempty"`  // For CLI providers (binary path)
+	Args    []string          `yaml:"args,omitempty"`     // Default CLI arguments
+	BaseURL string            `yaml:"base_url,omitempty"` // For Ollama (default: http://localhost:11434) and openai-compatible APIs
+	Headers map[string]string `yaml:"headers,omitempty"`  // Extra HTTP headers for openai-compatible APIs
 }
 
 // PolicyConfig holds approval policy settings
@@ -46,15 +63,77 @@ type PolicyConfig struct {
 	Level     string   `yaml:"level"`     // "deny", "allowlist", "full"
 	AskMode   string   `yaml:"ask_mode"`  // "off", "on-miss", "always"
 	Allowlist []string `yaml:"allowlist"` // Approved command patterns
+
+	// Rules decide calls by tool name and arguments; deny wins over ask, ask over allow
+	Rules []PolicyRule `yaml:"rules,omitempty"`
+}
+
+// SandboxConfig selects how bash commands are isolated from the host
+type SandboxConfig struct {
+	Executor string            `yaml:"executor,omitempty"` // "host" (default), "bubblewrap" or "container"
+	Levels   map[string]string `yaml:"levels,omitempty"`   // Executor per policy level, e.g. full: container
+	Network  bool              `yaml:"network,omitempty"`  // Allow network access inside sandboxes
+	Writable []string          `yaml:"writable,omitempty"` // Writable paths besides the workspace
+	Runtime  string            `yaml:"runtime,omitempty"`  // Container runtime: docker or podman (default: whichever is installed)
+	Image    string            `yaml:"image,omitempty"`    // Container image (default: debian:stable-slim)
+}
+
+// PolicyRule matches tool calls by name and argument patterns
+type PolicyRule struct {
+	Tool   string            `yaml:"tool"`             // Tool name glob, e.g. "write" or "mcp_github_*"
+	Args   map[string]string `yaml:"args,omitempty"`   // Argument globs that must all match; "!" negates
+	Action string            `yaml:"action"`           // allow, ask or deny
+	Reason string            `yaml:"reason,omitempty"` // Shown when the rule asks or denies
+}
+
+// WebConfig holds the web tool's network policy and cache settings
+type WebConfig struct {
+	AllowPrivate bool          `yaml:"allow_private,omitempty"` // Allow localhost, private and link-local addresses
+	AllowDomains []string      `yaml:"allow_domains,omitempty"` // Only fetch these domains and their subdomains
+	DenyDomains  []string      `yaml:"deny_domains,omitempty"`  // Never fetch these domains and their subdomains
+	CacheTTL     time.Duration `yaml:"cache_ttl,omitempty"`     // Reuse fetched pages (default: 5m, negative disables)
+}
+
+// MCPServerConfig describes an external MCP server the agent connects to as a client.
+// Exactly one of Command (stdio transport) or URL (streamable HTTP transport) should be set.
+type MCPServerConfig struct {
+	Name            string            `yaml:"name"`                       // Namespace for the server's tools
+	Command         string            `yaml:"command,omitempty"`          // Stdio: binary to launch
+	Args            []string          `yaml:"args,omitempty"`             // Stdio: command arguments
+	Env             map[string]string `yaml:"env,omitempty"`              // Stdio: extra environment variables
+	URL             string            `yaml:"url,omitempty"`              // HTTP: streamable endpoint URL
+	Headers         map[string]string `yaml:"headers,omitempty"`          // HTTP: extra request headers
+	RequireApproval *bool             `yaml:"require_approval,omitempty"` // nil = true (ask via policy)
+	Disabled        bool              `yaml:"disabled,omitempty"`         // Skip this server
+}
+
+// NeedsApproval returns whether calls to this server's tools go through policy approval
+func (m *MCPServerConfig) NeedsApproval() bool {
+	if m.RequireApproval == nil {
+		return true
+	}
+	return *m.RequireApproval
+}
+
+// CompactionConfig controls LLM summarization of long sessions
+type CompactionConfig struct {
+	Threshold float64 `yaml:"threshold"`       // Fraction of the context window that triggers compaction (default: 0.8)
+	KeepTurns int     `yaml:"keep_turns"`      // Most recent turns kept verbatim (default: 4)
+	Model     string  `yaml:"model,omitempty"` // Summarization model (default: cheapest available)
 }
 
 // DefaultConfig returns a config with sensible defaults
 func DefaultConfig() *Config {
 	return &Config{
-		Providers:     []ProviderConfig{}, // Loaded from models.yaml
-		DataDir:       DefaultDataDir(),
-		MaxContext:    50,
-		MaxIterations: 100,
+		Providers:        []ProviderConfig{}, // Loaded from models.yaml
+		DataDir:          DefaultDataDir(),
+		MaxContext:       50,
+		MaxIterations:    100,
+		MaxParallelTools: 4,
+		Compaction: CompactionConfig{
+			Threshold: 0.8,
+			KeepTurns: 4,
+		},
 		Policy: PolicyConfig{
 			Level:   "allowlist",
 			AskMode: "on-miss",
@@ -97,6 +176,7 @@ func Load() (*Config, error) {
 
 	cfg.ServerURL = os.ExpandEnv(cfg.ServerURL)
 	cfg.Token = os.ExpandEnv(cfg.Token)
+	cfg.expandMCPServers()
 
 	// Load providers from models.yaml
 	cfg.loadProvidersFromModels()
@@ -119,6 +199,7 @@ func LoadFrom(path string) (*Config, error) {
 
 	cfg.ServerURL = os.ExpandEnv(cfg.ServerURL)
 	cfg.Token = os.ExpandEnv(cfg.Token)
+	cfg.expandMCPServers()
 
 	// Load providers from models.yaml
 	cfg.loadProvidersFromModels()
@@ -176,6 +257,20 @@ func (c *Config) FirstValidProvider() *ProviderConfig {
 	return nil
 }
 
+// expandMCPServers expands env vars in MCP server URLs, headers and environment
+func (c *Config) expandMCPServers() {
+	for i := range c.MCPServers {
+		srv := &c.MCPServers[i]
+		srv.URL = os.ExpandEnv(srv.URL)
+		for k, v := range srv.Headers {
+			srv.Headers[k] = os.ExpandEnv(v)
+		}
+		for k, v := range srv.Env {
+			srv.Env[k] = os.ExpandEnv(v)
+		}
+	}
+}
+
 // loadProvidersFromModels loads provider credentials from models.yaml
 func (c *Config) loadProvidersFromModels() {
 	// Initialize the models store with the data directory
@@ -190,7 +285,9 @@ func (c *Config) loadProvidersFromModels() {
 	// Convert credentials to ProviderConfig entries
 	for name, cred := range creds {
 		providerType := "api"
-		if cred.Command != "" {
+		if cred.Type != "" {
+			providerType = cred.Type
+		} else if cred.Command != "" {
 			providerType = "cli"
 		} else if name == "ollama" {
 			providerType = "ollama"
@@ -212,6 +309,14 @@ func (c *Config) loadProvidersFromModels() {
 			args = strings.Fields(cred.Args)
 		}
 
+		var headers map[string]string
+		if len(cred.Headers) > 0 {
+			headers = make(map[string]string, len(cred.Headers))
+			for k, v := range cred.Headers {
+				headers[k] = os.ExpandEnv(v)
+			}
+		}
+
 		c.Providers = append(c.Providers, ProviderConfig{
 			Name:    name,
 			Type:    providerType,
@@ -220,6 +325,7 @@ func (c *Config) loadProvidersFromModels() {
 			Command: cred.Command,
 			Args:    args,
 			BaseURL: os.ExpandEnv(cred.BaseURL), // Expand env vars
+			Headers: headers,
 		})
 	}
 }
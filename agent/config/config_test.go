@@ -266,3 +266,71 @@ max_context: 75
 		t.Errorf("expected MaxContext 75, got %d", loaded.MaxContext)
 	}
 }
+
+func TestBudgetLimitsFor(t *testing.T) {
+	runLimit := BudgetLimit{HardUSD: 0.5}
+	dayLimit := BudgetLimit{SoftUSD: 1, HardUSD: 2}
+	b := BudgetConfig{
+		PerRun:     BudgetLimit{HardUSD: 5},
+		PerSession: BudgetLimit{SoftTokens: 1000},
+		PerDay:     BudgetLimit{HardUSD: 20},
+		Overrides: []BudgetOverride{
+			{Match: "cron-*", PerRun: &runLimit, PerDay: &dayLimit},
+		},
+	}
+
+	limits := b.LimitsFor("default")
+	if limits.PerRun.HardUSD != 5 || !limits.SessionPerDay.IsZero() {
+		t.Errorf("unexpected default limits: %+v", limits)
+	}
+
+	limits = b.LimitsFor("cron-nightly")
+	if limits.PerRun.HardUSD != 0.5 {
+		t.Errorf("expected cron run override, got %+v", limits.PerRun)
+	}
+	if limits.PerSession.SoftTokens != 1000 {
+		t.Errorf("expected default session limit to remain, got %+v", limits.PerSession)
+	}
+	if limits.SessionPerDay.HardUSD != 2 || limits.PerDay.HardUSD != 20 {
+		t.Errorf("unexpected day limits: %+v", limits)
+	}
+
+	if !(&BudgetConfig{}).IsZero() {
+		t.Error("expected empty budget config to be zero")
+	}
+}
+
+func TestLoadOpenAICompatibleProviders(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("GATEWAY_TOKEN", "secret")
+	models := `version: "1.0"
+credentials:
+  gateway:
+    type: openai-compatible
+    base_url: https://llm.internal/v1
+    api_key: ${GATEWAY_TOKEN}
+    headers:
+      X-Team: agents
+      X-Token: ${GATEWAY_TOKEN}
+providers:
+  gateway:
+    - id: qwen3-coder
+      active: true
+`
+	if err := os.WriteFile(filepath.Join(dir, "models.yaml"), []byte(models), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &Config{DataDir: dir}
+	cfg.loadProvidersFromModels()
+	if len(cfg.Providers) != 1 {
+		t.Fatalf("expected 1 provider, got %+v", cfg.Providers)
+	}
+	p := cfg.Providers[0]
+	if p.Name != "gateway" || p.Type != "openai-compatible" || p.BaseURL != "https://llm.internal/v1" || p.Model != "qwen3-coder" {
+		t.Errorf("unexpected provider %+v", p)
+	}
+	if p.APIKey != "secret" || p.Headers["X-Team"] != "agents" || p.Headers["X-Token"] != "secret" {
+		t.Errorf("expected env vars expanded in key and headers, got %+v", p)
+	}
+}
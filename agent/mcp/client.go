@@ -0,0 +1,566 @@
+package mcp
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"gobot/agent/config"
+	"gobot/agent/tools"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+const (
+	connectTimeout    = 30 * time.Second
+	minReconnectDelay = 1 * time.Second
+	maxReconnectDelay = 2 * time.Minute
+	maxToolNameLength = 64 // Anthropic/OpenAI tool name limit
+)
+
+// ClientManager connects to external MCP servers and mounts their tools into a registry
+type ClientManager struct {
+	registry *tools.Registry
+
+	mu      sync.Mutex
+	servers map[string]*remoteServer
+}
+
+// NewClientManager creates a manager that registers remote tools into the given registry
+func NewClientManager(registry *tools.Registry) *ClientManager {
+	return &ClientManager{
+		registry: registry,
+		servers:  make(map[string]*remoteServer),
+	}
+}
+
+// Mount connects to each configured server and registers its tools.
+// Servers that fail to connect are retried in the background until ctx is cancelled.
+func (m *ClientManager) Mount(ctx context.Context, servers []config.MCPServerConfig) {
+	for _, cfg := range servers {
+		if cfg.Disabled {
+			continue
+		}
+		if cfg.Name == "" || (cfg.Command == "" && cfg.URL == "") {
+			fmt.Printf("[MCP] Skipping server %q: name and command or url are required\n", cfg.Name)
+			continue
+		}
+
+		srv := newRemoteServer(cfg, m.registry)
+		m.mu.Lock()
+		if _, exists := m.servers[cfg.Name]; exists {
+			m.mu.Unlock()
+			fmt.Printf("[MCP] Skipping duplicate server name %q\n", cfg.Name)
+			continue
+		}
+		m.servers[cfg.Name] = srv
+		m.mu.Unlock()
+
+		m.start(ctx, srv)
+	}
+}
+
+// start makes a first synchronous connection attempt so tools are available
+// before the first run, then supervises the connection in the background
+func (m *ClientManager) start(ctx context.Context, srv *remoteServer) {
+	if err := srv.connect(ctx); err != nil {
+		fmt.Printf("[MCP] Failed to connect to %s: %v (will retry)\n", srv.cfg.Name, err)
+	}
+	go srv.supervise(ctx)
+}
+
+// Servers returns the status of all mounted servers
+func (m *ClientManager) Servers() []ServerStatus {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	statuses := make([]ServerStatus, 0, len(m.servers))
+	for _, srv := range m.servers {
+		statuses = append(statuses, srv.status())
+	}
+	return statuses
+}
+
+// Close disconnects from all servers and unregisters their tools
+func (m *ClientManager) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var errs []error
+	for name, srv := range m.servers {
+		if err := srv.close(); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+		}
+		delete(m.servers, name)
+	}
+	return errors.Join(errs...)
+}
+
+// ServerStatus describes the state of a mounted MCP server
+type ServerStatus struct {
+	Name      string   `json:"name"`
+	Connected bool     `json:"connected"`
+	Tools     []string `json:"tools"`
+	LastError string   `json:"last_error,omitempty"`
+}
+
+// remoteServer tracks the connection to one external MCP server
+type remoteServer struct {
+	cfg      config.MCPServerConfig
+	registry *tools.Registry
+	client   *mcp.Client
+
+	// transport builds a fresh transport for each connection attempt
+	transport func() (mcp.Transport, error)
+
+	mu        sync.RWMutex
+	session   *mcp.ClientSession
+	toolNames []string
+	lastErr   error
+	closed    bool
+
+	refresh chan struct{}
+}
+
+// newRemoteServer creates a remote server for the given config
+func newRemoteServer(cfg config.MCPServerConfig, registry *tools.Registry) *remoteServer {
+	s := &remoteServer{
+		cfg:      cfg,
+		registry: registry,
+		refresh:  make(chan struct{}, 1),
+	}
+	s.transport = s.defaultTransport
+	s.client = mcp.NewClient(&mcp.Implementation{
+		Name:    "gobot-agent",
+		Version: "1.0.0",
+	}, &mcp.ClientOptions{
+		ToolListChangedHandler: func(context.Context, *mcp.ToolListChangedRequest) {
+			select {
+			case s.refresh <- struct{}{}:
+			default:
+			}
+		},
+	})
+	return s
+}
+
+// defaultTransport builds a stdio or streamable HTTP transport from config
+func (s *remoteServer) defaultTransport() (mcp.Transport, error) {
+	if s.cfg.Command != "" {
+		cmd := exec.Command(s.cfg.Command, s.cfg.Args...)
+		cmd.Env = os.Environ()
+		for k, v := range s.cfg.Env {
+			cmd.Env = append(cmd.Env, k+"="+v)
+		}
+		return &mcp.CommandTransport{Command: cmd}, nil
+	}
+
+	if s.cfg.URL != "" {
+		httpClient := &http.Client{}
+		if len(s.cfg.Headers) > 0 {
+			httpClient.Transport = &headerTransport{
+				headers: s.cfg.Headers,
+				base:    http.DefaultTransport,
+			}
+		}
+		return &mcp.StreamableClientTransport{
+			Endpoint:   s.cfg.URL,
+			HTTPClient: httpClient,
+		}, nil
+	}
+
+	return nil, fmt.Errorf("no command or url configured")
+}
+
+// connect opens a session and syncs the server's tools into the registry
+func (s *remoteServer) connect(ctx context.Context) error {
+	transport, err := s.transport()
+	if err != nil {
+		s.setError(err)
+		return err
+	}
+
+	connectCtx, cancel := context.WithTimeout(ctx, connectTimeout)
+	defer cancel()
+
+	session, err := s.client.Connect(connectCtx, transport, nil)
+	if err != nil {
+		s.setError(err)
+		return err
+	}
+
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		session.Close()
+		return fmt.Errorf("server closed")
+	}
+	s.session = session
+	s.lastErr = nil
+	s.mu.Unlock()
+
+	if err := s.syncTools(connectCtx); err != nil {
+		s.setError(err)
+		session.Close()
+		return err
+	}
+
+	fmt.Printf("[MCP] Connected to %s (%d tools)\n", s.cfg.Name, len(s.registeredTools()))
+	return nil
+}
+
+// supervise waits for the session to end and reconnects with exponential backoff
+func (s *remoteServer) supervise(ctx context.Context) {
+	delay := minReconnectDelay
+
+	for {
+		if session := s.currentSession(); session != nil {
+			done := make(chan struct{})
+			go func() {
+				session.Wait()
+				close(done)
+			}()
+
+			// Wait for disconnect, refreshing tools when the server announces changes
+		waitLoop:
+			for {
+				select {
+				case <-ctx.Done():
+					s.close()
+					return
+				case <-s.refresh:
+					if err := s.syncTools(ctx); err != nil {
+						fmt.Printf("[MCP] Failed to refresh tools for %s: %v\n", s.cfg.Name, err)
+					}
+				case <-done:
+					break waitLoop
+				}
+			}
+
+			s.mu.Lock()
+			if s.session == session {
+				s.session = nil
+			}
+			closed := s.closed
+			s.mu.Unlock()
+			if closed {
+				return
+			}
+			fmt.Printf("[MCP] Disconnected from %s, reconnecting\n", s.cfg.Name)
+			delay = minReconnectDelay
+		}
+
+		select {
+		case <-ctx.Done():
+			s.close()
+			return
+		case <-time.After(delay):
+		}
+
+		if s.isClosed() {
+			return
+		}
+		if err := s.connect(ctx); err != nil {
+			delay *= 2
+			if delay > maxReconnectDelay {
+				delay = maxReconnectDelay
+			}
+		}
+	}
+}
+
+// syncTools lists the server's tools and registers them, removing any that disappeared
+func (s *remoteServer) syncTools(ctx context.Context) error {
+	session := s.currentSession()
+	if session == nil {
+		return fmt.Errorf("not connected")
+	}
+
+	var names []string
+	for tool, err := range session.Tools(ctx, nil) {
+		if err != nil {
+			return fmt.Errorf("failed to list tools: %w", err)
+		}
+
+		schema, err := json.Marshal(tool.InputSchema)
+		if err != nil || tool.InputSchema == nil {
+			schema = json.RawMessage(`{"type":"object","properties":{}}`)
+		}
+
+		rt := &remoteTool{
+			server:      s,
+			name:        ToolName(s.cfg.Name, tool.Name),
+			remoteName:  tool.Name,
+			description: tool.Description,
+			schema:      schema,
+		}
+		s.register(rt)
+		names = append(names, rt.name)
+	}
+
+	s.mu.Lock()
+	previous := s.toolNames
+	s.toolNames = names
+	s.mu.Unlock()
+
+	current := make(map[string]bool, len(names))
+	for _, n := range names {
+		current[n] = true
+	}
+	for _, n := range previous {
+		if !current[n] {
+			s.registry.Unregister(n)
+		}
+	}
+
+	return nil
+}
+
+// registerMu serializes name checks and registration across servers sharing a registry
+var registerMu sync.Mutex
+
+// register adds a remote tool to the registry. If its name already belongs to another tool
+// (sanitizing and truncating can map different names together), a hash suffix keeps both.
+func (s *remoteServer) register(rt *remoteTool) {
+	registerMu.Lock()
+	defer registerMu.Unlock()
+
+	if existing, ok := s.registry.Get(rt.name); ok {
+		if other, ok := existing.(*remoteTool); !ok || other.server != s || other.remoteName != rt.remoteName {
+			unique := uniqueToolName(s.cfg.Name, rt.remoteName)
+			if _, ok := s.registry.Get(unique); !ok {
+				fmt.Printf("[MCP] Tool %q from %s collides with %s, registering it as %s\n", rt.remoteName, s.cfg.Name, rt.name, unique)
+			}
+			rt.name = unique
+		}
+	}
+	s.registry.Register(rt)
+}
+
+// callTool invokes a tool on the remote server
+func (s *remoteServer) callTool(ctx context.Context, name string, input json.RawMessage) (*tools.ToolResult, error) {
+	session := s.currentSession()
+	if session == nil {
+		return &tools.ToolResult{
+			Content: fmt.Sprintf("MCP server %q is not connected (reconnecting)", s.cfg.Name),
+			IsError: true,
+		}, nil
+	}
+
+	var args any = map[string]any{}
+	if len(input) > 0 && string(input) != "null" {
+		args = input
+	}
+
+	result, err := session.CallTool(ctx, &mcp.CallToolParams{
+		Name:      name,
+		Arguments: args,
+	})
+	if err != nil {
+		if errors.Is(err, mcp.ErrConnectionClosed) {
+			// Force the supervisor to notice the dead connection
+			session.Close()
+		}
+		return nil, err
+	}
+
+	return &tools.ToolResult{
+		Content: formatContent(result),
+		IsError: result.IsError,
+	}, nil
+}
+
+// currentSession returns the active session, or nil when disconnected
+func (s *remoteServer) currentSession() *mcp.ClientSession {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.session
+}
+
+// registeredTools returns the namespaced names of the mounted tools
+func (s *remoteServer) registeredTools() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append([]string(nil), s.toolNames...)
+}
+
+// setError records the last connection error
+func (s *remoteServer) setError(err error) {
+	s.mu.Lock()
+	s.lastErr = err
+	s.mu.Unlock()
+}
+
+// isClosed returns true once close has been called
+func (s *remoteServer) isClosed() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.closed
+}
+
+// status returns a snapshot of the server state
+func (s *remoteServer) status() ServerStatus {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	st := ServerStatus{
+		Name:      s.cfg.Name,
+		Connected: s.session != nil,
+		Tools:     append([]string(nil), s.toolNames...),
+	}
+	if s.lastErr != nil {
+		st.LastError = s.lastErr.Error()
+	}
+	return st
+}
+
+// close ends the session and unregisters all tools
+func (s *remoteServer) close() error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.closed = true
+	session := s.session
+	s.session = nil
+	names := s.toolNames
+	s.toolNames = nil
+	s.mu.Unlock()
+
+	for _, n := range names {
+		s.registry.Unregister(n)
+	}
+
+	if session != nil {
+		return session.Close()
+	}
+	return nil
+}
+
+// remoteTool exposes a single MCP server tool through the tools.Tool interface
+type remoteTool struct {
+	server      *remoteServer
+	name        string
+	remoteName  string
+	description string
+	schema      json.RawMessage
+}
+
+// Name returns the namespaced tool name
+func (t *remoteTool) Name() string {
+	return t.name
+}
+
+// Description returns the remote tool description, tagged with its server
+func (t *remoteTool) Description() string {
+	return fmt.Sprintf("[MCP: %s] %s", t.server.cfg.Name, t.description)
+}
+
+// Schema returns the remote tool's input schema
+func (t *remoteTool) Schema() json.RawMessage {
+	return t.schema
+}
+
+// Execute forwards the call to the remote server
+func (t *remoteTool) Execute(ctx context.Context, input json.RawMessage) (*tools.ToolResult, error) {
+	return t.server.callTool(ctx, t.remoteName, input)
+}
+
+// RequiresApproval returns the server's configured approval setting
+func (t *remoteTool) RequiresApproval() bool {
+	return t.server.cfg.NeedsApproval()
+}
+
+// ToolName builds the namespaced registry name for a remote tool (mcp_<server>_<tool>)
+func ToolName(server, tool string) string {
+	name := "mcp_" + sanitizeName(server) + "_" + sanitizeName(tool)
+	if len(name) > maxToolNameLength {
+		name = name[:maxToolNameLength]
+	}
+	return name
+}
+
+// uniqueToolName is ToolName with a short hash of the original server and tool names appended
+func uniqueToolName(server, tool string) string {
+	sum := sha256.Sum256([]byte(server + "\x00" + tool))
+	suffix := "_" + hex.EncodeToString(sum[:4])
+
+	name := ToolName(server, tool)
+	if len(name) > maxToolNameLength-len(suffix) {
+		name = name[:maxToolNameLength-len(suffix)]
+	}
+	return name + suffix
+}
+
+// sanitizeName replaces characters not allowed in provider tool names
+func sanitizeName(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_', r == '-':
+			b.WriteRune(r)
+		default:
+			b.WriteRune('_')
+		}
+	}
+	return b.String()
+}
+
+// formatContent flattens MCP result content into text for the model
+func formatContent(result *mcp.CallToolResult) string {
+	var parts []string
+	for _, c := range result.Content {
+		switch v := c.(type) {
+		case *mcp.TextContent:
+			parts = append(parts, v.Text)
+		case *mcp.ImageContent:
+			parts = append(parts, fmt.Sprintf("[image: %s, %d bytes]", v.MIMEType, len(v.Data)))
+		case *mcp.AudioContent:
+			parts = append(parts, fmt.Sprintf("[audio: %s, %d bytes]", v.MIMEType, len(v.Data)))
+		case *mcp.ResourceLink:
+			parts = append(parts, fmt.Sprintf("[resource: %s]", v.URI))
+		case *mcp.EmbeddedResource:
+			if v.Resource != nil && v.Resource.Text != "" {
+				parts = append(parts, v.Resource.Text)
+			} else if v.Resource != nil {
+				parts = append(parts, fmt.Sprintf("[resource: %s]", v.Resource.URI))
+			}
+		}
+	}
+
+	if len(parts) == 0 && result.StructuredContent != nil {
+		if data, err := json.Marshal(result.StructuredContent); err == nil {
+			parts = append(parts, string(data))
+		}
+	}
+
+	if len(parts) == 0 {
+		return "(no output)"
+	}
+	return strings.Join(parts, "\n")
+}
+
+// headerTransport adds static headers to every request
+type headerTransport struct {
+	headers map[string]string
+	base    http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *headerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	for k, v := range t.headers {
+		req.Header.Set(k, v)
+	}
+	return t.base.RoundTrip(req)
+}
@@ -0,0 +1,152 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"gobot/agent/ai"
+	"gobot/agent/config"
+	"gobot/agent/tools"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+type echoInput struct {
+	Text string `json:"text"`
+}
+
+// newEchoServer starts an in-memory MCP server exposing a single echo tool
+func newEchoServer(t *testing.T, ctx context.Context) mcp.Transport {
+	t.Helper()
+
+	server := mcp.NewServer(&mcp.Implementation{Name: "test", Version: "1.0.0"}, nil)
+	mcp.AddTool(server, &mcp.Tool{Name: "echo", Description: "Echo text"},
+		func(ctx context.Context, req *mcp.CallToolRequest, in echoInput) (*mcp.CallToolResult, any, error) {
+			return &mcp.CallToolResult{
+				Content: []mcp.Content{&mcp.TextContent{Text: "echo: " + in.Text}},
+			}, nil, nil
+		})
+
+	serverTransport, clientTransport := mcp.NewInMemoryTransports()
+	if _, err := server.Connect(ctx, serverTransport, nil); err != nil {
+		t.Fatalf("server connect failed: %v", err)
+	}
+	return clientTransport
+}
+
+func TestClientManagerMountsRemoteTools(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	registry := tools.NewRegistry(tools.NewPolicyFromConfig("full", "off", nil))
+	manager := NewClientManager(registry)
+	defer manager.Close()
+
+	noApproval := false
+	srv := newRemoteServer(config.MCPServerConfig{Name: "test.srv", Command: "unused", RequireApproval: &noApproval}, registry)
+	transport := newEchoServer(t, ctx)
+	srv.transport = func() (mcp.Transport, error) { return transport, nil }
+	manager.servers[srv.cfg.Name] = srv
+	manager.start(ctx, srv)
+
+	name := ToolName("test.srv", "echo")
+	if name != "mcp_test_srv_echo" {
+		t.Fatalf("unexpected tool name: %s", name)
+	}
+
+	tool, ok := registry.Get(name)
+	if !ok {
+		t.Fatalf("expected %s to be registered", name)
+	}
+	if tool.RequiresApproval() {
+		t.Error("expected approval to be disabled by server config")
+	}
+
+	result := registry.Execute(ctx, &ai.ToolCall{
+		ID:    "1",
+		Name:  name,
+		Input: json.RawMessage(`{"text":"hi"}`),
+	})
+	if result.IsError {
+		t.Fatalf("unexpected error: %s", result.Content)
+	}
+	if result.Content != "echo: hi" {
+		t.Errorf("unexpected content: %q", result.Content)
+	}
+
+	statuses := manager.Servers()
+	if len(statuses) != 1 || !statuses[0].Connected {
+		t.Errorf("expected one connected server, got %+v", statuses)
+	}
+
+	manager.Close()
+	if _, ok := registry.Get(name); ok {
+		t.Error("expected tool to be unregistered after close")
+	}
+}
+
+func TestToolNameSanitizesAndTruncates(t *testing.T) {
+	if got := ToolName("my server", "read/file"); got != "mcp_my_server_read_file" {
+		t.Errorf("unexpected name: %s", got)
+	}
+
+	long := ToolName("server", string(make([]byte, 100)))
+	if len(long) != maxToolNameLength {
+		t.Errorf("expected name truncated to %d, got %d", maxToolNameLength, len(long))
+	}
+}
+
+func TestCollidingToolNamesAreDisambiguated(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	registry := tools.NewRegistry(tools.NewPolicyFromConfig("full", "off", nil))
+	manager := NewClientManager(registry)
+	defer manager.Close()
+
+	// Both sanitize to mcp_my_srv_echo
+	var servers []*remoteServer
+	for _, name := range []string{"my.srv", "my_srv"} {
+		srv := newRemoteServer(config.MCPServerConfig{Name: name, Command: "unused"}, registry)
+		transport := newEchoServer(t, ctx)
+		srv.transport = func() (mcp.Transport, error) { return transport, nil }
+		manager.servers[name] = srv
+		manager.start(ctx, srv)
+		servers = append(servers, srv)
+	}
+
+	first, second := servers[0].registeredTools(), servers[1].registeredTools()
+	if len(first) != 1 || first[0] != "mcp_my_srv_echo" {
+		t.Fatalf("expected the first server to keep the plain name, got %v", first)
+	}
+	if len(second) != 1 || second[0] != uniqueToolName("my_srv", "echo") || second[0] == first[0] {
+		t.Fatalf("expected the second server to get a hashed name, got %v", second)
+	}
+	for i, srv := range servers {
+		name := srv.registeredTools()[0]
+		tool, ok := registry.Get(name)
+		if !ok || tool.(*remoteTool).server != srv {
+			t.Errorf("server %d: %s is not registered to it", i, name)
+		}
+	}
+
+	// Re-syncing keeps each tool under the name it already has
+	if err := servers[1].syncTools(ctx); err != nil {
+		t.Fatalf("syncTools failed: %v", err)
+	}
+	if got := servers[1].registeredTools(); len(got) != 1 || got[0] != second[0] {
+		t.Errorf("expected name to stay %s after re-sync, got %v", second[0], got)
+	}
+
+	if long := uniqueToolName("server", string(make([]byte, 100))); len(long) != maxToolNameLength {
+		t.Errorf("expected hashed name truncated to %d, got %d", maxToolNameLength, len(long))
+	}
+}
+
+func TestNeedsApprovalDefaultsToTrue(t *testing.T) {
+	cfg := config.MCPServerConfig{Name: "x"}
+	if !cfg.NeedsApproval() {
+		t.Error("expected approval by default")
+	}
+}
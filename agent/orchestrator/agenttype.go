@@ -0,0 +1,216 @@
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"gobot/agent/ai"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultAgentType is used when a spawn request doesn't name a type
+const DefaultAgentType = "general"
+
+// AgentType describes a kind of sub-agent: what it is told, which tools it may use,
+// which model it runs on and how long it may work. Built-in types can be overridden and
+// new ones added with YAML files in ~/.gobot/agents/.
+type AgentType struct {
+	// Name is the identifier passed as agent_type to the task tool
+	Name string `yaml:"name"`
+
+	// Description tells the parent agent when to use this type
+	Description string `yaml:"description"`
+
+	// Prompt is role guidance added to the sub-agent's system prompt
+	Prompt string `yaml:"prompt"`
+
+	// Tools lists the tools the sub-agent may call; names may use glob patterns
+	// like "mcp_github_*". Empty allows every tool.
+	Tools []string `yaml:"tools"`
+
+	// Model pins a model ("provider/model"); when it is unavailable the task routing is used
+	Model string `yaml:"model"`
+
+	// Task routes model selection as this task type (vision, audio, reasoning, code, general).
+	// Empty classifies each request from the conversation.
+	Task string `yaml:"task"`
+
+	// MaxIterations caps the sub-agent's tool loop (0 = config max_iterations)
+	MaxIterations int `yaml:"max_iterations"`
+
+	// Timeout is how long the sub-agent may run unless the spawn request sets one (e.g. "10m")
+	Timeout time.Duration `yaml:"timeout"`
+
+	// FilePath stores where a user-defined type was loaded from
+	FilePath string `yaml:"-"`
+}
+
+// readOnlyTools can inspect files and the web but never change anything
+var readOnlyTools = []string{"read", "glob", "grep", "web", "web_search"}
+
+// BuiltinAgentTypes returns the agent types available without any configuration
+func BuiltinAgentTypes() []*AgentType {
+	return []*AgentType{
+		{
+			Name:        "general",
+			Description: "General-purpose agent with every tool",
+		},
+		{
+			Name:        "explore",
+			Description: "Codebase exploration; read-only",
+			Prompt: `You are an EXPLORATION agent. Your job is to:
+- Search through codebases to find relevant files and code
+- Understand patterns and architecture
+- Report findings clearly
+- Do NOT modify any files - only read and analyze`,
+			Tools:         readOnlyTools,
+			Task:          string(ai.TaskTypeCode),
+			MaxIterations: 30,
+		},
+		{
+			Name:        "plan",
+			Description: "Planning; read-only",
+			Prompt: `You are a PLANNING agent. Your job is to:
+- Analyze the task and break it into steps
+- Identify files that need to be modified
+- Consider edge cases and potential issues
+- Create a clear, actionable plan
+- Do NOT implement the plan - only create it`,
+			Tools:         readOnlyTools,
+			Task:          string(ai.TaskTypeReasoning),
+			MaxIterations: 30,
+		},
+	}
+}
+
+// Validate checks if the agent type definition is valid
+func (t *AgentType) Validate() error {
+	if t.Name == "" {
+		return fmt.Errorf("agent type name is required")
+	}
+	if strings.ContainsAny(t.Name, " \t\n/") {
+		return fmt.Errorf("agent type %q: name must not contain spaces or slashes", t.Name)
+	}
+	if t.Model != "" {
+		if providerID, _ := ai.ParseModelID(t.Model); providerID == "" {
+			return fmt.Errorf("agent type %q: model must be provider/model, got %q", t.Name, t.Model)
+		}
+	}
+	switch ai.TaskType(t.Task) {
+	case "", ai.TaskTypeVision, ai.TaskTypeAudio, ai.TaskTypeReasoning, ai.TaskTypeCode, ai.TaskTypeGeneral:
+	default:
+		return fmt.Errorf("agent type %q: unknown task %q", t.Name, t.Task)
+	}
+	for _, pattern := range t.Tools {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("agent type %q: invalid tool pattern %q", t.Name, pattern)
+		}
+	}
+	if t.MaxIterations < 0 {
+		return fmt.Errorf("agent type %q: max_iterations must not be negative", t.Name)
+	}
+	if t.Timeout < 0 {
+		return fmt.Errorf("agent type %q: timeout must not be negative", t.Name)
+	}
+	return nil
+}
+
+// AllowsTool reports whether the sub-agent may call the named tool
+func (t *AgentType) AllowsTool(name string) bool {
+	if len(t.Tools) == 0 {
+		return true
+	}
+	for _, pattern := range t.Tools {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// LoadAgentTypes reads agent type definitions from the .yaml and .yml files in dir.
+// A missing directory is not an error.
+func LoadAgentTypes(dir string) ([]*AgentType, error) {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read agent types: %w", err)
+	}
+
+	var types []*AgentType
+	for _, entry := range entries {
+		ext := strings.ToLower(filepath.Ext(entry.Name()))
+		if entry.IsDir() || (ext != ".yaml" && ext != ".yml") {
+			continue
+		}
+
+		filePath := filepath.Join(dir, entry.Name())
+		data, err := os.ReadFile(filePath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", filePath, err)
+		}
+
+		var agentType AgentType
+		if err := yaml.Unmarshal(data, &agentType); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", filePath, err)
+		}
+		if agentType.Name == "" {
+			agentType.Name = strings.TrimSuffix(entry.Name(), filepath.Ext(entry.Name()))
+		}
+		if err := agentType.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid agent type %s: %w", filePath, err)
+		}
+		agentType.FilePath = filePath
+		types = append(types, &agentType)
+	}
+
+	return types, nil
+}
+
+// scopedTools exposes only the tools an agent type allows
+type scopedTools struct {
+	tools     ToolExecutor
+	agentType *AgentType
+}
+
+// List returns the definitions of the allowed tools
+func (s *scopedTools) List() []ai.ToolDefinition {
+	all := s.tools.List()
+	defs := make([]ai.ToolDefinition, 0, len(all))
+	for _, def := range all {
+		if s.agentType.AllowsTool(def.Name) {
+			defs = append(defs, def)
+		}
+	}
+	return defs
+}
+
+// Execute runs an allowed tool; calls to other tools are refused without running
+func (s *scopedTools) Execute(ctx context.Context, call *ai.ToolCall) *ToolExecResult {
+	if !s.agentType.AllowsTool(call.Name) {
+		return &ToolExecResult{
+			Content: fmt.Sprintf("Error: tool %q is not available to %s agents", call.Name, s.agentType.Name),
+			IsError: true,
+		}
+	}
+	return s.tools.Execute(ctx, call)
+}
+
+// sortedTypes returns agent types ordered by name
+func sortedTypes(types map[string]*AgentType) []*AgentType {
+	list := make([]*AgentType, 0, len(types))
+	for _, t := range types {
+		list = append(list, t)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
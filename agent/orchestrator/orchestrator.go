@@ -3,12 +3,17 @@ package orchestrator
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"os"
+	"path/filepath"
 	"strings"
 	"sync"
+	"syscall"
 	"time"
 
 	"gobot/agent/ai"
+	"gobot/agent/audit"
 	"gobot/agent/config"
 	"gobot/agent/session"
 )
@@ -38,26 +43,46 @@ const (
 
 // SubAgent represents a spawned sub-agent
 type SubAgent struct {
-	ID          string
-	Task        string
-	Description string
-	Status      AgentStatus
-	Result      string
-	Error       error
-	StartedAt   time.Time
-	CompletedAt time.Time
-	Events      []ai.StreamEvent
-	cancel      context.CancelFunc
+	ID               string
+	ParentSessionKey string // Session whose agent spawned it
+	SessionKey       string // The sub-agent's own session
+	Task             string
+	Description      string
+	Type             string // Agent type name
+	Status           AgentStatus
+	Result           string
+	Error            error
+	StartedAt        time.Time
+	CompletedAt      time.Time
+	Events           []ai.StreamEvent
+	cancel           context.CancelFunc
 }
 
+// SubAgentEvent is a stream event from a sub-agent, tagged with the sub-agent it came from.
+// A final EventTypeDone event carries the result (Text) or error once the sub-agent stops.
+type SubAgentEvent struct {
+	AgentID          string
+	ParentSessionKey string
+	Description      string
+	Status           AgentStatus
+	Event            ai.StreamEvent
+}
+
+// EventHandler receives sub-agent events as they happen
+type EventHandler func(SubAgentEvent)
+
 // Orchestrator manages multiple concurrent sub-agents
 type Orchestrator struct {
-	mu        sync.RWMutex
-	agents    map[string]*SubAgent
-	sessions  *session.Manager
-	providers []ai.Provider
-	tools     ToolExecutor
-	config    *config.Config
+	mu          sync.RWMutex
+	agents      map[string]*SubAgent
+	sessions    *session.Manager
+	providers   []ai.Provider
+	providerMap map[string]ai.Provider // providerID -> Provider for model-based routing
+	tools       ToolExecutor
+	config      *config.Config
+	selector    *ai.ModelSelector
+	types       map[string]*AgentType // Agent types by name
+	onEvent     EventHandler
 
 	// Limits
 	maxConcurrent int
@@ -67,6 +92,9 @@ type Orchestrator struct {
 	results chan AgentResult
 }
 
+// DefaultTimeout bounds a sub-agent when neither the request nor its type sets a timeout
+const DefaultTimeout = 5 * time.Minute
+
 // AgentResult is sent when a sub-agent completes
 type AgentResult struct {
 	AgentID string
@@ -77,30 +105,92 @@ type AgentResult struct {
 
 // NewOrchestrator creates a new orchestrator
 func NewOrchestrator(cfg *config.Config, sessions *session.Manager, providers []ai.Provider, toolExecutor ToolExecutor) *Orchestrator {
-	return &Orchestrator{
+	providerMap := make(map[string]ai.Provider)
+	for _, p := range providers {
+		// Providers are in priority order; keep the first for each ID
+		if _, exists := providerMap[p.ID()]; !exists {
+			providerMap[p.ID()] = p
+		}
+	}
+
+	types := make(map[string]*AgentType)
+	for _, t := range BuiltinAgentTypes() {
+		types[t.Name] = t
+	}
+
+	// User-defined types from ~/.gobot/agents/ add to or replace the built-ins
+	userTypes, err := LoadAgentTypes(filepath.Join(cfg.DataDir, "agents"))
+	if err != nil {
+		fmt.Printf("[orchestrator] Warning: failed to load agent types: %v\n", err)
+	}
+	for _, t := range userTypes {
+		types[t.Name] = t
+	}
+
+	o := &Orchestrator{
 		agents:        make(map[string]*SubAgent),
 		sessions:      sessions,
 		providers:     providers,
+		providerMap:   providerMap,
 		tools:         toolExecutor,
 		config:        cfg,
+		types:         types,
 		maxConcurrent: 5,  // Max 5 concurrent sub-agents
 		maxPerParent:  10, // Max 10 sub-agents per parent session
 		results:       make(chan AgentResult, 100),
 	}
+	o.recoverRuns()
+	return o
+}
+
+// SetModelSelector sets the model selector used to route sub-agents to models
+func (o *Orchestrator) SetModelSelector(selector *ai.ModelSelector) {
+	o.selector = selector
+}
+
+// SetEventHandler sets the handler that receives sub-agent events
+func (o *Orchestrator) SetEventHandler(handler EventHandler) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.onEvent = handler
+}
+
+// AgentTypes returns the available agent types ordered by name
+func (o *Orchestrator) AgentTypes() []*AgentType {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	return sortedTypes(o.types)
+}
+
+// AgentType returns an agent type by name ("" is the default type)
+func (o *Orchestrator) AgentType(name string) (*AgentType, bool) {
+	if name == "" {
+		name = DefaultAgentType
+	}
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	t, ok := o.types[name]
+	return t, ok
 }
 
 // SpawnRequest contains parameters for spawning a sub-agent
 type SpawnRequest struct {
-	ParentSessionKey string // Parent session for context inheritance
-	Task             string // Task description for the sub-agent
-	Description      string // Short description for tracking
-	Wait             bool   // Wait for completion before returning
-	Timeout          time.Duration
-	SystemPrompt     string // Optional custom system prompt
+	ParentSessionKey string        // Parent session for context inheritance
+	Task             string        // Task description for the sub-agent
+	Description      string        // Short description for tracking
+	Wait             bool          // Wait for completion before returning
+	Timeout          time.Duration // Overrides the agent type's timeout
+	SystemPrompt     string        // Optional custom system prompt
+	AgentType        string        // Agent type name (default "general")
 }
 
 // Spawn creates and starts a new sub-agent
 func (o *Orchestrator) Spawn(ctx context.Context, req *SpawnRequest) (*SubAgent, error) {
+	agentType, ok := o.AgentType(req.AgentType)
+	if !ok {
+		return nil, fmt.Errorf("unknown agent type %q", req.AgentType)
+	}
+
 	o.mu.Lock()
 
 	// Check limits
@@ -119,25 +209,33 @@ func (o *Orchestrator) Spawn(ctx context.Context, req *SpawnRequest) (*SubAgent,
 	agentID := fmt.Sprintf("agent-%d-%d", time.Now().UnixNano(), len(o.agents))
 
 	// Create sub-agent
-	agentCtx, cancel := context.WithCancel(ctx)
-	if req.Timeout > 0 {
-		agentCtx, cancel = context.WithTimeout(ctx, req.Timeout)
+	timeout := req.Timeout
+	if timeout <= 0 {
+		timeout = agentType.Timeout
 	}
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	agentCtx, cancel := context.WithTimeout(ctx, timeout)
 
 	agent := &SubAgent{
-		ID:          agentID,
-		Task:        req.Task,
-		Description: req.Description,
-		Status:      StatusPending,
-		StartedAt:   time.Now(),
-		cancel:      cancel,
+		ID:               agentID,
+		ParentSessionKey: req.ParentSessionKey,
+		SessionKey:       fmt.Sprintf("subagent-%s", agentID),
+		Task:             req.Task,
+		Description:      req.Description,
+		Type:             agentType.Name,
+		Status:           StatusPending,
+		StartedAt:        time.Now(),
+		cancel:           cancel,
 	}
 
 	o.agents[agentID] = agent
 	o.mu.Unlock()
+	o.persist(agent)
 
 	// Start the agent in a goroutine
-	go o.runAgent(agentCtx, agent, req)
+	go o.runAgent(agentCtx, agent, agentType, req)
 
 	// If wait requested, block until completion
 	if req.Wait {
@@ -148,45 +246,55 @@ func (o *Orchestrator) Spawn(ctx context.Context, req *SpawnRequest) (*SubAgent,
 }
 
 // runAgent executes the sub-agent's task
-func (o *Orchestrator) runAgent(ctx context.Context, agent *SubAgent, req *SpawnRequest) {
+func (o *Orchestrator) runAgent(ctx context.Context, agent *SubAgent, agentType *AgentType, req *SpawnRequest) {
 	// Update status
 	o.mu.Lock()
 	agent.Status = StatusRunning
 	o.mu.Unlock()
+	o.persist(agent)
 
-	defer func() {
-		o.mu.Lock()
-		agent.CompletedAt = time.Now()
-		if agent.Status == StatusRunning {
-			if agent.Error != nil {
-				agent.Status = StatusFailed
-			} else {
-				agent.Status = StatusCompleted
-			}
-		}
-		o.mu.Unlock()
+	result, err := o.runTask(ctx, agent, agentType, req)
 
-		// Send result
-		o.results <- AgentResult{
-			AgentID: agent.ID,
-			Success: agent.Status == StatusCompleted,
-			Result:  agent.Result,
-			Error:   agent.Error,
+	o.mu.Lock()
+	agent.CompletedAt = time.Now()
+	agent.Error = err
+	if err == nil {
+		agent.Result = result
+	}
+	if agent.Status == StatusRunning {
+		if agent.Error != nil {
+			agent.Status = StatusFailed
+		} else {
+			agent.Status = StatusCompleted
 		}
-	}()
+	}
+	final := AgentResult{
+		AgentID: agent.ID,
+		Success: agent.Status == StatusCompleted,
+		Result:  agent.Result,
+		Error:   agent.Error,
+	}
+	o.mu.Unlock()
+
+	o.persist(agent)
+	o.emit(agent, ai.StreamEvent{Type: ai.EventTypeDone, Text: final.Result, Error: final.Error})
+
+	// Send result
+	o.results <- final
+}
 
+// runTask sets up the sub-agent's session and runs the agentic loop
+func (o *Orchestrator) runTask(ctx context.Context, agent *SubAgent, agentType *AgentType, req *SpawnRequest) (string, error) {
 	// Create a unique session for this sub-agent
-	sessionKey := fmt.Sprintf("subagent-%s", agent.ID)
-	sess, err := o.sessions.GetOrCreate(sessionKey)
+	sess, err := o.sessions.GetOrCreate(agent.SessionKey)
 	if err != nil {
-		agent.Error = fmt.Errorf("failed to create session: %w", err)
-		return
+		return "", fmt.Errorf("failed to create session: %w", err)
 	}
 
 	// Build system prompt for sub-agent
 	systemPrompt := req.SystemPrompt
 	if systemPrompt == "" {
-		systemPrompt = o.buildSubAgentPrompt(req.Task)
+		systemPrompt = o.buildSubAgentPrompt(req.Task, agentType)
 	}
 
 	// Add the task as user message
@@ -196,31 +304,30 @@ func (o *Orchestrator) runAgent(ctx context.Context, agent *SubAgent, req *Spawn
 		Content:   req.Task,
 	})
 	if err != nil {
-		agent.Error = fmt.Errorf("failed to save task message: %w", err)
-		return
+		return "", fmt.Errorf("failed to save task message: %w", err)
 	}
 
 	// Run the agentic loop
-	result, err := o.executeLoop(ctx, sess.ID, systemPrompt, agent)
-	if err != nil {
-		agent.Error = err
-		return
-	}
-
-	agent.Result = result
+	return o.executeLoop(audit.WithSession(ctx, agent.SessionKey), sess.ID, systemPrompt, agent, agentType)
 }
 
 // executeLoop runs the agentic loop for a sub-agent
-func (o *Orchestrator) executeLoop(ctx context.Context, sessionID, systemPrompt string, agent *SubAgent) (string, error) {
+func (o *Orchestrator) executeLoop(ctx context.Context, sessionID, systemPrompt string, agent *SubAgent, agentType *AgentType) (string, error) {
 	if len(o.providers) == 0 {
 		return "", fmt.Errorf("no providers configured")
 	}
 
-	maxIterations := o.config.MaxIterations
+	maxIterations := agentType.MaxIterations
+	if maxIterations <= 0 {
+		maxIterations = o.config.MaxIterations
+	}
 	if maxIterations <= 0 {
 		maxIterations = 50 // Lower limit for sub-agents
 	}
 
+	tools := &scopedTools{tools: o.tools, agentType: agentType}
+	failed := make(map[string]bool) // Models and providers that hit rate or auth limits this run
+
 	var finalResult strings.Builder
 
 	for iteration := 0; iteration < maxIterations; iteration++ {
@@ -233,21 +340,38 @@ func (o *Orchestrator) executeLoop(ctx context.Context, sessionID, systemPrompt
 		default:
 		}
 
-		// Get session messages
-		messages, err := o.sessions.GetMessages(sessionID, o.config.MaxContext)
+		// Get session messages, keeping tool calls and results paired
+		messages, err := o.sessions.GetMessages(sessionID, 0)
 		if err != nil {
 			return "", err
 		}
+		messages = session.BuildContext(messages, session.ContextLimits{MaxMessages: o.config.MaxContext})
+
+		provider, selectedModel, modelName := o.route(agentType, messages, failed)
+		if provider == nil {
+			return finalResult.String(), fmt.Errorf("no provider available")
+		}
 
-		// Try providers in order
-		provider := o.providers[0]
 		events, err := provider.Stream(ctx, &ai.ChatRequest{
 			Messages: messages,
-			Tools:    o.tools.List(),
+			Tools:    tools.List(),
 			System:   systemPrompt,
+			Model:    modelName,
 		})
 
 		if err != nil {
+			if ai.IsRateLimitOrAuth(err) {
+				// Fail over to the next model (or provider) and try again
+				if selectedModel != "" {
+					failed[selectedModel] = true
+					if o.selector != nil {
+						o.selector.MarkFailed(selectedModel)
+					}
+				} else {
+					failed[provider.ID()] = true
+				}
+				continue
+			}
 			return "", err
 		}
 
@@ -261,6 +385,7 @@ func (o *Orchestrator) executeLoop(ctx context.Context, sessionID, systemPrompt
 			o.mu.Lock()
 			agent.Events = append(agent.Events, event)
 			o.mu.Unlock()
+			o.emit(agent, event)
 
 			switch event.Type {
 			case ai.EventTypeText:
@@ -280,14 +405,15 @@ func (o *Orchestrator) executeLoop(ctx context.Context, sessionID, systemPrompt
 			}
 		}
 
-		// Save assistant message
+		// Save assistant message; file changes made by its tool calls are checkpointed under its ID
+		var messageID int64
 		if assistantContent.Len() > 0 || len(toolCalls) > 0 {
 			var toolCallsJSON []byte
 			if len(toolCalls) > 0 {
 				toolCallsJSON, _ = json.Marshal(toolCalls)
 			}
 
-			o.sessions.AppendMessage(sessionID, session.Message{
+			messageID, _ = o.sessions.InsertMessage(sessionID, session.Message{
 				SessionID: sessionID,
 				Role:      "assistant",
 				Content:   assistantContent.String(),
@@ -300,7 +426,7 @@ func (o *Orchestrator) executeLoop(ctx context.Context, sessionID, systemPrompt
 			var toolResults []session.ToolResult
 
 			for _, tc := range toolCalls {
-				result := o.tools.Execute(ctx, &ai.ToolCall{
+				result := tools.Execute(session.WithMessage(ctx, sessionID, messageID), &ai.ToolCall{
 					ID:    tc.ID,
 					Name:  tc.Name,
 					Input: tc.Input,
@@ -311,6 +437,7 @@ func (o *Orchestrator) executeLoop(ctx context.Context, sessionID, systemPrompt
 					Content:    result.Content,
 					IsError:    result.IsError,
 				})
+				o.emit(agent, ai.StreamEvent{Type: ai.EventTypeToolResult, Text: result.Content})
 			}
 
 			// Save tool results
@@ -331,9 +458,43 @@ func (o *Orchestrator) executeLoop(ctx context.Context, sessionID, systemPrompt
 	return finalResult.String(), nil
 }
 
+// route picks the provider and model for a sub-agent's next request: the agent type's
+// pinned model, then the selector's routing for its task type, then the providers in
+// order. failed holds models and providers that already hit rate or auth limits.
+func (o *Orchestrator) route(agentType *AgentType, messages []session.Message, failed map[string]bool) (provider ai.Provider, selectedModel, modelName string) {
+	if agentType.Model != "" && !failed[agentType.Model] {
+		providerID, mn := ai.ParseModelID(agentType.Model)
+		if p, ok := o.providerMap[providerID]; ok {
+			return p, agentType.Model, mn
+		}
+	}
+
+	if o.selector != nil {
+		if agentType.Task != "" {
+			selectedModel = o.selector.SelectForTask(ai.TaskType(agentType.Task))
+		} else {
+			selectedModel = o.selector.Select(messages)
+		}
+		if selectedModel != "" && !failed[selectedModel] {
+			providerID, mn := ai.ParseModelID(selectedModel)
+			if p, ok := o.providerMap[providerID]; ok {
+				return p, selectedModel, mn
+			}
+		}
+	}
+
+	// Without a routed model, use the providers' default models in priority order
+	for _, p := range o.providers {
+		if !failed[p.ID()] {
+			return p, "", ""
+		}
+	}
+	return nil, "", ""
+}
+
 // buildSubAgentPrompt creates a system prompt for sub-agents
-func (o *Orchestrator) buildSubAgentPrompt(task string) string {
-	return fmt.Sprintf(`You are a focused sub-agent working on a specific task.
+func (o *Orchestrator) buildSubAgentPrompt(task string, agentType *AgentType) string {
+	prompt := fmt.Sprintf(`You are a focused sub-agent working on a specific task.
 
 Your task: %s
 
@@ -346,6 +507,10 @@ Guidelines:
 6. Do not engage in conversation - just complete the task
 
 When you have completed the task, provide your final response summarizing what was accomplished.`, task)
+	if agentType.Prompt != "" {
+		prompt += "\n\n" + agentType.Prompt
+	}
+	return prompt
 }
 
 // waitForAgent blocks until the agent completes
@@ -378,12 +543,20 @@ func (o *Orchestrator) waitForAgent(ctx context.Context, agentID string) (*SubAg
 	}
 }
 
-// GetAgent returns a sub-agent by ID
+// GetAgent returns a sub-agent by ID, including finished runs from earlier processes
 func (o *Orchestrator) GetAgent(agentID string) (*SubAgent, bool) {
 	o.mu.RLock()
-	defer o.mu.RUnlock()
 	agent, exists := o.agents[agentID]
-	return agent, exists
+	o.mu.RUnlock()
+	if exists || o.sessions == nil {
+		return agent, exists
+	}
+
+	run, err := o.sessions.GetSubAgentRun(agentID)
+	if err != nil {
+		return nil, false
+	}
+	return agentFromRun(run), true
 }
 
 // ListAgents returns all sub-agents
@@ -412,6 +585,7 @@ func (o *Orchestrator) CancelAgent(agentID string) error {
 		return fmt.Errorf("agent is not running: %s", agent.Status)
 	}
 
+	// runAgent saves the cancelled state once the loop stops
 	agent.Status = StatusCancelled
 	if agent.cancel != nil {
 		agent.cancel()
@@ -444,3 +618,107 @@ func (o *Orchestrator) Cleanup(maxAge time.Duration) int {
 
 	return removed
 }
+
+// emit sends a sub-agent event to the event handler, if one is set
+func (o *Orchestrator) emit(agent *SubAgent, event ai.StreamEvent) {
+	o.mu.RLock()
+	handler := o.onEvent
+	e := SubAgentEvent{
+		AgentID:          agent.ID,
+		ParentSessionKey: agent.ParentSessionKey,
+		Description:      agent.Description,
+		Status:           agent.Status,
+		Event:            event,
+	}
+	o.mu.RUnlock()
+
+	if handler != nil {
+		handler(e)
+	}
+}
+
+// persist saves a sub-agent's current state so it outlives the process
+func (o *Orchestrator) persist(agent *SubAgent) {
+	if o.sessions == nil {
+		return
+	}
+
+	o.mu.RLock()
+	run := &session.SubAgentRun{
+		ID:               agent.ID,
+		ParentSessionKey: agent.ParentSessionKey,
+		SessionKey:       agent.SessionKey,
+		Type:             agent.Type,
+		Description:      agent.Description,
+		Task:             agent.Task,
+		Status:           string(agent.Status),
+		Result:           agent.Result,
+		PID:              os.Getpid(),
+		StartedAt:        agent.StartedAt,
+		CompletedAt:      agent.CompletedAt,
+	}
+	if agent.Error != nil {
+		run.Error = agent.Error.Error()
+	}
+	o.mu.RUnlock()
+
+	if err := o.sessions.SaveSubAgentRun(run); err != nil {
+		fmt.Printf("[orchestrator] Warning: %v\n", err)
+	}
+}
+
+// recoverRuns marks runs left pending or running by an agent process that has exited as failed
+func (o *Orchestrator) recoverRuns() {
+	if o.sessions == nil {
+		return
+	}
+
+	runs, err := o.sessions.ListSubAgentRuns("")
+	if err != nil {
+		fmt.Printf("[orchestrator] Warning: failed to load sub-agent runs: %v\n", err)
+		return
+	}
+	for _, run := range runs {
+		if run.Status != string(StatusPending) && run.Status != string(StatusRunning) {
+			continue
+		}
+		if processAlive(run.PID) {
+			continue // Another agent process (e.g. a daemon beside this CLI) still owns it
+		}
+		run.Status = string(StatusFailed)
+		run.Error = "interrupted: agent process exited"
+		run.CompletedAt = time.Now()
+		if err := o.sessions.SaveSubAgentRun(&run); err != nil {
+			fmt.Printf("[orchestrator] Warning: %v\n", err)
+		}
+	}
+}
+
+// processAlive reports whether a process with the given PID exists
+func processAlive(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+	err := syscall.Kill(pid, 0)
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
+
+// agentFromRun converts a persisted run to a SubAgent (without events or a cancel func)
+func agentFromRun(run *session.SubAgentRun) *SubAgent {
+	agent := &SubAgent{
+		ID:               run.ID,
+		ParentSessionKey: run.ParentSessionKey,
+		SessionKey:       run.SessionKey,
+		Task:             run.Task,
+		Description:      run.Description,
+		Type:             run.Type,
+		Status:           AgentStatus(run.Status),
+		Result:           run.Result,
+		StartedAt:        run.StartedAt,
+		CompletedAt:      run.CompletedAt,
+	}
+	if run.Error != "" {
+		agent.Error = errors.New(run.Error)
+	}
+	return agent
+}
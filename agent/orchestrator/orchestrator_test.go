@@ -0,0 +1,262 @@
+package orchestrator
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"gobot/agent/ai"
+	"gobot/agent/config"
+	"gobot/agent/session"
+	"gobot/internal/provider"
+)
+
+// fakeTools is a ToolExecutor that records the calls it runs
+type fakeTools struct {
+	mu    sync.Mutex
+	names []string
+	calls []string
+}
+
+func (f *fakeTools) Execute(ctx context.Context, call *ai.ToolCall) *ToolExecResult {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls = append(f.calls, call.Name)
+	return &ToolExecResult{Content: "ok"}
+}
+
+func (f *fakeTools) List() []ai.ToolDefinition {
+	defs := make([]ai.ToolDefinition, 0, len(f.names))
+	for _, name := range f.names {
+		defs = append(defs, ai.ToolDefinition{Name: name})
+	}
+	return defs
+}
+
+func newTestOrchestrator(t *testing.T, providers []ai.Provider, tools ToolExecutor) *Orchestrator {
+	t.Helper()
+	cfg := config.DefaultConfig()
+	cfg.DataDir = t.TempDir()
+
+	sessions, err := session.New(t.TempDir() + "/test.db")
+	if err != nil {
+		t.Fatalf("failed to create session manager: %v", err)
+	}
+	t.Cleanup(func() { sessions.Close() })
+
+	return NewOrchestrator(cfg, sessions, providers, tools)
+}
+
+func TestLoadAgentTypes(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "reviewer.yaml"), []byte(`
+description: Reviews diffs
+prompt: Review the change and report problems.
+tools: [read, grep, "mcp_github_*"]
+model: anthropic/claude-sonnet-4-5
+task: code
+max_iterations: 12
+timeout: 10m
+`), 0644)
+	os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("not an agent"), 0644)
+
+	types, err := LoadAgentTypes(dir)
+	if err != nil {
+		t.Fatalf("LoadAgentTypes failed: %v", err)
+	}
+	if len(types) != 1 {
+		t.Fatalf("expected 1 agent type, got %d", len(types))
+	}
+
+	reviewer := types[0]
+	if reviewer.Name != "reviewer" {
+		t.Errorf("expected name from file name, got %q", reviewer.Name)
+	}
+	if reviewer.Timeout != 10*time.Minute || reviewer.MaxIterations != 12 || reviewer.Task != "code" {
+		t.Errorf("unexpected limits: %+v", reviewer)
+	}
+	if !reviewer.AllowsTool("grep") || !reviewer.AllowsTool("mcp_github_create_issue") || reviewer.AllowsTool("write") {
+		t.Errorf("unexpected tool scope: %v", reviewer.Tools)
+	}
+
+	if types, err := LoadAgentTypes(filepath.Join(dir, "missing")); err != nil || types != nil {
+		t.Errorf("missing directory should load nothing, got %v, %v", types, err)
+	}
+
+	os.WriteFile(filepath.Join(dir, "bad.yml"), []byte("task: dreaming\n"), 0644)
+	if _, err := LoadAgentTypes(dir); err == nil {
+		t.Error("expected error for unknown task type")
+	}
+}
+
+func TestUserAgentTypeOverridesBuiltin(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.DataDir = t.TempDir()
+	os.MkdirAll(filepath.Join(cfg.DataDir, "agents"), 0755)
+	os.WriteFile(filepath.Join(cfg.DataDir, "agents", "explore.yaml"), []byte("tools: [read]\n"), 0644)
+
+	o := NewOrchestrator(cfg, nil, nil, &fakeTools{})
+
+	explore, ok := o.AgentType("explore")
+	if !ok || len(explore.Tools) != 1 || explore.Tools[0] != "read" {
+		t.Errorf("expected user-defined explore type, got %+v", explore)
+	}
+	if general, ok := o.AgentType(""); !ok || general.Name != DefaultAgentType {
+		t.Errorf("expected default agent type, got %+v", general)
+	}
+
+	var names []string
+	for _, agentType := range o.AgentTypes() {
+		names = append(names, agentType.Name)
+	}
+	if len(names) != 3 || names[0] != "explore" || names[1] != "general" || names[2] != "plan" {
+		t.Errorf("expected sorted agent types, got %v", names)
+	}
+}
+
+func TestExploreAgentCannotWrite(t *testing.T) {
+	tools := &fakeTools{names: []string{"bash", "edit", "glob", "grep", "read", "write"}}
+	p := ai.NewScriptedProvider("test",
+		ai.ToolCallTurn(ai.ScriptedCall{Name: "write", Input: map[string]string{"path": "x"}}),
+		ai.ToolCallTurn(ai.ScriptedCall{Name: "read", Input: map[string]string{"path": "x"}}),
+		ai.TextTurn("found it"),
+	)
+	o := newTestOrchestrator(t, []ai.Provider{p}, tools)
+
+	agent, err := o.Spawn(context.Background(), &SpawnRequest{Task: "look around", AgentType: "explore", Wait: true})
+	if err != nil {
+		t.Fatalf("Spawn failed: %v", err)
+	}
+	if agent.Status != StatusCompleted || agent.Type != "explore" {
+		t.Fatalf("expected completed explore agent, got %s %s (%v)", agent.Type, agent.Status, agent.Error)
+	}
+
+	for _, def := range p.Requests()[0].Tools {
+		switch def.Name {
+		case "read", "glob", "grep":
+		default:
+			t.Errorf("explore agent was offered %q", def.Name)
+		}
+	}
+	if len(tools.calls) != 1 || tools.calls[0] != "read" {
+		t.Errorf("expected only the read call to run, got %v", tools.calls)
+	}
+
+	if _, err := o.Spawn(context.Background(), &SpawnRequest{Task: "x", AgentType: "nope"}); err == nil {
+		t.Error("expected error for unknown agent type")
+	}
+}
+
+func TestSubAgentFailsOverOnRateLimit(t *testing.T) {
+	limited := ai.NewScriptedProvider("anthropic", ai.ErrorTurn(&ai.ProviderError{Type: "rate_limit_error", Message: "slow down"}))
+	backup := ai.NewScriptedProvider("openai", ai.TextTurn("planned"))
+
+	o := newTestOrchestrator(t, []ai.Provider{limited, backup}, &fakeTools{})
+	o.SetModelSelector(ai.NewModelSelector(&provider.ModelsConfig{
+		TaskRouting: &provider.TaskRouting{
+			Reasoning: "anthropic/big",
+			Fallbacks: map[string][]string{"reasoning": {"openai/small"}},
+		},
+		Providers: map[string][]provider.ModelInfo{
+			"anthropic": {{ID: "big"}},
+			"openai":    {{ID: "small"}},
+		},
+	}))
+
+	agent, err := o.Spawn(context.Background(), &SpawnRequest{Task: "make a plan", AgentType: "plan", Wait: true})
+	if err != nil {
+		t.Fatalf("Spawn failed: %v", err)
+	}
+	if agent.Result != "planned" {
+		t.Errorf("expected result from backup model, got %q (%v)", agent.Result, agent.Error)
+	}
+	if got := limited.Requests()[0].Model; got != "big" {
+		t.Errorf("expected routed model big first, got %q", got)
+	}
+	if got := backup.Requests()[0].Model; got != "small" {
+		t.Errorf("expected failover to small, got %q", got)
+	}
+}
+
+func TestSubAgentRunIsPersistedAndStreamed(t *testing.T) {
+	p := ai.NewScriptedProvider("test",
+		ai.ToolCallTurn(ai.ScriptedCall{Name: "read", Input: map[string]string{"path": "x"}}),
+		ai.TextTurn("all done"),
+	)
+	o := newTestOrchestrator(t, []ai.Provider{p}, &fakeTools{names: []string{"read"}})
+
+	var mu sync.Mutex
+	var events []SubAgentEvent
+	o.SetEventHandler(func(e SubAgentEvent) {
+		mu.Lock()
+		defer mu.Unlock()
+		events = append(events, e)
+	})
+
+	agent, err := o.Spawn(context.Background(), &SpawnRequest{Task: "read x", ParentSessionKey: "parent", Wait: true})
+	if err != nil {
+		t.Fatalf("Spawn failed: %v", err)
+	}
+
+	run, err := o.sessions.GetSubAgentRun(agent.ID)
+	if err != nil {
+		t.Fatalf("run was not persisted: %v", err)
+	}
+	if run.Status != string(StatusCompleted) || run.Result != "all done" || run.ParentSessionKey != "parent" {
+		t.Errorf("unexpected persisted run: %+v", run)
+	}
+	sess, err := o.sessions.GetByKey(agent.SessionKey)
+	if err != nil {
+		t.Fatalf("sub-agent session was not created: %v", err)
+	}
+	if history, _ := o.sessions.GetMessages(sess.ID, 0); len(history) == 0 {
+		t.Error("expected the sub-agent conversation in its own session")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	var sawTool, sawResult bool
+	for _, e := range events {
+		if e.AgentID != agent.ID || e.ParentSessionKey != "parent" {
+			t.Errorf("event not tagged with its sub-agent: %+v", e)
+		}
+		sawTool = sawTool || (e.Event.Type == ai.EventTypeToolCall && e.Event.ToolCall.Name == "read")
+		sawResult = sawResult || e.Event.Type == ai.EventTypeToolResult
+	}
+	if !sawTool || !sawResult {
+		t.Errorf("expected tool call and result events, got %+v", events)
+	}
+	if last := events[len(events)-1]; last.Event.Type != ai.EventTypeDone || last.Status != StatusCompleted || last.Event.Text != "all done" {
+		t.Errorf("expected final done event, got %+v", last)
+	}
+}
+
+func TestInterruptedRunsAreFailedOnStartup(t *testing.T) {
+	sessions, err := session.New(t.TempDir() + "/test.db")
+	if err != nil {
+		t.Fatalf("failed to create session manager: %v", err)
+	}
+	defer sessions.Close()
+
+	now := time.Now()
+	sessions.SaveSubAgentRun(&session.SubAgentRun{ID: "orphan", SessionKey: "subagent-orphan", Status: "running", StartedAt: now})
+	sessions.SaveSubAgentRun(&session.SubAgentRun{ID: "live", SessionKey: "subagent-live", Status: "running", PID: os.Getpid(), StartedAt: now})
+
+	cfg := config.DefaultConfig()
+	cfg.DataDir = t.TempDir()
+	o := NewOrchestrator(cfg, sessions, nil, &fakeTools{})
+
+	agent, ok := o.GetAgent("orphan")
+	if !ok {
+		t.Fatal("GetAgent should fall back to the database")
+	}
+	if agent.Status != StatusFailed || agent.Error == nil {
+		t.Errorf("expected orphaned run to be failed, got %s (%v)", agent.Status, agent.Error)
+	}
+	if live, _ := sessions.GetSubAgentRun("live"); live.Status != "running" {
+		t.Errorf("run owned by a live process should be left alone, got %s", live.Status)
+	}
+}
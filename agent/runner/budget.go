@@ -0,0 +1,111 @@
+package runner
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"gobot/agent/ai"
+	"gobot/agent/config"
+)
+
+// BudgetError is sent as the error event when a hard spend limit stops a run
+type BudgetError struct {
+	Scope string  // "run", "session", "day" or "session/day"
+	Unit  string  // "usd" or "tokens"
+	Spent float64 // Amount spent so far in the scope
+	Limit float64 // Configured limit that was reached
+	Hard  bool    // Hard limits stop the run, soft limits downgrade the model
+}
+
+func (e *BudgetError) Error() string {
+	kind := "soft"
+	if e.Hard {
+		kind = "hard"
+	}
+	if e.Unit == "usd" {
+		return fmt.Sprintf("%s budget exceeded for %s: $%.4f of $%.4f", kind, e.Scope, e.Spent, e.Limit)
+	}
+	return fmt.Sprintf("%s budget exceeded for %s: %.0f of %.0f tokens", kind, e.Scope, e.Spent, e.Limit)
+}
+
+// IsBudgetExceeded reports whether err is a hard budget stop
+func IsBudgetExceeded(err error) bool {
+	var be *BudgetError
+	return errors.As(err, &be) && be.Hard
+}
+
+// checkBudget evaluates all configured limits for a session before a provider call.
+// Returns a hard error if any hard limit is reached, else a soft error if any soft limit is reached, else nil.
+func (r *Runner) checkBudget(sessionKey string, runUsage *ai.Usage) *BudgetError {
+	if r.config.Budgets.IsZero() {
+		return nil
+	}
+	limits := r.config.Budgets.LimitsFor(sessionKey)
+
+	var soft *BudgetError
+	check := func(scope string, limit config.BudgetLimit, tokens int64, cost float64) *BudgetError {
+		if be := evaluateLimit(scope, limit, tokens, cost); be != nil {
+			if be.Hard {
+				return be
+			}
+			if soft == nil {
+				soft = be
+			}
+		}
+		return nil
+	}
+
+	if be := check("run", limits.PerRun, int64(runUsage.TotalTokens()), runUsage.CostUSD); be != nil {
+		return be
+	}
+
+	today := time.Now()
+	scopes := []struct {
+		name       string
+		limit      config.BudgetLimit
+		sessionKey string
+		day        time.Time
+	}{
+		{"session", limits.PerSession, sessionKey, time.Time{}},
+		{"day", limits.PerDay, "", today},
+		{"session/day", limits.SessionPerDay, sessionKey, today},
+	}
+	for _, s := range scopes {
+		if s.limit.IsZero() {
+			continue
+		}
+		spent, err := r.sessions.Spend(s.sessionKey, s.day)
+		if err != nil {
+			fmt.Printf("[runner] Warning: failed to read usage for %s budget: %v\n", s.name, err)
+			continue
+		}
+		if be := check(s.name, s.limit, spent.TotalTokens(), spent.CostUSD); be != nil {
+			return be
+		}
+	}
+
+	return soft
+}
+
+// evaluateLimit compares spend against one limit, preferring hard over soft
+func evaluateLimit(scope string, limit config.BudgetLimit, tokens int64, cost float64) *BudgetError {
+	switch {
+	case limit.HardUSD > 0 && cost >= limit.HardUSD:
+		return &BudgetError{Scope: scope, Unit: "usd", Spent: cost, Limit: limit.HardUSD, Hard: true}
+	case limit.HardTokens > 0 && tokens >= limit.HardTokens:
+		return &BudgetError{Scope: scope, Unit: "tokens", Spent: float64(tokens), Limit: float64(limit.HardTokens), Hard: true}
+	case limit.SoftUSD > 0 && cost >= limit.SoftUSD:
+		return &BudgetError{Scope: scope, Unit: "usd", Spent: cost, Limit: limit.SoftUSD}
+	case limit.SoftTokens > 0 && tokens >= limit.SoftTokens:
+		return &BudgetError{Scope: scope, Unit: "tokens", Spent: float64(tokens), Limit: float64(limit.SoftTokens)}
+	}
+	return nil
+}
+
+// canServe reports whether a provider is configured for a "provider/model" ID
+func (r *Runner) canServe(modelID string) bool {
+	providerID, _ := ai.ParseModelID(modelID)
+	_, ok := r.providerMap[providerID]
+	return ok
+}
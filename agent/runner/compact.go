@@ -0,0 +1,280 @@
+package runner
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"gobot/agent/ai"
+	"gobot/agent/session"
+	"gobot/internal/provider"
+)
+
+// compactionPrompt instructs the summarization model
+const compactionPrompt = `You compress conversation history for an AI agent that will continue the conversation.
+
+Write a concise summary of the transcript you are given. Preserve everything the agent needs to continue the work:
+- The user's goals, requests, preferences and constraints
+- Decisions made and the reasoning behind them
+- Files, commands, URLs and identifiers that were read, created or changed
+- Important tool results, errors and how they were resolved
+- Work that is still pending or in progress
+
+If the transcript begins with an earlier summary, merge it into yours. Write plain prose and bullet points only; do not address the user.`
+
+// summaryHeader prefixes compaction summaries stored in the session
+const summaryHeader = "[Summary of earlier conversation]\n"
+
+// outputReserve is the context headroom kept for the model's response
+const outputReserve = 8192
+
+// Transcript truncation limits for the summarization request
+const (
+	maxTranscriptToolInput  = 500
+	maxTranscriptToolResult = 2000
+)
+
+// contextWindow returns the context window of a "provider/model" ID, or 0 if unknown
+func (r *Runner) contextWindow(modelID string) int {
+	var info *provider.ModelInfo
+	if r.selector != nil {
+		info = r.selector.GetModelInfo(modelID)
+	}
+	if info == nil {
+		info = ai.LookupModelInfo(provider.GetModelsConfig(), modelID)
+	}
+	if info == nil {
+		return 0
+	}
+	return info.ContextWindow
+}
+
+// shouldCompact reports whether the prompt is close enough to the context window to compact
+func (r *Runner) shouldCompact(messages []session.Message, systemPrompt string, window int) bool {
+	threshold := r.config.Compaction.Threshold
+	if threshold <= 0 || threshold > 1 {
+		threshold = 0.8
+	}
+
+	estimate := session.EstimateTokens(messages) + r.promptOverhead(systemPrompt)
+	return float64(estimate) >= threshold*float64(window)
+}
+
+// promptOverhead estimates the tokens used by the system prompt and tool schemas
+func (r *Runner) promptOverhead(systemPrompt string) int {
+	tokens := len(systemPrompt) / 4
+	for _, def := range r.tools.List() {
+		tokens += (len(def.Name) + len(def.Description) + len(def.InputSchema)) / 4
+	}
+	return tokens
+}
+
+// historyBudget returns the tokens available for session messages in a context window,
+// after reserving headroom for the system prompt, tool schemas and the response
+func (r *Runner) historyBudget(systemPrompt string, window int) int {
+	output := outputReserve
+	if output > window/4 {
+		output = window / 4
+	}
+	budget := window - r.promptOverhead(systemPrompt) - output
+	if budget < 1 {
+		budget = 1 // Still send the newest turn
+	}
+	return budget
+}
+
+// compact summarizes all but the most recent turns of a session and archives the originals.
+// current/currentModel are used for summarization when no cheaper model is available.
+// Returns the number of messages archived (0 if there was nothing worth compacting).
+func (r *Runner) compact(ctx context.Context, sessionID string, messages []session.Message, current ai.Provider, currentModel string) (int, error) {
+	keepTurns := r.config.Compaction.KeepTurns
+	if keepTurns <= 0 {
+		keepTurns = 4
+	}
+
+	turns := session.Turns(messages)
+	if len(turns) <= keepTurns {
+		return 0, nil
+	}
+	var old []session.Message
+	for _, turn := range turns[:len(turns)-keepTurns] {
+		old = append(old, turn...)
+	}
+	keepFromID := turns[len(turns)-keepTurns][0].ID
+
+	// Re-summarizing nothing but an earlier summary makes no progress
+	worthCompacting := false
+	for _, msg := range old {
+		if msg.Role != "system" {
+			worthCompacting = true
+			break
+		}
+	}
+	if !worthCompacting {
+		return 0, nil
+	}
+
+	summary, err := r.summarize(ctx, old, current, currentModel)
+	if err != nil {
+		fmt.Printf("[runner] Warning: summarization failed, using simple summary: %v\n", err)
+		summary = session.Message{Content: r.generateSummary(ctx, old)}
+	}
+
+	archived, err := r.sessions.Compact(sessionID, summary, keepFromID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to compact session: %w", err)
+	}
+	fmt.Printf("[Runner] Compacted session %s: archived %d messages\n", sessionID, archived)
+	return archived, nil
+}
+
+// summarize asks a cheap model to summarize messages, returning the summary message with its usage
+func (r *Runner) summarize(ctx context.Context, messages []session.Message, current ai.Provider, currentModel string) (session.Message, error) {
+	modelID := r.config.Compaction.Model
+	if modelID == "" && r.selector != nil {
+		modelID = r.selector.SelectCheapest(r.canServe)
+	}
+
+	p := current
+	modelName := currentModel
+	if modelID != "" {
+		providerID, mn := ai.ParseModelID(modelID)
+		if candidate, ok := r.providerMap[providerID]; ok {
+			p = candidate
+			modelName = mn
+		}
+	}
+	if p == nil {
+		return session.Message{}, fmt.Errorf("no provider available")
+	}
+
+	events, err := p.Stream(ctx, &ai.ChatRequest{
+		System: compactionPrompt,
+		Messages: []session.Message{
+			{Role: "user", Content: formatTranscript(messages)},
+		},
+		Model: modelName,
+	})
+	if err != nil {
+		return session.Message{}, err
+	}
+
+	var text strings.Builder
+	var usage *ai.Usage
+	for event := range events {
+		switch event.Type {
+		case ai.EventTypeText:
+			text.WriteString(event.Text)
+		case ai.EventTypeUsage:
+			if event.Usage != nil {
+				usage = r.priceUsage(event.Usage, modelID, p.ID())
+			}
+		case ai.EventTypeError:
+			return session.Message{}, event.Error
+		}
+	}
+
+	content := strings.TrimSpace(text.String())
+	if content == "" {
+		return session.Message{}, fmt.Errorf("empty summary")
+	}
+
+	summary := session.Message{Content: summaryHeader + content}
+	if usage != nil {
+		summary.Model = usage.Model
+		summary.InputTokens = usage.InputTokens
+		summary.OutputTokens = usage.OutputTokens
+		summary.CacheReadTokens = usage.CacheReadTokens
+		summary.CacheWriteTokens = usage.CacheWriteTokens
+		summary.CostUSD = usage.CostUSD
+	}
+	return summary, nil
+}
+
+// formatTranscript renders messages, including tool calls and results, as plain text for summarization
+func formatTranscript(messages []session.Message) string {
+	var sb strings.Builder
+	for _, msg := range messages {
+		switch msg.Role {
+		case "system":
+			sb.WriteString("Earlier summary:\n")
+			sb.WriteString(strings.TrimPrefix(msg.Content, summaryHeader))
+			sb.WriteString("\n\n")
+		case "user":
+			sb.WriteString("User: ")
+			sb.WriteString(msg.Content)
+			sb.WriteString("\n\n")
+		case "assistant":
+			if msg.Content != "" {
+				sb.WriteString("Assistant: ")
+				sb.WriteString(msg.Content)
+				sb.WriteString("\n")
+			}
+			var calls []session.ToolCall
+			if len(msg.ToolCalls) > 0 && json.Unmarshal(msg.ToolCalls, &calls) == nil {
+				for _, tc := range calls {
+					fmt.Fprintf(&sb, "Tool call %s: %s\n", tc.Name, truncate(string(tc.Input), maxTranscriptToolInput))
+				}
+			}
+			sb.WriteString("\n")
+		case "tool":
+			var results []session.ToolResult
+			if len(msg.ToolResults) > 0 && json.Unmarshal(msg.ToolResults, &results) == nil {
+				for _, tr := range results {
+					label := "Tool result"
+					if tr.IsError {
+						label = "Tool error"
+					}
+					fmt.Fprintf(&sb, "%s: %s\n", label, truncate(tr.Content, maxTranscriptToolResult))
+				}
+			}
+			sb.WriteString("\n")
+		}
+	}
+	return strings.TrimSpace(sb.String())
+}
+
+// splitSummaries moves compaction summaries (system messages) out of the message list
+// so they can be sent as part of the system prompt, which every provider supports
+func splitSummaries(messages []session.Message) (summaries string, rest []session.Message) {
+	var parts []string
+	rest = make([]session.Message, 0, len(messages))
+	for _, msg := range messages {
+		if msg.Role == "system" {
+			parts = append(parts, msg.Content)
+			continue
+		}
+		rest = append(rest, msg)
+	}
+	return strings.Join(parts, "\n\n"), rest
+}
+
+// truncate shortens s to at most max bytes, marking the cut
+func truncate(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	return s[:max] + "... [truncated]"
+}
+
+// generateSummary creates a simple summary of user requests, used when LLM summarization fails
+func (r *Runner) generateSummary(_ context.Context, messages []session.Message) string {
+	var summary strings.Builder
+	summary.WriteString(summaryHeader)
+
+	// Extract key points from messages
+	for _, msg := range messages {
+		if msg.Role == "user" && msg.Content != "" {
+			summary.WriteString("- User request: ")
+			content := msg.Content
+			if len(content) > 200 {
+				content = content[:200] + "..."
+			}
+			summary.WriteString(content)
+			summary.WriteString("\n")
+		}
+	}
+
+	return summary.String()
+}
@@ -0,0 +1,109 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"gobot/agent/ai"
+	"gobot/agent/config"
+)
+
+// How long an auth profile's key is skipped after it is rate limited or rejected
+const (
+	rateLimitCooldown = time.Minute
+	authCooldown      = time.Hour
+)
+
+// ProfileStore hands out API keys for providers and tracks how each one fares.
+// config.AuthProfileManager implements it.
+type ProfileStore interface {
+	GetBestProfile(ctx context.Context, provider string) (*config.AuthProfile, error)
+	RecordUsage(ctx context.Context, profileID string) error
+	RecordError(ctx context.Context, profileID string) error
+	SetCooldown(ctx context.Context, profileID string, until time.Time) error
+}
+
+// ProfileFactory creates a provider that uses an auth profile's key, or nil if it can't be used
+type ProfileFactory func(profile *config.AuthProfile) ai.Provider
+
+// profileEntry is the provider created for an auth profile
+type profileEntry struct {
+	profile  config.AuthProfile
+	provider ai.Provider
+}
+
+// SetAuthProfiles makes the runner pick a provider's API key per request from auth profiles,
+// rotating to the next key when one is rate limited or rejected
+func (r *Runner) SetAuthProfiles(store ProfileStore, factory ProfileFactory) {
+	r.profiles = store
+	r.profileFactory = factory
+	r.profileProviders = make(map[string]profileEntry)
+}
+
+// profileProvider returns a provider for providerID using its best available key, or nil
+// when no auth profile can serve it
+func (r *Runner) profileProvider(ctx context.Context, providerID string) (ai.Provider, *config.AuthProfile) {
+	if r.profiles == nil || providerID == "" {
+		return nil, nil
+	}
+	profile, err := r.profiles.GetBestProfile(ctx, providerID)
+	if err != nil {
+		fmt.Printf("[runner] Warning: failed to get auth profile for %s: %v\n", providerID, err)
+		return nil, nil
+	}
+	if profile == nil {
+		return nil, nil
+	}
+
+	r.profileMu.Lock()
+	defer r.profileMu.Unlock()
+
+	// Providers are reused while the profile's key and endpoint stay the same
+	entry, ok := r.profileProviders[profile.ID]
+	if !ok || entry.profile.APIKey != profile.APIKey || entry.profile.Model != profile.Model || entry.profile.BaseURL != profile.BaseURL {
+		entry = profileEntry{profile: *profile, provider: r.profileFactory(profile)}
+		r.profileProviders[profile.ID] = entry
+	}
+	if entry.provider == nil {
+		return nil, nil
+	}
+	return entry.provider, profile
+}
+
+// recordProfile records the outcome of a call made with an auth profile's key. A rate limited
+// or rejected key is put on cooldown, and the result reports whether the provider has another
+// key to rotate to.
+func (r *Runner) recordProfile(profile *config.AuthProfile, callErr error) bool {
+	if profile == nil {
+		return false
+	}
+	// Recorded even when the run was cancelled
+	ctx := context.Background()
+
+	if callErr == nil {
+		if err := r.profiles.RecordUsage(ctx, profile.ID); err != nil {
+			fmt.Printf("[runner] Warning: failed to record usage of auth profile %s: %v\n", profile.Name, err)
+		}
+		return false
+	}
+	if err := r.profiles.RecordError(ctx, profile.ID); err != nil {
+		fmt.Printf("[runner] Warning: failed to record error of auth profile %s: %v\n", profile.Name, err)
+	}
+	if !ai.IsRateLimitOrAuth(callErr) {
+		return false
+	}
+
+	cooldown := rateLimitCooldown
+	if ai.IsAuthError(callErr) {
+		cooldown = authCooldown
+	}
+	if err := r.profiles.SetCooldown(ctx, profile.ID, time.Now().Add(cooldown)); err != nil {
+		fmt.Printf("[runner] Warning: failed to cool down auth profile %s: %v\n", profile.Name, err)
+		return false
+	}
+	fmt.Printf("[Runner] Auth profile %s (%s) cooling down for %s: %v\n", profile.Name, profile.Provider, cooldown, callErr)
+
+	next, err := r.profiles.GetBestProfile(ctx, profile.Provider)
+	return err == nil && next != nil
+}
@@ -0,0 +1,104 @@
+package runner
+
+// sessionQueue serializes runs on one session and holds steering messages for the active run
+type sessionQueue struct {
+	tail   chan struct{} // Closed when the most recently queued run finishes
+	queued int           // Runs waiting or executing
+	active bool          // A run is executing and accepts steering
+	steer  []string      // Messages waiting to be added before the next provider call
+}
+
+// enqueue reserves the next turn on a session. The caller runs once prev is closed
+// (or immediately if prev is nil) and must release the turn with dequeue.
+func (r *Runner) enqueue(sessionKey string) (prev, next chan struct{}) {
+	r.queueMu.Lock()
+	defer r.queueMu.Unlock()
+
+	q := r.queues[sessionKey]
+	if q == nil {
+		q = &sessionQueue{}
+		r.queues[sessionKey] = q
+	}
+	prev, next = q.tail, make(chan struct{})
+	q.tail = next
+	q.queued++
+	return prev, next
+}
+
+// dequeue releases a turn taken with enqueue, letting the next queued run start
+func (r *Runner) dequeue(sessionKey string, next chan struct{}) {
+	r.queueMu.Lock()
+	defer r.queueMu.Unlock()
+
+	close(next)
+	q := r.queues[sessionKey]
+	q.queued--
+	if q.queued == 0 {
+		delete(r.queues, sessionKey)
+	}
+}
+
+// activate marks the session's run as executing so it accepts steering
+func (r *Runner) activate(sessionKey string) {
+	r.queueMu.Lock()
+	defer r.queueMu.Unlock()
+	r.queues[sessionKey].active = true
+}
+
+// takeSteer returns the steering messages waiting for a session's run
+func (r *Runner) takeSteer(sessionKey string) []string {
+	r.queueMu.Lock()
+	defer r.queueMu.Unlock()
+
+	q := r.queues[sessionKey]
+	messages := q.steer
+	q.steer = nil
+	return messages
+}
+
+// finishSteer is called when a run is about to complete. It returns any steering messages
+// that arrived during the final provider call; if there are none, the run stops accepting
+// steering so later messages start a new run instead of being lost.
+func (r *Runner) finishSteer(sessionKey string) []string {
+	r.queueMu.Lock()
+	defer r.queueMu.Unlock()
+
+	q := r.queues[sessionKey]
+	messages := q.steer
+	q.steer = nil
+	if len(messages) == 0 {
+		q.active = false
+	}
+	return messages
+}
+
+// closeSteer stops a session's run accepting steering and returns messages it never picked up
+func (r *Runner) closeSteer(sessionKey string) []string {
+	r.queueMu.Lock()
+	defer r.queueMu.Unlock()
+
+	q := r.queues[sessionKey]
+	messages := q.steer
+	q.steer = nil
+	q.active = false
+	return messages
+}
+
+// Steer delivers a user message to the run executing on a session. It is added to the
+// history before the run's next provider call, so the agent can be corrected without
+// cancelling it. Returns false if no run is executing; start a new run instead.
+func (r *Runner) Steer(sessionKey, text string) bool {
+	if sessionKey == "" {
+		sessionKey = "default"
+	}
+
+	r.queueMu.Lock()
+	defer r.queueMu.Unlock()
+
+	q := r.queues[sessionKey]
+	if q == nil || !q.active {
+		return false
+	}
+	q.steer = append(q.steer, text)
+	return true
+}
@@ -3,13 +3,16 @@ package runner
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 
 	"gobot/agent/ai"
+	"gobot/agent/audit"
 	"gobot/agent/config"
 	"gobot/agent/memory"
 	"gobot/agent/session"
@@ -35,6 +38,12 @@ Important:
 
 Always verify your changes work before considering a task complete.`
 
+// ErrCancelled is reported when a run is stopped before it finishes
+var ErrCancelled = errors.New("run cancelled")
+
+// CancelledMarker is saved to the session when a run is cancelled
+const CancelledMarker = "[Run cancelled]"
+
 // Runner executes the agentic loop
 type Runner struct {
 	sessions        *session.Manager
@@ -47,14 +56,23 @@ type Runner struct {
 	autoExtract     bool
 	selector        *ai.ModelSelector
 	fuzzyMatcher    *ai.FuzzyMatcher // For user model switch requests
+
+	profiles         ProfileStore // API keys per provider; nil uses the providers as given
+	profileFactory   ProfileFactory
+	profileMu        sync.Mutex
+	profileProviders map[string]profileEntry // Auth profile ID -> provider using its key
+
+	queueMu sync.Mutex
+	queues  map[string]*sessionQueue // Session key -> runs waiting on it
 }
 
 // RunRequest contains parameters for a run
 type RunRequest struct {
-	SessionKey    string // Session identifier (uses "default" if empty)
-	Prompt        string // User prompt
-	System        string // Override system prompt
-	ModelOverride string // User-specified model override (e.g., "anthropic/claude-opus-4-5")
+	SessionKey    string                // Session identifier (uses "default" if empty)
+	Prompt        string                // User prompt
+	Attachments   []session.ContentPart // Images, documents or audio sent with the prompt
+	System        string                // Override system prompt
+	ModelOverride string                // User-specified model override (e.g., "anthropic/claude-opus-4-5")
 }
 
 // New creates a new runner
@@ -101,6 +119,7 @@ func New(cfg *config.Config, sessions *session.Manager, providers []ai.Provider,
 		tools:       toolRegistry,
 		config:      cfg,
 		skillLoader: skillLoader,
+		queues:      make(map[string]*sessionQueue),
 	}
 }
 
@@ -169,27 +188,71 @@ func (r *Runner) Run(ctx context.Context, req *RunRequest) (<-chan ai.StreamEven
 		return nil, fmt.Errorf("failed to get session: %w", err)
 	}
 
-	// Add user message to session
-	if req.Prompt != "" {
-		err = r.sessions.AppendMessage(sess.ID, session.Message{
-			SessionID: sess.ID,
-			Role:      "user",
-			Content:   req.Prompt,
-		})
-		if err != nil {
-			return nil, fmt.Errorf("failed to save message: %w", err)
+	resultCh := make(chan ai.StreamEvent, 100)
+
+	// Runs on the same session take turns so their messages don't interleave
+	prev, next := r.enqueue(req.SessionKey)
+	go func() {
+		defer close(resultCh)
+
+		if prev != nil {
+			select {
+			case <-prev:
+			case <-ctx.Done():
+				// Cancelled while queued: nothing was saved, but keep our place so order holds
+				go func() {
+					<-prev
+					r.dequeue(req.SessionKey, next)
+				}()
+				resultCh <- ai.StreamEvent{Type: ai.EventTypeError, Error: ErrCancelled}
+				return
+			}
+		}
+		defer r.dequeue(req.SessionKey, next)
+
+		r.activate(req.SessionKey)
+		defer func() {
+			// Keep steering messages that arrived too late for the run
+			r.appendSteered(sess.ID, r.closeSteer(req.SessionKey))
+		}()
+
+		// Add user message to session
+		if req.Prompt != "" || len(req.Attachments) > 0 {
+			err := r.sessions.AppendMessage(sess.ID, session.Message{
+				SessionID: sess.ID,
+				Role:      "user",
+				Content:   req.Prompt,
+				Parts:     req.Attachments,
+			})
+			if err != nil {
+				resultCh <- ai.StreamEvent{Type: ai.EventTypeError, Error: fmt.Errorf("failed to save message: %w", err)}
+				return
+			}
 		}
-	}
 
-	resultCh := make(chan ai.StreamEvent, 100)
-	go r.runLoop(ctx, sess.ID, req.System, req.ModelOverride, resultCh)
+		r.runLoop(ctx, sess.ID, req.SessionKey, req.System, req.ModelOverride, resultCh)
+	}()
 
 	return resultCh, nil
 }
 
+// appendSteered adds steering messages to the session as user messages
+func (r *Runner) appendSteered(sessionID string, messages []string) {
+	for _, text := range messages {
+		if err := r.sessions.AppendMessage(sessionID, session.Message{
+			SessionID: sessionID,
+			Role:      "user",
+			Content:   text,
+		}); err != nil {
+			fmt.Printf("[runner] Warning: failed to save steering message: %v\n", err)
+		}
+	}
+}
+
 // runLoop is the main agentic execution loop
-func (r *Runner) runLoop(ctx context.Context, sessionID, systemPrompt, modelOverride string, resultCh chan<- ai.StreamEvent) {
-	defer close(resultCh)
+func (r *Runner) runLoop(ctx context.Context, sessionID, sessionKey, systemPrompt, modelOverride string, resultCh chan<- ai.StreamEvent) {
+	// Attribute tool calls in this run to the session in the audit log
+	ctx = audit.WithSession(ctx, sessionKey)
 
 	if systemPrompt == "" {
 		systemPrompt = DefaultSystemPrompt
@@ -226,13 +289,22 @@ func (r *Runner) runLoop(ctx context.Context, sessionID, systemPrompt, modelOver
 	}
 
 	compactionAttempted := false
+	var runUsage ai.Usage
 
 	// MAIN LOOP: Model selection + agentic execution
 	for iteration < maxIterations {
 		iteration++
 
-		// Get session messages
-		messages, err := r.sessions.GetMessages(sessionID, r.config.MaxContext)
+		if ctx.Err() != nil {
+			r.cancelled(sessionID, "", nil, resultCh)
+			return
+		}
+
+		// Messages the user sent while the run was busy join the history before the next call
+		r.appendSteered(sessionID, r.takeSteer(sessionKey))
+
+		// Get session messages (compaction keeps the active history bounded)
+		messages, err := r.sessions.GetMessages(sessionID, 0)
 		if err != nil {
 			resultCh <- ai.StreamEvent{Type: ai.EventTypeError, Error: err}
 			return
@@ -279,11 +351,65 @@ func (r *Runner) runLoop(ctx context.Context, sessionID, systemPrompt, modelOver
 			return
 		}
 
+		// Enforce spend budgets: hard limits stop the run, soft limits downgrade the model
+		if budgetErr := r.checkBudget(sessionKey, &runUsage); budgetErr != nil {
+			if budgetErr.Hard {
+				fmt.Printf("[Runner] Stopping session %s: %v\n", sessionKey, budgetErr)
+				resultCh <- ai.StreamEvent{Type: ai.EventTypeError, Error: budgetErr}
+				return
+			}
+			if r.selector != nil {
+				if cheaper := r.selector.SelectCheaper(selectedModel, r.canServe); cheaper != "" {
+					fmt.Printf("[Runner] %v - downgrading %s -> %s\n", budgetErr, selectedModel, cheaper)
+					providerID, mn := ai.ParseModelID(cheaper)
+					selectedModel = cheaper
+					modelName = mn
+					provider = r.providerMap[providerID]
+				}
+			}
+		}
+
+		// Use the provider's best API key when keys are managed as auth profiles
+		keyProviderID := provider.ID()
+		if selectedModel != "" {
+			keyProviderID, _ = ai.ParseModelID(selectedModel)
+		}
+		var profile *config.AuthProfile
+		if p, prof := r.profileProvider(ctx, keyProviderID); p != nil {
+			provider, profile = p, prof
+		}
+
+		// Summarize older turns when the prompt nears the model's context window,
+		// then fit the history into what is left after the system prompt, tools and output.
+		// Without a known window, fall back to sending the last MaxContext messages.
+		limits := session.ContextLimits{MaxMessages: r.config.MaxContext}
+		if window := r.contextWindow(selectedModel); window > 0 {
+			if r.shouldCompact(messages, systemPrompt, window) {
+				if archived, err := r.compact(ctx, sessionID, messages, provider, modelName); err != nil {
+					fmt.Printf("[runner] Warning: compaction failed: %v\n", err)
+				} else if archived > 0 {
+					if messages, err = r.sessions.GetMessages(sessionID, 0); err != nil {
+						resultCh <- ai.StreamEvent{Type: ai.EventTypeError, Error: err}
+						return
+					}
+				}
+			}
+			limits = session.ContextLimits{MaxTokens: r.historyBudget(systemPrompt, window)}
+		}
+		messages = session.BuildContext(messages, limits)
+
+		// Compaction summaries travel in the system prompt
+		requestSystem := systemPrompt
+		summaries, messages := splitSummaries(messages)
+		if summaries != "" {
+			requestSystem = systemPrompt + "\n\n---\n\n" + summaries
+		}
+
 		// Build chat request
 		chatReq := &ai.ChatRequest{
 			Messages: messages,
 			Tools:    r.tools.List(),
-			System:   systemPrompt,
+			System:   requestSystem,
 			Model:    modelName,
 		}
 
@@ -301,21 +427,29 @@ func (r *Runner) runLoop(ctx context.Context, sessionID, systemPrompt, modelOver
 		fmt.Printf("[Runner] provider.Stream returned: events=%v err=%v\n", events != nil, err)
 
 		if err != nil {
+			if ctx.Err() != nil {
+				r.cancelled(sessionID, "", nil, resultCh)
+				return
+			}
 			if ai.IsContextOverflow(err) && !compactionAttempted {
 				compactionAttempted = true
 				// Compact session and retry
-				summary := r.generateSummary(ctx, messages)
-				if compactErr := r.sessions.Compact(sessionID, summary); compactErr == nil {
+				history, _ := r.sessions.GetMessages(sessionID, 0)
+				if archived, compactErr := r.compact(ctx, sessionID, history, provider, modelName); compactErr == nil && archived > 0 {
 					continue // Retry with compacted session
 				}
 			}
 			if ai.IsRateLimitOrAuth(err) {
-				// Mark model as failed and try again with a different one
+				// Rotate to the provider's next key first, then fail over to a different model
+				if r.recordProfile(profile, err) {
+					continue
+				}
 				if r.selector != nil && selectedModel != "" {
 					r.selector.MarkFailed(selectedModel)
 				}
 				continue
 			}
+			r.recordProfile(profile, err)
 			resultCh <- ai.StreamEvent{Type: ai.EventTypeError, Error: err}
 			return
 		}
@@ -324,8 +458,30 @@ func (r *Runner) runLoop(ctx context.Context, sessionID, systemPrompt, modelOver
 		hasToolCalls := false
 		var assistantContent strings.Builder
 		var toolCalls []session.ToolCall
+		var usage *ai.Usage
+		var retryErr error
 
 		for event := range events {
+			if ctx.Err() != nil {
+				// Drain what the provider still sends without forwarding it, keeping its usage
+				if event.Type == ai.EventTypeUsage && event.Usage != nil {
+					usage = r.priceUsage(event.Usage, selectedModel, provider.ID())
+				}
+				continue
+			}
+
+			// A key rejected before any output is handled like an error from Stream
+			if event.Type == ai.EventTypeError && ai.IsRateLimitOrAuth(event.Error) && assistantContent.Len() == 0 && len(toolCalls) == 0 {
+				retryErr = event.Error
+				continue
+			}
+
+			// Price usage before forwarding so callers see the cost
+			if event.Type == ai.EventTypeUsage && event.Usage != nil {
+				usage = r.priceUsage(event.Usage, selectedModel, provider.ID())
+				event.Usage = usage
+			}
+
 			// Forward event to caller
 			resultCh <- event
 
@@ -342,36 +498,67 @@ func (r *Runner) runLoop(ctx context.Context, sessionID, systemPrompt, modelOver
 				})
 
 			case ai.EventTypeError:
+				r.recordProfile(profile, event.Error)
+				r.saveFailedUsage(sessionID, assistantContent.String(), usage)
+				return
+			}
+		}
+
+		if ctx.Err() != nil {
+			r.cancelled(sessionID, assistantContent.String(), usage, resultCh)
+			return
+		}
+
+		if retryErr != nil {
+			if r.recordProfile(profile, retryErr) {
+				continue
+			}
+			if r.selector == nil || selectedModel == "" {
+				resultCh <- ai.StreamEvent{Type: ai.EventTypeError, Error: retryErr}
 				return
 			}
+			r.selector.MarkFailed(selectedModel)
+			continue
 		}
+		r.recordProfile(profile, nil)
+
+		runUsage.Add(usage)
 
-		// Save assistant message
+		// Save assistant message; file changes made by its tool calls are checkpointed under its ID
+		var messageID int64
 		if assistantContent.Len() > 0 || len(toolCalls) > 0 {
 			var toolCallsJSON json.RawMessage
 			if len(toolCalls) > 0 {
 				toolCallsJSON, _ = json.Marshal(toolCalls)
 			}
 
-			r.sessions.AppendMessage(sessionID, session.Message{
+			var err error
+			messageID, err = r.sessions.InsertMessage(sessionID, withUsage(session.Message{
 				SessionID: sessionID,
 				Role:      "assistant",
 				Content:   assistantContent.String(),
 				ToolCalls: toolCallsJSON,
-			})
+			}, usage))
+			if err != nil {
+				resultCh <- ai.StreamEvent{Type: ai.EventTypeError, Error: fmt.Errorf("failed to save message: %w", err)}
+				return
+			}
 		}
 
 		// Execute tool calls
 		if hasToolCalls {
-			var toolResults []session.ToolResult
-
-			for _, tc := range toolCalls {
-				result := r.tools.Execute(ctx, &ai.ToolCall{
+			calls := make([]*ai.ToolCall, len(toolCalls))
+			for i, tc := range toolCalls {
+				calls[i] = &ai.ToolCall{
 					ID:    tc.ID,
 					Name:  tc.Name,
 					Input: tc.Input,
-				})
+				}
+			}
 
+			// Independent calls run concurrently; results come back in call order
+			toolResults := make([]session.ToolResult, 0, len(toolCalls))
+			r.tools.ExecuteAll(session.WithMessage(ctx, sessionID, messageID), calls, func(i int, result *tools.ToolResult) {
 				// Send tool result event
 				resultCh <- ai.StreamEvent{
 					Type: ai.EventTypeToolResult,
@@ -379,25 +566,34 @@ func (r *Runner) runLoop(ctx context.Context, sessionID, systemPrompt, modelOver
 				}
 
 				toolResults = append(toolResults, session.ToolResult{
-					ToolCallID: tc.ID,
+					ToolCallID: toolCalls[i].ID,
 					Content:    result.Content,
 					IsError:    result.IsError,
 				})
-			}
+			})
 
 			// Save tool results
 			toolResultsJSON, _ := json.Marshal(toolResults)
-			r.sessions.AppendMessage(sessionID, session.Message{
+			err := r.sessions.AppendMessage(sessionID, session.Message{
 				SessionID:   sessionID,
 				Role:        "tool",
 				ToolResults: toolResultsJSON,
 			})
+			if err != nil {
+				resultCh <- ai.StreamEvent{Type: ai.EventTypeError, Error: fmt.Errorf("failed to save tool results: %w", err)}
+				return
+			}
 
 			// Continue to next iteration for more tool calls
 			continue
 		}
 
-		// No tool calls - LLM decided task is complete
+		// No tool calls - LLM decided task is complete, unless the user steered meanwhile
+		if steered := r.finishSteer(sessionKey); len(steered) > 0 {
+			r.appendSteered(sessionID, steered)
+			continue
+		}
+
 		// Run memory extraction in background
 		go r.extractAndStoreMemories(sessionID)
 		resultCh <- ai.StreamEvent{Type: ai.EventTypeDone}
@@ -411,26 +607,67 @@ func (r *Runner) runLoop(ctx context.Context, sessionID, systemPrompt, modelOver
 	}
 }
 
-// generateSummary creates a summary of the conversation for compaction
-func (r *Runner) generateSummary(_ context.Context, messages []session.Message) string {
-	// Simple summary: just note that conversation was compacted
-	var summary strings.Builder
-	summary.WriteString("[Previous conversation summary]\n")
-
-	// Extract key points from messages
-	for _, msg := range messages {
-		if msg.Role == "user" && msg.Content != "" {
-			summary.WriteString("- User request: ")
-			content := msg.Content
-			if len(content) > 200 {
-				content = content[:200] + "..."
-			}
-			summary.WriteString(content)
-			summary.WriteString("\n")
-		}
+// cancelled saves the partial reply with CancelledMarker and the usage spent on it, then reports
+// ErrCancelled. Unfinished tool calls are dropped since they never ran.
+func (r *Runner) cancelled(sessionID, partial string, usage *ai.Usage, resultCh chan<- ai.StreamEvent) {
+	content := CancelledMarker
+	if partial != "" {
+		content = partial + "\n\n" + CancelledMarker
+	}
+	if err := r.sessions.AppendMessage(sessionID, withUsage(session.Message{
+		SessionID: sessionID,
+		Role:      "assistant",
+		Content:   content,
+	}, usage)); err != nil {
+		fmt.Printf("[runner] Warning: failed to save cancellation: %v\n", err)
+	}
+	resultCh <- ai.StreamEvent{Type: ai.EventTypeError, Error: ErrCancelled}
+}
+
+// saveFailedUsage keeps the usage of a call that ended in an error so budgets still count it.
+// The partial reply is archived, leaving it out of the conversation.
+func (r *Runner) saveFailedUsage(sessionID, partial string, usage *ai.Usage) {
+	if usage == nil {
+		return
+	}
+	if err := r.sessions.AppendMessage(sessionID, withUsage(session.Message{
+		SessionID: sessionID,
+		Role:      "assistant",
+		Content:   partial,
+		Archived:  true,
+	}, usage)); err != nil {
+		fmt.Printf("[runner] Warning: failed to save usage of failed call: %v\n", err)
 	}
+}
+
+// withUsage records a call's model, token counts and cost on the message it produced
+func withUsage(msg session.Message, usage *ai.Usage) session.Message {
+	if usage != nil {
+		msg.Model = usage.Model
+		msg.InputTokens = usage.InputTokens
+		msg.OutputTokens = usage.OutputTokens
+		msg.CacheReadTokens = usage.CacheReadTokens
+		msg.CacheWriteTokens = usage.CacheWriteTokens
+		msg.CostUSD = usage.CostUSD
+	}
+	return msg
+}
 
-	return summary.String()
+// priceUsage normalizes the model ID on a usage report and fills in its cost
+func (r *Runner) priceUsage(u *ai.Usage, selectedModel, providerID string) *ai.Usage {
+	priced := *u
+	switch {
+	case selectedModel != "":
+		priced.Model = selectedModel
+	case priced.Model != "" && !strings.Contains(priced.Model, "/"):
+		// Provider IDs like "ollama-llama3.2" carry the model; keep only the provider type
+		providerType, _, _ := strings.Cut(providerID, "-")
+		priced.Model = providerType + "/" + priced.Model
+	case priced.Model == "":
+		priced.Model = providerID
+	}
+	priced.CostUSD = ai.EstimateCost(priced.Model, &priced)
+	return &priced
 }
 
 // Chat is a convenience method for one-shot chat without tool use
@@ -3,6 +3,10 @@ package runner
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -10,6 +14,7 @@ import (
 	"gobot/agent/config"
 	"gobot/agent/session"
 	"gobot/agent/tools"
+	"gobot/internal/provider"
 )
 
 // mockProvider implements ai.Provider for testing
@@ -149,6 +154,49 @@ func TestRunSimpleResponse(t *testing.T) {
 	}
 }
 
+func TestRunRecordsUsage(t *testing.T) {
+	cfg := config.DefaultConfig()
+
+	tmpDir := t.TempDir()
+	sessions, err := session.New(tmpDir + "/test.db")
+	if err != nil {
+		t.Fatalf("failed to create session manager: %v", err)
+	}
+	defer sessions.Close()
+
+	provider := &mockProvider{
+		id: "ollama-llama3.2",
+		events: []ai.StreamEvent{
+			{Type: ai.EventTypeText, Text: "Hi"},
+			{Type: ai.EventTypeUsage, Usage: &ai.Usage{Model: "llama3.2", InputTokens: 12, OutputTokens: 3}},
+		},
+	}
+
+	r := New(cfg, sessions, []ai.Provider{provider}, tools.NewRegistry(nil))
+
+	events, err := r.Run(context.Background(), &RunRequest{SessionKey: "usage", Prompt: "Hello"})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	var usage *ai.Usage
+	for event := range events {
+		if event.Type == ai.EventTypeUsage {
+			usage = event.Usage
+		}
+	}
+	if usage == nil || usage.Model != "ollama/llama3.2" {
+		t.Fatalf("expected usage event with normalized model, got %+v", usage)
+	}
+
+	sess, _ := sessions.GetOrCreate("usage")
+	messages, _ := sessions.GetMessages(sess.ID, 0)
+	last := messages[len(messages)-1]
+	if last.Role != "assistant" || last.InputTokens != 12 || last.OutputTokens != 3 || last.Model != "ollama/llama3.2" {
+		t.Errorf("usage not persisted on assistant message: %+v", last)
+	}
+}
+
 func TestRunWithToolCall(t *testing.T) {
 	cfg := config.DefaultConfig()
 	cfg.MaxIterations = 10
@@ -370,6 +418,44 @@ func TestChatWithError(t *testing.T) {
 	}
 }
 
+func TestRunKeepsUsageOfFailedCall(t *testing.T) {
+	cfg := config.DefaultConfig()
+
+	sessions, err := session.New(t.TempDir() + "/test.db")
+	if err != nil {
+		t.Fatalf("failed to create session manager: %v", err)
+	}
+	defer sessions.Close()
+
+	provider := &mockProvider{
+		id: "test",
+		events: []ai.StreamEvent{
+			{Type: ai.EventTypeText, Text: "Partial"},
+			{Type: ai.EventTypeUsage, Usage: &ai.Usage{Model: "m", InputTokens: 30, OutputTokens: 2}},
+			{Type: ai.EventTypeError, Error: &ai.ProviderError{Message: "connection reset"}},
+		},
+	}
+	r := New(cfg, sessions, []ai.Provider{provider}, tools.NewRegistry(nil))
+
+	events, err := r.Run(context.Background(), &RunRequest{SessionKey: "failed", Prompt: "Hi"})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	for range events {
+	}
+
+	// The spend counts toward budgets while the partial reply stays out of the conversation
+	spent, _ := sessions.Spend("failed", time.Now())
+	if spent.Calls != 1 || spent.InputTokens != 30 {
+		t.Errorf("expected failed call usage to be recorded, got %+v", spent)
+	}
+	sess, _ := sessions.GetOrCreate("failed")
+	messages, _ := sessions.GetMessages(sess.ID, 0)
+	if len(messages) != 1 || messages[0].Role != "user" {
+		t.Errorf("expected only the prompt in the conversation, got %+v", messages)
+	}
+}
+
 func TestDefaultSystemPrompt(t *testing.T) {
 	if DefaultSystemPrompt == "" {
 		t.Error("DefaultSystemPrompt is empty")
@@ -415,3 +501,495 @@ func TestGenerateSummary(t *testing.T) {
 		t.Error("summary should contain user message")
 	}
 }
+
+func TestRunStopsAtHardBudget(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Budgets.PerSession = config.BudgetLimit{HardUSD: 1}
+
+	tmpDir := t.TempDir()
+	sessions, err := session.New(tmpDir + "/test.db")
+	if err != nil {
+		t.Fatalf("failed to create session manager: %v", err)
+	}
+	defer sessions.Close()
+
+	// Prior spend in this session already exceeds the hard limit
+	sess, _ := sessions.GetOrCreate("cron-nightly")
+	sessions.AppendMessage(sess.ID, session.Message{Role: "assistant", Content: "earlier", InputTokens: 10, CostUSD: 1.5})
+
+	provider := &mockProvider{id: "test", events: []ai.StreamEvent{{Type: ai.EventTypeText, Text: "should not run"}}}
+	r := New(cfg, sessions, []ai.Provider{provider}, tools.NewRegistry(nil))
+
+	events, err := r.Run(context.Background(), &RunRequest{SessionKey: "cron-nightly", Prompt: "again"})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	var runErr error
+	for event := range events {
+		if event.Type == ai.EventTypeError {
+			runErr = event.Error
+		}
+	}
+
+	if !IsBudgetExceeded(runErr) {
+		t.Fatalf("expected budget error, got %v", runErr)
+	}
+	if provider.callCount != 0 {
+		t.Errorf("expected provider not to be called, got %d calls", provider.callCount)
+	}
+}
+
+func TestRunDowngradesAtSoftBudget(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Budgets.PerSession = config.BudgetLimit{SoftUSD: 1}
+
+	tmpDir := t.TempDir()
+	sessions, err := session.New(tmpDir + "/test.db")
+	if err != nil {
+		t.Fatalf("failed to create session manager: %v", err)
+	}
+	defer sessions.Close()
+
+	sess, _ := sessions.GetOrCreate("soft")
+	sessions.AppendMessage(sess.ID, session.Message{Role: "assistant", Content: "earlier", InputTokens: 10, CostUSD: 1.5})
+
+	expensive := &mockProvider{id: "anthropic", events: []ai.StreamEvent{{Type: ai.EventTypeText, Text: "expensive"}}}
+	cheap := &mockProvider{id: "openai", events: []ai.StreamEvent{{Type: ai.EventTypeText, Text: "cheap"}}}
+
+	r := New(cfg, sessions, []ai.Provider{expensive, cheap}, tools.NewRegistry(nil))
+	r.SetModelSelector(ai.NewModelSelector(&provider.ModelsConfig{
+		TaskRouting: &provider.TaskRouting{General: "anthropic/big"},
+		Providers: map[string][]provider.ModelInfo{
+			"anthropic": {{ID: "big", Pricing: &provider.ModelPricing{Input: 15, Output: 75}}},
+			"openai":    {{ID: "small", Pricing: &provider.ModelPricing{Input: 0.1, Output: 0.4}}},
+		},
+	}))
+
+	events, err := r.Run(context.Background(), &RunRequest{SessionKey: "soft", Prompt: "hello"})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	var text string
+	for event := range events {
+		switch event.Type {
+		case ai.EventTypeText:
+			text += event.Text
+		case ai.EventTypeError:
+			t.Fatalf("unexpected error: %v", event.Error)
+		}
+	}
+
+	if text != "cheap" || expensive.callCount != 0 {
+		t.Errorf("expected downgrade to cheaper model, got %q (expensive calls: %d)", text, expensive.callCount)
+	}
+}
+
+func TestRunRotatesAuthProfiles(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.MaxIterations = 5
+
+	tmpDir := t.TempDir()
+	sessions, err := session.New(tmpDir + "/test.db")
+	if err != nil {
+		t.Fatalf("failed to create session manager: %v", err)
+	}
+	defer sessions.Close()
+
+	profiles, err := config.NewAuthProfileManager(tmpDir + "/test.db")
+	if err != nil {
+		t.Fatalf("failed to create auth profile manager: %v", err)
+	}
+	defer profiles.Close()
+	ctx := context.Background()
+	profiles.CreateProfile(ctx, &config.AuthProfile{ID: "a", Name: "first", Provider: "anthropic", APIKey: "key-a", Priority: 1, IsActive: true})
+	profiles.CreateProfile(ctx, &config.AuthProfile{ID: "b", Name: "second", Provider: "anthropic", APIKey: "key-b", Priority: 1, IsActive: true})
+
+	// The first key is rate limited; the second works
+	byKey := map[string]*mockProvider{
+		"key-a": {id: "anthropic", err: &ai.ProviderError{Code: "rate_limit_exceeded", Message: "slow down"}},
+		"key-b": {id: "anthropic", events: []ai.StreamEvent{{Type: ai.EventTypeText, Text: "hello"}}},
+	}
+	fallback := &mockProvider{id: "anthropic"}
+
+	r := New(cfg, sessions, []ai.Provider{fallback}, tools.NewRegistry(nil))
+	r.SetAuthProfiles(profiles, func(p *config.AuthProfile) ai.Provider { return byKey[p.APIKey] })
+
+	for i := 0; i < 2; i++ {
+		events, err := r.Run(ctx, &RunRequest{SessionKey: "keys", Prompt: "hi"})
+		if err != nil {
+			t.Fatalf("Run failed: %v", err)
+		}
+		var text string
+		for event := range events {
+			switch event.Type {
+			case ai.EventTypeText:
+				text += event.Text
+			case ai.EventTypeError:
+				t.Fatalf("unexpected error: %v", event.Error)
+			}
+		}
+		if text != "hello" {
+			t.Errorf("run %d: expected the second key to answer, got %q", i+1, text)
+		}
+	}
+
+	// The limited key was tried once, then skipped while cooling down
+	if byKey["key-a"].callCount != 1 || byKey["key-b"].callCount != 2 || fallback.callCount != 0 {
+		t.Errorf("unexpected calls: a=%d b=%d fallback=%d", byKey["key-a"].callCount, byKey["key-b"].callCount, fallback.callCount)
+	}
+	active, _ := profiles.ListActiveProfiles(ctx, "anthropic")
+	if len(active) != 1 || active[0].ID != "b" || active[0].UsageCount != 2 {
+		t.Errorf("expected only the second key available with 2 uses, got %+v", active)
+	}
+}
+
+// compactTestProvider answers summarization requests with a summary and records the other requests
+type compactTestProvider struct {
+	summaryCalls int
+	requests     []*ai.ChatRequest
+}
+
+func (p *compactTestProvider) ID() string {
+	return "test"
+}
+
+func (p *compactTestProvider) Stream(ctx context.Context, req *ai.ChatRequest) (<-chan ai.StreamEvent, error) {
+	ch := make(chan ai.StreamEvent, 2)
+	if req.System == compactionPrompt {
+		p.summaryCalls++
+		ch <- ai.StreamEvent{Type: ai.EventTypeText, Text: "User asked about gophers."}
+		ch <- ai.StreamEvent{Type: ai.EventTypeUsage, Usage: &ai.Usage{InputTokens: 50, OutputTokens: 10}}
+	} else {
+		p.requests = append(p.requests, req)
+		ch <- ai.StreamEvent{Type: ai.EventTypeText, Text: "ok"}
+	}
+	close(ch)
+	return ch, nil
+}
+
+func TestRunCompactsNearContextWindow(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Compaction.KeepTurns = 1
+
+	tmpDir := t.TempDir()
+	sessions, err := session.New(tmpDir + "/test.db")
+	if err != nil {
+		t.Fatalf("failed to create session manager: %v", err)
+	}
+	defer sessions.Close()
+
+	sess, _ := sessions.GetOrCreate("long")
+	long := strings.Repeat("gopher ", 200)
+	for i := 0; i < 3; i++ {
+		sessions.AppendMessage(sess.ID, session.Message{Role: "user", Content: long})
+		sessions.AppendMessage(sess.ID, session.Message{Role: "assistant", Content: long})
+	}
+
+	p := &compactTestProvider{}
+	r := New(cfg, sessions, []ai.Provider{p}, tools.NewRegistry(nil))
+	r.SetModelSelector(ai.NewModelSelector(&provider.ModelsConfig{
+		TaskRouting: &provider.TaskRouting{General: "test/small"},
+		Providers: map[string][]provider.ModelInfo{
+			"test": {{ID: "small", ContextWindow: 2000}},
+		},
+	}))
+
+	events, err := r.Run(context.Background(), &RunRequest{SessionKey: "long", Prompt: "and now?", System: "base"})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	for event := range events {
+		if event.Type == ai.EventTypeError {
+			t.Fatalf("unexpected error: %v", event.Error)
+		}
+	}
+
+	if p.summaryCalls != 1 {
+		t.Fatalf("expected 1 summarization call, got %d", p.summaryCalls)
+	}
+	if len(p.requests) != 1 {
+		t.Fatalf("expected 1 chat request, got %d", len(p.requests))
+	}
+	req := p.requests[0]
+	if !strings.Contains(req.System, "User asked about gophers.") {
+		t.Error("expected summary in system prompt")
+	}
+	if len(req.Messages) != 1 || req.Messages[0].Content != "and now?" {
+		t.Errorf("expected only the last turn to be sent verbatim, got %d messages", len(req.Messages))
+	}
+
+	history, _ := sessions.GetHistory(sess.ID)
+	archived := 0
+	for _, m := range history {
+		if m.Archived {
+			archived++
+		}
+	}
+	if archived != 6 {
+		t.Errorf("expected 6 archived messages, got %d", archived)
+	}
+}
+
+func TestRunReplaysRecordedToolLoop(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.MaxIterations = 5
+
+	tmpDir := t.TempDir()
+	sessions, err := session.New(tmpDir + "/test.db")
+	if err != nil {
+		t.Fatalf("failed to create session manager: %v", err)
+	}
+	defer sessions.Close()
+
+	registry := tools.NewRegistry(nil)
+	registry.RegisterDefaults()
+
+	run := func(p ai.Provider, key string) string {
+		r := New(cfg, sessions, []ai.Provider{p}, registry)
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		events, err := r.Run(ctx, &RunRequest{SessionKey: key, Prompt: "Find the missing files"})
+		if err != nil {
+			t.Fatalf("Run failed: %v", err)
+		}
+		var out strings.Builder
+		for event := range events {
+			switch event.Type {
+			case ai.EventTypeText:
+				out.WriteString(event.Text)
+			case ai.EventTypeToolCall:
+				out.WriteString("[" + event.ToolCall.Name + "]")
+			case ai.EventTypeError:
+				t.Fatalf("unexpected error: %v", event.Error)
+			}
+		}
+		return out.String()
+	}
+
+	cassettePath := tmpDir + "/loop.json"
+	recorder, _ := ai.OpenCassette(cassettePath)
+	live := ai.NewScriptedProvider("scripted",
+		ai.ToolCallTurn(ai.ScriptedCall{Name: "glob", Input: map[string]string{"pattern": "*.does-not-exist"}}),
+		ai.TextTurn("Nothing matched."),
+	)
+	recorded := run(recorder.Record(live), "record")
+
+	cassette, err := ai.OpenCassette(cassettePath)
+	if err != nil {
+		t.Fatalf("failed to open cassette: %v", err)
+	}
+	replayed := run(cassette.Replay("scripted"), "replay")
+
+	if recorded != "[glob]Nothing matched." || replayed != recorded {
+		t.Errorf("recorded %q, replayed %q", recorded, replayed)
+	}
+}
+
+// blockingProvider streams some text, then holds the stream open until the request is cancelled
+type blockingProvider struct {
+	text  string
+	usage *ai.Usage // Reported before blocking, if set
+}
+
+func (p *blockingProvider) ID() string {
+	return "blocking"
+}
+
+func (p *blockingProvider) Stream(ctx context.Context, req *ai.ChatRequest) (<-chan ai.StreamEvent, error) {
+	ch := make(chan ai.StreamEvent)
+	go func() {
+		defer close(ch)
+		ch <- ai.StreamEvent{Type: ai.EventTypeText, Text: p.text}
+		if p.usage != nil {
+			ch <- ai.StreamEvent{Type: ai.EventTypeUsage, Usage: p.usage}
+		}
+		<-ctx.Done()
+		ch <- ai.StreamEvent{Type: ai.EventTypeError, Error: ctx.Err()}
+	}()
+	return ch, nil
+}
+
+func TestRunCancelSavesMarker(t *testing.T) {
+	cfg := config.DefaultConfig()
+
+	sessions, err := session.New(t.TempDir() + "/test.db")
+	if err != nil {
+		t.Fatalf("failed to create session manager: %v", err)
+	}
+	defer sessions.Close()
+
+	provider := &blockingProvider{text: "Working on it", usage: &ai.Usage{Model: "slow", InputTokens: 40, OutputTokens: 4}}
+	r := New(cfg, sessions, []ai.Provider{provider}, tools.NewRegistry(nil))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events, err := r.Run(ctx, &RunRequest{SessionKey: "cancel", Prompt: "Do something slow"})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	var runErr error
+	for event := range events {
+		switch event.Type {
+		case ai.EventTypeText:
+			cancel()
+		case ai.EventTypeError:
+			runErr = event.Error
+		case ai.EventTypeDone:
+			t.Fatal("cancelled run reported done")
+		}
+	}
+	if !errors.Is(runErr, ErrCancelled) {
+		t.Fatalf("expected ErrCancelled, got %v", runErr)
+	}
+
+	sess, _ := sessions.GetOrCreate("cancel")
+	messages, err := sessions.GetMessages(sess.ID, 0)
+	if err != nil {
+		t.Fatalf("GetMessages failed: %v", err)
+	}
+	last := messages[len(messages)-1]
+	if last.Role != "assistant" || last.Content != "Working on it\n\n"+CancelledMarker {
+		t.Errorf("expected partial reply with cancel marker, got %s %q", last.Role, last.Content)
+	}
+	if last.InputTokens != 40 || last.OutputTokens != 4 {
+		t.Errorf("expected usage of the cancelled call to be kept, got %+v", last)
+	}
+
+	// A cancelled context stops the next run before it reaches the provider
+	events, err = r.Run(ctx, &RunRequest{SessionKey: "cancel", Prompt: "Again"})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	for event := range events {
+		if event.Type == ai.EventTypeText {
+			t.Errorf("provider called after cancel: %q", event.Text)
+		}
+	}
+}
+
+// gatedProvider holds its first response until the gate is closed and numbers each reply
+type gatedProvider struct {
+	gate    chan struct{}
+	started chan struct{} // Closed when the first request arrives
+
+	mu       sync.Mutex
+	requests []*ai.ChatRequest
+}
+
+func newGatedProvider() *gatedProvider {
+	return &gatedProvider{gate: make(chan struct{}), started: make(chan struct{})}
+}
+
+func (p *gatedProvider) ID() string {
+	return "gated"
+}
+
+func (p *gatedProvider) Stream(ctx context.Context, req *ai.ChatRequest) (<-chan ai.StreamEvent, error) {
+	p.mu.Lock()
+	p.requests = append(p.requests, req)
+	n := len(p.requests)
+	p.mu.Unlock()
+
+	ch := make(chan ai.StreamEvent)
+	go func() {
+		defer close(ch)
+		if n == 1 {
+			close(p.started)
+			<-p.gate
+		}
+		ch <- ai.StreamEvent{Type: ai.EventTypeText, Text: fmt.Sprintf("reply %d", n)}
+	}()
+	return ch, nil
+}
+
+func drain(t *testing.T, events <-chan ai.StreamEvent) {
+	t.Helper()
+	for event := range events {
+		if event.Type == ai.EventTypeError {
+			t.Errorf("unexpected error: %v", event.Error)
+		}
+	}
+}
+
+func TestRunSerializesSession(t *testing.T) {
+	cfg := config.DefaultConfig()
+	sessions, err := session.New(t.TempDir() + "/test.db")
+	if err != nil {
+		t.Fatalf("failed to create session manager: %v", err)
+	}
+	defer sessions.Close()
+
+	provider := newGatedProvider()
+	r := New(cfg, sessions, []ai.Provider{provider}, tools.NewRegistry(nil))
+	ctx := context.Background()
+
+	first, err := r.Run(ctx, &RunRequest{SessionKey: "queue", Prompt: "first"})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	second, err := r.Run(ctx, &RunRequest{SessionKey: "queue", Prompt: "second"})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	<-provider.started
+	close(provider.gate)
+	drain(t, first)
+	drain(t, second)
+
+	sess, _ := sessions.GetOrCreate("queue")
+	messages, _ := sessions.GetMessages(sess.ID, 0)
+	var got []string
+	for _, m := range messages {
+		got = append(got, m.Role+":"+m.Content)
+	}
+	want := []string{"user:first", "assistant:reply 1", "user:second", "assistant:reply 2"}
+	if strings.Join(got, ", ") != strings.Join(want, ", ") {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+func TestRunSteer(t *testing.T) {
+	cfg := config.DefaultConfig()
+	sessions, err := session.New(t.TempDir() + "/test.db")
+	if err != nil {
+		t.Fatalf("failed to create session manager: %v", err)
+	}
+	defer sessions.Close()
+
+	provider := newGatedProvider()
+	r := New(cfg, sessions, []ai.Provider{provider}, tools.NewRegistry(nil))
+
+	if r.Steer("steer", "too early") {
+		t.Error("Steer succeeded with no run in progress")
+	}
+
+	events, err := r.Run(context.Background(), &RunRequest{SessionKey: "steer", Prompt: "write a haiku"})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	// Steer while the first provider call is in flight; the run picks it up instead of finishing
+	<-provider.started
+	if !r.Steer("steer", "make it about autumn") {
+		t.Fatal("Steer failed during a run")
+	}
+	close(provider.gate)
+	drain(t, events)
+
+	if len(provider.requests) != 2 {
+		t.Fatalf("expected 2 provider calls, got %d", len(provider.requests))
+	}
+	sent := provider.requests[1].Messages
+	if last := sent[len(sent)-1]; last.Role != "user" || last.Content != "make it about autumn" {
+		t.Errorf("expected steering message last in second request, got %s %q", last.Role, last.Content)
+	}
+
+	if r.Steer("steer", "too late") {
+		t.Error("Steer succeeded after the run finished")
+	}
+}
@@ -0,0 +1,189 @@
+package session
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrSessionNotFound is returned when a session key or ID does not exist
+var ErrSessionNotFound = errors.New("session not found")
+
+// SessionNode is a session and the sessions forked from it
+type SessionNode struct {
+	Session
+	Children []*SessionNode `json:"children,omitempty"`
+}
+
+// Get retrieves a session by ID
+func (m *Manager) Get(sessionID string) (*Session, error) {
+	s, err := scanSession(m.db.QueryRow(
+		"SELECT "+sessionColumns+" FROM sessions WHERE id = ?",
+		sessionID,
+	))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("%w: %s", ErrSessionNotFound, sessionID)
+	}
+	return s, err
+}
+
+// GetByKey retrieves a session by key without creating it
+func (m *Manager) GetByKey(sessionKey string) (*Session, error) {
+	s, err := m.getByKey(sessionKey)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("%w: %s", ErrSessionNotFound, sessionKey)
+	}
+	return s, err
+}
+
+// Fork copies a session's messages up to and including atMessageID into a new session.
+// atMessageID 0 forks at the latest message; newKey "" picks "<key>-fork-N".
+// Copied messages keep their timestamps and compaction state but not their token usage,
+// so spend is only counted once.
+func (m *Manager) Fork(sessionID string, atMessageID int64, newKey string) (*Session, error) {
+	parent, err := m.Get(sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	if atMessageID == 0 {
+		err := m.db.QueryRow(`
+			SELECT id FROM messages
+			WHERE session_id = ? AND archived = 0 AND deleted_at IS NULL
+			ORDER BY `+messageOrder+` DESC, id DESC
+			LIMIT 1
+		`, sessionID).Scan(&atMessageID)
+		if err != nil && err != sql.ErrNoRows {
+			return nil, err
+		}
+	} else if err := m.checkBranchPoint(sessionID, atMessageID); err != nil {
+		return nil, err
+	}
+
+	if newKey == "" {
+		if newKey, err = m.nextForkKey(parent.SessionKey); err != nil {
+			return nil, err
+		}
+	}
+
+	tx, err := m.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	fork := &Session{
+		ID:            generateID(),
+		SessionKey:    newKey,
+		CreatedAt:     time.Now(),
+		ParentID:      parent.ID,
+		ForkMessageID: atMessageID,
+	}
+	fork.UpdatedAt = fork.CreatedAt
+	_, err = tx.Exec(
+		`INSERT INTO sessions (id, session_key, created_at, updated_at, parent_id, fork_message_id)
+		VALUES (?, ?, ?, ?, ?, ?)`,
+		fork.ID, fork.SessionKey, fork.CreatedAt, fork.UpdatedAt, fork.ParentID, fork.ForkMessageID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create fork %q: %w", newKey, err)
+	}
+
+	if atMessageID > 0 {
+		_, err = tx.Exec(`
+			INSERT INTO messages (session_id, role, content, tool_calls, tool_results, created_at, model, archived, parts)
+			SELECT ?, role, content, tool_calls, tool_results, created_at, model, archived, parts
+			FROM messages
+			WHERE session_id = ? AND deleted_at IS NULL AND `+atOrBefore+`
+			ORDER BY `+messageOrder+` ASC, id ASC
+		`, fork.ID, sessionID, atMessageID, atMessageID, atMessageID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to copy messages: %w", err)
+		}
+	}
+
+	return fork, tx.Commit()
+}
+
+// Rewind soft-deletes every message after toMessageID, returning how many were removed.
+// Removed messages no longer appear in GetMessages or GetHistory.
+func (m *Manager) Rewind(sessionID string, toMessageID int64) (int, error) {
+	if err := m.checkBranchPoint(sessionID, toMessageID); err != nil {
+		return 0, err
+	}
+
+	res, err := m.db.Exec(`
+		UPDATE messages SET deleted_at = ?
+		WHERE session_id = ? AND deleted_at IS NULL AND NOT `+atOrBefore,
+		time.Now(), sessionID, toMessageID, toMessageID, toMessageID,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to rewind: %w", err)
+	}
+	removed, _ := res.RowsAffected()
+
+	_, err = m.db.Exec("UPDATE sessions SET updated_at = ? WHERE id = ?", time.Now(), sessionID)
+	return int(removed), err
+}
+
+// Tree returns all sessions arranged by fork parentage, newest first at each level
+func (m *Manager) Tree() ([]*SessionNode, error) {
+	sessions, err := m.ListSessions()
+	if err != nil {
+		return nil, err
+	}
+
+	nodes := make(map[string]*SessionNode, len(sessions))
+	for _, s := range sessions {
+		nodes[s.ID] = &SessionNode{Session: s}
+	}
+
+	var roots []*SessionNode
+	for _, s := range sessions {
+		node := nodes[s.ID]
+		if parent, ok := nodes[s.ParentID]; ok && s.ParentID != "" {
+			parent.Children = append(parent.Children, node)
+			continue
+		}
+		roots = append(roots, node) // Includes forks whose parent was deleted
+	}
+	return roots, nil
+}
+
+// atOrBefore matches messages ordered at or before a message ID (bind the ID three times)
+const atOrBefore = `(COALESCE(seq, id) < (SELECT COALESCE(seq, id) FROM messages WHERE id = ?)
+	OR (COALESCE(seq, id) = (SELECT COALESCE(seq, id) FROM messages WHERE id = ?) AND id <= ?))`
+
+// checkBranchPoint verifies a message can be forked from or rewound to
+func (m *Manager) checkBranchPoint(sessionID string, messageID int64) error {
+	var archived int
+	var deletedAt sql.NullTime
+	err := m.db.QueryRow(
+		"SELECT archived, deleted_at FROM messages WHERE id = ? AND session_id = ?",
+		messageID, sessionID,
+	).Scan(&archived, &deletedAt)
+	switch {
+	case err == sql.ErrNoRows:
+		return fmt.Errorf("message %d not found in session", messageID)
+	case err != nil:
+		return err
+	case deletedAt.Valid:
+		return fmt.Errorf("message %d was removed by an earlier rewind", messageID)
+	case archived != 0:
+		return fmt.Errorf("message %d was compacted; choose a message after the summary", messageID)
+	}
+	return nil
+}
+
+// nextForkKey returns the first unused "<key>-fork-N"
+func (m *Manager) nextForkKey(sessionKey string) (string, error) {
+	for n := 1; ; n++ {
+		key := fmt.Sprintf("%s-fork-%d", sessionKey, n)
+		if _, err := m.getByKey(key); err == sql.ErrNoRows {
+			return key, nil
+		} else if err != nil {
+			return "", err
+		}
+	}
+}
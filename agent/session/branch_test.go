@@ -0,0 +1,92 @@
+package session
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func TestForkRewindAndTree(t *testing.T) {
+	manager, err := New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to create manager: %v", err)
+	}
+	defer manager.Close()
+
+	sess, _ := manager.GetOrCreate("main")
+	for _, m := range []Message{
+		user("first"), {Role: "assistant", Content: "one", InputTokens: 10, CostUSD: 0.5},
+		user("second"), assistant("bad answer"),
+	} {
+		manager.AppendMessage(sess.ID, m)
+	}
+	messages, _ := manager.GetMessages(sess.ID, 0)
+	forkPoint := messages[1].ID // "one"
+
+	// Fork at the first answer
+	fork, err := manager.Fork(sess.ID, forkPoint, "")
+	if err != nil {
+		t.Fatalf("Fork failed: %v", err)
+	}
+	if fork.SessionKey != "main-fork-1" || fork.ParentID != sess.ID || fork.ForkMessageID != forkPoint {
+		t.Errorf("unexpected fork: %+v", fork)
+	}
+	forked, _ := manager.GetMessages(fork.ID, 0)
+	if got := describe(forked); got != "user:first assistant:one" {
+		t.Errorf("forked messages = %s", got)
+	}
+
+	// Copies don't double-count spend
+	report, _ := manager.Usage(UsageFilter{})
+	if report.Total.CostUSD != 0.5 {
+		t.Errorf("expected total cost 0.5, got %v", report.Total.CostUSD)
+	}
+
+	// The original is untouched; rewinding it soft-deletes later messages
+	removed, err := manager.Rewind(sess.ID, forkPoint)
+	if err != nil {
+		t.Fatalf("Rewind failed: %v", err)
+	}
+	if removed != 2 {
+		t.Errorf("expected 2 removed messages, got %d", removed)
+	}
+	remaining, _ := manager.GetHistory(sess.ID)
+	if got := describe(remaining); got != "user:first assistant:one" {
+		t.Errorf("rewound messages = %s", got)
+	}
+	if _, err := manager.Rewind(sess.ID, messages[3].ID); err == nil {
+		t.Error("expected error rewinding to a removed message")
+	}
+
+	// Fork of a fork, then the tree
+	nested, err := manager.Fork(fork.ID, 0, "retry")
+	if err != nil {
+		t.Fatalf("nested Fork failed: %v", err)
+	}
+	if nested.ForkMessageID == 0 {
+		t.Error("expected fork at latest message")
+	}
+	manager.GetOrCreate("other")
+
+	roots, err := manager.Tree()
+	if err != nil {
+		t.Fatalf("Tree failed: %v", err)
+	}
+	if len(roots) != 2 {
+		t.Fatalf("expected 2 roots, got %d", len(roots))
+	}
+	var main *SessionNode
+	for _, r := range roots {
+		if r.SessionKey == "main" {
+			main = r
+		}
+	}
+	if main == nil || len(main.Children) != 1 || len(main.Children[0].Children) != 1 ||
+		main.Children[0].Children[0].SessionKey != "retry" {
+		t.Errorf("unexpected tree: %+v", roots)
+	}
+
+	if _, err := manager.GetByKey("missing"); !errors.Is(err, ErrSessionNotFound) {
+		t.Errorf("expected ErrSessionNotFound, got %v", err)
+	}
+}
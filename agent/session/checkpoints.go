@@ -0,0 +1,251 @@
+package session
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// ErrNoCheckpoints is returned when there are no file changes to undo
+var ErrNoCheckpoints = errors.New("no file changes to undo")
+
+// MaxCheckpointSize is the largest file snapshotted before a change
+const MaxCheckpointSize = 10 << 20
+
+// Checkpoint is a file's contents saved before a tool call changed it
+type Checkpoint struct {
+	ID         int64     `json:"id"`
+	SessionID  string    `json:"session_id"`
+	MessageID  int64     `json:"message_id"` // Assistant message whose tool call changed the file
+	Path       string    `json:"path"`
+	Existed    bool      `json:"existed"` // False when the tool created the file
+	Size       int64     `json:"size"`
+	CreatedAt  time.Time `json:"created_at"`
+	RestoredAt time.Time `json:"restored_at,omitempty"` // Zero until undone
+}
+
+// RestoredFile is a file put back by RestoreCheckpoints
+type RestoredFile struct {
+	Path      string `json:"path"`
+	MessageID int64  `json:"message_id"` // Earliest undone message that changed it
+	Deleted   bool   `json:"deleted"`    // The file didn't exist before, so it was removed
+}
+
+// messageCtx is the context key for the message whose tool calls are running
+type messageCtx struct {
+	sessionID string
+	messageID int64
+}
+
+// WithMessage returns a context that attributes tool calls to an assistant message, so file
+// checkpoints can be undone by session and message
+func WithMessage(ctx context.Context, sessionID string, messageID int64) context.Context {
+	return context.WithValue(ctx, messageCtx{}, messageCtx{sessionID: sessionID, messageID: messageID})
+}
+
+// MessageFrom returns the session and message set by WithMessage
+func MessageFrom(ctx context.Context) (sessionID string, messageID int64, ok bool) {
+	m, ok := ctx.Value(messageCtx{}).(messageCtx)
+	return m.sessionID, m.messageID, ok
+}
+
+// migrateCheckpoints creates the file checkpoints table
+func (m *Manager) migrateCheckpoints() error {
+	_, err := m.db.Exec(`
+	CREATE TABLE IF NOT EXISTS file_checkpoints (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		session_id TEXT NOT NULL,
+		message_id INTEGER NOT NULL,
+		path TEXT NOT NULL,
+		existed INTEGER NOT NULL,
+		content BLOB,
+		mode INTEGER,
+		created_at INTEGER NOT NULL,
+		restored_at INTEGER
+	);
+	CREATE INDEX IF NOT EXISTS idx_file_checkpoints_session ON file_checkpoints(session_id, message_id);
+	`)
+	return err
+}
+
+// SaveCheckpoint snapshots a file before a tool call in messageID changes it. Only the first
+// change per message is kept, so restoring puts the file back as it was before the message.
+func (m *Manager) SaveCheckpoint(sessionID string, messageID int64, path string) error {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+
+	var exists int
+	err = m.db.QueryRow(`SELECT COUNT(*) FROM file_checkpoints
+		WHERE session_id = ? AND message_id = ? AND path = ? AND restored_at IS NULL`,
+		sessionID, messageID, path).Scan(&exists)
+	if err != nil || exists > 0 {
+		return err
+	}
+
+	existed := true
+	var content []byte
+	var mode fs.FileMode
+	info, err := os.Stat(path)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		existed = false
+	case err != nil:
+		return err
+	case info.IsDir():
+		return fmt.Errorf("%s is a directory", path)
+	case info.Size() > MaxCheckpointSize:
+		return fmt.Errorf("%s is too large to checkpoint (%d bytes)", path, info.Size())
+	default:
+		if content, err = os.ReadFile(path); err != nil {
+			return err
+		}
+		mode = info.Mode().Perm()
+	}
+
+	_, err = m.db.Exec(`INSERT INTO file_checkpoints
+		(session_id, message_id, path, existed, content, mode, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		sessionID, messageID, path, existed, content, int64(mode), time.Now().UnixMilli())
+	if err != nil {
+		return fmt.Errorf("failed to save checkpoint: %w", err)
+	}
+	return nil
+}
+
+// ListCheckpoints returns a session's checkpoints, oldest first
+func (m *Manager) ListCheckpoints(sessionID string) ([]Checkpoint, error) {
+	return m.queryCheckpoints(`SELECT id, session_id, message_id, path, existed, LENGTH(content), created_at, restored_at
+		FROM file_checkpoints WHERE session_id = ? ORDER BY message_id, id`, sessionID)
+}
+
+// PendingCheckpoints returns the checkpoints RestoreCheckpoints would apply: the earliest
+// unrestored one per file changed after toMessageID. A toMessageID of 0 means the last run,
+// everything after the latest user message that precedes a change.
+func (m *Manager) PendingCheckpoints(sessionID string, toMessageID int64) ([]Checkpoint, error) {
+	if toMessageID <= 0 {
+		var latest sql.NullInt64
+		err := m.db.QueryRow(`SELECT MAX(message_id) FROM file_checkpoints
+			WHERE session_id = ? AND restored_at IS NULL`, sessionID).Scan(&latest)
+		if err != nil {
+			return nil, err
+		}
+		if !latest.Valid {
+			return nil, ErrNoCheckpoints
+		}
+
+		var userMessage sql.NullInt64
+		err = m.db.QueryRow(`SELECT MAX(id) FROM messages
+			WHERE session_id = ? AND role = 'user' AND id < ?`, sessionID, latest.Int64).Scan(&userMessage)
+		if err != nil {
+			return nil, err
+		}
+		toMessageID = userMessage.Int64
+	}
+
+	all, err := m.queryCheckpoints(`SELECT id, session_id, message_id, path, existed, LENGTH(content), created_at, restored_at
+		FROM file_checkpoints WHERE session_id = ? AND message_id > ? AND restored_at IS NULL
+		ORDER BY message_id, id`, sessionID, toMessageID)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool)
+	var pending []Checkpoint
+	for _, c := range all {
+		if !seen[c.Path] {
+			seen[c.Path] = true
+			pending = append(pending, c)
+		}
+	}
+	if len(pending) == 0 {
+		return nil, ErrNoCheckpoints
+	}
+	return pending, nil
+}
+
+// RestoreCheckpoints puts files changed after toMessageID (0 for the last run) back the way
+// they were at that message, deleting files the agent created. Restored checkpoints aren't
+// applied again.
+func (m *Manager) RestoreCheckpoints(sessionID string, toMessageID int64) ([]RestoredFile, error) {
+	pending, err := m.PendingCheckpoints(sessionID, toMessageID)
+	if err != nil {
+		return nil, err
+	}
+
+	var restored []RestoredFile
+	var errs []error
+	for _, c := range pending {
+		if err := m.restoreCheckpoint(c); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", c.Path, err))
+			continue
+		}
+
+		// Later snapshots of the file are superseded by this one
+		_, err := m.db.Exec(`UPDATE file_checkpoints SET restored_at = ?
+			WHERE session_id = ? AND path = ? AND message_id >= ? AND restored_at IS NULL`,
+			time.Now().UnixMilli(), sessionID, c.Path, c.MessageID)
+		if err != nil {
+			errs = append(errs, err)
+		}
+		restored = append(restored, RestoredFile{Path: c.Path, MessageID: c.MessageID, Deleted: !c.Existed})
+	}
+	return restored, errors.Join(errs...)
+}
+
+// restoreCheckpoint writes a snapshot back to disk, or removes a file that didn't exist
+func (m *Manager) restoreCheckpoint(c Checkpoint) error {
+	if !c.Existed {
+		if err := os.Remove(c.Path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		return nil
+	}
+
+	var content []byte
+	var mode int64
+	err := m.db.QueryRow("SELECT content, mode FROM file_checkpoints WHERE id = ?", c.ID).Scan(&content, &mode)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(c.Path), 0755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(c.Path, content, fs.FileMode(mode)); err != nil {
+		return err
+	}
+	return os.Chmod(c.Path, fs.FileMode(mode))
+}
+
+// queryCheckpoints runs a checkpoint query without loading file contents
+func (m *Manager) queryCheckpoints(query string, args ...any) ([]Checkpoint, error) {
+	rows, err := m.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var checkpoints []Checkpoint
+	for rows.Next() {
+		var c Checkpoint
+		var size sql.NullInt64
+		var createdAt int64
+		var restoredAt sql.NullInt64
+		if err := rows.Scan(&c.ID, &c.SessionID, &c.MessageID, &c.Path, &c.Existed, &size, &createdAt, &restoredAt); err != nil {
+			return nil, err
+		}
+		c.Size = size.Int64
+		c.CreatedAt = time.UnixMilli(createdAt)
+		if restoredAt.Valid {
+			c.RestoredAt = time.UnixMilli(restoredAt.Int64)
+		}
+		checkpoints = append(checkpoints, c)
+	}
+	return checkpoints, rows.Err()
+}
@@ -0,0 +1,122 @@
+package session
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCheckpointsUndoRuns(t *testing.T) {
+	m, err := New(t.TempDir() + "/test.db")
+	if err != nil {
+		t.Fatalf("failed to create manager: %v", err)
+	}
+	defer m.Close()
+
+	sess, err := m.GetOrCreate("work")
+	if err != nil {
+		t.Fatalf("GetOrCreate failed: %v", err)
+	}
+	dir := t.TempDir()
+	existing := filepath.Join(dir, "main.go")
+	created := filepath.Join(dir, "sub", "new.go")
+	os.WriteFile(existing, []byte("v1"), 0600)
+
+	// change simulates a tool call in an assistant message that rewrites a file
+	change := func(messageID int64, path, content string) {
+		t.Helper()
+		if err := m.SaveCheckpoint(sess.ID, messageID, path); err != nil {
+			t.Fatalf("SaveCheckpoint failed: %v", err)
+		}
+		os.MkdirAll(filepath.Dir(path), 0755)
+		os.WriteFile(path, []byte(content), 0644)
+	}
+	message := func(role string) int64 {
+		t.Helper()
+		id, err := m.InsertMessage(sess.ID, Message{Role: role, Content: role})
+		if err != nil {
+			t.Fatalf("InsertMessage failed: %v", err)
+		}
+		return id
+	}
+	read := func(path string) string {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "<missing>"
+		}
+		return string(data)
+	}
+
+	if _, err := m.RestoreCheckpoints(sess.ID, 0); !errors.Is(err, ErrNoCheckpoints) {
+		t.Fatalf("expected ErrNoCheckpoints, got %v", err)
+	}
+
+	// First run: edits main.go twice in one message, then again in the next
+	message("user")
+	first := message("assistant")
+	change(first, existing, "v2")
+	change(first, existing, "v3")
+	second := message("assistant")
+	change(second, existing, "v4")
+
+	// Second run: edits main.go and creates a file
+	message("user")
+	third := message("assistant")
+	change(third, existing, "v5")
+	change(third, created, "new")
+
+	pending, err := m.PendingCheckpoints(sess.ID, 0)
+	if err != nil || len(pending) != 2 {
+		t.Fatalf("expected 2 pending checkpoints for the last run, got %+v (%v)", pending, err)
+	}
+
+	// Undoing the last run puts main.go back to v4 and removes the new file
+	restored, err := m.RestoreCheckpoints(sess.ID, 0)
+	if err != nil || len(restored) != 2 {
+		t.Fatalf("RestoreCheckpoints failed: %+v (%v)", restored, err)
+	}
+	if got := read(existing); got != "v4" {
+		t.Errorf("expected v4 after undoing the last run, got %q", got)
+	}
+	if got := read(created); got != "<missing>" || !restored[1].Deleted {
+		t.Errorf("expected the created file to be deleted, got %q (%+v)", got, restored[1])
+	}
+
+	// Undoing again reaches the first run; --to keeps changes up to a message
+	if _, err := m.RestoreCheckpoints(sess.ID, first); err != nil {
+		t.Fatalf("RestoreCheckpoints to message failed: %v", err)
+	}
+	if got := read(existing); got != "v3" {
+		t.Errorf("expected v3 as at message %d, got %q", first, got)
+	}
+	if _, err := m.RestoreCheckpoints(sess.ID, 0); err != nil {
+		t.Fatalf("RestoreCheckpoints failed: %v", err)
+	}
+	if got := read(existing); got != "v1" {
+		t.Errorf("expected the original contents, got %q", got)
+	}
+	if info, _ := os.Stat(existing); info.Mode().Perm() != 0600 {
+		t.Errorf("expected mode 0600 to be restored, got %v", info.Mode().Perm())
+	}
+	if _, err := m.RestoreCheckpoints(sess.ID, 0); !errors.Is(err, ErrNoCheckpoints) {
+		t.Errorf("expected nothing left to undo, got %v", err)
+	}
+
+	all, err := m.ListCheckpoints(sess.ID)
+	if err != nil || len(all) != 4 {
+		t.Fatalf("expected 4 checkpoints, got %d (%v)", len(all), err)
+	}
+	for _, c := range all {
+		if c.RestoredAt.IsZero() {
+			t.Errorf("checkpoint %d should be marked undone", c.ID)
+		}
+	}
+
+	if err := m.DeleteSession(sess.ID); err != nil {
+		t.Fatalf("DeleteSession failed: %v", err)
+	}
+	if all, _ := m.ListCheckpoints(sess.ID); len(all) != 0 {
+		t.Errorf("expected checkpoints to be deleted with the session, got %d", len(all))
+	}
+}
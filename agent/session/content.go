@@ -0,0 +1,208 @@
+package session
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Content part types
+const (
+	PartText     = "text"
+	PartImage    = "image"
+	PartDocument = "document"
+	PartAudio    = "audio"
+)
+
+// MaxPartSize is the largest attachment accepted in a message
+const MaxPartSize = 10 << 20
+
+// ContentPart is a piece of multimodal message content. Attachments carry their bytes
+// inline or, once stored, a reference to a blob in the session database.
+type ContentPart struct {
+	Type      string `json:"type"` // text, image, document, audio
+	Text      string `json:"text,omitempty"`
+	MediaType string `json:"media_type,omitempty"` // e.g. image/png, application/pdf, audio/wav
+	Name      string `json:"name,omitempty"`       // Original file name
+	Data      []byte `json:"data,omitempty"`       // Inline bytes (base64 in JSON)
+	BlobRef   string `json:"blob_ref,omitempty"`   // Stored blob ("sha256:<hex>")
+}
+
+// NewPart creates an attachment part, detecting the media type from the name or the
+// contents when it isn't given
+func NewPart(name, mediaType string, data []byte) (ContentPart, error) {
+	if len(data) == 0 {
+		return ContentPart{}, fmt.Errorf("attachment %s is empty", name)
+	}
+	if len(data) > MaxPartSize {
+		return ContentPart{}, fmt.Errorf("attachment %s is %d bytes (max %d)", name, len(data), MaxPartSize)
+	}
+
+	mediaType, _, _ = mime.ParseMediaType(mediaType)
+	if mediaType == "" || mediaType == "application/octet-stream" {
+		mediaType, _, _ = mime.ParseMediaType(mime.TypeByExtension(strings.ToLower(filepath.Ext(name))))
+	}
+	if mediaType == "" {
+		mediaType, _, _ = mime.ParseMediaType(http.DetectContentType(data))
+	}
+
+	part := ContentPart{MediaType: mediaType, Name: name, Data: data}
+	switch {
+	case strings.HasPrefix(mediaType, "image/"):
+		part.Type = PartImage
+	case strings.HasPrefix(mediaType, "audio/"):
+		part.Type = PartAudio
+	case mediaType == "application/pdf" || IsTextMedia(mediaType):
+		part.Type = PartDocument
+	default:
+		return ContentPart{}, fmt.Errorf("attachment %s has unsupported type %s", name, mediaType)
+	}
+	return part, nil
+}
+
+// ReadPart creates an attachment part from a file
+func ReadPart(path string) (ContentPart, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return ContentPart{}, err
+	}
+	if info.Size() > MaxPartSize {
+		return ContentPart{}, fmt.Errorf("attachment %s is %d bytes (max %d)", path, info.Size(), MaxPartSize)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ContentPart{}, err
+	}
+	return NewPart(filepath.Base(path), "", data)
+}
+
+// IsTextMedia reports whether a media type is plain text that can be sent as a text part
+func IsTextMedia(mediaType string) bool {
+	switch mediaType {
+	case "application/json", "application/xml", "application/yaml", "application/x-yaml", "application/javascript":
+		return true
+	}
+	return strings.HasPrefix(mediaType, "text/")
+}
+
+// AsText renders a part for models that can't take it natively: text documents inline,
+// anything else as a short placeholder
+func (p ContentPart) AsText() string {
+	switch {
+	case p.Type == PartText:
+		return p.Text
+	case len(p.Data) == 0:
+		return fmt.Sprintf("[%s attachment %s (%s) is no longer available]", p.Type, p.Name, p.MediaType)
+	case p.Type == PartDocument && IsTextMedia(p.MediaType):
+		return fmt.Sprintf("<file name=%q>\n%s\n</file>", p.Name, p.Data)
+	default:
+		return fmt.Sprintf("[%s attachment %s (%s) not supported by this model]", p.Type, p.Name, p.MediaType)
+	}
+}
+
+// ContentParts returns the message content as parts: the text content first, then any
+// attached parts
+func (m Message) ContentParts() []ContentPart {
+	parts := make([]ContentPart, 0, len(m.Parts)+1)
+	if m.Content != "" {
+		parts = append(parts, ContentPart{Type: PartText, Text: m.Content})
+	}
+	return append(parts, m.Parts...)
+}
+
+// HasPart reports whether the message has an attached part of the given type
+func (m Message) HasPart(partType string) bool {
+	for _, p := range m.Parts {
+		if p.Type == partType {
+			return true
+		}
+	}
+	return false
+}
+
+// migrateBlobs creates the table holding attachment bytes, shared by every message
+// (and forked session) that references them
+func (m *Manager) migrateBlobs() error {
+	_, err := m.db.Exec(`
+	CREATE TABLE IF NOT EXISTS blobs (
+		ref TEXT PRIMARY KEY,
+		media_type TEXT NOT NULL,
+		data BLOB NOT NULL,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+	`)
+	return err
+}
+
+// execer is satisfied by *sql.DB and *sql.Tx
+type execer interface {
+	Exec(query string, args ...any) (sql.Result, error)
+}
+
+// storeParts moves inline attachment bytes into the blobs table and returns the parts as
+// JSON for the messages table, or NULL when there are none
+func storeParts(db execer, parts []ContentPart) (sql.NullString, error) {
+	if len(parts) == 0 {
+		return sql.NullString{}, nil
+	}
+
+	stored := make([]ContentPart, len(parts))
+	for i, p := range parts {
+		if len(p.Data) > 0 {
+			sum := sha256.Sum256(p.Data)
+			p.BlobRef = "sha256:" + hex.EncodeToString(sum[:])
+			_, err := db.Exec(
+				"INSERT OR IGNORE INTO blobs (ref, media_type, data, created_at) VALUES (?, ?, ?, ?)",
+				p.BlobRef, p.MediaType, p.Data, time.Now(),
+			)
+			if err != nil {
+				return sql.NullString{}, fmt.Errorf("failed to store attachment: %w", err)
+			}
+			p.Data = nil
+		}
+		stored[i] = p
+	}
+
+	data, err := json.Marshal(stored)
+	if err != nil {
+		return sql.NullString{}, err
+	}
+	return sql.NullString{String: string(data), Valid: true}, nil
+}
+
+// loadBlobs fills in the bytes of attachment parts from the blobs table. A missing blob
+// leaves the part without data.
+func (m *Manager) loadBlobs(messages []Message) ([]Message, error) {
+	for i := range messages {
+		for j := range messages[i].Parts {
+			p := &messages[i].Parts[j]
+			if p.BlobRef == "" || len(p.Data) > 0 {
+				continue
+			}
+			err := m.db.QueryRow("SELECT data FROM blobs WHERE ref = ?", p.BlobRef).Scan(&p.Data)
+			if err != nil && err != sql.ErrNoRows {
+				return nil, fmt.Errorf("failed to load attachment %s: %w", p.BlobRef, err)
+			}
+		}
+	}
+	return messages, nil
+}
+
+// pruneBlobs deletes blobs no longer referenced by any message
+func pruneBlobs(db execer) error {
+	_, err := db.Exec(`
+		DELETE FROM blobs WHERE NOT EXISTS (
+			SELECT 1 FROM messages, json_each(messages.parts) AS part
+			WHERE messages.parts IS NOT NULL AND json_extract(part.value, '$.blob_ref') = blobs.ref
+		)
+	`)
+	return err
+}
@@ -0,0 +1,117 @@
+package session
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// pngHeader is enough of a PNG for content sniffing
+var pngHeader = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+func TestNewPart(t *testing.T) {
+	tests := []struct {
+		name, mediaType string
+		data            []byte
+		wantType        string
+		wantMedia       string
+	}{
+		{"photo.png", "", pngHeader, PartImage, "image/png"},
+		{"upload", "", pngHeader, PartImage, "image/png"},                             // Sniffed from the bytes
+		{"clip.wav", "audio/wav; rate=16000", []byte("RIFF"), PartAudio, "audio/wav"}, // Parameters dropped
+		{"report.pdf", "application/octet-stream", []byte("%PDF-1.7"), PartDocument, "application/pdf"},
+		{"notes.md", "text/markdown", []byte("# notes"), PartDocument, "text/markdown"},
+	}
+	for _, tt := range tests {
+		part, err := NewPart(tt.name, tt.mediaType, tt.data)
+		if err != nil {
+			t.Errorf("NewPart(%s) failed: %v", tt.name, err)
+			continue
+		}
+		if part.Type != tt.wantType || part.MediaType != tt.wantMedia {
+			t.Errorf("NewPart(%s) = %s %s, want %s %s", tt.name, part.Type, part.MediaType, tt.wantType, tt.wantMedia)
+		}
+	}
+
+	if _, err := NewPart("app.exe", "application/x-msdownload", []byte("MZ")); err == nil {
+		t.Error("expected an unsupported type to be rejected")
+	}
+	if _, err := NewPart("big.png", "image/png", make([]byte, MaxPartSize+1)); err == nil {
+		t.Error("expected an oversized attachment to be rejected")
+	}
+
+	path := filepath.Join(t.TempDir(), "notes.txt")
+	os.WriteFile(path, []byte("remember the milk"), 0644)
+	part, err := ReadPart(path)
+	if err != nil || part.Name != "notes.txt" || part.Type != PartDocument {
+		t.Fatalf("ReadPart = %+v (%v)", part, err)
+	}
+	if got := part.AsText(); got != "<file name=\"notes.txt\">\nremember the milk\n</file>" {
+		t.Errorf("unexpected text rendering %q", got)
+	}
+}
+
+func TestMessagePartsStorage(t *testing.T) {
+	m, err := New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to create manager: %v", err)
+	}
+	defer m.Close()
+
+	image := ContentPart{Type: PartImage, MediaType: "image/png", Name: "a.png", Data: pngHeader}
+	sess, _ := m.GetOrCreate("main")
+	for _, msg := range []Message{
+		{Role: "user", Content: "what is this?", Parts: []ContentPart{image}},
+		{Role: "user", Content: "and this?", Parts: []ContentPart{image, {Type: PartText, Text: "same picture"}}},
+	} {
+		if err := m.AppendMessage(sess.ID, msg); err != nil {
+			t.Fatalf("AppendMessage failed: %v", err)
+		}
+	}
+
+	// The bytes are stored once and loaded back into every message
+	var blobs int
+	m.db.QueryRow("SELECT COUNT(*) FROM blobs").Scan(&blobs)
+	if blobs != 1 {
+		t.Errorf("expected 1 stored blob, got %d", blobs)
+	}
+	var raw string
+	m.db.QueryRow("SELECT parts FROM messages LIMIT 1").Scan(&raw)
+	if strings.Contains(raw, `"data"`) {
+		t.Errorf("expected the parts column to hold a reference, got %s", raw)
+	}
+
+	messages, err := m.GetMessages(sess.ID, 0)
+	if err != nil || len(messages) != 2 {
+		t.Fatalf("GetMessages = %d messages (%v)", len(messages), err)
+	}
+	got := messages[1].ContentParts()
+	if len(got) != 3 || got[0].Text != "and this?" || got[2].Text != "same picture" {
+		t.Fatalf("unexpected content parts %+v", got)
+	}
+	if !bytes.Equal(got[1].Data, pngHeader) || !strings.HasPrefix(got[1].BlobRef, "sha256:") || !messages[0].HasPart(PartImage) {
+		t.Errorf("unexpected image part %+v", got[1])
+	}
+
+	// Forks share blobs, which are only deleted with the last message using them
+	fork, err := m.Fork(sess.ID, messages[0].ID, "")
+	if err != nil {
+		t.Fatalf("Fork failed: %v", err)
+	}
+	if err := m.DeleteSession(sess.ID); err != nil {
+		t.Fatalf("DeleteSession failed: %v", err)
+	}
+	forked, _ := m.GetMessages(fork.ID, 0)
+	if len(forked) != 1 || !bytes.Equal(forked[0].Parts[0].Data, pngHeader) {
+		t.Fatalf("expected the fork to keep the image, got %+v", forked)
+	}
+	if err := m.Reset(fork.ID); err != nil {
+		t.Fatalf("Reset failed: %v", err)
+	}
+	m.db.QueryRow("SELECT COUNT(*) FROM blobs").Scan(&blobs)
+	if blobs != 0 {
+		t.Errorf("expected unreferenced blobs to be pruned, got %d", blobs)
+	}
+}
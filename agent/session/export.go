@@ -0,0 +1,350 @@
+package session
+
+import (
+	"bufio"
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"strings"
+	"time"
+)
+
+// Export formats supported by Export
+const (
+	FormatJSONL    = "jsonl"
+	FormatMarkdown = "markdown"
+	FormatHTML     = "html"
+)
+
+// exportVersion is written in the JSONL header so future formats can be detected
+const exportVersion = 1
+
+// ExportHeader is the first line of a JSONL export
+type ExportHeader struct {
+	Version int      `json:"version"`
+	Session *Session `json:"session"`
+}
+
+// Export writes a session's full history, including compacted messages, in the given format
+func (m *Manager) Export(w io.Writer, sessionID, format string) error {
+	sess, err := m.Get(sessionID)
+	if err != nil {
+		return err
+	}
+	messages, err := m.GetHistory(sessionID)
+	if err != nil {
+		return err
+	}
+
+	switch format {
+	case FormatJSONL, "":
+		return WriteJSONL(w, sess, messages)
+	case FormatMarkdown, "md":
+		return WriteMarkdown(w, sess, messages)
+	case FormatHTML:
+		return WriteHTML(w, sess, messages)
+	default:
+		return fmt.Errorf("unknown export format %q (use jsonl, markdown or html)", format)
+	}
+}
+
+// Import creates a session under sessionKey from exported messages, keeping their
+// timestamps, tool calls, tool results, usage and compaction state.
+// It fails if sessionKey already exists.
+func (m *Manager) Import(sessionKey string, messages []Message) (*Session, error) {
+	if _, err := m.getByKey(sessionKey); err == nil {
+		return nil, fmt.Errorf("session %q already exists", sessionKey)
+	} else if err != sql.ErrNoRows {
+		return nil, err
+	}
+
+	tx, err := m.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	sess := &Session{
+		ID:         generateID(),
+		SessionKey: sessionKey,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+	if len(messages) > 0 {
+		sess.CreatedAt = messages[0].CreatedAt
+	}
+	_, err = tx.Exec(
+		"INSERT INTO sessions (id, session_key, created_at, updated_at) VALUES (?, ?, ?, ?)",
+		sess.ID, sess.SessionKey, sess.CreatedAt, sess.UpdatedAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create session: %w", err)
+	}
+
+	for i, msg := range messages {
+		if msg.Role == "" {
+			return nil, fmt.Errorf("message %d has no role", i+1)
+		}
+		createdAt := msg.CreatedAt
+		if createdAt.IsZero() {
+			createdAt = now
+		}
+
+		var toolCalls, toolResults, model sql.NullString
+		if len(msg.ToolCalls) > 0 {
+			toolCalls = sql.NullString{String: string(msg.ToolCalls), Valid: true}
+		}
+		if len(msg.ToolResults) > 0 {
+			toolResults = sql.NullString{String: string(msg.ToolResults), Valid: true}
+		}
+		if msg.Model != "" {
+			model = sql.NullString{String: msg.Model, Valid: true}
+		}
+		parts, err := storeParts(tx, msg.Parts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to import message %d: %w", i+1, err)
+		}
+
+		_, err = tx.Exec(
+			`INSERT INTO messages (session_id, role, content, tool_calls, tool_results, created_at,
+				model, input_tokens, output_tokens, cost_usd, archived, parts, cache_read_tokens, cache_write_tokens)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			sess.ID, msg.Role, msg.Content, toolCalls, toolResults, createdAt,
+			model, msg.InputTokens, msg.OutputTokens, msg.CostUSD, msg.Archived, parts, msg.CacheReadTokens, msg.CacheWriteTokens,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to import message %d: %w", i+1, err)
+		}
+		if !msg.Archived {
+			sess.MessageCount++
+		}
+	}
+
+	return sess, tx.Commit()
+}
+
+// WriteJSONL writes a header line describing the session followed by one message per line
+func WriteJSONL(w io.Writer, sess *Session, messages []Message) error {
+	enc := json.NewEncoder(w)
+	if err := enc.Encode(ExportHeader{Version: exportVersion, Session: sess}); err != nil {
+		return err
+	}
+	for _, msg := range messages {
+		if err := enc.Encode(msg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ReadJSONL parses a JSONL export. The header line is optional, so a plain
+// message-per-line file is accepted too (the returned session is then nil).
+func ReadJSONL(r io.Reader) (*Session, []Message, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024) // Tool results can be large
+
+	var sess *Session
+	var messages []Message
+	line := 0
+	for scanner.Scan() {
+		line++
+		data := bytes.TrimSpace(scanner.Bytes())
+		if len(data) == 0 {
+			continue
+		}
+
+		if line == 1 {
+			var header ExportHeader
+			if err := json.Unmarshal(data, &header); err == nil && header.Session != nil {
+				if header.Version > exportVersion {
+					return nil, nil, fmt.Errorf("export version %d is newer than supported (%d)", header.Version, exportVersion)
+				}
+				sess = header.Session
+				continue
+			}
+		}
+
+		var msg Message
+		if err := json.Unmarshal(data, &msg); err != nil {
+			return nil, nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		if msg.Role == "" {
+			return nil, nil, fmt.Errorf("line %d: message has no role", line)
+		}
+		messages = append(messages, msg)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	return sess, messages, nil
+}
+
+// transcriptEntry is a message prepared for the Markdown and HTML renderers
+type transcriptEntry struct {
+	Role     string
+	Time     string
+	Model    string
+	Content  string
+	Archived bool
+	Parts    []string // Attachment descriptions
+	Calls    []transcriptCall
+	Results  []transcriptResult
+}
+
+type transcriptCall struct {
+	ID    string
+	Name  string
+	Input string
+}
+
+type transcriptResult struct {
+	ToolCallID string
+	Content    string
+	IsError    bool
+}
+
+// transcript converts messages into renderer entries, pretty-printing tool inputs
+func transcript(messages []Message) []transcriptEntry {
+	entries := make([]transcriptEntry, 0, len(messages))
+	for _, msg := range messages {
+		entry := transcriptEntry{
+			Role:     msg.Role,
+			Time:     msg.CreatedAt.Format("2006-01-02 15:04:05"),
+			Model:    msg.Model,
+			Content:  msg.Content,
+			Archived: msg.Archived,
+		}
+
+		for _, p := range msg.Parts {
+			if p.Type == PartText {
+				entry.Content = strings.TrimSpace(entry.Content + "\n\n" + p.Text)
+				continue
+			}
+			entry.Parts = append(entry.Parts, fmt.Sprintf("%s %s (%s, %d bytes)", p.Type, p.Name, p.MediaType, len(p.Data)))
+		}
+
+		var calls []ToolCall
+		json.Unmarshal(msg.ToolCalls, &calls)
+		for _, c := range calls {
+			input := string(c.Input)
+			var pretty bytes.Buffer
+			if json.Indent(&pretty, c.Input, "", "  ") == nil {
+				input = pretty.String()
+			}
+			entry.Calls = append(entry.Calls, transcriptCall{ID: c.ID, Name: c.Name, Input: input})
+		}
+
+		var results []ToolResult
+		json.Unmarshal(msg.ToolResults, &results)
+		for _, r := range results {
+			entry.Results = append(entry.Results, transcriptResult{ToolCallID: r.ToolCallID, Content: r.Content, IsError: r.IsError})
+		}
+
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// WriteMarkdown renders a session as a readable Markdown transcript
+func WriteMarkdown(w io.Writer, sess *Session, messages []Message) error {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "# Session: %s\n\n", sess.SessionKey)
+	fmt.Fprintf(&sb, "- Created: %s\n", sess.CreatedAt.Format(time.RFC3339))
+	fmt.Fprintf(&sb, "- Updated: %s\n", sess.UpdatedAt.Format(time.RFC3339))
+	fmt.Fprintf(&sb, "- Messages: %d\n", len(messages))
+
+	for _, e := range transcript(messages) {
+		heading := strings.ToUpper(e.Role[:1]) + e.Role[1:]
+		if e.Role == "system" {
+			heading = "Summary"
+		}
+		fmt.Fprintf(&sb, "\n## %s · %s", heading, e.Time)
+		if e.Model != "" {
+			fmt.Fprintf(&sb, " · %s", e.Model)
+		}
+		if e.Archived {
+			sb.WriteString(" · (compacted)")
+		}
+		sb.WriteString("\n\n")
+
+		if e.Content != "" {
+			sb.WriteString(e.Content)
+			sb.WriteString("\n\n")
+		}
+		for _, p := range e.Parts {
+			fmt.Fprintf(&sb, "**Attachment** %s\n\n", p)
+		}
+		for _, c := range e.Calls {
+			fmt.Fprintf(&sb, "**Tool call** `%s` (%s)\n\n", c.Name, c.ID)
+			writeFence(&sb, "json", c.Input)
+		}
+		for _, r := range e.Results {
+			label := "Tool result"
+			if r.IsError {
+				label = "Tool error"
+			}
+			fmt.Fprintf(&sb, "**%s** (%s)\n\n", label, r.ToolCallID)
+			writeFence(&sb, "", r.Content)
+		}
+	}
+
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// writeFence writes a fenced code block, lengthening the fence if the content contains one
+func writeFence(sb *strings.Builder, lang, content string) {
+	fence := "```"
+	for strings.Contains(content, fence) {
+		fence += "`"
+	}
+	fmt.Fprintf(sb, "%s%s\n%s\n%s\n\n", fence, lang, strings.TrimRight(content, "\n"), fence)
+}
+
+// htmlTemplate renders a standalone transcript page
+var htmlTemplate = template.Must(template.New("session").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Session: {{.Session.SessionKey}}</title>
+<style>
+body { font-family: -apple-system, BlinkMacSystemFont, "Segoe UI", sans-serif; max-width: 960px; margin: 2rem auto; padding: 0 1rem; color: #1f2328; }
+.meta { color: #656d76; font-size: 0.875rem; }
+.message { border: 1px solid #d0d7de; border-radius: 6px; margin: 1rem 0; padding: 0.75rem 1rem; }
+.message.user { background: #f6f8fa; }
+.message.system { background: #fff8c5; }
+.message.archived { opacity: 0.6; }
+.role { font-weight: 600; text-transform: capitalize; }
+.content { white-space: pre-wrap; }
+pre { background: #f6f8fa; border-radius: 6px; padding: 0.75rem; overflow-x: auto; }
+.error pre { background: #ffebe9; }
+</style>
+</head>
+<body>
+<h1>Session: {{.Session.SessionKey}}</h1>
+<p class="meta">Created {{.Session.CreatedAt.Format "2006-01-02 15:04:05"}} · Updated {{.Session.UpdatedAt.Format "2006-01-02 15:04:05"}} · {{len .Entries}} messages</p>
+{{range .Entries}}
+<div class="message {{.Role}}{{if .Archived}} archived{{end}}">
+<div class="meta"><span class="role">{{if eq .Role "system"}}summary{{else}}{{.Role}}{{end}}</span> · {{.Time}}{{if .Model}} · {{.Model}}{{end}}{{if .Archived}} · compacted{{end}}</div>
+{{if .Content}}<div class="content">{{.Content}}</div>{{end}}
+{{range .Parts}}<div class="meta"><strong>Attachment</strong> {{.}}</div>
+{{end}}{{range .Calls}}<div class="call"><strong>Tool call</strong> <code>{{.Name}}</code> <span class="meta">{{.ID}}</span><pre>{{.Input}}</pre></div>
+{{end}}{{range .Results}}<div class="result{{if .IsError}} error{{end}}"><strong>{{if .IsError}}Tool error{{else}}Tool result{{end}}</strong> <span class="meta">{{.ToolCallID}}</span><pre>{{.Content}}</pre></div>
+{{end}}</div>
+{{end}}
+</body>
+</html>
+`))
+
+// WriteHTML renders a session as a standalone HTML page
+func WriteHTML(w io.Writer, sess *Session, messages []Message) error {
+	return htmlTemplate.Execute(w, struct {
+		Session *Session
+		Entries []transcriptEntry
+	}{sess, transcript(messages)})
+}
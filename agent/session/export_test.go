@@ -0,0 +1,126 @@
+package session
+
+import (
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestExportImportRoundTrip(t *testing.T) {
+	manager, err := New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to create manager: %v", err)
+	}
+	defer manager.Close()
+
+	sess, _ := manager.GetOrCreate("incident")
+	call := assistant("checking", "c1")
+	call.Model = "anthropic/claude-sonnet"
+	call.InputTokens = 120
+	call.CostUSD = 0.01
+	for _, m := range []Message{
+		user("old"), assistant("old answer"),
+		user("why is the disk full?"), call, toolResults("/var/log 98%", "c1"), assistant("Logs filled it."),
+	} {
+		manager.AppendMessage(sess.ID, m)
+	}
+	active, _ := manager.GetMessages(sess.ID, 0)
+	manager.Compact(sess.ID, Message{Content: "summary"}, active[2].ID)
+
+	var buf bytes.Buffer
+	if err := manager.Export(&buf, sess.ID, FormatJSONL); err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+
+	header, messages, err := ReadJSONL(&buf)
+	if err != nil {
+		t.Fatalf("ReadJSONL failed: %v", err)
+	}
+	if header == nil || header.SessionKey != "incident" {
+		t.Fatalf("unexpected header: %+v", header)
+	}
+
+	imported, err := manager.Import("incident-copy", messages)
+	if err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+	if _, err := manager.Import("incident-copy", messages); err == nil {
+		t.Error("expected error importing over an existing session")
+	}
+
+	original, _ := manager.GetHistory(sess.ID)
+	copied, _ := manager.GetHistory(imported.ID)
+	if describe(copied) != describe(original) {
+		t.Fatalf("history mismatch:\n got  %s\n want %s", describe(copied), describe(original))
+	}
+	for i := range original {
+		o, c := original[i], copied[i]
+		if !c.CreatedAt.Equal(o.CreatedAt) || c.Archived != o.Archived || c.Model != o.Model ||
+			c.InputTokens != o.InputTokens || string(c.ToolResults) != string(o.ToolResults) {
+			t.Errorf("message %d differs:\n got  %+v\n want %+v", i, c, o)
+		}
+		var calls []ToolCall
+		if len(c.ToolCalls) > 0 && json.Unmarshal(c.ToolCalls, &calls) != nil {
+			t.Errorf("message %d has invalid tool calls: %s", i, c.ToolCalls)
+		}
+	}
+
+	// Only the summary and the kept turn are active in the copy
+	activeCopy, _ := manager.GetMessages(imported.ID, 0)
+	if got, want := describe(activeCopy), describe(active[2:]); got != "system:summary "+want {
+		t.Errorf("active copy = %s", got)
+	}
+}
+
+func TestReadJSONLWithoutHeader(t *testing.T) {
+	input := `{"role":"user","content":"hi","created_at":"2026-01-02T03:04:05Z"}
+
+{"role":"assistant","content":"hello","created_at":"2026-01-02T03:04:06Z"}
+`
+	sess, messages, err := ReadJSONL(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("ReadJSONL failed: %v", err)
+	}
+	if sess != nil {
+		t.Errorf("expected no header, got %+v", sess)
+	}
+	if got := describe(messages); got != "user:hi assistant:hello" {
+		t.Errorf("messages = %s", got)
+	}
+
+	if _, _, err := ReadJSONL(strings.NewReader(`{"content":"no role"}`)); err == nil {
+		t.Error("expected error for message without role")
+	}
+}
+
+func TestExportMarkdownAndHTML(t *testing.T) {
+	sess := &Session{SessionKey: "demo"}
+	messages := []Message{
+		user("run <ls>"),
+		assistant("", "c1"),
+		{Role: "tool", ToolResults: json.RawMessage(`[{"tool_call_id":"c1","content":"a.go","is_error":true}]`)},
+	}
+
+	var md bytes.Buffer
+	if err := WriteMarkdown(&md, sess, messages); err != nil {
+		t.Fatalf("WriteMarkdown failed: %v", err)
+	}
+	for _, want := range []string{"# Session: demo", "## User", "run <ls>", "**Tool call** `read` (c1)", "**Tool error** (c1)", "a.go"} {
+		if !strings.Contains(md.String(), want) {
+			t.Errorf("markdown missing %q:\n%s", want, md.String())
+		}
+	}
+
+	var page bytes.Buffer
+	if err := WriteHTML(&page, sess, messages); err != nil {
+		t.Fatalf("WriteHTML failed: %v", err)
+	}
+	if strings.Contains(page.String(), "run <ls>") || !strings.Contains(page.String(), "run &lt;ls&gt;") {
+		t.Error("HTML export should escape message content")
+	}
+	if !strings.Contains(page.String(), "Tool error") {
+		t.Error("HTML export missing tool error")
+	}
+}
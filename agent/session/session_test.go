@@ -1,8 +1,11 @@
 package session
 
 import (
+	"fmt"
 	"path/filepath"
+	"sync"
 	"testing"
+	"time"
 )
 
 func TestSessionManager(t *testing.T) {
@@ -124,3 +127,127 @@ func TestListSessions(t *testing.T) {
 		t.Errorf("expected 3 sessions, got %d", len(sessions))
 	}
 }
+
+func TestCompactArchivesMessages(t *testing.T) {
+	tmpDir := t.TempDir()
+	manager, err := New(filepath.Join(tmpDir, "test.db"))
+	if err != nil {
+		t.Fatalf("failed to create manager: %v", err)
+	}
+	defer manager.Close()
+
+	sess, _ := manager.GetOrCreate("compact")
+	for _, m := range []Message{
+		{Role: "user", Content: "first"},
+		{Role: "assistant", Content: "one"},
+		{Role: "user", Content: "second"},
+		{Role: "assistant", Content: "two"},
+		{Role: "user", Content: "third"},
+		{Role: "assistant", Content: "three"},
+	} {
+		if err := manager.AppendMessage(sess.ID, m); err != nil {
+			t.Fatalf("failed to append: %v", err)
+		}
+	}
+
+	messages, _ := manager.GetMessages(sess.ID, 0)
+	turns := Turns(messages)
+	if len(turns) != 3 {
+		t.Fatalf("expected 3 turns, got %d", len(turns))
+	}
+
+	// The conversation happened yesterday; it is compacted today
+	if _, err := manager.db.Exec("UPDATE messages SET created_at = ? WHERE session_id = ?", time.Now().Add(-24*time.Hour), sess.ID); err != nil {
+		t.Fatalf("failed to backdate messages: %v", err)
+	}
+
+	// Keep the last two turns
+	keepFrom := turns[1][0].ID
+	archived, err := manager.Compact(sess.ID, Message{Content: "summary", InputTokens: 100, CostUSD: 0.01}, keepFrom)
+	if err != nil {
+		t.Fatalf("Compact failed: %v", err)
+	}
+	if archived != 2 {
+		t.Errorf("expected 2 archived messages, got %d", archived)
+	}
+
+	active, _ := manager.GetMessages(sess.ID, 0)
+	var got []string
+	for _, m := range active {
+		got = append(got, m.Role+":"+m.Content)
+	}
+	want := []string{"system:summary", "user:second", "assistant:two", "user:third", "assistant:three"}
+	if len(got) != len(want) {
+		t.Fatalf("active messages = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("active messages = %v, want %v", got, want)
+		}
+	}
+
+	// The archive keeps the full history in order
+	history, _ := manager.GetHistory(sess.ID)
+	if len(history) != 7 {
+		t.Fatalf("expected 7 messages in history, got %d", len(history))
+	}
+	if !history[0].Archived || !history[1].Archived || history[2].Archived || history[2].Role != "system" {
+		t.Errorf("unexpected history: %+v", history[:3])
+	}
+
+	// Summarization spend counts toward today's usage even though the summary sorts earlier
+	today := time.Now().Truncate(24 * time.Hour)
+	report, _ := manager.Usage(UsageFilter{SessionKey: "compact", Since: today})
+	if report.Total.InputTokens != 100 {
+		t.Errorf("expected summary usage to be recorded today, got %d input tokens", report.Total.InputTokens)
+	}
+
+	// Compacting again archives the earlier summary too
+	keepFrom = active[3].ID
+	if archived, err := manager.Compact(sess.ID, Message{Content: "summary 2"}, keepFrom); err != nil || archived != 3 {
+		t.Fatalf("second Compact = %d, %v; want 3 archived", archived, err)
+	}
+	active, _ = manager.GetMessages(sess.ID, 0)
+	if len(active) != 3 || active[0].Content != "summary 2" || active[1].Content != "third" {
+		t.Errorf("unexpected active messages after second compaction: %+v", active)
+	}
+
+	list, _ := manager.ListSessions()
+	if list[0].MessageCount != 3 {
+		t.Errorf("expected 3 active messages, got %d", list[0].MessageCount)
+	}
+}
+
+func TestConcurrentWriters(t *testing.T) {
+	manager, err := New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to create manager: %v", err)
+	}
+	defer manager.Close()
+
+	// Each goroutine is a separate agent run writing to its own session
+	var wg sync.WaitGroup
+	errs := make(chan error, 8*20)
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			sess, err := manager.GetOrCreate(fmt.Sprintf("run-%d", i))
+			if err != nil {
+				errs <- err
+				return
+			}
+			for j := 0; j < 20; j++ {
+				if err := manager.AppendMessage(sess.ID, Message{Role: "user", Content: "hi"}); err != nil {
+					errs <- err
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Errorf("concurrent write failed: %v", err)
+	}
+}
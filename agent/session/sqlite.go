@@ -17,7 +17,19 @@ type Message struct {
 	Content     string          `json:"content,omitempty"`
 	ToolCalls   json.RawMessage `json:"tool_calls,omitempty"`
 	ToolResults json.RawMessage `json:"tool_results,omitempty"`
+	Parts       []ContentPart   `json:"parts,omitempty"` // Attachments (images, documents, audio) sent with Content
 	CreatedAt   time.Time       `json:"created_at"`
+
+	// Token accounting (assistant messages only)
+	Model            string  `json:"model,omitempty"`
+	InputTokens      int     `json:"input_tokens,omitempty"` // Includes cache reads and writes
+	OutputTokens     int     `json:"output_tokens,omitempty"`
+	CacheReadTokens  int     `json:"cache_read_tokens,omitempty"`
+	CacheWriteTokens int     `json:"cache_write_tokens,omitempty"`
+	CostUSD          float64 `json:"cost_usd,omitempty"`
+
+	// Archived messages were replaced by a compaction summary or are a failed call's partial reply; kept for history only
+	Archived bool `json:"archived,omitempty"`
 }
 
 // ToolCall represents a tool invocation
@@ -40,6 +52,12 @@ type Session struct {
 	SessionKey string    `json:"session_key"`
 	CreatedAt  time.Time `json:"created_at"`
 	UpdatedAt  time.Time `json:"updated_at"`
+
+	// Branching: set when the session was forked from another one
+	ParentID      string `json:"parent_id,omitempty"`
+	ForkMessageID int64  `json:"fork_message_id,omitempty"` // Last parent message copied into the fork
+
+	MessageCount int `json:"message_count,omitempty"` // Active messages (set by ListSessions)
 }
 
 // Manager handles session persistence
@@ -49,7 +67,8 @@ type Manager struct {
 
 // New creates a new session manager
 func New(dbPath string) (*Manager, error) {
-	db, err := sql.Open("sqlite", dbPath)
+	// Agent runs write concurrently; WAL and a busy timeout keep writers from failing with SQLITE_BUSY
+	db, err := sql.Open("sqlite", dbPath+"?_pragma=journal_mode(WAL)&_pragma=busy_timeout(5000)")
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
@@ -88,7 +107,69 @@ func (m *Manager) migrate() error {
 	CREATE INDEX IF NOT EXISTS idx_sessions_key ON sessions(session_key);
 	`
 
-	_, err := m.db.Exec(schema)
+	if _, err := m.db.Exec(schema); err != nil {
+		return err
+	}
+
+	// Columns added after the initial schema
+	columns := []struct{ table, name, def string }{
+		{"messages", "model", "TEXT"},
+		{"messages", "input_tokens", "INTEGER DEFAULT 0"},
+		{"messages", "output_tokens", "INTEGER DEFAULT 0"},
+		{"messages", "cost_usd", "REAL DEFAULT 0"},
+		{"messages", "archived", "INTEGER DEFAULT 0"},
+		{"messages", "deleted_at", "DATETIME"},
+		{"messages", "parts", "TEXT"},
+		{"messages", "cache_read_tokens", "INTEGER DEFAULT 0"},
+		{"messages", "cache_write_tokens", "INTEGER DEFAULT 0"},
+		{"messages", "seq", "INTEGER"},
+		{"sessions", "parent_id", "TEXT"},
+		{"sessions", "fork_message_id", "INTEGER"},
+	}
+	for _, c := range columns {
+		if err := m.addColumnIfMissing(c.table, c.name, c.def); err != nil {
+			return err
+		}
+	}
+
+	if err := m.migrateSubAgents(); err != nil {
+		return err
+	}
+	if err := m.migrateBlobs(); err != nil {
+		return err
+	}
+	return m.migrateCheckpoints()
+}
+
+// addColumnIfMissing adds a column to an existing table when it is not already present
+func (m *Manager) addColumnIfMissing(table, column, definition string) error {
+	rows, err := m.db.Query(fmt.Sprintf("PRAGMA table_info(%s)", table))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			cid        int
+			name, typ  string
+			notNull    int
+			dflt       sql.NullString
+			primaryKey int
+		)
+		if err := rows.Scan(&cid, &name, &typ, &notNull, &dflt, &primaryKey); err != nil {
+			return err
+		}
+		if name == column {
+			return nil
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	rows.Close()
+
+	_, err = m.db.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, definition))
 	return err
 }
 
@@ -129,35 +210,47 @@ func (m *Manager) GetOrCreate(sessionKey string) (*Session, error) {
 
 // getByKey retrieves a session by its key
 func (m *Manager) getByKey(sessionKey string) (*Session, error) {
-	var s Session
-	err := m.db.QueryRow(
-		"SELECT id, session_key, created_at, updated_at FROM sessions WHERE session_key = ?",
+	return scanSession(m.db.QueryRow(
+		"SELECT "+sessionColumns+" FROM sessions WHERE session_key = ?",
 		sessionKey,
-	).Scan(&s.ID, &s.SessionKey, &s.CreatedAt, &s.UpdatedAt)
-	if err != nil {
+	))
+}
+
+// sessionColumns is the column list read by scanSession
+const sessionColumns = "id, session_key, created_at, updated_at, parent_id, fork_message_id"
+
+// scanSession reads a session row selected with sessionColumns
+func scanSession(row interface{ Scan(...any) error }, extra ...any) (*Session, error) {
+	var s Session
+	var parentID sql.NullString
+	var forkMessageID sql.NullInt64
+	dest := append([]any{&s.ID, &s.SessionKey, &s.CreatedAt, &s.UpdatedAt, &parentID, &forkMessageID}, extra...)
+	if err := row.Scan(dest...); err != nil {
 		return nil, err
 	}
+	s.ParentID = parentID.String
+	s.ForkMessageID = forkMessageID.Int64
 	return &s, nil
 }
 
-// GetMessages retrieves messages for a session with an optional limit
+// GetMessages retrieves the active (non-archived) messages for a session with an optional limit
 func (m *Manager) GetMessages(sessionID string, limit int) ([]Message, error) {
 	query := `
-		SELECT id, session_id, role, content, tool_calls, tool_results, created_at
+		SELECT ` + messageColumns + `
 		FROM messages
-		WHERE session_id = ?
-		ORDER BY created_at ASC
+		WHERE session_id = ? AND archived = 0 AND deleted_at IS NULL
+		ORDER BY ` + messageOrder + ` ASC, id ASC
 	`
 	if limit > 0 {
 		// Get the last N messages
 		query = `
-			SELECT id, session_id, role, content, tool_calls, tool_results, created_at
+			SELECT ` + messageColumns + `
 			FROM (
 				SELECT * FROM messages
-				WHERE session_id = ?
-				ORDER BY created_at DESC
+				WHERE session_id = ? AND archived = 0 AND deleted_at IS NULL
+				ORDER BY ` + messageOrder + ` DESC, id DESC
 				LIMIT ?
-			) ORDER BY created_at ASC
+			) ORDER BY ` + messageOrder + ` ASC, id ASC
 		`
 	}
 
@@ -173,23 +266,83 @@ func (m *Manager) GetMessages(sessionID string, limit int) ([]Message, error) {
 	}
 	defer rows.Close()
 
+	messages, err := scanMessages(rows)
+	if err != nil {
+		return nil, err
+	}
+	return m.loadBlobs(messages)
+}
+
+// GetHistory retrieves every message for a session, including those archived by compaction
+// (messages removed by Rewind are excluded)
+func (m *Manager) GetHistory(sessionID string) ([]Message, error) {
+	rows, err := m.db.Query(`
+		SELECT `+messageColumns+`
+		FROM messages
+		WHERE session_id = ? AND deleted_at IS NULL
+		ORDER BY `+messageOrder+` ASC, id ASC
+	`, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	messages, err := scanMessages(rows)
+	if err != nil {
+		return nil, err
+	}
+	return m.loadBlobs(messages)
+}
+
+// messageOrder is the position messages sort by (ties break on id). Messages sort in insertion
+// order except compaction summaries, which take the position of the messages they replace.
+const messageOrder = "COALESCE(seq, id)"
+
+// beforeMessage matches messages ordered before a message ID (bind the ID three times)
+const beforeMessage = `(COALESCE(seq, id) < (SELECT COALESCE(seq, id) FROM messages WHERE id = ?)
+	OR (COALESCE(seq, id) = (SELECT COALESCE(seq, id) FROM messages WHERE id = ?) AND id < ?))`
+
+// messageColumns is the column list read by scanMessages
+const messageColumns = `id, session_id, role, content, tool_calls, tool_results, created_at,
+			model, input_tokens, output_tokens, cost_usd, archived, parts,
+			cache_read_tokens, cache_write_tokens`
+
+// scanMessages reads message rows selected with messageColumns
+func scanMessages(rows *sql.Rows) ([]Message, error) {
 	var messages []Message
 	for rows.Next() {
 		var msg Message
-		var toolCalls, toolResults sql.NullString
+		var content, toolCalls, toolResults, model, parts sql.NullString
+		var inputTokens, outputTokens, archived, cacheRead, cacheWrite sql.NullInt64
+		var cost sql.NullFloat64
 		err := rows.Scan(
-			&msg.ID, &msg.SessionID, &msg.Role, &msg.Content,
+			&msg.ID, &msg.SessionID, &msg.Role, &content,
 			&toolCalls, &toolResults, &msg.CreatedAt,
+			&model, &inputTokens, &outputTokens, &cost, &archived, &parts,
+			&cacheRead, &cacheWrite,
 		)
 		if err != nil {
 			return nil, err
 		}
+		msg.Content = content.String
+		msg.Model = model.String
+		msg.InputTokens = int(inputTokens.Int64)
+		msg.OutputTokens = int(outputTokens.Int64)
+		msg.CacheReadTokens = int(cacheRead.Int64)
+		msg.CacheWriteTokens = int(cacheWrite.Int64)
+		msg.CostUSD = cost.Float64
+		msg.Archived = archived.Int64 != 0
 		if toolCalls.Valid {
 			msg.ToolCalls = json.RawMessage(toolCalls.String)
 		}
 		if toolResults.Valid {
 			msg.ToolResults = json.RawMessage(toolResults.String)
 		}
+		if parts.Valid {
+			if err := json.Unmarshal([]byte(parts.String), &msg.Parts); err != nil {
+				return nil, fmt.Errorf("message %d has invalid parts: %w", msg.ID, err)
+			}
+		}
 		messages = append(messages, msg)
 	}
 
@@ -198,6 +351,12 @@ func (m *Manager) GetMessages(sessionID string, limit int) ([]Message, error) {
 
 // AppendMessage adds a message to a session
 func (m *Manager) AppendMessage(sessionID string, msg Message) error {
+	_, err := m.InsertMessage(sessionID, msg)
+	return err
+}
+
+// InsertMessage adds a message to a session and returns its ID
+func (m *Manager) InsertMessage(sessionID string, msg Message) (int64, error) {
 	var toolCalls, toolResults sql.NullString
 	if len(msg.ToolCalls) > 0 {
 		toolCalls = sql.NullString{String: string(msg.ToolCalls), Valid: true}
@@ -206,12 +365,29 @@ func (m *Manager) AppendMessage(sessionID string, msg Message) error {
 		toolResults = sql.NullString{String: string(msg.ToolResults), Valid: true}
 	}
 
-	_, err := m.db.Exec(
-		"INSERT INTO messages (session_id, role, content, tool_calls, tool_results, created_at) VALUES (?, ?, ?, ?, ?, ?)",
+	var model sql.NullString
+	if msg.Model != "" {
+		model = sql.NullString{String: msg.Model, Valid: true}
+	}
+
+	parts, err := storeParts(m.db, msg.Parts)
+	if err != nil {
+		return 0, err
+	}
+
+	res, err := m.db.Exec(
+		`INSERT INTO messages (session_id, role, content, tool_calls, tool_results, created_at,
+			model, input_tokens, output_tokens, cost_usd, archived, parts, cache_read_tokens, cache_write_tokens)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
 		sessionID, msg.Role, msg.Content, toolCalls, toolResults, time.Now(),
+		model, msg.InputTokens, msg.OutputTokens, msg.CostUSD, msg.Archived, parts, msg.CacheReadTokens, msg.CacheWriteTokens,
 	)
 	if err != nil {
-		return fmt.Errorf("failed to append message: %w", err)
+		return 0, fmt.Errorf("failed to append message: %w", err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
 	}
 
 	// Update session timestamp
@@ -219,68 +395,80 @@ func (m *Manager) AppendMessage(sessionID string, msg Message) error {
 		"UPDATE sessions SET updated_at = ? WHERE id = ?",
 		time.Now(), sessionID,
 	)
-	return err
+	return id, err
 }
 
-// Compact summarizes old messages to reduce context size
-// This should be called when context window is getting full
-func (m *Manager) Compact(sessionID string, summaryContent string) error {
+// Compact archives the active messages older than keepFromID and inserts summary in their place.
+// Archived messages are hidden from GetMessages but remain available through GetHistory.
+// Returns the number of messages archived.
+func (m *Manager) Compact(sessionID string, summary Message, keepFromID int64) (int, error) {
 	tx, err := m.db.Begin()
 	if err != nil {
-		return err
+		return 0, err
 	}
 	defer tx.Rollback()
 
-	// Get message count
-	var count int
-	err = tx.QueryRow("SELECT COUNT(*) FROM messages WHERE session_id = ?", sessionID).Scan(&count)
-	if err != nil {
-		return err
+	// The summary takes the position of the newest message it replaces, so it sorts after
+	// everything archived (ties break on id) and before the kept messages
+	var summarySeq int64
+	err = tx.QueryRow(`
+		SELECT `+messageOrder+` FROM messages
+		WHERE session_id = ? AND archived = 0 AND deleted_at IS NULL AND (? = 0 OR `+beforeMessage+`)
+		ORDER BY `+messageOrder+` DESC, id DESC
+		LIMIT 1
+	`, sessionID, keepFromID, keepFromID, keepFromID, keepFromID).Scan(&summarySeq)
+	if err == sql.ErrNoRows {
+		return 0, nil // Nothing to compact
 	}
-
-	// Keep the last 10 messages, summarize the rest
-	keepCount := 10
-	if count <= keepCount {
-		return nil // Nothing to compact
+	if err != nil {
+		return 0, err
 	}
 
-	// Delete old messages (keeping the newest ones)
-	_, err = tx.Exec(`
-		DELETE FROM messages
-		WHERE session_id = ? AND id NOT IN (
-			SELECT id FROM messages
-			WHERE session_id = ?
-			ORDER BY created_at DESC
-			LIMIT ?
-		)
-	`, sessionID, sessionID, keepCount)
+	res, err := tx.Exec(
+		"UPDATE messages SET archived = 1 WHERE session_id = ? AND archived = 0 AND deleted_at IS NULL AND (? = 0 OR "+beforeMessage+")",
+		sessionID, keepFromID, keepFromID, keepFromID, keepFromID,
+	)
 	if err != nil {
-		return err
+		return 0, err
 	}
+	archived, _ := res.RowsAffected()
 
-	// Insert summary as a system message at the beginning
+	var model sql.NullString
+	if summary.Model != "" {
+		model = sql.NullString{String: summary.Model, Valid: true}
+	}
+	// Stamped now so the summarization spend counts toward today's usage
 	_, err = tx.Exec(
-		"INSERT INTO messages (session_id, role, content, created_at) VALUES (?, 'system', ?, ?)",
-		sessionID, summaryContent, time.Now().Add(-time.Hour), // Slightly in the past
+		`INSERT INTO messages (session_id, role, content, created_at, seq,
+			model, input_tokens, output_tokens, cost_usd, cache_read_tokens, cache_write_tokens)
+		VALUES (?, 'system', ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		sessionID, summary.Content, time.Now(), summarySeq,
+		model, summary.InputTokens, summary.OutputTokens, summary.CostUSD, summary.CacheReadTokens, summary.CacheWriteTokens,
 	)
 	if err != nil {
-		return err
+		return 0, err
 	}
 
-	return tx.Commit()
+	return int(archived), tx.Commit()
 }
 
 // Reset clears all messages from a session
 func (m *Manager) Reset(sessionID string) error {
-	_, err := m.db.Exec("DELETE FROM messages WHERE session_id = ?", sessionID)
-	return err
+	if _, err := m.db.Exec("DELETE FROM messages WHERE session_id = ?", sessionID); err != nil {
+		return err
+	}
+	return pruneBlobs(m.db)
 }
 
 // ListSessions returns all sessions
 func (m *Manager) ListSessions() ([]Session, error) {
-	rows, err := m.db.Query(
-		"SELECT id, session_key, created_at, updated_at FROM sessions ORDER BY updated_at DESC",
-	)
+	rows, err := m.db.Query(`
+		SELECT ` + sessionColumns + `,
+			(SELECT COUNT(*) FROM messages m
+				WHERE m.session_id = sessions.id AND m.archived = 0 AND m.deleted_at IS NULL)
+		FROM sessions
+		ORDER BY updated_at DESC
+	`)
 	if err != nil {
 		return nil, err
 	}
@@ -288,11 +476,13 @@ func (m *Manager) ListSessions() ([]Session, error) {
 
 	var sessions []Session
 	for rows.Next() {
-		var s Session
-		if err := rows.Scan(&s.ID, &s.SessionKey, &s.CreatedAt, &s.UpdatedAt); err != nil {
+		var count int
+		s, err := scanSession(rows, &count)
+		if err != nil {
 			return nil, err
 		}
-		sessions = append(sessions, s)
+		s.MessageCount = count
+		sessions = append(sessions, *s)
 	}
 	return sessions, rows.Err()
 }
@@ -310,11 +500,20 @@ func (m *Manager) DeleteSession(sessionID string) error {
 		return err
 	}
 
+	_, err = tx.Exec("DELETE FROM file_checkpoints WHERE session_id = ?", sessionID)
+	if err != nil {
+		return err
+	}
+
 	_, err = tx.Exec("DELETE FROM sessions WHERE id = ?", sessionID)
 	if err != nil {
 		return err
 	}
 
+	if err := pruneBlobs(tx); err != nil {
+		return err
+	}
+
 	return tx.Commit()
 }
 
@@ -0,0 +1,139 @@
+package session
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrSubAgentNotFound is returned when a sub-agent run ID does not exist
+var ErrSubAgentNotFound = errors.New("sub-agent not found")
+
+// SubAgentRun is a persisted orchestrator sub-agent run
+type SubAgentRun struct {
+	ID               string    `json:"id"`
+	ParentSessionKey string    `json:"parent_session_key,omitempty"` // Session whose agent spawned it
+	SessionKey       string    `json:"session_key"`                  // The sub-agent's own session
+	Type             string    `json:"type"`
+	Description      string    `json:"description"`
+	Task             string    `json:"task"`
+	Status           string    `json:"status"` // pending, running, completed, failed, cancelled
+	Result           string    `json:"result,omitempty"`
+	Error            string    `json:"error,omitempty"`
+	PID              int       `json:"pid,omitempty"` // Agent process running it, to detect runs orphaned by a restart
+	StartedAt        time.Time `json:"started_at"`
+	CompletedAt      time.Time `json:"completed_at,omitempty"` // Zero while running
+}
+
+// migrateSubAgents creates the sub-agent runs table
+func (m *Manager) migrateSubAgents() error {
+	_, err := m.db.Exec(`
+	CREATE TABLE IF NOT EXISTS subagent_runs (
+		id TEXT PRIMARY KEY,
+		parent_session_key TEXT,
+		session_key TEXT NOT NULL,
+		type TEXT,
+		description TEXT,
+		task TEXT,
+		status TEXT NOT NULL,
+		result TEXT,
+		error TEXT,
+		pid INTEGER,
+		started_at INTEGER NOT NULL,
+		completed_at INTEGER
+	);
+	CREATE INDEX IF NOT EXISTS idx_subagent_runs_parent ON subagent_runs(parent_session_key, started_at);
+	`)
+	return err
+}
+
+// SaveSubAgentRun inserts or updates a sub-agent run
+func (m *Manager) SaveSubAgentRun(run *SubAgentRun) error {
+	var completedAt sql.NullInt64
+	if !run.CompletedAt.IsZero() {
+		completedAt = sql.NullInt64{Int64: run.CompletedAt.UnixMilli(), Valid: true}
+	}
+
+	_, err := m.db.Exec(`
+		INSERT INTO subagent_runs (id, parent_session_key, session_key, type, description, task,
+			status, result, error, pid, started_at, completed_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(id) DO UPDATE SET
+			status = excluded.status,
+			result = excluded.result,
+			error = excluded.error,
+			pid = excluded.pid,
+			completed_at = excluded.completed_at`,
+		run.ID, run.ParentSessionKey, run.SessionKey, run.Type, run.Description, run.Task,
+		run.Status, run.Result, run.Error, run.PID, run.StartedAt.UnixMilli(), completedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save sub-agent run: %w", err)
+	}
+	return nil
+}
+
+// GetSubAgentRun retrieves a sub-agent run by ID
+func (m *Manager) GetSubAgentRun(id string) (*SubAgentRun, error) {
+	run, err := scanSubAgentRun(m.db.QueryRow("SELECT "+subAgentColumns+" FROM subagent_runs WHERE id = ?", id))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("%w: %s", ErrSubAgentNotFound, id)
+	}
+	return run, err
+}
+
+// ListSubAgentRuns returns the sub-agent runs spawned from a session ("" = all), newest first
+func (m *Manager) ListSubAgentRuns(parentSessionKey string) ([]SubAgentRun, error) {
+	query := "SELECT " + subAgentColumns + " FROM subagent_runs"
+	var args []any
+	if parentSessionKey != "" {
+		query += " WHERE parent_session_key = ?"
+		args = append(args, parentSessionKey)
+	}
+	query += " ORDER BY started_at DESC, id DESC"
+
+	rows, err := m.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var runs []SubAgentRun
+	for rows.Next() {
+		run, err := scanSubAgentRun(rows)
+		if err != nil {
+			return nil, err
+		}
+		runs = append(runs, *run)
+	}
+	return runs, rows.Err()
+}
+
+// subAgentColumns is the column list read by scanSubAgentRun
+const subAgentColumns = `id, parent_session_key, session_key, type, description, task,
+	status, result, error, pid, started_at, completed_at`
+
+// scanSubAgentRun reads a row selected with subAgentColumns
+func scanSubAgentRun(row interface{ Scan(...any) error }) (*SubAgentRun, error) {
+	var run SubAgentRun
+	var parentKey, agentType, description, task, result, errText sql.NullString
+	var pid, completedAt sql.NullInt64
+	var startedAt int64
+	if err := row.Scan(&run.ID, &parentKey, &run.SessionKey, &agentType, &description, &task,
+		&run.Status, &result, &errText, &pid, &startedAt, &completedAt); err != nil {
+		return nil, err
+	}
+	run.ParentSessionKey = parentKey.String
+	run.Type = agentType.String
+	run.Description = description.String
+	run.Task = task.String
+	run.Result = result.String
+	run.Error = errText.String
+	run.PID = int(pid.Int64)
+	run.StartedAt = time.UnixMilli(startedAt)
+	if completedAt.Valid {
+		run.CompletedAt = time.UnixMilli(completedAt.Int64)
+	}
+	return &run, nil
+}
@@ -0,0 +1,59 @@
+package session
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSubAgentRuns(t *testing.T) {
+	m, err := New(t.TempDir() + "/test.db")
+	if err != nil {
+		t.Fatalf("failed to create manager: %v", err)
+	}
+	defer m.Close()
+
+	started := time.Now().Add(-time.Minute).Truncate(time.Millisecond)
+	first := &SubAgentRun{ID: "a1", ParentSessionKey: "parent", SessionKey: "subagent-a1", Type: "explore",
+		Description: "find it", Task: "look", Status: "running", PID: 42, StartedAt: started}
+	second := &SubAgentRun{ID: "a2", ParentSessionKey: "other", SessionKey: "subagent-a2",
+		Status: "pending", StartedAt: started.Add(time.Second)}
+	for _, run := range []*SubAgentRun{first, second} {
+		if err := m.SaveSubAgentRun(run); err != nil {
+			t.Fatalf("SaveSubAgentRun failed: %v", err)
+		}
+	}
+
+	first.Status = "completed"
+	first.Result = "found"
+	first.CompletedAt = started.Add(30 * time.Second)
+	if err := m.SaveSubAgentRun(first); err != nil {
+		t.Fatalf("SaveSubAgentRun update failed: %v", err)
+	}
+
+	got, err := m.GetSubAgentRun("a1")
+	if err != nil {
+		t.Fatalf("GetSubAgentRun failed: %v", err)
+	}
+	if got.Status != "completed" || got.Result != "found" || got.Type != "explore" || got.PID != 42 {
+		t.Errorf("unexpected run: %+v", got)
+	}
+	if !got.StartedAt.Equal(started) || !got.CompletedAt.Equal(first.CompletedAt) {
+		t.Errorf("unexpected times: %v, %v", got.StartedAt, got.CompletedAt)
+	}
+
+	runs, err := m.ListSubAgentRuns("")
+	if err != nil {
+		t.Fatalf("ListSubAgentRuns failed: %v", err)
+	}
+	if len(runs) != 2 || runs[0].ID != "a2" || !runs[0].CompletedAt.IsZero() {
+		t.Errorf("expected newest run first, got %+v", runs)
+	}
+	if runs, _ := m.ListSubAgentRuns("parent"); len(runs) != 1 || runs[0].ID != "a1" {
+		t.Errorf("expected runs filtered by parent session, got %+v", runs)
+	}
+
+	if _, err := m.GetSubAgentRun("missing"); !errors.Is(err, ErrSubAgentNotFound) {
+		t.Errorf("expected ErrSubAgentNotFound, got %v", err)
+	}
+}
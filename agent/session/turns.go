@@ -0,0 +1,46 @@
+package session
+
+// Turns groups messages into conversation turns.
+// A turn starts at a user message and includes every assistant, tool and system message up to the next one;
+// messages before the first user message form their own leading turn.
+func Turns(messages []Message) [][]Message {
+	var turns [][]Message
+	for i, msg := range messages {
+		if i == 0 || msg.Role == "user" {
+			turns = append(turns, nil)
+		}
+		turns[len(turns)-1] = append(turns[len(turns)-1], msg)
+	}
+	return turns
+}
+
+// imageTokens is roughly what providers charge for an image of about a megapixel
+const imageTokens = 1600
+
+// EstimateTokens approximates the prompt tokens used by a message (about 4 characters per token)
+func (m Message) EstimateTokens() int {
+	chars := len(m.Content) + len(m.ToolCalls) + len(m.ToolResults)
+	tokens := 0
+	for _, p := range m.Parts {
+		switch {
+		case p.Type == PartText:
+			chars += len(p.Text)
+		case p.Type == PartImage:
+			tokens += imageTokens
+		case IsTextMedia(p.MediaType):
+			chars += len(p.Data)
+		default:
+			chars += len(p.Data) / 10 // Binary documents and audio compress well into tokens
+		}
+	}
+	return tokens + chars/4 + 4 // Per-message framing overhead
+}
+
+// EstimateTokens approximates the prompt tokens used by a list of messages
+func EstimateTokens(messages []Message) int {
+	total := 0
+	for _, m := range messages {
+		total += m.EstimateTokens()
+	}
+	return total
+}
@@ -0,0 +1,170 @@
+package session
+
+import (
+	"database/sql"
+	"sort"
+	"time"
+)
+
+// UsageFilter narrows a usage report
+type UsageFilter struct {
+	SessionKey string    // Only include this session (empty = all)
+	Since      time.Time // Inclusive lower bound (zero = no bound)
+	Until      time.Time // Exclusive upper bound (zero = no bound)
+}
+
+// UsageTotals aggregates token counts and cost for a group of messages
+type UsageTotals struct {
+	Key             string  `json:"key"`
+	Calls           int     `json:"calls"` // Provider calls (assistant messages with usage)
+	InputTokens     int64   `json:"input_tokens"`
+	OutputTokens    int64   `json:"output_tokens"`
+	CacheReadTokens int64   `json:"cache_read_tokens"` // Part of InputTokens served from the prompt cache
+	CostUSD         float64 `json:"cost_usd"`
+}
+
+// TotalTokens returns input plus output tokens
+func (t *UsageTotals) TotalTokens() int64 {
+	return t.InputTokens + t.OutputTokens
+}
+
+// UsageReport summarizes token usage grouped by session, day and model
+type UsageReport struct {
+	Total     UsageTotals   `json:"total"`
+	BySession []UsageTotals `json:"by_session"`
+	ByDay     []UsageTotals `json:"by_day"`
+	ByModel   []UsageTotals `json:"by_model"`
+}
+
+// Usage returns token and cost totals for messages matching the filter
+func (m *Manager) Usage(filter UsageFilter) (*UsageReport, error) {
+	query := `
+		SELECT s.session_key, m.created_at, m.model, m.input_tokens, m.output_tokens, m.cache_read_tokens, m.cost_usd
+		FROM messages m
+		JOIN sessions s ON s.id = m.session_id
+		WHERE (m.input_tokens > 0 OR m.output_tokens > 0)
+	`
+	var args []any
+	if filter.SessionKey != "" {
+		query += " AND s.session_key = ?"
+		args = append(args, filter.SessionKey)
+	}
+
+	rows, err := m.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	report := &UsageReport{Total: UsageTotals{Key: "total"}}
+	bySession := make(map[string]*UsageTotals)
+	byDay := make(map[string]*UsageTotals)
+	byModel := make(map[string]*UsageTotals)
+
+	for rows.Next() {
+		var (
+			sessionKey   string
+			createdAt    time.Time
+			model        sql.NullString
+			inputTokens  sql.NullInt64
+			outputTokens sql.NullInt64
+			cacheRead    sql.NullInt64
+			cost         sql.NullFloat64
+		)
+		if err := rows.Scan(&sessionKey, &createdAt, &model, &inputTokens, &outputTokens, &cacheRead, &cost); err != nil {
+			return nil, err
+		}
+
+		// Time filtering is done here rather than in SQL since timestamps are stored as driver-formatted text
+		if !filter.Since.IsZero() && createdAt.Before(filter.Since) {
+			continue
+		}
+		if !filter.Until.IsZero() && !createdAt.Before(filter.Until) {
+			continue
+		}
+
+		modelKey := model.String
+		if modelKey == "" {
+			modelKey = "unknown"
+		}
+		day := createdAt.Local().Format("2006-01-02")
+
+		for _, t := range []*UsageTotals{
+			&report.Total,
+			usageBucket(bySession, sessionKey),
+			usageBucket(byDay, day),
+			usageBucket(byModel, modelKey),
+		} {
+			t.Calls++
+			t.InputTokens += inputTokens.Int64
+			t.OutputTokens += outputTokens.Int64
+			t.CacheReadTokens += cacheRead.Int64
+			t.CostUSD += cost.Float64
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	report.BySession = sortedUsage(bySession, false)
+	report.ByDay = sortedUsage(byDay, true)
+	report.ByModel = sortedUsage(byModel, false)
+	return report, nil
+}
+
+// Spend returns token and cost totals for one session (empty = all) on one local day
+// (zero = all time). It sums in SQL, so it is cheap enough to check before every provider call.
+func (m *Manager) Spend(sessionKey string, day time.Time) (UsageTotals, error) {
+	query := `
+		SELECT COUNT(*), COALESCE(SUM(m.input_tokens), 0), COALESCE(SUM(m.output_tokens), 0),
+			COALESCE(SUM(m.cache_read_tokens), 0), COALESCE(SUM(m.cost_usd), 0)
+		FROM messages m
+		JOIN sessions s ON s.id = m.session_id
+		WHERE (m.input_tokens > 0 OR m.output_tokens > 0)
+	`
+	var args []any
+	if sessionKey != "" {
+		query += " AND s.session_key = ?"
+		args = append(args, sessionKey)
+	}
+	if !day.IsZero() {
+		// Stored timestamps are driver-formatted text that starts with the writer's local date
+		query += " AND substr(m.created_at, 1, 10) = ?"
+		args = append(args, day.Local().Format("2006-01-02"))
+	}
+
+	t := UsageTotals{Key: "total"}
+	err := m.db.QueryRow(query, args...).Scan(&t.Calls, &t.InputTokens, &t.OutputTokens, &t.CacheReadTokens, &t.CostUSD)
+	return t, err
+}
+
+// usageBucket returns the totals entry for key, creating it if needed
+func usageBucket(buckets map[string]*UsageTotals, key string) *UsageTotals {
+	t, ok := buckets[key]
+	if !ok {
+		t = &UsageTotals{Key: key}
+		buckets[key] = t
+	}
+	return t
+}
+
+// sortedUsage flattens buckets, ordered by key (byKey) or by descending cost then tokens
+func sortedUsage(buckets map[string]*UsageTotals, byKey bool) []UsageTotals {
+	result := make([]UsageTotals, 0, len(buckets))
+	for _, t := range buckets {
+		result = append(result, *t)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if byKey {
+			return result[i].Key < result[j].Key
+		}
+		if result[i].CostUSD != result[j].CostUSD {
+			return result[i].CostUSD > result[j].CostUSD
+		}
+		if result[i].TotalTokens() != result[j].TotalTokens() {
+			return result[i].TotalTokens() > result[j].TotalTokens()
+		}
+		return result[i].Key < result[j].Key
+	})
+	return result
+}
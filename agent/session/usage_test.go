@@ -0,0 +1,138 @@
+package session
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+func TestMessageUsagePersisted(t *testing.T) {
+	manager, err := New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to create manager: %v", err)
+	}
+	defer manager.Close()
+
+	sess, _ := manager.GetOrCreate("usage")
+	err = manager.AppendMessage(sess.ID, Message{
+		Role:         "assistant",
+		Content:      "hi",
+		Model:        "anthropic/claude-test",
+		InputTokens:  100,
+		OutputTokens: 20,
+		CostUSD:      0.0006,
+	})
+	if err != nil {
+		t.Fatalf("failed to append message: %v", err)
+	}
+
+	messages, err := manager.GetMessages(sess.ID, 10)
+	if err != nil {
+		t.Fatalf("failed to get messages: %v", err)
+	}
+	m := messages[0]
+	if m.Model != "anthropic/claude-test" || m.InputTokens != 100 || m.OutputTokens != 20 || m.CostUSD != 0.0006 {
+		t.Errorf("usage not round-tripped: %+v", m)
+	}
+}
+
+func TestUsageReport(t *testing.T) {
+	manager, err := New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to create manager: %v", err)
+	}
+	defer manager.Close()
+
+	a, _ := manager.GetOrCreate("a")
+	b, _ := manager.GetOrCreate("cron-nightly")
+
+	manager.AppendMessage(a.ID, Message{Role: "user", Content: "hello"})
+	manager.AppendMessage(a.ID, Message{Role: "assistant", Model: "m1", InputTokens: 100, OutputTokens: 10, CacheReadTokens: 80, CacheWriteTokens: 15, CostUSD: 0.5})
+	manager.AppendMessage(a.ID, Message{Role: "assistant", Model: "m2", InputTokens: 50, OutputTokens: 5, CostUSD: 0.1})
+	manager.AppendMessage(b.ID, Message{Role: "assistant", Model: "m1", InputTokens: 10, OutputTokens: 1, CostUSD: 1})
+
+	report, err := manager.Usage(UsageFilter{})
+	if err != nil {
+		t.Fatalf("failed to get usage: %v", err)
+	}
+
+	if report.Total.Calls != 3 || report.Total.InputTokens != 160 || report.Total.OutputTokens != 16 || report.Total.CacheReadTokens != 80 {
+		t.Errorf("unexpected total: %+v", report.Total)
+	}
+	if len(report.ByModel) != 2 || report.ByModel[0].Key != "m1" || report.ByModel[0].Calls != 2 {
+		t.Errorf("unexpected by-model: %+v", report.ByModel)
+	}
+	if len(report.BySession) != 2 || report.BySession[0].Key != "cron-nightly" {
+		t.Errorf("expected most expensive session first, got %+v", report.BySession)
+	}
+	if len(report.ByDay) != 1 || report.ByDay[0].Key != time.Now().Format("2006-01-02") {
+		t.Errorf("unexpected by-day: %+v", report.ByDay)
+	}
+
+	messages, _ := manager.GetMessages(a.ID, 0)
+	if got := messages[1]; got.CacheReadTokens != 80 || got.CacheWriteTokens != 15 {
+		t.Errorf("expected cache token counts to be stored, got %+v", got)
+	}
+
+	// Session filter
+	report, _ = manager.Usage(UsageFilter{SessionKey: "a"})
+	if report.Total.Calls != 2 {
+		t.Errorf("expected 2 calls for session a, got %d", report.Total.Calls)
+	}
+
+	// Spend sums the same totals in SQL
+	spend, err := manager.Spend("a", time.Now())
+	if err != nil {
+		t.Fatalf("failed to get spend: %v", err)
+	}
+	if spend.Calls != 2 || spend.InputTokens != 150 || spend.OutputTokens != 15 || spend.CostUSD != 0.6 {
+		t.Errorf("unexpected session spend today: %+v", spend)
+	}
+	if spend, _ := manager.Spend("", time.Time{}); spend.Calls != 3 || spend.CostUSD != 1.6 {
+		t.Errorf("unexpected total spend: %+v", spend)
+	}
+	if spend, _ := manager.Spend("", time.Now().Add(24*time.Hour)); spend.Calls != 0 {
+		t.Errorf("expected no spend tomorrow, got %+v", spend)
+	}
+
+	// Time filter excludes everything before tomorrow
+	report, _ = manager.Usage(UsageFilter{Since: time.Now().Add(24 * time.Hour)})
+	if report.Total.Calls != 0 {
+		t.Errorf("expected no calls after since filter, got %d", report.Total.Calls)
+	}
+}
+
+func TestMigrateAddsUsageColumnsToExistingDB(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "old.db")
+
+	// Create a database with the original messages schema
+	db, err := sql.Open("sqlite", dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = db.Exec(`
+		CREATE TABLE sessions (id TEXT PRIMARY KEY, session_key TEXT NOT NULL UNIQUE,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP, updated_at DATETIME DEFAULT CURRENT_TIMESTAMP);
+		CREATE TABLE messages (id INTEGER PRIMARY KEY AUTOINCREMENT, session_id TEXT NOT NULL,
+			role TEXT NOT NULL, content TEXT, tool_calls TEXT, tool_results TEXT,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP);
+	`)
+	db.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	manager, err := New(dbPath)
+	if err != nil {
+		t.Fatalf("failed to migrate old database: %v", err)
+	}
+	defer manager.Close()
+
+	sess, _ := manager.GetOrCreate("old")
+	if err := manager.AppendMessage(sess.ID, Message{Role: "assistant", Content: "x", InputTokens: 1}); err != nil {
+		t.Fatalf("failed to append after migration: %v", err)
+	}
+}
@@ -0,0 +1,167 @@
+package session
+
+import "encoding/json"
+
+// ContextLimits bounds the history sent to a model
+type ContextLimits struct {
+	MaxTokens   int // Estimated token budget for messages (0 = unlimited)
+	MaxMessages int // Message cap (0 = unlimited)
+}
+
+// BuildContext selects the newest messages that fit within limits.
+// Compaction summaries (system messages) are always kept, older turns are dropped whole,
+// and every tool result stays paired with the assistant tool call that produced it.
+// The newest turn is always included; if it alone is too large, its oldest tool exchanges are dropped.
+func BuildContext(messages []Message, limits ContextLimits) []Message {
+	var summaries, rest []Message
+	for _, msg := range PairToolMessages(messages) {
+		if msg.Role == "system" {
+			summaries = append(summaries, msg)
+			continue
+		}
+		rest = append(rest, msg)
+	}
+
+	tokens := EstimateTokens(summaries)
+	count := len(summaries)
+	fits := func(t, n int) bool {
+		return (limits.MaxTokens <= 0 || tokens+t <= limits.MaxTokens) &&
+			(limits.MaxMessages <= 0 || count+n <= limits.MaxMessages)
+	}
+
+	turns := Turns(rest)
+	start := len(turns)
+	for start > 0 {
+		turn := turns[start-1]
+		if start < len(turns) && !fits(EstimateTokens(turn), len(turn)) {
+			break
+		}
+		tokens += EstimateTokens(turn)
+		count += len(turn)
+		start--
+	}
+
+	var kept []Message
+	for i, turn := range turns[start:] {
+		if i == 0 && start == len(turns)-1 && !fits(0, 0) {
+			turn = trimTurn(turn, limits, EstimateTokens(summaries), len(summaries))
+		}
+		kept = append(kept, turn...)
+	}
+
+	return append(summaries, kept...)
+}
+
+// trimTurn drops the oldest assistant/tool exchanges after a turn's opening message until the turn fits.
+// The opening message and the final exchange are always kept.
+func trimTurn(turn []Message, limits ContextLimits, baseTokens, baseCount int) []Message {
+	if len(turn) < 2 {
+		return turn
+	}
+
+	// Split into units: an assistant message plus the tool message answering it
+	head := turn[0]
+	var units [][]Message
+	for _, msg := range turn[1:] {
+		if msg.Role == "tool" && len(units) > 0 {
+			units[len(units)-1] = append(units[len(units)-1], msg)
+			continue
+		}
+		units = append(units, []Message{msg})
+	}
+
+	size := func(units [][]Message) (tokens, count int) {
+		tokens, count = baseTokens+head.EstimateTokens(), baseCount+1
+		for _, u := range units {
+			tokens += EstimateTokens(u)
+			count += len(u)
+		}
+		return tokens, count
+	}
+	for len(units) > 1 {
+		tokens, count := size(units)
+		if (limits.MaxTokens <= 0 || tokens <= limits.MaxTokens) &&
+			(limits.MaxMessages <= 0 || count <= limits.MaxMessages) {
+			break
+		}
+		units = units[1:]
+	}
+
+	trimmed := []Message{head}
+	for _, u := range units {
+		trimmed = append(trimmed, u...)
+	}
+	return trimmed
+}
+
+// PairToolMessages removes unpaired tool calls and results.
+// A tool message is kept only directly after the assistant message whose calls it answers;
+// calls without results and results without calls are dropped, as are messages left empty.
+func PairToolMessages(messages []Message) []Message {
+	result := make([]Message, 0, len(messages))
+	for i := 0; i < len(messages); i++ {
+		msg := messages[i]
+
+		switch {
+		case msg.Role == "tool":
+			// Not consumed by a preceding assistant message: orphaned
+			continue
+
+		case msg.Role == "assistant" && len(msg.ToolCalls) > 0:
+			var calls []ToolCall
+			if err := json.Unmarshal(msg.ToolCalls, &calls); err != nil {
+				continue
+			}
+
+			var results []ToolResult
+			var toolMsg *Message
+			if i+1 < len(messages) && messages[i+1].Role == "tool" {
+				toolMsg = &messages[i+1]
+				json.Unmarshal(toolMsg.ToolResults, &results)
+				i++
+			}
+
+			answered := make(map[string]bool, len(results))
+			for _, r := range results {
+				answered[r.ToolCallID] = true
+			}
+			called := make(map[string]bool, len(calls))
+			var pairedCalls []ToolCall
+			for _, c := range calls {
+				if answered[c.ID] {
+					pairedCalls = append(pairedCalls, c)
+					called[c.ID] = true
+				}
+			}
+			var pairedResults []ToolResult
+			for _, r := range results {
+				if called[r.ToolCallID] {
+					pairedResults = append(pairedResults, r)
+				}
+			}
+
+			if len(pairedCalls) != len(calls) {
+				msg.ToolCalls = nil
+				if len(pairedCalls) > 0 {
+					msg.ToolCalls, _ = json.Marshal(pairedCalls)
+				}
+			}
+			if len(msg.ToolCalls) == 0 && msg.Content == "" {
+				continue
+			}
+			result = append(result, msg)
+
+			if len(pairedResults) > 0 {
+				tool := *toolMsg
+				if len(pairedResults) != len(results) {
+					tool.ToolResults, _ = json.Marshal(pairedResults)
+				}
+				result = append(result, tool)
+			}
+
+		default:
+			result = append(result, msg)
+		}
+	}
+	return result
+}
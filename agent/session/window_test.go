@@ -0,0 +1,164 @@
+package session
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func user(content string) Message {
+	return Message{Role: "user", Content: content}
+}
+
+func assistant(content string, callIDs ...string) Message {
+	msg := Message{Role: "assistant", Content: content}
+	if len(callIDs) > 0 {
+		var calls []ToolCall
+		for _, id := range callIDs {
+			calls = append(calls, ToolCall{ID: id, Name: "read", Input: json.RawMessage(`{}`)})
+		}
+		msg.ToolCalls, _ = json.Marshal(calls)
+	}
+	return msg
+}
+
+func toolResults(content string, callIDs ...string) Message {
+	var results []ToolResult
+	for _, id := range callIDs {
+		results = append(results, ToolResult{ToolCallID: id, Content: content})
+	}
+	data, _ := json.Marshal(results)
+	return Message{Role: "tool", ToolResults: data}
+}
+
+// describe renders messages compactly, e.g. "user:a assistant[c1] tool[c1]"
+func describe(messages []Message) string {
+	var parts []string
+	for _, m := range messages {
+		part := m.Role
+		if m.Content != "" {
+			part += ":" + m.Content
+		}
+		var ids []string
+		var calls []ToolCall
+		json.Unmarshal(m.ToolCalls, &calls)
+		for _, c := range calls {
+			ids = append(ids, c.ID)
+		}
+		var results []ToolResult
+		json.Unmarshal(m.ToolResults, &results)
+		for _, r := range results {
+			ids = append(ids, r.ToolCallID)
+		}
+		if len(ids) > 0 {
+			part += "[" + strings.Join(ids, ",") + "]"
+		}
+		parts = append(parts, part)
+	}
+	return strings.Join(parts, " ")
+}
+
+func TestPairToolMessages(t *testing.T) {
+	tests := []struct {
+		name     string
+		messages []Message
+		want     string
+	}{
+		{
+			name:     "paired",
+			messages: []Message{user("a"), assistant("", "c1"), toolResults("ok", "c1"), assistant("done")},
+			want:     "user:a assistant[c1] tool[c1] assistant:done",
+		},
+		{
+			name:     "orphan result at start",
+			messages: []Message{toolResults("ok", "c1"), assistant("done"), user("b")},
+			want:     "assistant:done user:b",
+		},
+		{
+			name:     "call without result",
+			messages: []Message{user("a"), assistant("", "c1"), user("b")},
+			want:     "user:a user:b",
+		},
+		{
+			name:     "call without result keeps text",
+			messages: []Message{user("a"), assistant("thinking", "c1"), user("b")},
+			want:     "user:a assistant:thinking user:b",
+		},
+		{
+			name:     "partial results",
+			messages: []Message{user("a"), assistant("", "c1", "c2"), toolResults("ok", "c2", "c3")},
+			want:     "user:a assistant[c2] tool[c2]",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := describe(PairToolMessages(tt.messages)); got != tt.want {
+				t.Errorf("got  %s\nwant %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildContext(t *testing.T) {
+	long := strings.Repeat("x", 400) // ~100 tokens
+
+	history := []Message{
+		{Role: "system", Content: "summary"},
+		user("one"), assistant("", "c1"), toolResults(long, "c1"), assistant("r1"),
+		user("two"), assistant("", "c2"), toolResults(long, "c2"), assistant("r2"),
+		user("three"), assistant("r3"),
+	}
+
+	tests := []struct {
+		name   string
+		limits ContextLimits
+		want   string
+	}{
+		{
+			name:   "unlimited",
+			limits: ContextLimits{},
+			want:   "system:summary user:one assistant[c1] tool[c1] assistant:r1 user:two assistant[c2] tool[c2] assistant:r2 user:three assistant:r3",
+		},
+		{
+			name:   "message cap drops whole turns",
+			limits: ContextLimits{MaxMessages: 8},
+			want:   "system:summary user:two assistant[c2] tool[c2] assistant:r2 user:three assistant:r3",
+		},
+		{
+			name:   "token budget drops whole turns",
+			limits: ContextLimits{MaxTokens: 150},
+			want:   "system:summary user:two assistant[c2] tool[c2] assistant:r2 user:three assistant:r3",
+		},
+		{
+			name:   "newest turn always kept",
+			limits: ContextLimits{MaxTokens: 1},
+			want:   "system:summary user:three assistant:r3",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := describe(BuildContext(history, tt.limits)); got != tt.want {
+				t.Errorf("got  %s\nwant %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildContextTrimsLongTurn(t *testing.T) {
+	long := strings.Repeat("x", 400)
+	history := []Message{
+		user("old"), assistant("r0"),
+		user("task"),
+		assistant("", "c1"), toolResults(long, "c1"),
+		assistant("", "c2"), toolResults(long, "c2"),
+		assistant("", "c3"), toolResults(long, "c3"),
+	}
+
+	got := describe(BuildContext(history, ContextLimits{MaxTokens: 300}))
+	want := "user:task assistant[c2] tool[c2] assistant[c3] tool[c3]"
+	if got != want {
+		t.Errorf("got  %s\nwant %s", got, want)
+	}
+}
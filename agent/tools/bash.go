@@ -6,13 +6,20 @@ import (
 	"encoding/json"
 	"fmt"
 	"os/exec"
+	"path/filepath"
 	"strings"
+	"sync"
+	"syscall"
 	"time"
+
+	"gobot/agent/audit"
 )
 
 // BashTool executes shell commands
 type BashTool struct {
+	mu     sync.RWMutex
 	policy *Policy
+	jobs   *JobManager // Runs background commands (optional)
 }
 
 // NewBashTool creates a new bash tool
@@ -20,6 +27,30 @@ func NewBashTool(policy *Policy) *BashTool {
 	return &BashTool{policy: policy}
 }
 
+// SetPolicy switches the policy that picks the executor
+func (t *BashTool) SetPolicy(policy *Policy) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.policy = policy
+}
+
+// SetJobs enables background commands, tracked by jobs
+func (t *BashTool) SetJobs(jobs *JobManager) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.jobs = jobs
+}
+
+// executor returns the executor for the current policy level
+func (t *BashTool) executor() Executor {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	if t.policy == nil {
+		return HostExecutor{}
+	}
+	return t.policy.Executor()
+}
+
 // Name returns the tool name
 func (t *BashTool) Name() string {
 	return "bash"
@@ -29,7 +60,9 @@ func (t *BashTool) Name() string {
 func (t *BashTool) Description() string {
 	return `Execute a bash command. Use for running shell commands, scripts, and system operations.
 Be careful with destructive commands - they require user approval.
-Prefer using dedicated tools (read, write, glob, grep) for file operations.`
+Prefer using dedicated tools (read, write, glob, grep) for file operations.
+Set background to run dev servers, watchers and long builds without waiting: it returns a job ID
+to use with the jobs tool to read output, send input and kill the command.`
 }
 
 // Schema returns the JSON schema for the tool input
@@ -43,11 +76,15 @@ func (t *BashTool) Schema() json.RawMessage {
 			},
 			"timeout": {
 				"type": "integer",
-				"description": "Timeout in seconds (default: 120)"
+				"description": "Timeout in seconds (default: 120; background jobs have none unless set)"
 			},
 			"cwd": {
 				"type": "string",
 				"description": "Working directory for the command"
+			},
+			"background": {
+				"type": "boolean",
+				"description": "Run in the background and return a job ID instead of waiting for the command to finish"
 			}
 		},
 		"required": ["command"]
@@ -56,9 +93,10 @@ func (t *BashTool) Schema() json.RawMessage {
 
 // BashInput represents the tool input
 type BashInput struct {
-	Command string `json:"command"`
-	Timeout int    `json:"timeout"`
-	Cwd     string `json:"cwd"`
+	Command    string `json:"command"`
+	Timeout    int    `json:"timeout"`
+	Cwd        string `json:"cwd"`
+	Background bool   `json:"background"`
 }
 
 // Execute runs the bash command
@@ -75,6 +113,10 @@ func (t *BashTool) Execute(ctx context.Context, input json.RawMessage) (*ToolRes
 		}, nil
 	}
 
+	if in.Background {
+		return t.startJob(ctx, in), nil
+	}
+
 	// Set default timeout
 	timeout := 120 * time.Second
 	if in.Timeout > 0 {
@@ -85,10 +127,9 @@ func (t *BashTool) Execute(ctx context.Context, input json.RawMessage) (*ToolRes
 	ctx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
 
-	// Create command
-	cmd := exec.CommandContext(ctx, "bash", "-c", in.Command)
-	if in.Cwd != "" {
-		cmd.Dir = in.Cwd
+	cmd, failed := t.command(ctx, in)
+	if failed != nil {
+		return failed, nil
 	}
 
 	// Capture output
@@ -120,6 +161,12 @@ func (t *BashTool) Execute(ctx context.Context, input json.RawMessage) (*ToolRes
 				IsError: true,
 			}, nil
 		}
+		if ctx.Err() == context.Canceled {
+			return &ToolResult{
+				Content: fmt.Sprintf("Command cancelled\n%s", result.String()),
+				IsError: true,
+			}, nil
+		}
 		if exitErr, ok := err.(*exec.ExitError); ok {
 			return &ToolResult{
 				Content: fmt.Sprintf("Command exited with code %d\n%s", exitErr.ExitCode(), result.String()),
@@ -148,8 +195,83 @@ func (t *BashTool) Execute(ctx context.Context, input json.RawMessage) (*ToolRes
 	}, nil
 }
 
+// command prepares the command with the executor for the current policy level, in its own
+// process group so cancelling ctx kills the command's children too
+func (t *BashTool) command(ctx context.Context, in BashInput) (*exec.Cmd, *ToolResult) {
+	dir := in.Cwd
+	if dir != "" {
+		if abs, err := filepath.Abs(dir); err == nil {
+			dir = abs
+		}
+	}
+	executor := t.executor()
+	cmd, err := executor.Command(ctx, in.Command, dir)
+	if err != nil {
+		return nil, &ToolResult{
+			Content: fmt.Sprintf("Error: %s executor unavailable: %v", executor.Name(), err),
+			IsError: true,
+		}
+	}
+
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	stop := cmd.Cancel
+	cmd.Cancel = func() error {
+		if stop != nil {
+			stop()
+		}
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+	cmd.WaitDelay = 5 * time.Second
+	return cmd, nil
+}
+
+// startJob runs the command as a background job in the caller's session. The job outlives
+// the tool call, so it gets its own context, bounded only by an explicit timeout.
+func (t *BashTool) startJob(ctx context.Context, in BashInput) *ToolResult {
+	t.mu.RLock()
+	jobs := t.jobs
+	t.mu.RUnlock()
+	if jobs == nil {
+		return &ToolResult{
+			Content: "Error: background commands are not available here",
+			IsError: true,
+		}
+	}
+
+	var jobCtx context.Context
+	var cancel context.CancelFunc
+	if in.Timeout > 0 {
+		jobCtx, cancel = context.WithTimeout(context.Background(), time.Duration(in.Timeout)*time.Second)
+	} else {
+		jobCtx, cancel = context.WithCancel(context.Background())
+	}
+	cmd, failed := t.command(jobCtx, in)
+	if failed != nil {
+		cancel()
+		return failed
+	}
+
+	job, err := jobs.Start(jobCtx, audit.SessionFrom(ctx), in.Command, t.executor().Name(), cmd, cancel)
+	if err != nil {
+		return &ToolResult{
+			Content: fmt.Sprintf("Error: failed to start background job: %v", err),
+			IsError: true,
+		}
+	}
+
+	return &ToolResult{
+		Content: fmt.Sprintf("Started background job %s (pid %d). Use the jobs tool with job_id %q to read its output, send input or kill it.",
+			job.ID, job.PID, job.ID),
+	}
+}
+
 // RequiresApproval checks if this command needs approval
 func (t *BashTool) RequiresApproval() bool {
 	// Actual check happens in policy during Execute
 	return true
 }
+
+// SerialOnly returns true - shell commands may depend on each other's side effects
+func (t *BashTool) SerialOnly() bool {
+	return true
+}
@@ -111,6 +111,11 @@ func (t *BrowserTool) RequiresApproval() bool {
 	return true // Browser automation can be dangerous
 }
 
+// SerialOnly returns true - all actions drive the same browser page
+func (t *BrowserTool) SerialOnly() bool {
+	return true
+}
+
 func (t *BrowserTool) Execute(ctx context.Context, input json.RawMessage) (*ToolResult, error) {
 	var params browserInput
 	if err := json.Unmarshal(input, &params); err != nil {
@@ -133,6 +138,10 @@ func (t *BrowserTool) Execute(ctx context.Context, input json.RawMessage) (*Tool
 	browserCtx, cancel = context.WithTimeout(browserCtx, timeout)
 	defer cancel()
 
+	// The browser context derives from the allocator, so stop it when the run is cancelled
+	stop := context.AfterFunc(ctx, cancel)
+	defer stop()
+
 	var result string
 	var err error
 
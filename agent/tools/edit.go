@@ -160,7 +160,21 @@ func (t *EditTool) Execute(ctx context.Context, input json.RawMessage) (*ToolRes
 	}, nil
 }
 
+// ModifiedPaths returns the file the edit changes, so it can be checkpointed first
+func (t *EditTool) ModifiedPaths(input json.RawMessage) []string {
+	var in EditInput
+	if err := json.Unmarshal(input, &in); err != nil || in.Path == "" {
+		return nil
+	}
+	return []string{in.Path}
+}
+
 // RequiresApproval returns true - editing files needs approval
 func (t *EditTool) RequiresApproval() bool {
 	return true
 }
+
+// SerialOnly returns true - edits must not race with other file operations
+func (t *EditTool) SerialOnly() bool {
+	return true
+}
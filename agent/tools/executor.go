@@ -0,0 +1,230 @@
+package tools
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"gobot/agent/config"
+)
+
+// Executor starts shell commands for the bash tool
+type Executor interface {
+	// Name identifies the executor: "host", "bubblewrap" or "container"
+	Name() string
+
+	// Command prepares `bash -c command` to run in dir (empty for the workspace)
+	Command(ctx context.Context, command, dir string) (*exec.Cmd, error)
+}
+
+// Executor names accepted in config.yaml
+const (
+	ExecutorHost       = "host"
+	ExecutorBubblewrap = "bubblewrap"
+	ExecutorContainer  = "container"
+)
+
+// DefaultSandboxImage is the container image used when none is configured
+const DefaultSandboxImage = "debian:stable-slim"
+
+// HostExecutor runs commands directly as the gobot user
+type HostExecutor struct{}
+
+// Name returns the executor name
+func (HostExecutor) Name() string {
+	return ExecutorHost
+}
+
+// Command runs bash on the host
+func (HostExecutor) Command(ctx context.Context, command, dir string) (*exec.Cmd, error) {
+	cmd := exec.CommandContext(ctx, "bash", "-c", command)
+	cmd.Dir = dir
+	return cmd, nil
+}
+
+// BubblewrapExecutor runs commands in Linux namespaces with bwrap: the root file system is
+// read-only, the workspace and Writable paths are writable and /tmp is private
+type BubblewrapExecutor struct {
+	Workspace string
+	Writable  []string // Extra writable paths
+	Network   bool     // Keep network access
+	Binary    string   // Path to bwrap (default: looked up on PATH)
+}
+
+// Name returns the executor name
+func (e *BubblewrapExecutor) Name() string {
+	return ExecutorBubblewrap
+}
+
+// Command runs bash inside a bubblewrap sandbox
+func (e *BubblewrapExecutor) Command(ctx context.Context, command, dir string) (*exec.Cmd, error) {
+	binary := e.Binary
+	if binary == "" {
+		path, err := exec.LookPath("bwrap")
+		if err != nil {
+			return nil, fmt.Errorf("bubblewrap sandbox needs bwrap on PATH: %w", err)
+		}
+		binary = path
+	}
+	if dir == "" {
+		dir = e.Workspace
+	}
+
+	args := []string{
+		"--ro-bind", "/", "/",
+		"--dev", "/dev",
+		"--proc", "/proc",
+		"--tmpfs", "/tmp",
+	}
+	// Bound after the /tmp tmpfs so workspaces under /tmp stay visible
+	for _, path := range append([]string{e.Workspace}, e.Writable...) {
+		args = append(args, "--bind", path, path)
+	}
+	args = append(args, "--unshare-all")
+	if e.Network {
+		args = append(args, "--share-net")
+	}
+	args = append(args, "--die-with-parent", "--chdir", dir, "--", "bash", "-c", command)
+
+	return exec.CommandContext(ctx, binary, args...), nil
+}
+
+// ContainerExecutor runs each command in a fresh container with the workspace mounted
+// at the same path
+type ContainerExecutor struct {
+	Runtime   string // docker or podman (default: whichever is on PATH)
+	Image     string
+	Workspace string
+	Writable  []string // Extra paths mounted read-write
+	Network   bool     // Keep network access
+}
+
+// Name returns the executor name
+func (e *ContainerExecutor) Name() string {
+	return ExecutorContainer
+}
+
+// Command runs bash in a container that is removed when the command exits
+func (e *ContainerExecutor) Command(ctx context.Context, command, dir string) (*exec.Cmd, error) {
+	runtime := e.Runtime
+	if runtime == "" {
+		for _, candidate := range []string{"docker", "podman"} {
+			if _, err := exec.LookPath(candidate); err == nil {
+				runtime = candidate
+				break
+			}
+		}
+		if runtime == "" {
+			return nil, fmt.Errorf("container sandbox needs docker or podman on PATH")
+		}
+	}
+	image := e.Image
+	if image == "" {
+		image = DefaultSandboxImage
+	}
+
+	// Only mounted paths exist in the container
+	mounts := append([]string{e.Workspace}, e.Writable...)
+	workdir := e.Workspace
+	for _, path := range mounts {
+		if dir != "" && isWithin(dir, path) {
+			workdir = dir
+		}
+	}
+
+	suffix := make([]byte, 6)
+	rand.Read(suffix)
+	name := "gobot-" + hex.EncodeToString(suffix)
+
+	args := []string{"run", "--rm", "-i", "--init", "--name", name}
+	if !e.Network {
+		args = append(args, "--network", "none")
+	}
+	for _, path := range mounts {
+		args = append(args, "-v", path+":"+path)
+	}
+	args = append(args,
+		"-w", workdir,
+		"--user", fmt.Sprintf("%d:%d", os.Getuid(), os.Getgid()),
+		"-e", "HOME=/tmp",
+		image, "bash", "-c", command,
+	)
+
+	cmd := exec.CommandContext(ctx, runtime, args...)
+	// Killing the client doesn't stop the container, so stop it by name
+	cmd.Cancel = func() error {
+		return exec.Command(runtime, "kill", name).Run()
+	}
+	return cmd, nil
+}
+
+// NewExecutor creates an executor by name. Sandboxed executors confine writes to the
+// workspace and the configured writable paths.
+func NewExecutor(name string, cfg config.SandboxConfig, workspace string) (Executor, error) {
+	writable := make([]string, 0, len(cfg.Writable))
+	for _, path := range cfg.Writable {
+		writable = append(writable, resolveRulePath(path, workspace))
+	}
+
+	switch name {
+	case "", ExecutorHost:
+		return HostExecutor{}, nil
+	case ExecutorBubblewrap:
+		if _, err := exec.LookPath("bwrap"); err != nil {
+			return nil, fmt.Errorf("bubblewrap sandbox needs bwrap on PATH")
+		}
+		return &BubblewrapExecutor{Workspace: workspace, Writable: writable, Network: cfg.Network}, nil
+	case ExecutorContainer:
+		executor := &ContainerExecutor{Runtime: cfg.Runtime, Image: cfg.Image, Workspace: workspace, Writable: writable, Network: cfg.Network}
+		if executor.Runtime != "" {
+			if _, err := exec.LookPath(executor.Runtime); err != nil {
+				return nil, fmt.Errorf("container sandbox needs %s on PATH", executor.Runtime)
+			}
+		}
+		return executor, nil
+	default:
+		return nil, fmt.Errorf("unknown executor %q (want host, bubblewrap or container)", name)
+	}
+}
+
+// SetSandbox chooses the executor for each policy level from config.yaml
+func (p *Policy) SetSandbox(cfg config.SandboxConfig) error {
+	executors := make(map[PolicyLevel]Executor)
+	for _, level := range []PolicyLevel{PolicyDeny, PolicyAllowlist, PolicyFull} {
+		name := cfg.Executor
+		if override, ok := cfg.Levels[string(level)]; ok {
+			name = override
+		}
+		executor, err := NewExecutor(name, cfg, p.Workspace)
+		if err != nil {
+			return fmt.Errorf("sandbox for policy level %s: %w", level, err)
+		}
+		executors[level] = executor
+	}
+	for level := range cfg.Levels {
+		if _, ok := executors[PolicyLevel(level)]; !ok {
+			return fmt.Errorf("sandbox: unknown policy level %q", level)
+		}
+	}
+	p.Executors = executors
+	return nil
+}
+
+// Executor returns the executor for the policy's current level
+func (p *Policy) Executor() Executor {
+	if executor, ok := p.Executors[p.Level]; ok {
+		return executor
+	}
+	return HostExecutor{}
+}
+
+// isWithin reports whether path is dir or inside it
+func isWithin(path, dir string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
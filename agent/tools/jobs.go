@@ -0,0 +1,564 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os/exec"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"gobot/agent/audit"
+)
+
+// JobStatus is the state of a background job
+type JobStatus string
+
+const (
+	JobRunning  JobStatus = "running"
+	JobExited   JobStatus = "exited"
+	JobKilled   JobStatus = "killed"
+	JobTimedOut JobStatus = "timed_out"
+)
+
+// Job event types
+const (
+	JobEventOutput = "output"
+	JobEventExit   = "exit"
+)
+
+const (
+	// MaxJobOutput is how much recent output a job keeps; older output is dropped
+	MaxJobOutput = 1 << 20
+
+	// MaxJobsPerSession limits the jobs running at once in one session
+	MaxJobsPerSession = 8
+
+	// maxFinishedJobs is how many finished jobs a session keeps for reading
+	maxFinishedJobs = 16
+
+	// maxJobRead is the most output returned by one read
+	maxJobRead = 50000
+)
+
+// JobEvent reports output from a background job, or that it finished
+type JobEvent struct {
+	JobID      string    `json:"job_id"`
+	SessionKey string    `json:"session_key"`
+	Command    string    `json:"command"`
+	Type       string    `json:"type"` // JobEventOutput or JobEventExit
+	Output     string    `json:"output,omitempty"`
+	Status     JobStatus `json:"status"`
+	ExitCode   int       `json:"exit_code"`
+}
+
+// Job is a bash command running in the background
+type Job struct {
+	ID         string
+	SessionKey string
+	Command    string
+	Executor   string
+	PID        int
+	StartedAt  time.Time
+
+	mu       sync.Mutex
+	manager  *JobManager
+	status   JobStatus
+	exitCode int
+	endedAt  time.Time
+	output   []byte // Combined stdout and stderr, at most MaxJobOutput bytes
+	dropped  int64  // Bytes dropped from the front of output
+	cursor   int64  // Offset the next incremental read starts at
+	stdin    io.WriteCloser
+	cancel   context.CancelFunc
+	done     chan struct{}
+}
+
+// Write appends command output; stdout and stderr share it so they stay interleaved
+func (j *Job) Write(p []byte) (int, error) {
+	j.mu.Lock()
+	j.output = append(j.output, p...)
+	if excess := len(j.output) - MaxJobOutput; excess > 0 {
+		j.output = append(j.output[:0], j.output[excess:]...)
+		j.dropped += int64(excess)
+	}
+	event := j.event(JobEventOutput)
+	j.mu.Unlock()
+
+	event.Output = string(p)
+	j.manager.emit(event)
+	return len(p), nil
+}
+
+// Status returns the job's state and exit code (meaningful once it has finished)
+func (j *Job) Status() (JobStatus, int) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.status, j.exitCode
+}
+
+// Done is closed when the job finishes
+func (j *Job) Done() <-chan struct{} {
+	return j.done
+}
+
+// Output returns output from offset on, or from where the last read stopped when offset
+// is negative. start is where the returned output begins, which is later than offset if
+// that output was dropped; end is where the next read continues.
+func (j *Job) Output(offset int64) (output string, start, end int64) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if offset < 0 {
+		offset = j.cursor
+	}
+	start = max(offset, j.dropped)
+	total := j.dropped + int64(len(j.output))
+	end = min(total, start+maxJobRead)
+	if start > end {
+		start = end
+	}
+	j.cursor = end
+	return string(j.output[start-j.dropped : end-j.dropped]), start, end
+}
+
+// size returns the total amount of output the job has written, including dropped output
+func (j *Job) size() int64 {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.dropped + int64(len(j.output))
+}
+
+// Input writes text to the job's stdin, closing it afterwards if eof is set
+func (j *Job) Input(text string, eof bool) error {
+	j.mu.Lock()
+	stdin := j.stdin
+	if eof {
+		j.stdin = nil
+	}
+	j.mu.Unlock()
+
+	if stdin == nil {
+		return fmt.Errorf("stdin of %s is closed", j.ID)
+	}
+	if text != "" {
+		if _, err := io.WriteString(stdin, text); err != nil {
+			return fmt.Errorf("failed to write to %s: %w", j.ID, err)
+		}
+	}
+	if eof {
+		return stdin.Close()
+	}
+	return nil
+}
+
+// Kill stops the job and everything it started
+func (j *Job) Kill() {
+	j.cancel()
+}
+
+// event builds an event for the job's current state; j.mu must be held
+func (j *Job) event(eventType string) JobEvent {
+	return JobEvent{
+		JobID:      j.ID,
+		SessionKey: j.SessionKey,
+		Command:    j.Command,
+		Type:       eventType,
+		Status:     j.status,
+		ExitCode:   j.exitCode,
+	}
+}
+
+// summary describes the job for listings
+func (j *Job) summary() string {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	state := string(j.status)
+	if j.status == JobRunning {
+		state += fmt.Sprintf(" for %s", time.Since(j.StartedAt).Round(time.Second))
+	} else {
+		state += fmt.Sprintf(" (code %d) after %s", j.exitCode, j.endedAt.Sub(j.StartedAt).Round(time.Second))
+	}
+	unread := j.dropped + int64(len(j.output)) - max(j.cursor, j.dropped)
+	return fmt.Sprintf("%s  %s  %d unread bytes  %s", j.ID, state, unread, j.Command)
+}
+
+// JobManager runs background bash jobs and tracks them per session
+type JobManager struct {
+	mu      sync.Mutex
+	jobs    map[string]*Job
+	nextID  int
+	onEvent func(JobEvent)
+}
+
+// NewJobManager creates a job manager
+func NewJobManager() *JobManager {
+	return &JobManager{jobs: make(map[string]*Job)}
+}
+
+// SetEventHandler streams job output and exits to handler, e.g. to forward them to the UI
+func (m *JobManager) SetEventHandler(handler func(JobEvent)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.onEvent = handler
+}
+
+// emit sends an event to the event handler, if one is set
+func (m *JobManager) emit(event JobEvent) {
+	m.mu.Lock()
+	handler := m.onEvent
+	m.mu.Unlock()
+	if handler != nil {
+		handler(event)
+	}
+}
+
+// Start runs cmd as a job in sessionKey. ctx and cancel are the context cmd was created with;
+// cancel kills the job and is called once the job exits.
+func (m *JobManager) Start(ctx context.Context, sessionKey, command, executor string, cmd *exec.Cmd, cancel context.CancelFunc) (*Job, error) {
+	m.mu.Lock()
+	running := 0
+	var finished []*Job
+	for _, job := range m.jobs {
+		if job.SessionKey != sessionKey {
+			continue
+		}
+		if status, _ := job.Status(); status == JobRunning {
+			running++
+		} else {
+			finished = append(finished, job)
+		}
+	}
+	if running >= MaxJobsPerSession {
+		m.mu.Unlock()
+		return nil, fmt.Errorf("%d jobs are already running in this session; kill one first", running)
+	}
+	// Forget the oldest finished jobs
+	sort.Slice(finished, func(a, b int) bool { return finished[a].StartedAt.Before(finished[b].StartedAt) })
+	for len(finished) >= maxFinishedJobs {
+		delete(m.jobs, finished[0].ID)
+		finished = finished[1:]
+	}
+	m.nextID++
+	job := &Job{
+		ID:         fmt.Sprintf("job-%d", m.nextID),
+		SessionKey: sessionKey,
+		Command:    command,
+		Executor:   executor,
+		manager:    m,
+		status:     JobRunning,
+		cancel:     cancel,
+		done:       make(chan struct{}),
+	}
+	m.mu.Unlock()
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	job.stdin = stdin
+	cmd.Stdout = job
+	cmd.Stderr = job
+
+	if err := cmd.Start(); err != nil {
+		cancel()
+		return nil, err
+	}
+	job.PID = cmd.Process.Pid
+	job.StartedAt = time.Now()
+
+	m.mu.Lock()
+	m.jobs[job.ID] = job
+	m.mu.Unlock()
+
+	go m.wait(ctx, job, cmd)
+	return job, nil
+}
+
+// wait records how a job finished
+func (m *JobManager) wait(ctx context.Context, job *Job, cmd *exec.Cmd) {
+	err := cmd.Wait()
+	deadline := ctx.Err() == context.DeadlineExceeded
+	job.cancel()
+
+	job.mu.Lock()
+	job.endedAt = time.Now()
+	job.stdin = nil
+	job.exitCode = cmd.ProcessState.ExitCode()
+	switch {
+	case deadline:
+		job.status = JobTimedOut
+	case job.status == JobKilled:
+	case err != nil && job.exitCode < 0:
+		job.status = JobKilled
+	default:
+		job.status = JobExited
+	}
+	event := job.event(JobEventExit)
+	job.mu.Unlock()
+
+	close(job.done)
+	m.emit(event)
+}
+
+// Get returns a job by ID
+func (m *JobManager) Get(id string) (*Job, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	job, ok := m.jobs[id]
+	return job, ok
+}
+
+// List returns a session's jobs, oldest first
+func (m *JobManager) List(sessionKey string) []*Job {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var jobs []*Job
+	for _, job := range m.jobs {
+		if job.SessionKey == sessionKey {
+			jobs = append(jobs, job)
+		}
+	}
+	sort.Slice(jobs, func(a, b int) bool { return jobs[a].StartedAt.Before(jobs[b].StartedAt) })
+	return jobs
+}
+
+// Kill stops a job; it stays listed until its session ends
+func (m *JobManager) Kill(id string) error {
+	job, ok := m.Get(id)
+	if !ok {
+		return fmt.Errorf("job %s not found", id)
+	}
+	job.mu.Lock()
+	if job.status == JobRunning {
+		job.status = JobKilled
+	}
+	job.mu.Unlock()
+	job.Kill()
+	return nil
+}
+
+// EndSession kills a session's jobs and forgets them, returning how many were running
+func (m *JobManager) EndSession(sessionKey string) int {
+	killed := 0
+	for _, job := range m.List(sessionKey) {
+		if status, _ := job.Status(); status == JobRunning {
+			m.Kill(job.ID)
+			killed++
+		}
+		<-job.Done()
+
+		m.mu.Lock()
+		delete(m.jobs, job.ID)
+		m.mu.Unlock()
+	}
+	return killed
+}
+
+// KillAll stops every running job and waits for them to exit
+func (m *JobManager) KillAll() {
+	m.mu.Lock()
+	jobs := make([]*Job, 0, len(m.jobs))
+	for _, job := range m.jobs {
+		jobs = append(jobs, job)
+	}
+	m.mu.Unlock()
+
+	for _, job := range jobs {
+		m.Kill(job.ID)
+	}
+	for _, job := range jobs {
+		<-job.Done()
+	}
+}
+
+// JobsTool reads output from, writes input to and stops background bash jobs
+type JobsTool struct {
+	jobs *JobManager
+}
+
+// NewJobsTool creates a jobs tool for the jobs started by the bash tool
+func NewJobsTool(jobs *JobManager) *JobsTool {
+	return &JobsTool{jobs: jobs}
+}
+
+// JobsInput defines the input for the jobs tool
+type JobsInput struct {
+	Action string `json:"action"`           // "list", "output", "input", "kill"
+	JobID  string `json:"job_id,omitempty"` // Job to act on
+	Offset *int64 `json:"offset,omitempty"` // Output offset (default: where the last read stopped)
+	Wait   int    `json:"wait,omitempty"`   // Seconds to wait for the job to exit before reading
+	Text   string `json:"text,omitempty"`   // Text to write to stdin
+	EOF    bool   `json:"eof,omitempty"`    // Close stdin after writing
+}
+
+// Name returns the tool name
+func (t *JobsTool) Name() string {
+	return "jobs"
+}
+
+// Description returns the tool description
+func (t *JobsTool) Description() string {
+	return `Manage background jobs started with bash's "background": true. List jobs, read new output
+(each read continues where the last stopped), write to a job's stdin, or kill it.
+Jobs are stopped when the session ends.`
+}
+
+// Schema returns the JSON schema
+func (t *JobsTool) Schema() json.RawMessage {
+	return json.RawMessage(`{
+		"type": "object",
+		"properties": {
+			"action": {
+				"type": "string",
+				"description": "Action to perform: 'list' (jobs in this session), 'output' (read new output), 'input' (write to stdin), 'kill' (stop the job)",
+				"enum": ["list", "output", "input", "kill"]
+			},
+			"job_id": {
+				"type": "string",
+				"description": "Job ID returned by bash (required except for 'list')"
+			},
+			"offset": {
+				"type": "integer",
+				"description": "For 'output': byte offset to read from (default: where the last read stopped; 0 rereads everything kept)"
+			},
+			"wait": {
+				"type": "integer",
+				"description": "For 'output': seconds to wait for the job to exit first (max 60); returns early when it does"
+			},
+			"text": {
+				"type": "string",
+				"description": "For 'input': text to write to stdin (include a trailing newline to submit a line)"
+			},
+			"eof": {
+				"type": "boolean",
+				"description": "For 'input': close stdin after writing"
+			}
+		},
+		"required": ["action"]
+	}`)
+}
+
+// RequiresApproval returns true - writing to a job's stdin can run anything the job reads
+func (t *JobsTool) RequiresApproval() bool {
+	return true
+}
+
+// RequiresApprovalFor returns true only for input; reading and stopping the session's own jobs is safe
+func (t *JobsTool) RequiresApprovalFor(input json.RawMessage) bool {
+	var params JobsInput
+	json.Unmarshal(input, &params)
+	return params.Action == "input"
+}
+
+// Execute performs the jobs operation
+func (t *JobsTool) Execute(ctx context.Context, input json.RawMessage) (*ToolResult, error) {
+	var params JobsInput
+	if err := json.Unmarshal(input, &params); err != nil {
+		return &ToolResult{
+			Content: fmt.Sprintf("Invalid input: %v", err),
+			IsError: true,
+		}, nil
+	}
+
+	sessionKey := audit.SessionFrom(ctx)
+	if params.Action == "list" {
+		return t.list(sessionKey), nil
+	}
+
+	// Jobs are only visible to the session that started them
+	job, ok := t.jobs.Get(params.JobID)
+	if !ok || job.SessionKey != sessionKey {
+		return &ToolResult{
+			Content: fmt.Sprintf("Error: job %q not found in this session", params.JobID),
+			IsError: true,
+		}, nil
+	}
+
+	switch params.Action {
+	case "output":
+		return t.output(ctx, job, params), nil
+	case "input":
+		if err := job.Input(params.Text, params.EOF); err != nil {
+			return &ToolResult{Content: fmt.Sprintf("Error: %v", err), IsError: true}, nil
+		}
+		content := fmt.Sprintf("Wrote %d bytes to %s", len(params.Text), job.ID)
+		if params.EOF {
+			content += " and closed stdin"
+		}
+		return &ToolResult{Content: content}, nil
+	case "kill":
+		t.jobs.Kill(job.ID)
+		select {
+		case <-job.Done():
+		case <-time.After(5 * time.Second):
+		}
+		status, code := job.Status()
+		return &ToolResult{Content: fmt.Sprintf("%s %s (code %d)", job.ID, status, code)}, nil
+	default:
+		return &ToolResult{
+			Content: fmt.Sprintf("Unknown action: %s. Use 'list', 'output', 'input' or 'kill'", params.Action),
+			IsError: true,
+		}, nil
+	}
+}
+
+// list describes the session's jobs
+func (t *JobsTool) list(sessionKey string) *ToolResult {
+	jobs := t.jobs.List(sessionKey)
+	if len(jobs) == 0 {
+		return &ToolResult{Content: "No background jobs in this session."}
+	}
+
+	var b strings.Builder
+	for _, job := range jobs {
+		b.WriteString(job.summary())
+		b.WriteString("\n")
+	}
+	return &ToolResult{Content: b.String()}
+}
+
+// output waits for the job if asked and returns its new output
+func (t *JobsTool) output(ctx context.Context, job *Job, params JobsInput) *ToolResult {
+	if params.Wait > 0 {
+		wait := time.Duration(min(params.Wait, 60)) * time.Second
+		select {
+		case <-job.Done():
+		case <-time.After(wait):
+		case <-ctx.Done():
+		}
+	}
+
+	offset := int64(-1)
+	if params.Offset != nil {
+		offset = *params.Offset
+	}
+	output, start, end := job.Output(offset)
+	status, code := job.Status()
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %s", job.ID, status)
+	if status != JobRunning {
+		fmt.Fprintf(&b, " (code %d)", code)
+	}
+	fmt.Fprintf(&b, ", output bytes %d-%d", start, end)
+	if offset >= 0 && start > offset {
+		fmt.Fprintf(&b, " (bytes before %d were dropped)", start)
+	}
+	if remaining := job.size() - end; remaining > 0 {
+		fmt.Fprintf(&b, ", %d more bytes to read", remaining)
+	}
+	b.WriteString("\n")
+	if output == "" {
+		b.WriteString("(no new output)")
+	} else {
+		b.WriteString(output)
+	}
+	return &ToolResult{Content: b.String()}
+}
@@ -6,7 +6,10 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"os/user"
 	"strings"
+
+	"gobot/agent/audit"
 )
 
 // PolicyLevel defines the security level
@@ -36,7 +39,10 @@ type Policy struct {
 	Level            PolicyLevel
 	AskMode          AskMode
 	Allowlist        map[string]bool
-	ApprovalCallback ApprovalCallback // If set, used instead of stdin prompts
+	Rules            []*PolicyRule            // Argument-aware rules from config.yaml, checked before the allowlist
+	Workspace        string                   // Directory relative path rules resolve against
+	Executors        map[PolicyLevel]Executor // How bash runs at each level (host when unset)
+	ApprovalCallback ApprovalCallback         // If set, used instead of stdin prompts
 }
 
 // SafeBins are commands that never require approval
@@ -54,10 +60,12 @@ func NewPolicy() *Policy {
 		allowlist[cmd] = true
 	}
 
+	workspace, _ := os.Getwd()
 	return &Policy{
 		Level:     PolicyAllowlist,
 		AskMode:   AskModeOnMiss,
 		Allowlist: allowlist,
+		Workspace: workspace,
 	}
 }
 
@@ -109,33 +117,248 @@ func (p *Policy) RequiresApproval(cmd string) bool {
 	return p.AskMode != AskModeOff
 }
 
-// isAllowed checks if a command matches the allowlist
+// isAllowed checks if every command in a command line matches the allowlist
 func (p *Policy) isAllowed(cmd string) bool {
+	return p.checkCommand(cmd) == nil
+}
+
+// checkCommand parses a command line and explains why it isn't covered by the allowlist.
+// Every command in pipelines, lists, subshells and substitutions must be allowed, and
+// redirections may not write to files.
+func (p *Policy) checkCommand(cmd string) error {
 	cmd = strings.TrimSpace(cmd)
 
 	// Check exact match
 	if p.Allowlist[cmd] {
-		return true
+		return nil
 	}
 
-	// Check if command starts with an allowed prefix
-	parts := strings.Fields(cmd)
-	if len(parts) > 0 {
-		// Check binary name
-		if p.Allowlist[parts[0]] {
-			return true
+	script, err := parseShell(cmd)
+	if err != nil {
+		return fmt.Errorf("cannot parse command: %w", err)
+	}
+	return p.checkList(script)
+}
+
+// checkRuleCommand checks a command approved by an allow rule. A single plain command is
+// trusted as matched; chained commands, substitutions and file writes must pass checkCommand.
+func (p *Policy) checkRuleCommand(cmd string) error {
+	err := p.checkCommand(cmd)
+	if err == nil {
+		return nil
+	}
+	script, perr := parseShell(strings.TrimSpace(cmd))
+	if perr != nil || len(script.Pipelines) != 1 || len(script.Pipelines[0].Commands) != 1 {
+		return err
+	}
+	c := script.Pipelines[0].Commands[0]
+	if c.Body != nil || len(c.Assigns) > 0 {
+		return err
+	}
+	words := c.Words
+	for _, r := range c.Redirects {
+		if r.writes() {
+			return err
 		}
-		// Check binary with first arg (e.g., "git status")
-		if len(parts) > 1 && p.Allowlist[parts[0]+" "+parts[1]] {
-			return true
+		words = append(words, r.Target)
+	}
+	for _, word := range words {
+		if len(word.Subs) > 0 {
+			return err
 		}
 	}
+	return nil
+}
 
-	return false
+// checkList checks every command in a parsed list
+func (p *Policy) checkList(list *shellList) error {
+	for _, pipeline := range list.Pipelines {
+		for _, cmd := range pipeline.Commands {
+			if err := p.checkShellCommand(cmd); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// checkShellCommand checks one command, its redirections and any substitutions in its words
+func (p *Policy) checkShellCommand(cmd *shellCommand) error {
+	words := append(append([]shellWord{}, cmd.Assigns...), cmd.Words...)
+	for _, r := range cmd.Redirects {
+		if r.writes() {
+			return fmt.Errorf("redirection %s%s writes to a file", r.Op, r.Target.Text)
+		}
+		words = append(words, r.Target)
+		if r.Heredoc != nil {
+			words = append(words, *r.Heredoc)
+		}
+	}
+	for _, word := range words {
+		for _, sub := range word.Subs {
+			if err := p.checkList(sub); err != nil {
+				return err
+			}
+		}
+	}
+
+	if cmd.Body != nil {
+		return p.checkList(cmd.Body)
+	}
+	if len(cmd.Assigns) > 0 {
+		return fmt.Errorf("variable assignment %s is not allowed", cmd.Assigns[0].Text)
+	}
+	return p.checkSimpleCommand(cmd.Words)
+}
+
+// checkSimpleCommand matches a command and its first argument against the allowlist
+func (p *Policy) checkSimpleCommand(words []shellWord) error {
+	if len(words) == 0 {
+		return nil
+	}
+	name := words[0]
+	if !name.Literal || name.Glob {
+		return fmt.Errorf("command name %s is not a plain word", name.Text)
+	}
+
+	// env runs its arguments as a command
+	if name.Text == "env" && len(words) > 1 {
+		if arg := words[1].Text; strings.HasPrefix(arg, "-") || strings.Contains(arg, "=") {
+			return fmt.Errorf("env %s is not allowed", arg)
+		}
+		return p.checkSimpleCommand(words[1:])
+	}
+
+	allowed := p.Allowlist[name.Text]
+	if len(words) > 1 && words[1].Literal && p.Allowlist[name.Text+" "+words[1].Text] {
+		allowed = true
+	}
+	if !allowed {
+		texts := make([]string, len(words))
+		for i, w := range words {
+			texts[i] = w.Text
+		}
+		if !p.Allowlist[strings.Join(texts, " ")] {
+			return fmt.Errorf("%s is not in the allowlist", name.Text)
+		}
+	}
+	return checkCommandArgs(name.Text, words[1:])
+}
+
+// unsafeArgs are options that make otherwise read-only commands run programs or write files
+var unsafeArgs = map[string][]string{
+	"find": {"-exec", "-execdir", "-ok", "-okdir", "-delete", "-fprint", "-fprint0", "-fprintf", "-fls"},
+	"sort": {"-o", "--output", "--compress-program"},
+	"git":  {"--output", "--ext-diff"},
+	"date": {"-s", "--set"},
+}
+
+// outputOperands are read-only commands that write to a file named by an extra operand
+var outputOperands = map[string]struct {
+	inputs    int      // Operands read before the one that is written
+	valueOpts []string // Options whose value is the next argument
+}{
+	"uniq": {1, []string{"-f", "-s", "-w", "--skip-fields", "--skip-chars", "--check-chars"}},
+}
+
+// checkCommandArgs rejects arguments that turn an allowed command into one that writes or executes
+func checkCommandArgs(name string, args []shellWord) error {
+	for _, arg := range args {
+		for _, opt := range unsafeArgs[name] {
+			if arg.Text == opt || strings.HasPrefix(arg.Text, opt+"=") || (len(opt) == 2 && strings.HasPrefix(arg.Text, opt)) {
+				return fmt.Errorf("%s %s is not allowed", name, opt)
+			}
+		}
+	}
+
+	out, ok := outputOperands[name]
+	if !ok {
+		return nil
+	}
+	operands := 0
+	options := true
+	for i := 0; i < len(args); i++ {
+		arg := args[i].Text
+		switch {
+		case options && arg == "--":
+			options = false
+		case options && strings.HasPrefix(arg, "-") && arg != "-":
+			for _, opt := range out.valueOpts {
+				if arg == opt {
+					i++ // Skip the option's value
+				}
+			}
+		default:
+			if operands++; operands > out.inputs {
+				return fmt.Errorf("%s %s writes to a file", name, arg)
+			}
+		}
+	}
+	return nil
+}
+
+// writes reports whether a redirection writes to a file other than /dev/null
+func (r *shellRedirect) writes() bool {
+	switch r.Op {
+	case ">", ">>", ">|", "<>", "&>", "&>>":
+	case ">&":
+		if t := r.Target.Text; t == "-" || strings.Trim(t, "0123456789") == "" || (strings.HasSuffix(t, "-") && strings.Trim(t[:len(t)-1], "0123456789") == "") {
+			return false // Duplicates or closes a file descriptor
+		}
+	default:
+		return false
+	}
+	if !r.Target.Literal || r.Target.Glob {
+		return true
+	}
+	switch r.Target.Text {
+	case "/dev/null", "/dev/stdout", "/dev/stderr":
+		return false
+	}
+	return true
+}
+
+// Approval describes how a tool call was authorized
+type Approval struct {
+	Approved bool
+	Channel  string // audit.ChannelAuto, audit.ChannelCLI or audit.ChannelWeb
+	Approver string // Who decided: a user name, or "policy:<level>" for automatic decisions
+}
+
+// approvalCtx is the context key for the Approval being decided
+type approvalCtx struct{}
+
+// SetApprover records who answered an approval request.
+// ApprovalCallbacks call it with the ctx they were given so the audit log names the approver.
+func SetApprover(ctx context.Context, approver string) {
+	if a, ok := ctx.Value(approvalCtx{}).(*Approval); ok {
+		a.Approver = approver
+	}
 }
 
 // RequestApproval asks the user for approval
 func (p *Policy) RequestApproval(ctx context.Context, toolName string, input json.RawMessage) (bool, error) {
+	approval, err := p.Decide(ctx, toolName, input)
+	if err != nil {
+		return false, err
+	}
+	return approval.Approved, nil
+}
+
+// Decide asks for approval like RequestApproval and reports the channel that decided and who approved
+func (p *Policy) Decide(ctx context.Context, toolName string, input json.RawMessage) (*Approval, error) {
+	return p.decide(ctx, toolName, input, p.Evaluate(toolName, input, true))
+}
+
+// decide carries out a verdict, asking the user when it calls for approval
+func (p *Policy) decide(ctx context.Context, toolName string, input json.RawMessage, verdict Verdict) (*Approval, error) {
+	switch verdict.Action {
+	case RuleAllow:
+		return &Approval{Approved: true, Channel: audit.ChannelAuto, Approver: verdict.Approver}, nil
+	case RuleDeny:
+		return &Approval{Approved: false, Channel: audit.ChannelAuto, Approver: verdict.Approver}, nil
+	}
+
 	// Format the request nicely
 	var inputStr string
 	if toolName == "bash" {
@@ -150,39 +373,51 @@ func (p *Policy) RequestApproval(ctx context.Context, toolName string, input jso
 		inputStr = string(input)
 	}
 
-	// Check if we need to ask at all
-	if toolName == "bash" && !p.RequiresApproval(inputStr) {
-		return true, nil
-	}
-
 	// Use callback if set (for remote/web UI approval)
 	if p.ApprovalCallback != nil {
-		return p.ApprovalCallback(ctx, toolName, input)
+		approval := &Approval{Channel: audit.ChannelWeb}
+		approved, err := p.ApprovalCallback(context.WithValue(ctx, approvalCtx{}, approval), toolName, input)
+		if err != nil {
+			return nil, err
+		}
+		approval.Approved = approved
+		return approval, nil
 	}
 
 	// Fall back to stdin prompts for CLI mode
 	fmt.Printf("\n\033[33m⚠ Tool '%s' requires approval:\033[0m\n", toolName)
+	if verdict.Rule != nil {
+		fmt.Printf("\033[33m%s\033[0m\n", verdict.Reason)
+	}
 	fmt.Printf("\033[90m%s\033[0m\n", inputStr)
 	fmt.Print("\033[33mApprove? [y/N/a(lways)]: \033[0m")
 
 	reader := bufio.NewReader(os.Stdin)
 	response, err := reader.ReadString('\n')
 	if err != nil {
-		return false, err
+		return nil, err
 	}
 
 	response = strings.TrimSpace(strings.ToLower(response))
 
+	approval := &Approval{Channel: audit.ChannelCLI, Approver: terminalUser()}
 	switch response {
 	case "y", "yes":
-		return true, nil
+		approval.Approved = true
 	case "a", "always":
 		// Add to allowlist for this session
 		p.AddToAllowlist(inputStr)
-		return true, nil
-	default:
-		return false, nil
+		approval.Approved = true
+	}
+	return approval, nil
+}
+
+// terminalUser returns the name of the user answering terminal prompts
+func terminalUser() string {
+	if u, err := user.Current(); err == nil && u.Username != "" {
+		return u.Username
 	}
+	return os.Getenv("USER")
 }
 
 // AddToAllowlist adds a command pattern to the allowlist
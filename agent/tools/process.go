@@ -9,11 +9,15 @@ import (
 	"runtime"
 	"strconv"
 	"strings"
+	"sync"
 	"syscall"
 )
 
 // ProcessTool manages system processes
-type ProcessTool struct{}
+type ProcessTool struct {
+	mu     sync.RWMutex
+	policy *Policy
+}
 
 // ProcessInput defines the input for the process tool
 type ProcessInput struct {
@@ -34,8 +38,28 @@ type ProcessInfo struct {
 }
 
 // NewProcessTool creates a new process tool
-func NewProcessTool() *ProcessTool {
-	return &ProcessTool{}
+func NewProcessTool(policy *Policy) *ProcessTool {
+	return &ProcessTool{policy: policy}
+}
+
+// SetPolicy switches the policy that decides whether signals may be sent
+func (t *ProcessTool) SetPolicy(policy *Policy) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.policy = policy
+}
+
+// sandbox returns the name of the executor confining bash, or "" when bash runs on the host
+func (t *ProcessTool) sandbox() string {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	if t.policy == nil {
+		return ""
+	}
+	if name := t.policy.Executor().Name(); name != ExecutorHost {
+		return name
+	}
+	return ""
 }
 
 // Name returns the tool name
@@ -81,6 +105,11 @@ func (t *ProcessTool) RequiresApproval() bool {
 	return true
 }
 
+// SerialOnly returns true - process operations change shared system state
+func (t *ProcessTool) SerialOnly() bool {
+	return true
+}
+
 // Execute performs the process operation
 func (t *ProcessTool) Execute(ctx context.Context, input json.RawMessage) (*ToolResult, error) {
 	var params ProcessInput
@@ -101,6 +130,13 @@ func (t *ProcessTool) Execute(ctx context.Context, input json.RawMessage) (*Tool
 				IsError: true,
 			}, nil
 		}
+		// Sandboxed commands can't reach host processes, so neither may the agent
+		if sandbox := t.sandbox(); sandbox != "" {
+			return &ToolResult{
+				Content: fmt.Sprintf("Error: sending signals to host processes is disabled while commands run in the %s sandbox", sandbox),
+				IsError: true,
+			}, nil
+		}
 		return t.killProcess(params.PID, params.Signal)
 	case "info":
 		if params.PID <= 0 {
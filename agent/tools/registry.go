@@ -5,9 +5,13 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"sort"
 	"sync"
+	"time"
 
 	"gobot/agent/ai"
+	"gobot/agent/audit"
+	"gobot/agent/session"
 )
 
 // ToolResult represents the result of a tool execution
@@ -34,19 +38,116 @@ type Tool interface {
 	RequiresApproval() bool
 }
 
+// SerialTool is implemented by tools that must not run concurrently with other tool calls
+type SerialTool interface {
+	// SerialOnly returns true if the tool must run alone
+	SerialOnly() bool
+}
+
+// CallApprovalTool is implemented by tools that only need approval for some calls
+type CallApprovalTool interface {
+	// RequiresApprovalFor returns true if this call needs user approval
+	RequiresApprovalFor(input json.RawMessage) bool
+}
+
+// RequiresApproval returns true if a call to tool needs approval, before policy rules apply
+func RequiresApproval(tool Tool, input json.RawMessage) bool {
+	if ct, ok := tool.(CallApprovalTool); ok {
+		return ct.RequiresApprovalFor(input)
+	}
+	return tool.RequiresApproval()
+}
+
+// DefaultMaxParallel is the default number of tool calls run concurrently in one turn
+const DefaultMaxParallel = 4
+
 // Registry manages available tools
 type Registry struct {
-	mu     sync.RWMutex
-	tools  map[string]Tool
-	policy *Policy
+	mu          sync.RWMutex
+	tools       map[string]Tool
+	policy      *Policy
+	maxParallel int
+	serial      map[string]bool // Extra tools marked serial-only by config
+	auditLog    *audit.Log      // Records every call and approval decision (optional)
+	checkpoints CheckpointStore // Snapshots files before tools change them (optional)
+	jobs        *JobManager     // Background bash jobs
+
+	approvalMu sync.Mutex // Approvals are requested one at a time
 }
 
 // NewRegistry creates a new tool registry
 func NewRegistry(policy *Policy) *Registry {
 	return &Registry{
-		tools:  make(map[string]Tool),
-		policy: policy,
+		tools:       make(map[string]Tool),
+		policy:      policy,
+		maxParallel: DefaultMaxParallel,
+		serial:      make(map[string]bool),
+		jobs:        NewJobManager(),
+	}
+}
+
+// Jobs returns the background jobs started by the bash tool
+func (r *Registry) Jobs() *JobManager {
+	return r.jobs
+}
+
+// SetMaxParallel sets how many independent tool calls may run at once (1 = sequential)
+func (r *Registry) SetMaxParallel(n int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if n < 1 {
+		n = 1
 	}
+	r.maxParallel = n
+}
+
+// SetSerial marks additional tools as serial-only
+func (r *Registry) SetSerial(names ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, name := range names {
+		r.serial[name] = true
+	}
+}
+
+// IsSerial returns true if the named tool must not run concurrently with other calls
+func (r *Registry) IsSerial(name string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if r.serial[name] {
+		return true
+	}
+	if st, ok := r.tools[name].(SerialTool); ok {
+		return st.SerialOnly()
+	}
+	return false
+}
+
+// SetAuditLog records every tool call and approval decision to log
+func (r *Registry) SetAuditLog(log *audit.Log) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.auditLog = log
+}
+
+// FileTool is implemented by tools that change files, so they can be checkpointed first
+type FileTool interface {
+	// ModifiedPaths returns the files a call would change
+	ModifiedPaths(input json.RawMessage) []string
+}
+
+// CheckpointStore saves a file's contents before a tool call in a message changes it
+type CheckpointStore interface {
+	SaveCheckpoint(sessionID string, messageID int64, path string) error
+}
+
+// SetCheckpoints snapshots files before write and edit calls so runs can be undone.
+// Calls are attributed to a message with session.WithMessage.
+func (r *Registry) SetCheckpoints(store CheckpointStore) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.checkpoints = store
 }
 
 // Register adds a tool to the registry
@@ -56,6 +157,13 @@ func (r *Registry) Register(tool Tool) {
 	r.tools[tool.Name()] = tool
 }
 
+// Unregister removes a tool from the registry
+func (r *Registry) Unregister(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.tools, name)
+}
+
 // Get returns a tool by name
 func (r *Registry) Get(name string) (Tool, bool) {
 	r.mu.RLock()
@@ -64,7 +172,8 @@ func (r *Registry) Get(name string) (Tool, bool) {
 	return tool, ok
 }
 
-// List returns all tools as AI tool definitions
+// List returns all tools as AI tool definitions, sorted by name so the request prefix stays
+// the same between calls and can be served from the provider's prompt cache
 func (r *Registry) List() []ai.ToolDefinition {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
@@ -77,39 +186,147 @@ func (r *Registry) List() []ai.ToolDefinition {
 			InputSchema: tool.Schema(),
 		})
 	}
+	sort.Slice(defs, func(i, j int) bool { return defs[i].Name < defs[j].Name })
 	return defs
 }
 
 // Execute runs a tool and returns the result
 func (r *Registry) Execute(ctx context.Context, toolCall *ai.ToolCall) *ToolResult {
+	tool, approval, denied := r.authorize(ctx, toolCall)
+	if denied != nil {
+		return denied
+	}
+	return r.run(ctx, tool, approval, toolCall)
+}
+
+// ExecuteAll runs the tool calls of one turn, concurrently where safe, and returns results in call order.
+// Consecutive parallel-safe calls run together (up to the max parallelism); a serial-only call runs alone,
+// after everything before it and before anything after it. Approvals are requested one at a time,
+// in call order, before each group starts. onResult (optional) is called in call order as results complete.
+func (r *Registry) ExecuteAll(ctx context.Context, calls []*ai.ToolCall, onResult func(index int, result *ToolResult)) []*ToolResult {
+	results := make([]*ToolResult, len(calls))
+
+	r.mu.RLock()
+	maxParallel := r.maxParallel
+	r.mu.RUnlock()
+
+	for start := 0; start < len(calls); {
+		// Build the next group: one serial call, or a run of parallel-safe calls
+		end := start + 1
+		if maxParallel > 1 && !r.IsSerial(calls[start].Name) {
+			for end < len(calls) && end-start < maxParallel && !r.IsSerial(calls[end].Name) {
+				end++
+			}
+		}
+
+		// Approve sequentially so prompts never overlap
+		tools := make([]Tool, end-start)
+		approvals := make([]*Approval, end-start)
+		for i := start; i < end; i++ {
+			tool, approval, denied := r.authorize(ctx, calls[i])
+			if denied != nil {
+				results[i] = denied
+				continue
+			}
+			tools[i-start], approvals[i-start] = tool, approval
+		}
+
+		var wg sync.WaitGroup
+		for i := start; i < end; i++ {
+			if results[i] != nil {
+				continue
+			}
+			if end-start == 1 {
+				results[i] = r.run(ctx, tools[i-start], approvals[i-start], calls[i])
+				continue
+			}
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				results[i] = r.run(ctx, tools[i-start], approvals[i-start], calls[i])
+			}(i)
+		}
+		wg.Wait()
+
+		if onResult != nil {
+			for i := start; i < end; i++ {
+				onResult(i, results[i])
+			}
+		}
+		start = end
+	}
+
+	return results
+}
+
+// authorize looks up the tool and requests approval if needed.
+// Returns a non-nil error result if the call must not run; denials are audited here.
+func (r *Registry) authorize(ctx context.Context, toolCall *ai.ToolCall) (Tool, *Approval, *ToolResult) {
 	r.mu.RLock()
 	tool, ok := r.tools[toolCall.Name]
+	policy := r.policy
 	r.mu.RUnlock()
 
 	if !ok {
-		return &ToolResult{
+		result := &ToolResult{
 			Content: fmt.Sprintf("Unknown tool: %s", toolCall.Name),
 			IsError: true,
 		}
+		r.audit(ctx, toolCall, &Approval{}, result, 0)
+		return nil, nil, result
 	}
 
-	// Check if approval is required
-	if tool.RequiresApproval() && r.policy != nil {
-		approved, err := r.policy.RequestApproval(ctx, tool.Name(), toolCall.Input)
+	approval := &Approval{Approved: true, Channel: audit.ChannelAuto, Approver: "policy:no-approval-required"}
+
+	// Check the policy's rules and whether approval is required
+	if policy != nil {
+		verdict := policy.Evaluate(tool.Name(), toolCall.Input, RequiresApproval(tool, toolCall.Input))
+		if verdict.Action == RuleAsk {
+			r.approvalMu.Lock()
+		}
+		decided, err := policy.decide(ctx, tool.Name(), toolCall.Input, verdict)
+		if verdict.Action == RuleAsk {
+			r.approvalMu.Unlock()
+		}
 		if err != nil {
-			return &ToolResult{
+			result := &ToolResult{
 				Content: fmt.Sprintf("Approval error: %v", err),
 				IsError: true,
 			}
+			r.audit(ctx, toolCall, &Approval{}, result, 0)
+			return nil, nil, result
 		}
-		if !approved {
-			return &ToolResult{
+		approval = decided
+		if !approval.Approved {
+			result := &ToolResult{
 				Content: "Tool execution denied by user",
 				IsError: true,
 			}
+			if verdict.Action == RuleDeny {
+				result.Content = "Tool execution denied by policy: " + verdict.Reason
+			}
+			r.audit(ctx, toolCall, approval, result, 0)
+			return nil, nil, result
 		}
 	}
 
+	return tool, approval, nil
+}
+
+// run executes an approved tool, converting errors and panics into error results, and audits the call
+func (r *Registry) run(ctx context.Context, tool Tool, approval *Approval, toolCall *ai.ToolCall) (result *ToolResult) {
+	start := time.Now()
+	defer func() {
+		if p := recover(); p != nil {
+			result = &ToolResult{
+				Content: fmt.Sprintf("Tool error: panic: %v", p),
+				IsError: true,
+			}
+		}
+		r.audit(ctx, toolCall, approval, result, time.Since(start))
+	}()
+
+	r.checkpoint(ctx, tool, toolCall)
 	result, err := tool.Execute(ctx, toolCall.Input)
 	if err != nil {
 		return &ToolResult{
@@ -117,21 +334,85 @@ func (r *Registry) Execute(ctx context.Context, toolCall *ai.ToolCall) *ToolResu
 			IsError: true,
 		}
 	}
+	if result == nil {
+		result = &ToolResult{}
+	}
 
 	return result
 }
 
+// checkpoint snapshots the files a call will change, if a store is set and the call belongs to a message
+func (r *Registry) checkpoint(ctx context.Context, tool Tool, toolCall *ai.ToolCall) {
+	r.mu.RLock()
+	store := r.checkpoints
+	r.mu.RUnlock()
+	fileTool, ok := tool.(FileTool)
+	if store == nil || !ok {
+		return
+	}
+	sessionID, messageID, ok := session.MessageFrom(ctx)
+	if !ok {
+		return
+	}
+
+	for _, path := range fileTool.ModifiedPaths(toolCall.Input) {
+		if err := store.SaveCheckpoint(sessionID, messageID, path); err != nil {
+			fmt.Printf("[checkpoint] Warning: %v\n", err)
+		}
+	}
+}
+
+// audit writes a call to the audit log, if one is set
+func (r *Registry) audit(ctx context.Context, toolCall *ai.ToolCall, approval *Approval, result *ToolResult, duration time.Duration) {
+	r.mu.RLock()
+	log := r.auditLog
+	r.mu.RUnlock()
+	if log == nil {
+		return
+	}
+
+	err := log.Record(&audit.Entry{
+		SessionKey: audit.SessionFrom(ctx),
+		ToolCallID: toolCall.ID,
+		Tool:       toolCall.Name,
+		Input:      string(toolCall.Input),
+		Output:     result.Content,
+		IsError:    result.IsError,
+		Approved:   approval.Approved,
+		Channel:    approval.Channel,
+		Approver:   approval.Approver,
+		DurationMS: duration.Milliseconds(),
+	})
+	if err != nil {
+		fmt.Printf("[audit] Warning: %v\n", err)
+	}
+}
+
+// PolicyTool is implemented by tools whose behavior depends on the current policy
+type PolicyTool interface {
+	// SetPolicy is called when the registry's policy changes
+	SetPolicy(policy *Policy)
+}
+
 // SetPolicy updates the registry's policy
 func (r *Registry) SetPolicy(policy *Policy) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 	r.policy = policy
+	for _, tool := range r.tools {
+		if pt, ok := tool.(PolicyTool); ok {
+			pt.SetPolicy(policy)
+		}
+	}
 }
 
 // RegisterDefaults registers the default set of tools
 func (r *Registry) RegisterDefaults() {
 	// Core file tools
-	r.Register(NewBashTool(r.policy))
+	bash := NewBashTool(r.policy)
+	bash.SetJobs(r.jobs)
+	r.Register(bash)
+	r.Register(NewJobsTool(r.jobs))
 	r.Register(NewReadTool())
 	r.Register(NewWriteTool())
 	r.Register(NewEditTool())
@@ -139,7 +420,7 @@ func (r *Registry) RegisterDefaults() {
 	r.Register(NewGrepTool())
 
 	// Web tools
-	r.Register(NewWebTool())
+	r.Register(NewWebTool(WebConfig{}))
 	r.Register(NewSearchTool())
 
 	// Browser automation (headless by default)
@@ -159,7 +440,7 @@ func (r *Registry) RegisterDefaults() {
 	}
 
 	// Process management
-	r.Register(NewProcessTool())
+	r.Register(NewProcessTool(r.policy))
 
 	// Task/sub-agent spawning
 	r.Register(NewTaskTool())
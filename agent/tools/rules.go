@@ -0,0 +1,295 @@
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gobot/agent/config"
+)
+
+// RuleAction is the outcome of a policy rule
+type RuleAction string
+
+const (
+	RuleAllow RuleAction = "allow" // Run without asking
+	RuleAsk   RuleAction = "ask"   // Ask for approval even if the tool normally runs freely
+	RuleDeny  RuleAction = "deny"  // Refuse without asking
+)
+
+// rulePrecedence orders actions when several rules match: deny wins over ask, ask over allow
+var rulePrecedence = map[RuleAction]int{RuleDeny: 3, RuleAsk: 2, RuleAllow: 1}
+
+// pathArgs are argument names holding file system paths, matched against workspace-relative patterns
+var pathArgs = map[string]bool{
+	"path": true, "file_path": true, "file": true, "dir": true, "directory": true, "cwd": true,
+}
+
+// PolicyRule decides calls to matching tools by their arguments
+type PolicyRule struct {
+	Tool   string            // Tool name glob ("write", "mcp_github_*", "*")
+	Args   map[string]string // Argument patterns that must all match; "!" negates
+	Action RuleAction
+	Reason string // Shown when the rule asks or denies
+
+	args []argMatcher
+}
+
+// argMatcher matches one argument of a tool call
+type argMatcher struct {
+	name    string // Argument name; dots select nested fields
+	pattern string
+	negate  bool
+	re      *regexp.Regexp
+}
+
+// Verdict is what the policy decides for a tool call
+type Verdict struct {
+	Action   RuleAction
+	Rule     *PolicyRule // Matching rule, nil when the level and allowlist decided
+	Reason   string
+	Approver string // Recorded in the audit log when the policy decides without asking
+}
+
+// SetRules replaces the policy's argument rules with the ones from config.yaml.
+// Relative path patterns are resolved against the policy's workspace.
+func (p *Policy) SetRules(rules []config.PolicyRule) error {
+	parsed, err := parsePolicyRules(rules, p.Workspace)
+	if err != nil {
+		return err
+	}
+	p.Rules = parsed
+	return nil
+}
+
+// parsePolicyRules validates and compiles rules
+func parsePolicyRules(rules []config.PolicyRule, workspace string) ([]*PolicyRule, error) {
+	parsed := make([]*PolicyRule, 0, len(rules))
+	for i, rc := range rules {
+		rule := &PolicyRule{Tool: rc.Tool, Args: rc.Args, Action: RuleAction(rc.Action), Reason: rc.Reason}
+		if rule.Tool == "" {
+			return nil, fmt.Errorf("policy rule %d: tool is required", i+1)
+		}
+		if _, err := path.Match(rule.Tool, ""); err != nil {
+			return nil, fmt.Errorf("policy rule %d: invalid tool pattern %q", i+1, rule.Tool)
+		}
+		if _, ok := rulePrecedence[rule.Action]; !ok {
+			return nil, fmt.Errorf("policy rule %d: action must be allow, ask or deny, got %q", i+1, rc.Action)
+		}
+
+		names := make([]string, 0, len(rule.Args))
+		for name := range rule.Args {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			m, err := newArgMatcher(name, rule.Args[name], workspace)
+			if err != nil {
+				return nil, fmt.Errorf("policy rule %d: %w", i+1, err)
+			}
+			rule.args = append(rule.args, m)
+		}
+		parsed = append(parsed, rule)
+	}
+	return parsed, nil
+}
+
+// newArgMatcher compiles an argument pattern. Path arguments resolve relative patterns
+// against the workspace and "~/" against the home directory; url patterns without a
+// scheme match the host; everything else is a glob over the argument's value.
+func newArgMatcher(name, pattern, workspace string) (argMatcher, error) {
+	m := argMatcher{name: name, pattern: pattern}
+	if strings.HasPrefix(pattern, "!") {
+		m.negate = true
+		pattern = pattern[1:]
+	}
+	if pattern == "" {
+		return m, fmt.Errorf("empty pattern for argument %q", name)
+	}
+
+	if pathArgs[lastField(name)] {
+		pattern = resolveRulePath(pattern, workspace)
+	}
+	re, err := globRegexp(pattern)
+	if err != nil {
+		return m, fmt.Errorf("invalid pattern %q for argument %q: %w", m.pattern, name, err)
+	}
+	m.re = re
+	return m, nil
+}
+
+// matches reports whether the call's arguments satisfy the matcher
+func (m *argMatcher) matches(args map[string]any, workspace string) bool {
+	value, ok := lookupArg(args, m.name)
+	if !ok {
+		return false // A rule about an argument never applies to calls without it
+	}
+
+	switch field := lastField(m.name); {
+	case pathArgs[field]:
+		value = resolveRulePath(value, workspace)
+	case field == "url" && !strings.Contains(m.pattern, "://"):
+		if u, err := url.Parse(value); err == nil && u.Host != "" {
+			value = strings.ToLower(u.Hostname())
+		}
+	}
+	return m.re.MatchString(value) != m.negate
+}
+
+// Matches reports whether the rule applies to a call
+func (r *PolicyRule) Matches(toolName string, args map[string]any, workspace string) bool {
+	if ok, _ := path.Match(r.Tool, toolName); !ok {
+		return false
+	}
+	for i := range r.args {
+		if !r.args[i].matches(args, workspace) {
+			return false
+		}
+	}
+	return true
+}
+
+// String describes the rule for prompts and the audit log
+func (r *PolicyRule) String() string {
+	parts := []string{r.Tool}
+	for _, m := range r.args {
+		parts = append(parts, m.name+"="+m.pattern)
+	}
+	return strings.Join(parts, " ")
+}
+
+// Evaluate decides a tool call. Matching rules win, most restrictive first (a bash allow rule
+// only covers a single plain command or an allowlisted one); without one,
+// tools that don't require approval are allowed and bash commands are checked against
+// the level and allowlist.
+func (p *Policy) Evaluate(toolName string, input json.RawMessage, requiresApproval bool) Verdict {
+	if len(p.Rules) > 0 {
+		var args map[string]any
+		json.Unmarshal(input, &args)
+
+		var best *PolicyRule
+		for _, rule := range p.Rules {
+			if rule.Matches(toolName, args, p.Workspace) && (best == nil || rulePrecedence[rule.Action] > rulePrecedence[best.Action]) {
+				best = rule
+			}
+		}
+		if best != nil && best.Action == RuleAllow && toolName == "bash" {
+			// The rule vouches for the command it matched, not for anything chained onto it
+			command, _ := lookupArg(args, "command")
+			if err := p.checkRuleCommand(command); err != nil {
+				return Verdict{Action: RuleAsk, Rule: best, Reason: fmt.Sprintf("%v (beyond rule: %s)", err, best)}
+			}
+		}
+		if best != nil {
+			reason := best.Reason
+			if reason == "" {
+				reason = fmt.Sprintf("%s by rule: %s", best.Action, best)
+			}
+			return Verdict{Action: best.Action, Rule: best, Reason: reason, Approver: "policy:rule:" + best.String()}
+		}
+	}
+
+	if !requiresApproval {
+		return Verdict{Action: RuleAllow, Reason: "tool does not require approval", Approver: "policy:no-approval-required"}
+	}
+	if toolName == "bash" {
+		var bashInput struct {
+			Command string `json:"command"`
+		}
+		json.Unmarshal(input, &bashInput)
+		if !p.RequiresApproval(bashInput.Command) {
+			return Verdict{Action: RuleAllow, Reason: fmt.Sprintf("allowed by policy level %s", p.Level), Approver: "policy:" + string(p.Level)}
+		}
+		if p.Level == PolicyAllowlist {
+			if err := p.checkCommand(bashInput.Command); err != nil {
+				return Verdict{Action: RuleAsk, Reason: fmt.Sprintf("%v (policy level %s)", err, p.Level)}
+			}
+		}
+	}
+	return Verdict{Action: RuleAsk, Reason: fmt.Sprintf("tool requires approval (policy level %s)", p.Level)}
+}
+
+// lookupArg returns an argument as a string; dotted names select nested fields
+func lookupArg(args map[string]any, name string) (string, bool) {
+	var value any = args
+	for _, key := range strings.Split(name, ".") {
+		obj, ok := value.(map[string]any)
+		if !ok {
+			return "", false
+		}
+		if value, ok = obj[key]; !ok || value == nil {
+			return "", false
+		}
+	}
+
+	switch v := value.(type) {
+	case string:
+		return v, true
+	case map[string]any, []any:
+		data, _ := json.Marshal(v)
+		return string(data), true
+	default:
+		return fmt.Sprint(v), true
+	}
+}
+
+// lastField returns the final component of a dotted argument name
+func lastField(name string) string {
+	return name[strings.LastIndex(name, ".")+1:]
+}
+
+// resolveRulePath makes a path or path pattern absolute and clean
+func resolveRulePath(p, workspace string) string {
+	if p == "~" || strings.HasPrefix(p, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			p = filepath.Join(home, p[1:])
+		}
+	}
+	if !filepath.IsAbs(p) {
+		p = filepath.Join(workspace, p)
+	}
+	return filepath.Clean(p)
+}
+
+// globRegexp converts a glob to a regexp: * matches within a path segment, ** across
+// segments (a trailing /** also matches the directory itself) and ? one character
+func globRegexp(pattern string) (*regexp.Regexp, error) {
+	var sb strings.Builder
+	sb.WriteString("^")
+	literal := 0 // Start of the pending run of literal bytes
+	for i := 0; i < len(pattern); i++ {
+		c := pattern[i]
+		if c != '*' && c != '?' && c != '/' {
+			continue
+		}
+		sb.WriteString(regexp.QuoteMeta(pattern[literal:i]))
+		switch c {
+		case '*':
+			if i+1 < len(pattern) && pattern[i+1] == '*' {
+				i++
+				sb.WriteString(".*")
+			} else {
+				sb.WriteString("[^/]*")
+			}
+		case '?':
+			sb.WriteString("[^/]")
+		case '/':
+			if strings.HasPrefix(pattern[i:], "/**") && i+3 == len(pattern) {
+				sb.WriteString("(/.*)?")
+				i += 2
+			} else {
+				sb.WriteString("/")
+			}
+		}
+		literal = i + 1
+	}
+	sb.WriteString(regexp.QuoteMeta(pattern[literal:]))
+	sb.WriteString("$")
+	return regexp.Compile(sb.String())
+}
@@ -7,6 +7,7 @@ import (
 	"strings"
 	"time"
 
+	"gobot/agent/audit"
 	"gobot/agent/session"
 )
 
@@ -17,9 +18,11 @@ type SessionsTool struct {
 
 // SessionsInput defines the input for the sessions tool
 type SessionsInput struct {
-	Action     string `json:"action"`                // "list", "history", "status", "clear"
-	SessionKey string `json:"session_key,omitempty"` // Session key (for history/status/clear)
+	Action     string `json:"action"`                // "list", "history", "status", "clear", "fork", "rewind", "undo", "tree"
+	SessionKey string `json:"session_key,omitempty"` // Session key (for history/status/clear/fork/rewind/undo)
 	Limit      int    `json:"limit,omitempty"`       // Max messages to return (for history)
+	MessageID  int64  `json:"message_id,omitempty"`  // Message to fork at, rewind to or undo file changes after
+	NewKey     string `json:"new_key,omitempty"`     // Key for the forked session
 }
 
 // NewSessionsTool creates a new sessions tool
@@ -36,7 +39,7 @@ func (t *SessionsTool) Name() string {
 
 // Description returns the tool description
 func (t *SessionsTool) Description() string {
-	return "Query and manage conversation sessions. List all sessions, view history, check status, or clear a session."
+	return "Query and manage conversation sessions. List all sessions, view history, check status, clear a session, fork a session at a message into a new key, rewind a session to an earlier message, undo the file changes made by write and edit calls, or show the fork tree."
 }
 
 // Schema returns the JSON schema
@@ -46,12 +49,20 @@ func (t *SessionsTool) Schema() json.RawMessage {
 		"properties": {
 			"action": {
 				"type": "string",
-				"description": "Action to perform: 'list' (all sessions), 'history' (view messages), 'status' (session info), 'clear' (reset session)",
-				"enum": ["list", "history", "status", "clear"]
+				"description": "Action to perform: 'list' (all sessions), 'history' (view messages with IDs), 'status' (session info), 'clear' (reset session), 'fork' (copy up to a message into a new session), 'rewind' (remove messages after a message), 'undo' (restore files changed by write/edit after a message, default: the last run), 'tree' (sessions and their forks)",
+				"enum": ["list", "history", "status", "clear", "fork", "rewind", "undo", "tree"]
 			},
 			"session_key": {
 				"type": "string",
-				"description": "Session key (required for history/status/clear). Use 'list' action first to see available keys."
+				"description": "Session key (required for history/status/clear/fork/rewind, default for undo: the current session). Use 'list' action first to see available keys."
+			},
+			"message_id": {
+				"type": "integer",
+				"description": "Message ID from 'history' to fork at (default: latest), rewind to (required for rewind) or undo file changes after (default: the last run)"
+			},
+			"new_key": {
+				"type": "string",
+				"description": "Key for the forked session (default: <session_key>-fork-N)"
 			},
 			"limit": {
 				"type": "integer",
@@ -112,9 +123,38 @@ func (t *SessionsTool) Execute(ctx context.Context, input json.RawMessage) (*Too
 			}, nil
 		}
 		return t.clearSession(params.SessionKey)
+	case "fork":
+		if params.SessionKey == "" {
+			return &ToolResult{
+				Content: "Error: 'session_key' is required for fork action",
+				IsError: true,
+			}, nil
+		}
+		return t.forkSession(params.SessionKey, params.MessageID, params.NewKey)
+	case "rewind":
+		if params.SessionKey == "" || params.MessageID == 0 {
+			return &ToolResult{
+				Content: "Error: 'session_key' and 'message_id' are required for rewind action",
+				IsError: true,
+			}, nil
+		}
+		return t.rewindSession(params.SessionKey, params.MessageID)
+	case "undo":
+		if params.SessionKey == "" {
+			params.SessionKey = audit.SessionFrom(ctx)
+		}
+		if params.SessionKey == "" {
+			return &ToolResult{
+				Content: "Error: 'session_key' is required for undo action",
+				IsError: true,
+			}, nil
+		}
+		return t.undoFiles(params.SessionKey, params.MessageID)
+	case "tree":
+		return t.sessionTree()
 	default:
 		return &ToolResult{
-			Content: fmt.Sprintf("Unknown action: %s. Use 'list', 'history', 'status', or 'clear'", params.Action),
+			Content: fmt.Sprintf("Unknown action: %s. Use 'list', 'history', 'status', 'clear', 'fork', 'rewind', 'undo', or 'tree'", params.Action),
 			IsError: true,
 		}, nil
 	}
@@ -201,7 +241,7 @@ func (t *SessionsTool) sessionHistory(sessionKey string, limit int) (*ToolResult
 			roleIcon = "🔧"
 		}
 
-		sb.WriteString(fmt.Sprintf("%d. %s [%s]\n", i+1, roleIcon, msg.Role))
+		sb.WriteString(fmt.Sprintf("%d. %s [%s] (id %d)\n", i+1, roleIcon, msg.Role, msg.ID))
 
 		if msg.Content != "" {
 			content := msg.Content
@@ -326,3 +366,126 @@ func (t *SessionsTool) clearSession(sessionKey string) (*ToolResult, error) {
 		IsError: false,
 	}, nil
 }
+
+// forkSession copies a session up to a message into a new session
+func (t *SessionsTool) forkSession(sessionKey string, messageID int64, newKey string) (*ToolResult, error) {
+	sess, err := t.sessions.GetByKey(sessionKey)
+	if err != nil {
+		return &ToolResult{
+			Content: fmt.Sprintf("Error getting session: %v", err),
+			IsError: true,
+		}, nil
+	}
+
+	fork, err := t.sessions.Fork(sess.ID, messageID, newKey)
+	if err != nil {
+		return &ToolResult{
+			Content: fmt.Sprintf("Error forking session: %v", err),
+			IsError: true,
+		}, nil
+	}
+
+	return &ToolResult{
+		Content: fmt.Sprintf("Forked %s at message %d into new session: %s", sessionKey, fork.ForkMessageID, fork.SessionKey),
+		IsError: false,
+	}, nil
+}
+
+// rewindSession removes all messages after a message
+func (t *SessionsTool) rewindSession(sessionKey string, messageID int64) (*ToolResult, error) {
+	sess, err := t.sessions.GetByKey(sessionKey)
+	if err != nil {
+		return &ToolResult{
+			Content: fmt.Sprintf("Error getting session: %v", err),
+			IsError: true,
+		}, nil
+	}
+
+	removed, err := t.sessions.Rewind(sess.ID, messageID)
+	if err != nil {
+		return &ToolResult{
+			Content: fmt.Sprintf("Error rewinding session: %v", err),
+			IsError: true,
+		}, nil
+	}
+
+	return &ToolResult{
+		Content: fmt.Sprintf("Rewound %s to message %d (%d messages removed)", sessionKey, messageID, removed),
+		IsError: false,
+	}, nil
+}
+
+// undoFiles restores files changed by write and edit calls after a message
+func (t *SessionsTool) undoFiles(sessionKey string, messageID int64) (*ToolResult, error) {
+	sess, err := t.sessions.GetByKey(sessionKey)
+	if err != nil {
+		return &ToolResult{
+			Content: fmt.Sprintf("Error getting session: %v", err),
+			IsError: true,
+		}, nil
+	}
+
+	restored, err := t.sessions.RestoreCheckpoints(sess.ID, messageID)
+	if len(restored) == 0 && err != nil {
+		return &ToolResult{
+			Content: fmt.Sprintf("Error undoing file changes: %v", err),
+			IsError: true,
+		}, nil
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("Restored %d files in %s:\n", len(restored), sessionKey))
+	for _, f := range restored {
+		if f.Deleted {
+			sb.WriteString(fmt.Sprintf("- %s (deleted, created at message %d)\n", f.Path, f.MessageID))
+		} else {
+			sb.WriteString(fmt.Sprintf("- %s (as before message %d)\n", f.Path, f.MessageID))
+		}
+	}
+	if err != nil {
+		sb.WriteString(fmt.Sprintf("\nSome files could not be restored: %v\n", err))
+	}
+
+	return &ToolResult{
+		Content: sb.String(),
+		IsError: err != nil,
+	}, nil
+}
+
+// sessionTree shows sessions with their forks
+func (t *SessionsTool) sessionTree() (*ToolResult, error) {
+	roots, err := t.sessions.Tree()
+	if err != nil {
+		return &ToolResult{
+			Content: fmt.Sprintf("Error listing sessions: %v", err),
+			IsError: true,
+		}, nil
+	}
+
+	if len(roots) == 0 {
+		return &ToolResult{
+			Content: "No sessions found.",
+			IsError: false,
+		}, nil
+	}
+
+	var sb strings.Builder
+	var writeNodes func(nodes []*session.SessionNode, depth int)
+	writeNodes = func(nodes []*session.SessionNode, depth int) {
+		for _, n := range nodes {
+			sb.WriteString(strings.Repeat("  ", depth))
+			sb.WriteString("- " + n.SessionKey)
+			if n.ParentID != "" {
+				sb.WriteString(fmt.Sprintf(" (forked at message %d)", n.ForkMessageID))
+			}
+			sb.WriteString(fmt.Sprintf(", %d messages\n", n.MessageCount))
+			writeNodes(n.Children, depth+1)
+		}
+	}
+	writeNodes(roots, 0)
+
+	return &ToolResult{
+		Content: sb.String(),
+		IsError: false,
+	}, nil
+}
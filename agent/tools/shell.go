@@ -0,0 +1,644 @@
+package tools
+
+import (
+	"fmt"
+	"strings"
+)
+
+// shellList is a sequence of pipelines joined by ;, &, &&, || or newlines
+type shellList struct {
+	Pipelines []*shellPipeline
+}
+
+// shellPipeline is one or more commands joined by | or |&
+type shellPipeline struct {
+	Commands []*shellCommand
+	Negated  bool
+}
+
+// shellCommand is a simple command, a ( subshell ) or a { group; }
+type shellCommand struct {
+	Assigns   []shellWord // NAME=value prefixes
+	Words     []shellWord
+	Redirects []shellRedirect
+	Body      *shellList // Subshell or group body
+}
+
+// shellRedirect is a redirection such as >file, 2>&1 or <<EOF
+type shellRedirect struct {
+	Op      string // <, >, >>, >|, <>, &>, &>>, >&, <&, <<, <<-, <<<
+	Target  shellWord
+	Heredoc *shellWord // Here-document body
+
+	quoted bool // Here-document delimiter was quoted, so the body isn't expanded
+}
+
+// shellWord is a word after quote removal. Expansions are kept verbatim in Text.
+type shellWord struct {
+	Text    string
+	Literal bool         // No parameter, arithmetic or command expansion
+	Glob    bool         // Unquoted glob or brace characters
+	Subs    []*shellList // Command and process substitutions inside the word
+}
+
+// shellReserved are words that start compound commands the parser doesn't model
+var shellReserved = map[string]bool{
+	"if": true, "then": true, "elif": true, "else": true, "fi": true,
+	"for": true, "while": true, "until": true, "do": true, "done": true,
+	"case": true, "esac": true, "select": true, "function": true,
+	"time": true, "coproc": true, "[[": true, "]]": true, "}": true,
+}
+
+// shellParser is a recursive descent parser for the subset of bash the policy reasons about.
+// Anything outside that subset is a parse error, so the command needs approval.
+type shellParser struct {
+	src      string
+	pos      int
+	heredocs []*shellRedirect // Waiting for their bodies after the next newline
+}
+
+// parseShell parses a bash command line
+func parseShell(src string) (*shellList, error) {
+	p := &shellParser{src: src}
+	list, err := p.parseList("")
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.src) {
+		return nil, fmt.Errorf("unexpected %q", p.src[p.pos])
+	}
+	if err := p.readHeredocs(); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// parseList parses pipelines until the end of input or the closing token ("" for top level, ")" or "}")
+func (p *shellParser) parseList(closer string) (*shellList, error) {
+	list := &shellList{}
+	needCommand := false // After && or ||
+	for {
+		p.skipBlanks()
+		if needCommand && (p.pos >= len(p.src) || strings.ContainsRune(";&)}", rune(p.src[p.pos]))) {
+			return nil, fmt.Errorf("missing command after && or ||")
+		}
+		if p.pos >= len(p.src) {
+			if closer != "" {
+				return nil, fmt.Errorf("missing %q", closer)
+			}
+			return list, nil
+		}
+
+		switch c := p.src[p.pos]; {
+		case c == '\n':
+			p.pos++
+			if err := p.readHeredocs(); err != nil {
+				return nil, err
+			}
+			continue
+		case c == ';' || c == '&':
+			if len(list.Pipelines) == 0 || strings.HasPrefix(p.src[p.pos:], ";;") || strings.HasPrefix(p.src[p.pos:], "&&") {
+				return nil, fmt.Errorf("unexpected %q", c)
+			}
+			p.pos++
+			continue
+		case closer == ")" && c == ')':
+			p.pos++
+			return list, nil
+		case closer == "}" && p.atWord("}"):
+			p.pos++
+			return list, nil
+		}
+
+		pipeline, err := p.parsePipeline()
+		if err != nil {
+			return nil, err
+		}
+		list.Pipelines = append(list.Pipelines, pipeline)
+
+		p.skipBlanks()
+		needCommand = strings.HasPrefix(p.src[p.pos:], "&&") || strings.HasPrefix(p.src[p.pos:], "||")
+		if needCommand {
+			p.pos += 2
+			if err := p.skipBlanksAndNewlines(); err != nil {
+				return nil, err
+			}
+		}
+	}
+}
+
+// parsePipeline parses [!] command [| command]...
+func (p *shellParser) parsePipeline() (*shellPipeline, error) {
+	pipeline := &shellPipeline{}
+	if p.atWord("!") {
+		pipeline.Negated = true
+		p.pos++
+		p.skipBlanks()
+	}
+	for {
+		cmd, err := p.parseCommand()
+		if err != nil {
+			return nil, err
+		}
+		pipeline.Commands = append(pipeline.Commands, cmd)
+
+		p.skipBlanks()
+		if p.pos >= len(p.src) || p.src[p.pos] != '|' || strings.HasPrefix(p.src[p.pos:], "||") {
+			return pipeline, nil
+		}
+		p.pos++
+		if p.pos < len(p.src) && p.src[p.pos] == '&' {
+			p.pos++
+		}
+		if err := p.skipBlanksAndNewlines(); err != nil {
+			return nil, err
+		}
+		if p.pos >= len(p.src) {
+			return nil, fmt.Errorf("missing command after |")
+		}
+	}
+}
+
+// parseCommand parses a subshell, group or simple command with its redirections
+func (p *shellParser) parseCommand() (*shellCommand, error) {
+	cmd := &shellCommand{}
+	switch {
+	case strings.HasPrefix(p.src[p.pos:], "(("):
+		return nil, fmt.Errorf("unsupported shell syntax: ((")
+	case p.src[p.pos] == '(':
+		p.pos++
+		body, err := p.parseList(")")
+		if err != nil {
+			return nil, err
+		}
+		cmd.Body = body
+	case p.atWord("{"):
+		p.pos++
+		body, err := p.parseList("}")
+		if err != nil {
+			return nil, err
+		}
+		cmd.Body = body
+	}
+
+	for {
+		p.skipBlanks()
+		if p.pos >= len(p.src) || strings.ContainsRune(";&|)\n", rune(p.src[p.pos])) && !p.atRedirect() {
+			break
+		}
+		if cmd.Body == nil && len(cmd.Words) == 0 && p.atWord("}") {
+			break // Closes the enclosing group
+		}
+
+		if p.atRedirect() {
+			r, err := p.parseRedirect()
+			if err != nil {
+				return nil, err
+			}
+			cmd.Redirects = append(cmd.Redirects, r)
+			continue
+		}
+		if cmd.Body != nil {
+			return nil, fmt.Errorf("unexpected word after %q", ")")
+		}
+		if p.src[p.pos] == '(' {
+			return nil, fmt.Errorf("unexpected %q", "(")
+		}
+
+		word, err := p.parseWord()
+		if err != nil {
+			return nil, err
+		}
+		if len(cmd.Words) == 0 {
+			if isAssignment(word.Text) {
+				cmd.Assigns = append(cmd.Assigns, word)
+				continue
+			}
+			if word.Literal && shellReserved[word.Text] {
+				return nil, fmt.Errorf("unsupported shell syntax: %s", word.Text)
+			}
+		}
+		cmd.Words = append(cmd.Words, word)
+	}
+
+	if cmd.Body == nil && len(cmd.Words) == 0 && len(cmd.Assigns) == 0 && len(cmd.Redirects) == 0 {
+		if p.pos < len(p.src) {
+			return nil, fmt.Errorf("unexpected %q", p.src[p.pos])
+		}
+		return nil, fmt.Errorf("missing command")
+	}
+	return cmd, nil
+}
+
+// atRedirect reports whether a redirection starts at the current position
+func (p *shellParser) atRedirect() bool {
+	i := p.pos
+	for i < len(p.src) && p.src[i] >= '0' && p.src[i] <= '9' {
+		i++
+	}
+	if i == p.pos && strings.HasPrefix(p.src[i:], "&>") {
+		return true
+	}
+	if i == p.pos && strings.HasPrefix(p.src[i:], "{") {
+		if end := strings.IndexByte(p.src[i:], '}'); end > 1 && isName(p.src[i+1:i+end]) {
+			i += end + 1 // {varname}>file
+		}
+	}
+	if i >= len(p.src) || (p.src[i] != '<' && p.src[i] != '>') {
+		return false
+	}
+	return !strings.HasPrefix(p.src[i:], "<(") && !strings.HasPrefix(p.src[i:], ">(")
+}
+
+// parseRedirect parses a redirection and its target
+func (p *shellParser) parseRedirect() (shellRedirect, error) {
+	for p.pos < len(p.src) && p.src[p.pos] != '<' && p.src[p.pos] != '>' && p.src[p.pos] != '&' {
+		p.pos++ // File descriptor or {varname}
+	}
+
+	var r shellRedirect
+	for _, op := range []string{"&>>", "<<<", "<<-", "&>", ">>", ">|", "<>", ">&", "<&", "<<", "<", ">"} {
+		if strings.HasPrefix(p.src[p.pos:], op) {
+			r.Op = op
+			break
+		}
+	}
+	p.pos += len(r.Op)
+	p.skipBlanks()
+	if p.pos >= len(p.src) || strings.ContainsRune(";&|()<>\n", rune(p.src[p.pos])) {
+		return r, fmt.Errorf("missing target for %q", r.Op)
+	}
+
+	start := p.pos
+	target, err := p.parseWord()
+	if err != nil {
+		return r, err
+	}
+	r.Target = target
+	if r.Op == "<<" || r.Op == "<<-" {
+		r.quoted = strings.ContainsAny(p.src[start:p.pos], `'"\`)
+		r.Heredoc = &shellWord{Literal: true}
+		p.heredocs = append(p.heredocs, &r)
+	}
+	return r, nil
+}
+
+// readHeredocs reads the bodies of pending here-documents, which start after a newline
+func (p *shellParser) readHeredocs() error {
+	pending := p.heredocs
+	p.heredocs = nil
+	for _, r := range pending {
+		delim := r.Target.Text
+		var body strings.Builder
+		for {
+			if p.pos >= len(p.src) {
+				break // Unterminated: bash reads to end of input
+			}
+			line := p.src[p.pos:]
+			if end := strings.IndexByte(line, '\n'); end >= 0 {
+				line = line[:end]
+				p.pos += end + 1
+			} else {
+				p.pos = len(p.src)
+			}
+			check := line
+			if r.Op == "<<-" {
+				check = strings.TrimLeft(line, "\t")
+			}
+			if check == delim {
+				break
+			}
+			body.WriteString(line + "\n")
+		}
+
+		// Unquoted delimiters expand parameters and commands in the body
+		word := &shellWord{Text: body.String(), Literal: true}
+		if !r.quoted && strings.ContainsAny(word.Text, "$`") {
+			sub := &shellParser{src: word.Text}
+			expanded, err := sub.parseQuoted(true)
+			if err != nil {
+				return fmt.Errorf("here-document: %w", err)
+			}
+			word = &expanded
+		}
+		*r.Heredoc = *word
+	}
+	return nil
+}
+
+// parseWord parses one word: literal text, quotes, escapes and expansions up to the next metacharacter
+func (p *shellParser) parseWord() (shellWord, error) {
+	word := shellWord{Literal: true}
+	var text strings.Builder
+	start := p.pos
+
+	if strings.HasPrefix(p.src[p.pos:], "<(") || strings.HasPrefix(p.src[p.pos:], ">(") {
+		p.pos += 2
+		sub, err := p.parseList(")")
+		if err != nil {
+			return word, err
+		}
+		word.Literal = false
+		word.Subs = append(word.Subs, sub)
+		word.Text = p.src[start:p.pos]
+		return word, nil
+	}
+
+	for p.pos < len(p.src) {
+		c := p.src[p.pos]
+		if strings.ContainsRune(" \t\n;&|()<>", rune(c)) {
+			break
+		}
+		switch c {
+		case '\\':
+			if p.pos+1 < len(p.src) {
+				if p.src[p.pos+1] != '\n' {
+					text.WriteByte(p.src[p.pos+1])
+				}
+				p.pos += 2
+			} else {
+				p.pos++
+			}
+		case '\'':
+			end := strings.IndexByte(p.src[p.pos+1:], '\'')
+			if end < 0 {
+				return word, fmt.Errorf("unterminated single quote")
+			}
+			text.WriteString(p.src[p.pos+1 : p.pos+1+end])
+			p.pos += end + 2
+		case '"':
+			p.pos++
+			inner, err := p.parseQuoted(false)
+			if err != nil {
+				return word, err
+			}
+			text.WriteString(inner.Text)
+			word.merge(inner)
+		case '$', '`':
+			exp, err := p.parseExpansion()
+			if err != nil {
+				return word, err
+			}
+			text.WriteString(exp.Text)
+			word.merge(exp)
+		default:
+			if strings.ContainsRune("*?[{", rune(c)) {
+				word.Glob = true
+			}
+			text.WriteByte(c)
+			p.pos++
+		}
+	}
+
+	word.Text = text.String()
+	return word, nil
+}
+
+// parseQuoted parses the inside of double quotes up to the closing quote, or a
+// here-document body to the end of input
+func (p *shellParser) parseQuoted(heredoc bool) (shellWord, error) {
+	word := shellWord{Literal: true}
+	var text strings.Builder
+	for {
+		if p.pos >= len(p.src) {
+			if heredoc {
+				break
+			}
+			return word, fmt.Errorf("unterminated double quote")
+		}
+		c := p.src[p.pos]
+		if c == '"' && !heredoc {
+			p.pos++
+			break
+		}
+		switch c {
+		case '\\':
+			if p.pos+1 < len(p.src) && strings.ContainsRune("$`\"\\\n", rune(p.src[p.pos+1])) {
+				if p.src[p.pos+1] != '\n' {
+					text.WriteByte(p.src[p.pos+1])
+				}
+				p.pos += 2
+			} else {
+				text.WriteByte(c)
+				p.pos++
+			}
+		case '$', '`':
+			exp, err := p.parseExpansion()
+			if err != nil {
+				return word, err
+			}
+			text.WriteString(exp.Text)
+			word.merge(exp)
+		default:
+			text.WriteByte(c)
+			p.pos++
+		}
+	}
+	word.Text = text.String()
+	return word, nil
+}
+
+// parseExpansion parses $name, ${...}, $(...), $((...)), `...`, $'...' and $"..."
+func (p *shellParser) parseExpansion() (shellWord, error) {
+	start := p.pos
+	word := shellWord{}
+	rest := p.src[p.pos:]
+
+	switch {
+	case rest[0] == '`':
+		p.pos++
+		var inner strings.Builder
+		for {
+			if p.pos >= len(p.src) {
+				return word, fmt.Errorf("unterminated backquote")
+			}
+			c := p.src[p.pos]
+			if c == '`' {
+				p.pos++
+				break
+			}
+			if c == '\\' && p.pos+1 < len(p.src) && strings.ContainsRune("$`\\", rune(p.src[p.pos+1])) {
+				p.pos++
+				c = p.src[p.pos]
+			}
+			inner.WriteByte(c)
+			p.pos++
+		}
+		sub, err := parseShell(inner.String())
+		if err != nil {
+			return word, fmt.Errorf("command substitution: %w", err)
+		}
+		word.Subs = append(word.Subs, sub)
+	case strings.HasPrefix(rest, "$(("):
+		end := strings.Index(rest, "))")
+		if end < 0 {
+			return word, fmt.Errorf("unterminated arithmetic expansion")
+		}
+		// Bash evaluates variables in arithmetic recursively, including array subscripts
+		// that run commands, so only plain numeric expressions are understood
+		if expr := rest[3:end]; strings.Trim(expr, "0123456789+-*/%() \t") != "" {
+			return word, fmt.Errorf("unsupported arithmetic expansion: %s", rest[:end+2])
+		}
+		p.pos += end + 2
+	case strings.HasPrefix(rest, "$("):
+		p.pos += 2
+		sub, err := p.parseList(")")
+		if err != nil {
+			return word, fmt.Errorf("command substitution: %w", err)
+		}
+		word.Subs = append(word.Subs, sub)
+	case strings.HasPrefix(rest, "${"):
+		p.pos += 2
+		depth := 1
+		for depth > 0 {
+			if p.pos >= len(p.src) {
+				return word, fmt.Errorf("unterminated parameter expansion")
+			}
+			switch c := p.src[p.pos]; c {
+			case '{':
+				depth++
+				p.pos++
+			case '}':
+				depth--
+				p.pos++
+			case '\\':
+				p.pos += 2
+			case '\'':
+				end := strings.IndexByte(p.src[p.pos+1:], '\'')
+				if end < 0 {
+					return word, fmt.Errorf("unterminated single quote")
+				}
+				p.pos += end + 2
+			case '"':
+				p.pos++
+				inner, err := p.parseQuoted(false)
+				if err != nil {
+					return word, err
+				}
+				word.Subs = append(word.Subs, inner.Subs...)
+			case '$', '`':
+				inner, err := p.parseExpansion()
+				if err != nil {
+					return word, err
+				}
+				word.Subs = append(word.Subs, inner.Subs...)
+			default:
+				p.pos++
+			}
+		}
+		// ${var@P} expands the value like a prompt, which runs command substitutions in it
+		if strings.HasSuffix(p.src[start:p.pos], "@P}") {
+			return word, fmt.Errorf("unsupported parameter expansion: %s", p.src[start:p.pos])
+		}
+	case strings.HasPrefix(rest, "$'"):
+		end := 2
+		for end < len(rest) && rest[end] != '\'' {
+			if rest[end] == '\\' {
+				end++
+			}
+			end++
+		}
+		if end >= len(rest) {
+			return word, fmt.Errorf("unterminated $' quote")
+		}
+		p.pos += end + 1
+		// Escapes like \x72 can spell any command, so only escape-free strings are literal
+		if text := rest[2:end]; !strings.Contains(text, `\`) {
+			return shellWord{Text: text, Literal: true}, nil
+		}
+	case strings.HasPrefix(rest, `$"`):
+		p.pos += 2
+		return p.parseQuoted(false)
+	case len(rest) > 1 && (isNameChar(rest[1]) || strings.ContainsRune("@*#?$!-", rune(rest[1]))):
+		p.pos += 2
+		if isNameChar(rest[1]) && (rest[1] < '0' || rest[1] > '9') {
+			for p.pos < len(p.src) && isNameChar(p.src[p.pos]) {
+				p.pos++
+			}
+		}
+	default:
+		p.pos++
+		return shellWord{Text: "$", Literal: true}, nil
+	}
+
+	word.Text = p.src[start:p.pos]
+	return word, nil
+}
+
+// merge folds the flags and substitutions of part of a word into the word
+func (w *shellWord) merge(part shellWord) {
+	w.Literal = w.Literal && part.Literal
+	w.Subs = append(w.Subs, part.Subs...)
+}
+
+// skipBlanks skips spaces, tabs, line continuations and comments
+func (p *shellParser) skipBlanks() {
+	for p.pos < len(p.src) {
+		switch c := p.src[p.pos]; {
+		case c == ' ' || c == '\t':
+			p.pos++
+		case strings.HasPrefix(p.src[p.pos:], "\\\n"):
+			p.pos += 2
+		case c == '#':
+			for p.pos < len(p.src) && p.src[p.pos] != '\n' {
+				p.pos++
+			}
+		default:
+			return
+		}
+	}
+}
+
+// skipBlanksAndNewlines skips blanks and newlines, reading any pending here-documents
+func (p *shellParser) skipBlanksAndNewlines() error {
+	for {
+		p.skipBlanks()
+		if p.pos >= len(p.src) || p.src[p.pos] != '\n' {
+			return nil
+		}
+		p.pos++
+		if err := p.readHeredocs(); err != nil {
+			return err
+		}
+	}
+}
+
+// atWord reports whether the reserved word w (such as { or !) is at the current position
+func (p *shellParser) atWord(w string) bool {
+	if !strings.HasPrefix(p.src[p.pos:], w) {
+		return false
+	}
+	end := p.pos + len(w)
+	return end == len(p.src) || strings.ContainsRune(" \t\n;&|()<>", rune(p.src[end]))
+}
+
+// isAssignment reports whether a word is a NAME=value assignment
+func isAssignment(word string) bool {
+	eq := strings.IndexByte(word, '=')
+	if eq <= 0 {
+		return false
+	}
+	name := strings.TrimSuffix(strings.TrimSuffix(word[:eq], "+"), "]")
+	if i := strings.IndexByte(name, '['); i > 0 {
+		name = name[:i]
+	}
+	return isName(name)
+}
+
+// isName reports whether s is a valid shell variable name
+func isName(s string) bool {
+	if s == "" || (s[0] >= '0' && s[0] <= '9') {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if !isNameChar(s[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// isNameChar reports whether c can appear in a shell variable name
+func isNameChar(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"gobot/agent/ai"
+	"gobot/agent/audit"
 	"gobot/agent/config"
 	"gobot/agent/orchestrator"
 	"gobot/agent/session"
@@ -46,11 +47,12 @@ type TaskInput struct {
 	// Wait determines if we should wait for the agent to complete (default: true)
 	Wait *bool `json:"wait,omitempty"`
 
-	// Timeout in seconds (default: 300 = 5 minutes)
+	// Timeout in seconds (default: the agent type's timeout, or 300 = 5 minutes)
 	Timeout int `json:"timeout,omitempty"`
 
-	// AgentType hints at what kind of task this is (optional)
-	// Values: "explore" (codebase exploration), "plan" (planning), "general" (default)
+	// AgentType selects the sub-agent's tools, model routing and limits (optional)
+	// Built-in values: "explore" (codebase exploration), "plan" (planning), "general" (default);
+	// more can be defined in ~/.gobot/agents/*.yaml
 	AgentType string `json:"agent_type,omitempty"`
 }
 
@@ -86,9 +88,21 @@ func (t *TaskTool) Description() string {
 	return "Spawn a sub-agent to handle complex, multi-step tasks autonomously. Use this for tasks that require multiple tool calls, exploration, or independent work streams."
 }
 
-// Schema returns the JSON schema for the tool
+// Schema returns the JSON schema for the tool; agent_type lists the orchestrator's agent types
 func (t *TaskTool) Schema() json.RawMessage {
-	return json.RawMessage(`{
+	names := []string{"explore", "plan", "general"}
+	descriptions := []string{"'explore' (codebase exploration)", "'plan' (planning)", "'general' (default)"}
+	if t.orchestrator != nil {
+		names, descriptions = nil, nil
+		for _, agentType := range t.orchestrator.AgentTypes() {
+			names = append(names, agentType.Name)
+			descriptions = append(descriptions, fmt.Sprintf("'%s' (%s)", agentType.Name, agentType.Description))
+		}
+	}
+	enum, _ := json.Marshal(names)
+	typeDescription, _ := json.Marshal("Type of agent: " + strings.Join(descriptions, ", "))
+
+	return json.RawMessage(fmt.Sprintf(`{
 		"type": "object",
 		"properties": {
 			"description": {
@@ -106,17 +120,16 @@ func (t *TaskTool) Schema() json.RawMessage {
 			},
 			"timeout": {
 				"type": "integer",
-				"description": "Timeout in seconds (default: 300 = 5 minutes)",
-				"default": 300
+				"description": "Timeout in seconds (default: the agent type's timeout, or 300 = 5 minutes)"
 			},
 			"agent_type": {
 				"type": "string",
-				"description": "Type of agent: 'explore' (codebase exploration), 'plan' (planning), 'general' (default)",
-				"enum": ["explore", "plan", "general"]
+				"description": %s,
+				"enum": %s
 			}
 		},
 		"required": ["description", "prompt"]
-	}`)
+	}`, typeDescription, enum))
 }
 
 // RequiresApproval returns false - sub-agents inherit parent's policy
@@ -158,22 +171,26 @@ func (t *TaskTool) Execute(ctx context.Context, input json.RawMessage) (*ToolRes
 		wait = *params.Wait
 	}
 
-	// Default timeout to 5 minutes
-	timeout := 300
-	if params.Timeout > 0 {
-		timeout = params.Timeout
+	agentType, ok := t.orchestrator.AgentType(params.AgentType)
+	if !ok {
+		return &ToolResult{
+			Content: fmt.Sprintf("Error: unknown agent_type %q", params.AgentType),
+			IsError: true,
+		}, nil
 	}
 
 	// Build system prompt based on agent type
-	systemPrompt := buildAgentSystemPrompt(params.AgentType, params.Prompt)
+	systemPrompt := buildAgentSystemPrompt(agentType, params.Prompt)
 
-	// Spawn the sub-agent
+	// Spawn the sub-agent (a zero timeout uses the agent type's default)
 	agent, err := t.orchestrator.Spawn(ctx, &orchestrator.SpawnRequest{
-		Task:         params.Prompt,
-		Description:  params.Description,
-		Wait:         wait,
-		Timeout:      time.Duration(timeout) * time.Second,
-		SystemPrompt: systemPrompt,
+		ParentSessionKey: audit.SessionFrom(ctx),
+		Task:             params.Prompt,
+		Description:      params.Description,
+		Wait:             wait,
+		Timeout:          time.Duration(params.Timeout) * time.Second,
+		SystemPrompt:     systemPrompt,
+		AgentType:        agentType.Name,
 	})
 
 	if err != nil {
@@ -214,7 +231,7 @@ func (t *TaskTool) Execute(ctx context.Context, input json.RawMessage) (*ToolRes
 }
 
 // buildAgentSystemPrompt creates a system prompt based on agent type
-func buildAgentSystemPrompt(agentType, task string) string {
+func buildAgentSystemPrompt(agentType *orchestrator.AgentType, task string) string {
 	base := `You are a focused sub-agent working on a specific task. Complete the task efficiently and report your results.
 
 Guidelines:
@@ -226,29 +243,10 @@ Guidelines:
 
 `
 
-	switch agentType {
-	case "explore":
-		return base + `You are an EXPLORATION agent. Your job is to:
-- Search through codebases to find relevant files and code
-- Understand patterns and architecture
-- Report findings clearly
-- Do NOT modify any files - only read and analyze
-
-Task: ` + task
-
-	case "plan":
-		return base + `You are a PLANNING agent. Your job is to:
-- Analyze the task and break it into steps
-- Identify files that need to be modified
-- Consider edge cases and potential issues
-- Create a clear, actionable plan
-- Do NOT implement the plan - only create it
-
-Task: ` + task
-
-	default:
-		return base + `Task: ` + task
+	if agentType.Prompt != "" {
+		return base + agentType.Prompt + "\n\nTask: " + task
 	}
+	return base + `Task: ` + task
 }
 
 // truncateForDescription creates a short description from a prompt
@@ -341,6 +339,7 @@ func (t *AgentStatusTool) Execute(ctx context.Context, input json.RawMessage) (*
 		for _, agent := range agents {
 			result.WriteString(fmt.Sprintf("ID: %s\n", agent.ID))
 			result.WriteString(fmt.Sprintf("  Description: %s\n", agent.Description))
+			result.WriteString(fmt.Sprintf("  Type: %s\n", agent.Type))
 			result.WriteString(fmt.Sprintf("  Status: %s\n", agent.Status))
 			result.WriteString(fmt.Sprintf("  Started: %s\n", agent.StartedAt.Format(time.RFC3339)))
 			if !agent.CompletedAt.IsZero() {
@@ -372,6 +371,7 @@ func (t *AgentStatusTool) Execute(ctx context.Context, input json.RawMessage) (*
 		var result strings.Builder
 		result.WriteString(fmt.Sprintf("Agent: %s\n", agent.ID))
 		result.WriteString(fmt.Sprintf("Description: %s\n", agent.Description))
+		result.WriteString(fmt.Sprintf("Type: %s\n", agent.Type))
 		result.WriteString(fmt.Sprintf("Status: %s\n", agent.Status))
 		result.WriteString(fmt.Sprintf("Started: %s\n", agent.StartedAt.Format(time.RFC3339)))
 
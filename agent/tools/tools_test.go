@@ -4,8 +4,18 @@ import (
 	"context"
 	"encoding/json"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
+
+	"gobot/agent/ai"
+	"gobot/agent/audit"
+	"gobot/agent/config"
+	"gobot/agent/session"
 )
 
 func TestReadTool(t *testing.T) {
@@ -184,6 +194,29 @@ func TestBashTool(t *testing.T) {
 	}
 }
 
+func TestBashToolCancelKillsChildren(t *testing.T) {
+	policy := NewPolicy()
+	policy.Level = PolicyFull
+	tool := NewBashTool(policy)
+
+	// The subshell's sleep holds stdout open, so only killing the process group ends the command
+	input, _ := json.Marshal(BashInput{Command: "(sleep 30; echo late) & wait"})
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(100*time.Millisecond, cancel)
+
+	start := time.Now()
+	result, err := tool.Execute(ctx, input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed > 3*time.Second {
+		t.Errorf("cancel took %v", elapsed)
+	}
+	if !result.IsError || !strings.HasPrefix(result.Content, "Command cancelled") {
+		t.Errorf("expected cancelled result, got %q", result.Content)
+	}
+}
+
 func TestPolicyAllowlist(t *testing.T) {
 	policy := NewPolicy()
 
@@ -202,3 +235,560 @@ func TestPolicyAllowlist(t *testing.T) {
 		t.Error("rm should require approval")
 	}
 }
+
+// sleepTool records how many instances run at once
+type sleepTool struct {
+	name     string
+	serial   bool
+	approval bool
+	delay    time.Duration
+	running  *int32
+	peak     *int32
+}
+
+func (t *sleepTool) Name() string            { return t.name }
+func (t *sleepTool) Description() string     { return "test tool" }
+func (t *sleepTool) Schema() json.RawMessage { return json.RawMessage(`{"type":"object"}`) }
+func (t *sleepTool) RequiresApproval() bool  { return t.approval }
+func (t *sleepTool) SerialOnly() bool        { return t.serial }
+func (t *sleepTool) Execute(ctx context.Context, input json.RawMessage) (*ToolResult, error) {
+	n := atomic.AddInt32(t.running, 1)
+	for {
+		peak := atomic.LoadInt32(t.peak)
+		if n <= peak || atomic.CompareAndSwapInt32(t.peak, peak, n) {
+			break
+		}
+	}
+	time.Sleep(t.delay)
+	atomic.AddInt32(t.running, -1)
+	return &ToolResult{Content: t.name + ":" + string(input)}, nil
+}
+
+func TestExecuteAllPreservesOrder(t *testing.T) {
+	var running, peak int32
+	registry := NewRegistry(nil)
+	registry.SetMaxParallel(3)
+	registry.Register(&sleepTool{name: "slow", delay: 50 * time.Millisecond, running: &running, peak: &peak})
+	registry.Register(&sleepTool{name: "fast", delay: time.Millisecond, running: &running, peak: &peak})
+
+	calls := []*ai.ToolCall{
+		{ID: "1", Name: "slow", Input: json.RawMessage(`1`)},
+		{ID: "2", Name: "fast", Input: json.RawMessage(`2`)},
+		{ID: "3", Name: "missing"},
+		{ID: "4", Name: "fast", Input: json.RawMessage(`4`)},
+	}
+
+	var order []int
+	results := registry.ExecuteAll(context.Background(), calls, func(i int, result *ToolResult) {
+		order = append(order, i)
+	})
+
+	want := []string{"slow:1", "fast:2", "", "fast:4"}
+	for i, r := range results {
+		if i == 2 {
+			if !r.IsError {
+				t.Errorf("result 2: expected unknown tool error, got %q", r.Content)
+			}
+			continue
+		}
+		if r.Content != want[i] {
+			t.Errorf("result %d: got %q, want %q", i, r.Content, want[i])
+		}
+	}
+	for i, idx := range order {
+		if idx != i {
+			t.Fatalf("callbacks out of order: %v", order)
+		}
+	}
+	if peak < 2 {
+		t.Errorf("expected calls to overlap, peak concurrency %d", peak)
+	}
+	if peak > 3 {
+		t.Errorf("max parallelism exceeded: peak concurrency %d", peak)
+	}
+}
+
+func TestExecuteAllSerialTools(t *testing.T) {
+	var running, peak int32
+	registry := NewRegistry(nil)
+	registry.Register(&sleepTool{name: "reader", delay: 10 * time.Millisecond, running: &running, peak: &peak})
+	registry.Register(&sleepTool{name: "writer", serial: true, delay: 10 * time.Millisecond, running: &running, peak: &peak})
+	registry.Register(&sleepTool{name: "configured", delay: 10 * time.Millisecond, running: &running, peak: &peak})
+	registry.SetSerial("configured")
+
+	if !registry.IsSerial("writer") || !registry.IsSerial("configured") || registry.IsSerial("reader") {
+		t.Fatal("unexpected serial classification")
+	}
+
+	// Only serial tools: nothing may ever overlap
+	calls := []*ai.ToolCall{
+		{ID: "1", Name: "writer"},
+		{ID: "2", Name: "configured"},
+		{ID: "3", Name: "writer"},
+	}
+	registry.ExecuteAll(context.Background(), calls, nil)
+	if peak != 1 {
+		t.Errorf("serial tools overlapped: peak concurrency %d", peak)
+	}
+
+	// Default tools mark shell and file writes serial
+	defaults := NewRegistry(nil)
+	defaults.RegisterDefaults()
+	for _, name := range []string{"bash", "write", "edit"} {
+		if !defaults.IsSerial(name) {
+			t.Errorf("%s should be serial-only", name)
+		}
+	}
+	if defaults.IsSerial("read") {
+		t.Error("read should not be serial-only")
+	}
+}
+
+func TestExecuteAllApprovalsOneAtATime(t *testing.T) {
+	var running, peak int32
+	var mu sync.Mutex
+	var asked []string
+	var pending int32
+
+	policy := NewPolicy()
+	policy.ApprovalCallback = func(ctx context.Context, toolName string, input json.RawMessage) (bool, error) {
+		if atomic.AddInt32(&pending, 1) > 1 {
+			t.Error("approval requests overlapped")
+		}
+		time.Sleep(5 * time.Millisecond)
+		mu.Lock()
+		asked = append(asked, string(input))
+		mu.Unlock()
+		atomic.AddInt32(&pending, -1)
+		return string(input) != `"deny"`, nil
+	}
+
+	registry := NewRegistry(policy)
+	registry.Register(&sleepTool{name: "guarded", approval: true, delay: 5 * time.Millisecond, running: &running, peak: &peak})
+
+	calls := []*ai.ToolCall{
+		{ID: "1", Name: "guarded", Input: json.RawMessage(`"a"`)},
+		{ID: "2", Name: "guarded", Input: json.RawMessage(`"deny"`)},
+		{ID: "3", Name: "guarded", Input: json.RawMessage(`"b"`)},
+	}
+	results := registry.ExecuteAll(context.Background(), calls, nil)
+
+	if len(asked) != 3 || asked[0] != `"a"` || asked[1] != `"deny"` || asked[2] != `"b"` {
+		t.Errorf("approvals not requested in call order: %v", asked)
+	}
+	if results[0].IsError || !results[1].IsError || results[2].IsError {
+		t.Errorf("unexpected results: %+v %+v %+v", results[0], results[1], results[2])
+	}
+}
+
+func TestRegistryAuditsCallsAndApprovals(t *testing.T) {
+	auditLog, err := audit.Open(filepath.Join(t.TempDir(), "audit.db"))
+	if err != nil {
+		t.Fatalf("failed to open audit log: %v", err)
+	}
+	defer auditLog.Close()
+
+	policy := NewPolicy()
+	policy.ApprovalCallback = func(ctx context.Context, toolName string, input json.RawMessage) (bool, error) {
+		SetApprover(ctx, "alice")
+		return string(input) != `"deny"`, nil
+	}
+
+	var running, peak int32
+	registry := NewRegistry(policy)
+	registry.SetAuditLog(auditLog)
+	registry.Register(&sleepTool{name: "guarded", approval: true, running: &running, peak: &peak})
+	registry.Register(&sleepTool{name: "safe", running: &running, peak: &peak})
+
+	ctx := audit.WithSession(context.Background(), "ops")
+	registry.ExecuteAll(ctx, []*ai.ToolCall{
+		{ID: "1", Name: "safe", Input: json.RawMessage(`"x"`)},
+		{ID: "2", Name: "guarded", Input: json.RawMessage(`"ok"`)},
+		{ID: "3", Name: "guarded", Input: json.RawMessage(`"deny"`)},
+		{ID: "4", Name: "missing", Input: json.RawMessage(`{}`)},
+	}, nil)
+
+	entries, err := auditLog.Query(audit.Filter{SessionKey: "ops"})
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	byID := make(map[string]audit.Entry)
+	for _, e := range entries {
+		byID[e.ToolCallID] = e
+	}
+	if len(byID) != 4 {
+		t.Fatalf("expected 4 audited calls, got %d: %+v", len(byID), entries)
+	}
+
+	if e := byID["1"]; !e.Approved || e.Channel != audit.ChannelAuto || e.Output != `safe:"x"` {
+		t.Errorf("safe call: %+v", e)
+	}
+	if e := byID["2"]; !e.Approved || e.Channel != audit.ChannelWeb || e.Approver != "alice" || e.IsError {
+		t.Errorf("approved call: %+v", e)
+	}
+	if e := byID["3"]; e.Approved || !e.IsError || e.Approver != "alice" {
+		t.Errorf("denied call: %+v", e)
+	}
+	if e := byID["4"]; e.Approved || !e.IsError {
+		t.Errorf("unknown tool: %+v", e)
+	}
+
+	denied, _ := auditLog.Query(audit.Filter{DeniedOnly: true, Tool: "guarded"})
+	if len(denied) != 1 || denied[0].ToolCallID != "3" {
+		t.Errorf("denied filter returned %+v", denied)
+	}
+}
+
+func TestPolicyRules(t *testing.T) {
+	workspace := t.TempDir()
+	policy := NewPolicy()
+	policy.Workspace = workspace
+	err := policy.SetRules([]config.PolicyRule{
+		{Tool: "write", Args: map[string]string{"path": "./src/**"}, Action: "allow"},
+		{Tool: "write", Args: map[string]string{"path": "!./**"}, Action: "deny", Reason: "outside workspace"},
+		{Tool: "write", Args: map[string]string{"path": "./src/secrets/*"}, Action: "ask"},
+		{Tool: "web", Args: map[string]string{"url": "*.internal"}, Action: "deny"},
+		{Tool: "process", Args: map[string]string{"action": "kill"}, Action: "ask"},
+		{Tool: "mcp_*", Args: map[string]string{"options.force": "true"}, Action: "deny"},
+		{Tool: "bash", Args: map[string]string{"command": "ls *"}, Action: "allow"},
+		{Tool: "bash", Args: map[string]string{"command": "make *"}, Action: "allow"},
+	})
+	if err != nil {
+		t.Fatalf("SetRules failed: %v", err)
+	}
+
+	tests := []struct {
+		tool             string
+		input            string
+		requiresApproval bool
+		want             RuleAction
+	}{
+		{"write", `{"path":"src/main.go"}`, true, RuleAllow},
+		{"write", `{"path":"` + filepath.Join(workspace, "src", "pkg", "a.go") + `"}`, true, RuleAllow},
+		{"write", `{"path":"src/secrets/key"}`, true, RuleAsk}, // ask beats allow
+		{"write", `{"path":"../outside.txt"}`, true, RuleDeny},
+		{"write", `{"path":"/etc/passwd"}`, true, RuleDeny},
+		{"write", `{"path":"docs/readme.md"}`, true, RuleAsk}, // No rule: tool requires approval
+		{"web", `{"url":"http://wiki.corp.internal/page"}`, false, RuleDeny},
+		{"web", `{"url":"https://go.dev/doc"}`, false, RuleAllow},
+		{"process", `{"action":"kill","pid":42}`, false, RuleAsk},
+		{"process", `{"action":"list"}`, false, RuleAllow},
+		{"mcp_github_delete_repo", `{"options":{"force":true}}`, true, RuleDeny},
+		{"bash", `{"command":"git status"}`, true, RuleAllow},
+		{"bash", `{"command":"rm -rf /"}`, true, RuleAsk},
+		{"bash", `{"command":"make test"}`, true, RuleAllow},        // Rule covers a command off the allowlist
+		{"bash", `{"command":"ls; rm -rf ~"}`, true, RuleAsk},       // Allow rules don't cover chained commands
+		{"bash", `{"command":"ls && make install"}`, true, RuleAsk}, // Even ones another rule allows
+		{"bash", `{"command":"ls $(rm -rf ~)"}`, true, RuleAsk},
+		{"bash", `{"command":"ls > listing.txt"}`, true, RuleAsk},
+		{"bash", `{"command":"ls | grep go"}`, true, RuleAllow}, // Chained commands on the allowlist still pass
+	}
+	for _, tt := range tests {
+		if got := policy.Evaluate(tt.tool, json.RawMessage(tt.input), tt.requiresApproval); got.Action != tt.want {
+			t.Errorf("Evaluate(%s, %s) = %s (%s), want %s", tt.tool, tt.input, got.Action, got.Reason, tt.want)
+		}
+	}
+
+	for _, bad := range []config.PolicyRule{
+		{Args: map[string]string{"path": "x"}, Action: "deny"},
+		{Tool: "write", Action: "maybe"},
+		{Tool: "[", Action: "deny"},
+		{Tool: "write", Args: map[string]string{"path": "!"}, Action: "deny"},
+	} {
+		if err := NewPolicy().SetRules([]config.PolicyRule{bad}); err == nil {
+			t.Errorf("expected error for rule %+v", bad)
+		}
+	}
+}
+
+func TestRegistryAppliesPolicyRules(t *testing.T) {
+	var asked []string
+	policy := NewPolicy()
+	policy.ApprovalCallback = func(ctx context.Context, toolName string, input json.RawMessage) (bool, error) {
+		asked = append(asked, toolName)
+		return true, nil
+	}
+	policy.SetRules([]config.PolicyRule{
+		{Tool: "safe", Args: map[string]string{"target": "prod"}, Action: "ask"},
+		{Tool: "guarded", Args: map[string]string{"target": "prod"}, Action: "deny", Reason: "no prod changes"},
+		{Tool: "guarded", Args: map[string]string{"target": "dev"}, Action: "allow"},
+	})
+
+	var running, peak int32
+	registry := NewRegistry(policy)
+	registry.Register(&sleepTool{name: "guarded", approval: true, running: &running, peak: &peak})
+	registry.Register(&sleepTool{name: "safe", running: &running, peak: &peak})
+
+	results := registry.ExecuteAll(context.Background(), []*ai.ToolCall{
+		{ID: "1", Name: "guarded", Input: json.RawMessage(`{"target":"prod"}`)},
+		{ID: "2", Name: "guarded", Input: json.RawMessage(`{"target":"dev"}`)},
+		{ID: "3", Name: "safe", Input: json.RawMessage(`{"target":"prod"}`)},
+		{ID: "4", Name: "safe", Input: json.RawMessage(`{"target":"dev"}`)},
+	}, nil)
+
+	if !results[0].IsError || !strings.Contains(results[0].Content, "denied by policy: no prod changes") {
+		t.Errorf("expected policy denial, got %+v", results[0])
+	}
+	for _, i := range []int{1, 2, 3} {
+		if results[i].IsError {
+			t.Errorf("call %d should have run, got %+v", i+1, results[i])
+		}
+	}
+	if len(asked) != 1 || asked[0] != "safe" {
+		t.Errorf("only the ask rule should prompt, asked %v", asked)
+	}
+}
+
+// recordingExecutor runs commands on the host and remembers what it was asked to run
+type recordingExecutor struct {
+	name     string
+	commands []string
+}
+
+func (e *recordingExecutor) Name() string { return e.name }
+
+func (e *recordingExecutor) Command(ctx context.Context, command, dir string) (*exec.Cmd, error) {
+	e.commands = append(e.commands, command)
+	return HostExecutor{}.Command(ctx, "echo "+e.name+"; "+command, dir)
+}
+
+func TestBashToolUsesPolicyExecutor(t *testing.T) {
+	sandboxed := &recordingExecutor{name: "sandboxed"}
+	policy := NewPolicy()
+	policy.Executors = map[PolicyLevel]Executor{PolicyFull: sandboxed}
+
+	registry := NewRegistry(policy)
+	registry.RegisterDefaults()
+	bash, _ := registry.Get("bash")
+	process, _ := registry.Get("process")
+
+	// Allowlist level has no executor configured, so bash runs on the host
+	result, _ := bash.Execute(context.Background(), json.RawMessage(`{"command":"echo hi"}`))
+	if result.IsError || strings.TrimSpace(result.Content) != "hi" {
+		t.Fatalf("expected host output, got %+v", result)
+	}
+	result, _ = process.Execute(context.Background(), json.RawMessage(`{"action":"kill","pid":999999}`))
+	if strings.Contains(result.Content, "sandbox") {
+		t.Errorf("kill should not be blocked on the host, got %q", result.Content)
+	}
+
+	// Switching to full autonomy picks the sandboxed executor
+	full := NewPolicyFromConfig("full", "off", nil)
+	full.Executors = policy.Executors
+	registry.SetPolicy(full)
+
+	result, _ = bash.Execute(context.Background(), json.RawMessage(`{"command":"echo hi"}`))
+	if result.IsError || result.Content != "sandboxed\nhi\n" {
+		t.Fatalf("expected sandboxed output, got %+v", result)
+	}
+	if len(sandboxed.commands) != 1 || sandboxed.commands[0] != "echo hi" {
+		t.Errorf("unexpected executor commands %v", sandboxed.commands)
+	}
+	result, _ = process.Execute(context.Background(), json.RawMessage(`{"action":"kill","pid":999999}`))
+	if !result.IsError || !strings.Contains(result.Content, "sandboxed sandbox") {
+		t.Errorf("expected kill to be refused in the sandbox, got %+v", result)
+	}
+}
+
+func TestSandboxExecutorCommands(t *testing.T) {
+	bwrap := &BubblewrapExecutor{Workspace: "/work", Writable: []string{"/cache"}, Binary: "/usr/bin/bwrap"}
+	cmd, err := bwrap.Command(context.Background(), "make test", "")
+	if err != nil {
+		t.Fatalf("Command failed: %v", err)
+	}
+	args := strings.Join(cmd.Args, " ")
+	for _, want := range []string{
+		"--ro-bind / / --dev /dev --proc /proc --tmpfs /tmp --bind /work /work --bind /cache /cache --unshare-all --die-with-parent",
+		"--chdir /work -- bash -c make test",
+	} {
+		if !strings.Contains(args, want) {
+			t.Errorf("bwrap args %q missing %q", args, want)
+		}
+	}
+	if strings.Contains(args, "--share-net") {
+		t.Error("network should be unshared by default")
+	}
+
+	bwrap.Network = true
+	cmd, _ = bwrap.Command(context.Background(), "ls", "/work/src")
+	if args := strings.Join(cmd.Args, " "); !strings.Contains(args, "--share-net") || !strings.Contains(args, "--chdir /work/src") {
+		t.Errorf("unexpected bwrap args %q", args)
+	}
+
+	container := &ContainerExecutor{Runtime: "podman", Image: "golang:1.23", Workspace: "/work"}
+	cmd, err = container.Command(context.Background(), "go test ./...", "/elsewhere")
+	if err != nil {
+		t.Fatalf("Command failed: %v", err)
+	}
+	args = strings.Join(cmd.Args, " ")
+	for _, want := range []string{"podman run --rm -i --init --name gobot-", "--network none", "-v /work:/work", "-w /work ", "golang:1.23 bash -c go test ./..."} {
+		if !strings.Contains(args, want) {
+			t.Errorf("container args %q missing %q", args, want)
+		}
+	}
+	if cmd.Cancel == nil {
+		t.Error("expected the container to be stopped on cancel")
+	}
+}
+
+func TestPolicySetSandbox(t *testing.T) {
+	policy := NewPolicy()
+	if err := policy.SetSandbox(config.SandboxConfig{}); err != nil {
+		t.Fatalf("SetSandbox failed: %v", err)
+	}
+	if name := policy.Executor().Name(); name != ExecutorHost {
+		t.Errorf("expected host executor by default, got %s", name)
+	}
+
+	for _, cfg := range []config.SandboxConfig{
+		{Executor: "chroot"},
+		{Levels: map[string]string{"yolo": "host"}},
+		{Levels: map[string]string{"full": "vm"}},
+	} {
+		if err := policy.SetSandbox(cfg); err == nil {
+			t.Errorf("expected error for %+v", cfg)
+		}
+	}
+}
+
+func TestRegistryCheckpointsFileChanges(t *testing.T) {
+	sessions, err := session.New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("session.New failed: %v", err)
+	}
+	defer sessions.Close()
+	sess, _ := sessions.GetOrCreate("work")
+
+	policy := NewPolicy()
+	policy.ApprovalCallback = func(ctx context.Context, toolName string, input json.RawMessage) (bool, error) {
+		return true, nil
+	}
+	registry := NewRegistry(policy)
+	registry.Register(NewWriteTool())
+	registry.Register(NewEditTool())
+	registry.SetCheckpoints(sessions)
+
+	path := filepath.Join(t.TempDir(), "notes.txt")
+	os.WriteFile(path, []byte("original"), 0644)
+
+	ctx := session.WithMessage(context.Background(), sess.ID, 7)
+	registry.Execute(ctx, &ai.ToolCall{ID: "1", Name: "edit",
+		Input: json.RawMessage(`{"path":"` + path + `","old_string":"original","new_string":"edited"}`)})
+	registry.Execute(ctx, &ai.ToolCall{ID: "2", Name: "write",
+		Input: json.RawMessage(`{"path":"` + path + `","content":"rewritten"}`)})
+
+	// Calls outside a message aren't checkpointed
+	registry.Execute(context.Background(), &ai.ToolCall{ID: "3", Name: "write",
+		Input: json.RawMessage(`{"path":"` + path + `.other","content":"x"}`)})
+
+	checkpoints, _ := sessions.ListCheckpoints(sess.ID)
+	if len(checkpoints) != 1 || checkpoints[0].MessageID != 7 || checkpoints[0].Path != path {
+		t.Fatalf("expected one checkpoint for message 7, got %+v", checkpoints)
+	}
+
+	result, _ := NewSessionsTool(sessions).Execute(audit.WithSession(context.Background(), "work"), json.RawMessage(`{"action":"undo"}`))
+	if result.IsError || !strings.Contains(result.Content, "Restored 1 files in work") {
+		t.Fatalf("unexpected undo result: %+v", result)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "original" {
+		t.Errorf("expected the original contents back, got %q", data)
+	}
+}
+
+func TestBackgroundJobs(t *testing.T) {
+	registry := NewRegistry(NewPolicy())
+	registry.RegisterDefaults()
+	bash, _ := registry.Get("bash")
+	jobsTool, _ := registry.Get("jobs")
+
+	var mu sync.Mutex
+	var events []JobEvent
+	registry.Jobs().SetEventHandler(func(e JobEvent) {
+		mu.Lock()
+		events = append(events, e)
+		mu.Unlock()
+	})
+
+	ctx := audit.WithSession(context.Background(), "work")
+	call := func(tool Tool, input string) *ToolResult {
+		t.Helper()
+		result, err := tool.Execute(ctx, json.RawMessage(input))
+		if err != nil {
+			t.Fatalf("Execute(%s) failed: %v", input, err)
+		}
+		return result
+	}
+
+	// A background cat echoes its stdin until stdin is closed
+	result := call(bash, `{"command":"echo ready; cat","background":true}`)
+	if result.IsError || !strings.Contains(result.Content, "job-1") {
+		t.Fatalf("expected a job ID, got %+v", result)
+	}
+	job, _ := registry.Jobs().Get("job-1")
+
+	if result := call(jobsTool, `{"action":"input","job_id":"job-1","text":"hello\n","eof":true}`); result.IsError {
+		t.Fatalf("input failed: %+v", result)
+	}
+	result = call(jobsTool, `{"action":"output","job_id":"job-1","wait":10}`)
+	if !strings.Contains(result.Content, "job-1 exited (code 0)") || !strings.HasSuffix(result.Content, "ready\nhello\n") {
+		t.Fatalf("unexpected output %q", result.Content)
+	}
+
+	// Reads are incremental; an offset rereads
+	if result := call(jobsTool, `{"action":"output","job_id":"job-1"}`); !strings.Contains(result.Content, "(no new output)") {
+		t.Errorf("expected no new output, got %q", result.Content)
+	}
+	if result := call(jobsTool, `{"action":"output","job_id":"job-1","offset":6}`); !strings.HasSuffix(result.Content, "\nhello\n") {
+		t.Errorf("expected output from offset 6, got %q", result.Content)
+	}
+
+	mu.Lock()
+	last := events[len(events)-1]
+	mu.Unlock()
+	if last.Type != JobEventExit || last.JobID != "job-1" || last.SessionKey != "work" || last.Status != JobExited {
+		t.Errorf("unexpected final event %+v", last)
+	}
+
+	// Only input needs approval
+	if RequiresApproval(jobsTool, json.RawMessage(`{"action":"output","job_id":"job-1"}`)) ||
+		!RequiresApproval(jobsTool, json.RawMessage(`{"action":"input","job_id":"job-1","text":"x"}`)) {
+		t.Error("expected only input to require approval")
+	}
+
+	// Jobs belong to the session that started them
+	other := audit.WithSession(context.Background(), "other")
+	if result, _ := jobsTool.Execute(other, json.RawMessage(`{"action":"output","job_id":"job-1"}`)); !result.IsError {
+		t.Errorf("expected job-1 to be hidden from another session, got %+v", result)
+	}
+
+	// Kill stops a long-running job and its children
+	call(bash, `{"command":"sleep 60 & sleep 60","background":true}`)
+	result = call(jobsTool, `{"action":"kill","job_id":"job-2"}`)
+	if !strings.Contains(result.Content, "job-2 killed") {
+		t.Errorf("expected job-2 to be killed, got %q", result.Content)
+	}
+
+	// Background jobs only time out when asked to
+	call(bash, `{"command":"sleep 60","background":true,"timeout":1}`)
+	long, _ := registry.Jobs().Get("job-3")
+	select {
+	case <-long.Done():
+	case <-time.After(10 * time.Second):
+		t.Fatal("job-3 did not time out")
+	}
+	if status, _ := long.Status(); status != JobTimedOut {
+		t.Errorf("expected job-3 to time out, got %s", status)
+	}
+
+	// Ending the session stops its running jobs and forgets them all
+	call(bash, `{"command":"sleep 60","background":true}`)
+	if killed := registry.Jobs().EndSession("work"); killed != 1 {
+		t.Errorf("expected 1 running job to be killed, got %d", killed)
+	}
+	if jobs := registry.Jobs().List("work"); len(jobs) != 0 {
+		t.Errorf("expected no jobs after the session ended, got %d", len(jobs))
+	}
+	if status, _ := job.Status(); status != JobExited {
+		t.Errorf("finished job changed status to %s", status)
+	}
+}
@@ -1,27 +1,67 @@
 package tools
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
 	"io"
+	"mime"
 	"net/http"
+	"net/url"
 	"strings"
+	"sync"
 	"time"
+	"unicode/utf8"
+)
+
+// WebConfig configures the web tool
+type WebConfig struct {
+	// Policy limits which hosts may be fetched; private addresses are refused by default
+	Policy NetworkPolicy
+
+	// CacheTTL is how long fetched pages are reused (0 = 5 minutes, negative disables caching)
+	CacheTTL time.Duration
+
+	// Timeout bounds each request (0 = 30 seconds)
+	Timeout time.Duration
+}
+
+// Default web tool limits
+const (
+	DefaultWebCacheTTL = 5 * time.Minute
+	maxWebBody         = 10 << 20 // Bytes read from a response
+	maxWebContent      = 100000   // Characters returned to the model
+	maxWebCacheEntries = 64
 )
 
 // WebTool fetches content from URLs
 type WebTool struct {
 	client *http.Client
+	policy *NetworkPolicy
+	cache  *webCache
 }
 
 // NewWebTool creates a new web tool
-func NewWebTool() *WebTool {
-	return &WebTool{
-		client: &http.Client{
-			Timeout: 30 * time.Second,
-		},
+func NewWebTool(cfg WebConfig) *WebTool {
+	timeout := cfg.Timeout
+	if timeout == 0 {
+		timeout = 30 * time.Second
+	}
+	ttl := cfg.CacheTTL
+	if ttl == 0 {
+		ttl = DefaultWebCacheTTL
+	}
+
+	policy := cfg.Policy
+	t := &WebTool{
+		client: newPolicyClient(&policy, timeout),
+		policy: &policy,
+	}
+	if ttl > 0 {
+		t.cache = &webCache{ttl: ttl, entries: make(map[string]*webResponse)}
 	}
+	return t
 }
 
 // Name returns the tool name
@@ -31,9 +71,13 @@ func (t *WebTool) Name() string {
 
 // Description returns the tool description
 func (t *WebTool) Description() string {
-	return `Fetch content from a URL. Returns the raw content of the page.
-Use this to access web pages, APIs, or download files.
-Note: This is a simple HTTP GET request - for complex interactions, use the browser tool.`
+	return `Fetch content from a URL.
+HTML pages are returned as readable Markdown of the main content (navigation and scripts removed);
+use selector to extract specific elements and links to list the page's links. JSON is pretty-printed
+and PDF text is extracted. Use mode "raw" for the unprocessed response.
+GET responses are cached for a few minutes; set refresh to fetch again.
+Private and local network addresses are blocked unless the network policy allows them.
+Note: This is a simple HTTP request - for complex interactions, use the browser tool.`
 }
 
 // Schema returns the JSON schema for the tool input
@@ -45,6 +89,23 @@ func (t *WebTool) Schema() json.RawMessage {
 				"type": "string",
 				"description": "The URL to fetch"
 			},
+			"mode": {
+				"type": "string",
+				"enum": ["readable", "raw"],
+				"description": "readable (default) extracts content as Markdown; raw returns the response body unchanged"
+			},
+			"selector": {
+				"type": "string",
+				"description": "CSS selector of the elements to extract instead of the detected main content (e.g. 'table.prices', '#content > h2')"
+			},
+			"links": {
+				"type": "boolean",
+				"description": "Append a list of the links in the extracted content"
+			},
+			"refresh": {
+				"type": "boolean",
+				"description": "Bypass the cache and fetch the URL again"
+			},
 			"headers": {
 				"type": "object",
 				"description": "Optional headers to include in the request",
@@ -67,10 +128,25 @@ func (t *WebTool) Schema() json.RawMessage {
 
 // WebInput represents the tool input
 type WebInput struct {
-	URL     string            `json:"url"`
-	Headers map[string]string `json:"headers"`
-	Method  string            `json:"method"`
-	Body    string            `json:"body"`
+	URL      string            `json:"url"`
+	Mode     string            `json:"mode"`     // readable (default) or raw
+	Selector string            `json:"selector"` // CSS selector to extract
+	Links    bool              `json:"links"`    // Append the link list
+	Refresh  bool              `json:"refresh"`  // Bypass the cache
+	Headers  map[string]string `json:"headers"`
+	Method   string            `json:"method"`
+	Body     string            `json:"body"`
+}
+
+// webResponse is a fetched response, kept in the cache for GETs
+type webResponse struct {
+	Status      string
+	StatusCode  int
+	ContentType string
+	URL         *url.URL // Final URL after redirects
+	Body        []byte
+	Truncated   bool // Body was longer than maxWebBody
+	FetchedAt   time.Time
 }
 
 // Execute fetches the URL
@@ -86,25 +162,102 @@ func (t *WebTool) Execute(ctx context.Context, input json.RawMessage) (*ToolResu
 			IsError: true,
 		}, nil
 	}
+	if in.Mode != "" && in.Mode != "readable" && in.Mode != "raw" {
+		return &ToolResult{
+			Content: fmt.Sprintf("Error: unknown mode %q (use readable or raw)", in.Mode),
+			IsError: true,
+		}, nil
+	}
+
+	target, err := url.Parse(in.URL)
+	if err == nil {
+		err = checkURLScheme(target.Scheme)
+	}
+	if err != nil {
+		return &ToolResult{
+			Content: fmt.Sprintf("Error: invalid URL: %v", err),
+			IsError: true,
+		}, nil
+	}
+	if err := t.policy.CheckHost(target.Hostname()); err != nil {
+		return &ToolResult{
+			Content: fmt.Sprintf("Error: blocked by network policy: %v", err),
+			IsError: true,
+		}, nil
+	}
 
 	// Default to GET
-	method := in.Method
+	method := strings.ToUpper(in.Method)
 	if method == "" {
 		method = "GET"
 	}
 
+	// Only plain GETs are cached; custom headers may carry credentials or vary the response
+	cacheable := t.cache != nil && method == "GET" && in.Body == "" && len(in.Headers) == 0
+	var resp *webResponse
+	cached := false
+	if cacheable && !in.Refresh {
+		resp = t.cache.get(target.String())
+		cached = resp != nil
+	}
+	if resp == nil {
+		resp, err = t.fetch(ctx, method, target, &in)
+		if err != nil {
+			return &ToolResult{
+				Content: "Error " + err.Error(),
+				IsError: true,
+			}, nil
+		}
+		if cacheable && resp.StatusCode < 400 {
+			t.cache.put(target.String(), resp)
+		}
+	}
+
+	content, err := renderWebResponse(ctx, resp, &in)
+	if err != nil {
+		return &ToolResult{
+			Content: fmt.Sprintf("Error: %v", err),
+			IsError: true,
+		}, nil
+	}
+
+	// Truncate very long responses
+	if len(content) > maxWebContent {
+		content = content[:maxWebContent] + "\n... (content truncated)"
+	} else if resp.Truncated {
+		content += "\n... (response truncated)"
+	}
+
+	// Add status info
+	header := fmt.Sprintf("HTTP %s\nContent-Type: %s\nContent-Length: %d\n",
+		resp.Status,
+		resp.ContentType,
+		len(resp.Body),
+	)
+	if resp.URL.String() != target.String() {
+		header += fmt.Sprintf("URL: %s\n", resp.URL)
+	}
+	if cached {
+		header += fmt.Sprintf("Cached: fetched %s ago\n", time.Since(resp.FetchedAt).Round(time.Second))
+	}
+
+	return &ToolResult{
+		Content: header + "\n" + content,
+		IsError: resp.StatusCode >= 400,
+	}, nil
+}
+
+// fetch performs the request and reads the response body
+func (t *WebTool) fetch(ctx context.Context, method string, target *url.URL, in *WebInput) (*webResponse, error) {
 	// Create request
 	var body io.Reader
 	if in.Body != "" {
 		body = strings.NewReader(in.Body)
 	}
 
-	req, err := http.NewRequestWithContext(ctx, method, in.URL, body)
+	req, err := http.NewRequestWithContext(ctx, method, target.String(), body)
 	if err != nil {
-		return &ToolResult{
-			Content: fmt.Sprintf("Error creating request: %v", err),
-			IsError: true,
-		}, nil
+		return nil, fmt.Errorf("creating request: %w", err)
 	}
 
 	// Set default user agent
@@ -118,41 +271,119 @@ func (t *WebTool) Execute(ctx context.Context, input json.RawMessage) (*ToolResu
 	// Make request
 	resp, err := t.client.Do(req)
 	if err != nil {
-		return &ToolResult{
-			Content: fmt.Sprintf("Error fetching URL: %v", err),
-			IsError: true,
-		}, nil
+		return nil, fmt.Errorf("fetching URL: %w", err)
 	}
 	defer resp.Body.Close()
 
 	// Read response
-	content, err := io.ReadAll(resp.Body)
+	content, err := io.ReadAll(io.LimitReader(resp.Body, maxWebBody+1))
 	if err != nil {
-		return &ToolResult{
-			Content: fmt.Sprintf("Error reading response: %v", err),
-			IsError: true,
-		}, nil
+		return nil, fmt.Errorf("reading response: %w", err)
 	}
 
-	// Truncate very long responses
-	const maxContent = 100000
-	result := string(content)
-	if len(result) > maxContent {
-		result = result[:maxContent] + "\n... (content truncated)"
+	result := &webResponse{
+		Status:      resp.Status,
+		StatusCode:  resp.StatusCode,
+		ContentType: resp.Header.Get("Content-Type"),
+		URL:         resp.Request.URL,
+		Body:        content,
+		FetchedAt:   time.Now(),
+	}
+	if len(content) > maxWebBody {
+		result.Body = content[:maxWebBody]
+		result.Truncated = true
+	}
+	return result, nil
+}
+
+// renderWebResponse turns a response into text for the model according to its content type
+func renderWebResponse(ctx context.Context, resp *webResponse, in *WebInput) (string, error) {
+	if in.Mode == "raw" {
+		return string(resp.Body), nil
 	}
 
-	// Add status info
-	header := fmt.Sprintf("HTTP %d %s\nContent-Type: %s\nContent-Length: %d\n\n",
-		resp.StatusCode,
-		resp.Status,
-		resp.Header.Get("Content-Type"),
-		len(content),
-	)
+	mediaType, _, _ := mime.ParseMediaType(resp.ContentType)
+	sniffed := http.DetectContentType(resp.Body)
+	switch {
+	case mediaType == "application/pdf" || bytes.HasPrefix(resp.Body, []byte("%PDF-")):
+		if text := extractPDF(ctx, resp.Body); text != "" {
+			return text, nil
+		}
+		return "(no extractable text found in PDF)", nil
 
-	return &ToolResult{
-		Content: header + result,
-		IsError: resp.StatusCode >= 400,
-	}, nil
+	case mediaType == "text/html" || mediaType == "application/xhtml+xml" ||
+		(mediaType == "" && strings.HasPrefix(sniffed, "text/html")):
+		page, err := extractHTML(resp.Body, resp.URL, in.Selector)
+		if err != nil {
+			return "", err
+		}
+		var sb strings.Builder
+		if page.Title != "" {
+			sb.WriteString("Title: " + strings.TrimSpace(page.Title) + "\n\n")
+		}
+		sb.WriteString(page.Markdown)
+		if in.Links && len(page.Links) > 0 {
+			sb.WriteString("\n\nLinks:\n")
+			for i, link := range page.Links {
+				fmt.Fprintf(&sb, "%d. [%s](%s)\n", i+1, link.Text, link.URL)
+			}
+		}
+		return sb.String(), nil
+
+	case mediaType == "application/json" || strings.HasSuffix(mediaType, "+json") ||
+		(mediaType == "text/plain" && json.Valid(resp.Body)):
+		if pretty, ok := prettyJSON(resp.Body); ok {
+			return pretty, nil
+		}
+		return string(resp.Body), nil
+
+	case !utf8.Valid(resp.Body) && !strings.HasPrefix(sniffed, "text/"):
+		return fmt.Sprintf("(binary content, %d bytes)", len(resp.Body)), nil
+	}
+
+	return string(resp.Body), nil
+}
+
+// webCache holds recent GET responses keyed by URL
+type webCache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	entries map[string]*webResponse
+}
+
+// get returns a fresh cached response
+func (c *webCache) get(key string) *webResponse {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	resp, ok := c.entries[key]
+	if !ok {
+		return nil
+	}
+	if time.Since(resp.FetchedAt) > c.ttl {
+		delete(c.entries, key)
+		return nil
+	}
+	return resp
+}
+
+// put stores a response, evicting expired entries and then the oldest when full
+func (c *webCache) put(key string, resp *webResponse) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.entries) >= maxWebCacheEntries {
+		var oldest string
+		for k, e := range c.entries {
+			if time.Since(e.FetchedAt) > c.ttl {
+				delete(c.entries, k)
+			} else if oldest == "" || e.FetchedAt.Before(c.entries[oldest].FetchedAt) {
+				oldest = k
+			}
+		}
+		if len(c.entries) >= maxWebCacheEntries {
+			delete(c.entries, oldest)
+		}
+	}
+	c.entries[key] = resp
 }
 
 // RequiresApproval returns false - reading web is safe
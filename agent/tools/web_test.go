@@ -0,0 +1,273 @@
+package tools
+
+import (
+	"bytes"
+	"compress/zlib"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// localWebTool returns a web tool allowed to reach httptest servers on loopback
+func localWebTool() *WebTool {
+	return NewWebTool(WebConfig{Policy: NetworkPolicy{AllowPrivate: true}})
+}
+
+func fetchWeb(t *testing.T, tool *WebTool, in WebInput) *ToolResult {
+	t.Helper()
+	input, _ := json.Marshal(in)
+	result, err := tool.Execute(context.Background(), input)
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	return result
+}
+
+const articlePage = `<!DOCTYPE html>
+<html><head><title>Release Notes</title><script>var tracking = 1;</script></head>
+<body>
+<nav><a href="/">Home</a> <a href="/blog">Blog</a></nav>
+<aside>Sponsored content</aside>
+<article>
+  <h1>Version 2.0</h1>
+  <p>This release adds <strong>streaming</strong> and a new <a href="/docs/api">API guide</a>.</p>
+  <ul><li>Faster startup</li><li>Smaller binary<ul><li>By 30%</li></ul></li></ul>
+  <pre><code class="language-go">fmt.Println("hi")</code></pre>
+  <p style="display:none">Hidden text</p>
+</article>
+<footer>Copyright footer</footer>
+</body></html>`
+
+func TestWebToolReadableHTML(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprint(w, articlePage)
+	}))
+	defer srv.Close()
+
+	result := fetchWeb(t, localWebTool(), WebInput{URL: srv.URL + "/post", Links: true})
+	if result.IsError {
+		t.Fatalf("unexpected error: %s", result.Content)
+	}
+
+	for _, want := range []string{
+		"Title: Release Notes",
+		"# Version 2.0",
+		"This release adds **streaming** and a new [API guide](" + srv.URL + "/docs/api).",
+		"- Faster startup\n- Smaller binary\n  - By 30%",
+		"```go\nfmt.Println(\"hi\")\n```",
+		"Links:\n1. [API guide](" + srv.URL + "/docs/api)",
+	} {
+		if !strings.Contains(result.Content, want) {
+			t.Errorf("expected %q in:\n%s", want, result.Content)
+		}
+	}
+	for _, unwanted := range []string{"tracking", "Home", "Sponsored", "Copyright", "Hidden text", "<p>"} {
+		if strings.Contains(result.Content, unwanted) {
+			t.Errorf("did not expect %q in:\n%s", unwanted, result.Content)
+		}
+	}
+
+	raw := fetchWeb(t, localWebTool(), WebInput{URL: srv.URL, Mode: "raw"})
+	if !strings.Contains(raw.Content, "<nav>") {
+		t.Errorf("raw mode should return the markup, got:\n%s", raw.Content)
+	}
+}
+
+func TestWebToolSelector(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, `<html><body>
+<div id="main"><h2>Prices</h2>
+<table class="prices"><tr><th>Plan</th><th>Cost</th></tr><tr><td>Pro</td><td>$10</td></tr></table>
+<p class="note">Billed | monthly</p></div>
+<p class="note">Outside</p>
+</body></html>`)
+	}))
+	defer srv.Close()
+
+	tool := localWebTool()
+	result := fetchWeb(t, tool, WebInput{URL: srv.URL, Selector: "table.prices"})
+	if want := "| Plan | Cost |\n| --- | --- |\n| Pro | $10 |"; !strings.Contains(result.Content, want) {
+		t.Errorf("expected table %q in:\n%s", want, result.Content)
+	}
+
+	result = fetchWeb(t, tool, WebInput{URL: srv.URL, Selector: "#main > p.note, h2"})
+	if !strings.Contains(result.Content, "## Prices") || !strings.Contains(result.Content, "Billed | monthly") ||
+		strings.Contains(result.Content, "Outside") {
+		t.Errorf("unexpected selection:\n%s", result.Content)
+	}
+
+	for _, selector := range []string{"div:first-child", "> p", "[unclosed"} {
+		if result := fetchWeb(t, tool, WebInput{URL: srv.URL, Selector: selector}); !result.IsError {
+			t.Errorf("expected error for selector %q, got:\n%s", selector, result.Content)
+		}
+	}
+	if result := fetchWeb(t, tool, WebInput{URL: srv.URL, Selector: "section"}); !result.IsError {
+		t.Error("expected error when the selector matches nothing")
+	}
+}
+
+func TestWebToolJSONAndPDF(t *testing.T) {
+	var compressed bytes.Buffer
+	zw := zlib.NewWriter(&compressed)
+	zw.Write([]byte("BT /F1 12 Tf 72 712 Td [(Compressed)-250(page)] TJ ET"))
+	zw.Close()
+
+	pdf := []byte("%PDF-1.4\n1 0 obj\n<< /Length 44 >>\nstream\nBT /F1 12 Tf 72 720 Td (Hello \\(PDF\\)) Tj ET\nendstream\nendobj\n" +
+		"2 0 obj\n<< /Length " + fmt.Sprint(compressed.Len()) + " /Filter /FlateDecode >>\nstream\n" + compressed.String() + "\nendstream\nendobj\n%%EOF")
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/data":
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprint(w, `{"name":"gobot","tags":["a","b"]}`)
+		case "/doc.pdf":
+			w.Header().Set("Content-Type", "application/pdf")
+			w.Write(pdf)
+		}
+	}))
+	defer srv.Close()
+
+	tool := localWebTool()
+	result := fetchWeb(t, tool, WebInput{URL: srv.URL + "/data"})
+	if want := "{\n  \"name\": \"gobot\",\n  \"tags\": [\n    \"a\","; !strings.Contains(result.Content, want) {
+		t.Errorf("expected pretty JSON, got:\n%s", result.Content)
+	}
+
+	result = fetchWeb(t, tool, WebInput{URL: srv.URL + "/doc.pdf"})
+	if !strings.Contains(result.Content, "Hello (PDF)") || !strings.Contains(result.Content, "Compressed page") {
+		t.Errorf("expected PDF text, got:\n%s", result.Content)
+	}
+}
+
+func TestWebToolCache(t *testing.T) {
+	var hits atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := hits.Add(1)
+		w.Header().Set("Content-Type", "text/plain")
+		fmt.Fprintf(w, "response %d", n)
+	}))
+	defer srv.Close()
+
+	tool := localWebTool()
+	first := fetchWeb(t, tool, WebInput{URL: srv.URL})
+	second := fetchWeb(t, tool, WebInput{URL: srv.URL})
+	if hits.Load() != 1 || !strings.Contains(second.Content, "response 1") || !strings.Contains(second.Content, "Cached:") {
+		t.Errorf("expected second fetch from cache, hits=%d:\n%s", hits.Load(), second.Content)
+	}
+	if strings.Contains(first.Content, "Cached:") {
+		t.Error("first fetch should not be marked cached")
+	}
+
+	if refreshed := fetchWeb(t, tool, WebInput{URL: srv.URL, Refresh: true}); !strings.Contains(refreshed.Content, "response 2") {
+		t.Errorf("refresh should bypass the cache, got:\n%s", refreshed.Content)
+	}
+	fetchWeb(t, tool, WebInput{URL: srv.URL, Method: "POST", Body: "x"})
+	fetchWeb(t, tool, WebInput{URL: srv.URL, Headers: map[string]string{"Authorization": "Bearer x"}})
+	if hits.Load() != 4 {
+		t.Errorf("requests with a body or headers should not use the cache, hits=%d", hits.Load())
+	}
+
+	uncached := NewWebTool(WebConfig{Policy: NetworkPolicy{AllowPrivate: true}, CacheTTL: -1})
+	fetchWeb(t, uncached, WebInput{URL: srv.URL})
+	fetchWeb(t, uncached, WebInput{URL: srv.URL})
+	if hits.Load() != 6 {
+		t.Errorf("negative TTL should disable caching, hits=%d", hits.Load())
+	}
+}
+
+func TestWebToolNetworkPolicy(t *testing.T) {
+	var hits atomic.Int32
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		if r.URL.Path == "/redirect" {
+			http.Redirect(w, r, strings.Replace(srv.URL, "127.0.0.1", "localhost", 1)+"/admin", http.StatusFound)
+			return
+		}
+		fmt.Fprint(w, "secret")
+	}))
+	defer srv.Close()
+
+	// Private addresses are refused by default, by literal IP and by name
+	strict := NewWebTool(WebConfig{})
+	for _, target := range []string{srv.URL, strings.Replace(srv.URL, "127.0.0.1", "localhost", 1), "http://169.254.169.254/latest/meta-data/"} {
+		if result := fetchWeb(t, strict, WebInput{URL: target}); !result.IsError || !strings.Contains(result.Content, "network policy") {
+			t.Errorf("expected %s to be blocked, got:\n%s", target, result.Content)
+		}
+	}
+	if result := fetchWeb(t, strict, WebInput{URL: "file:///etc/passwd"}); !result.IsError {
+		t.Error("expected non-HTTP scheme to be refused")
+	}
+
+	// The client itself refuses private addresses once DNS has resolved, not just the URL check
+	client := newPolicyClient(&NetworkPolicy{}, 0)
+	if _, err := client.Get(srv.URL); err == nil || !strings.Contains(err.Error(), "private address") {
+		t.Errorf("expected the dialer to refuse loopback, got %v", err)
+	}
+
+	// Redirects are checked against the domain lists
+	denied := NewWebTool(WebConfig{Policy: NetworkPolicy{AllowPrivate: true, DenyDomains: []string{"localhost"}}})
+	if result := fetchWeb(t, denied, WebInput{URL: srv.URL + "/redirect"}); !result.IsError || !strings.Contains(result.Content, "deny_domains") {
+		t.Errorf("expected redirect to a denied domain to fail, got:\n%s", result.Content)
+	}
+
+	allowlisted := NewWebTool(WebConfig{Policy: NetworkPolicy{AllowPrivate: true, AllowDomains: []string{"example.com"}}})
+	if result := fetchWeb(t, allowlisted, WebInput{URL: srv.URL}); !result.IsError || !strings.Contains(result.Content, "allow_domains") {
+		t.Errorf("expected host outside allow_domains to be blocked, got:\n%s", result.Content)
+	}
+
+	if hits.Load() != 1 {
+		t.Errorf("only the allowed redirect source should have been requested, hits=%d", hits.Load())
+	}
+}
+
+func TestNetworkPolicyAddresses(t *testing.T) {
+	policy := &NetworkPolicy{}
+	tests := []struct {
+		addr    string
+		blocked bool
+	}{
+		{"8.8.8.8", false},
+		{"2606:4700::1111", false},
+		{"127.0.0.1", true},
+		{"10.1.2.3", true},
+		{"172.16.0.1", true},
+		{"192.168.1.1", true},
+		{"169.254.169.254", true},
+		{"100.64.0.1", true},
+		{"0.0.0.0", true},
+		{"::1", true},
+		{"fd00::1", true},
+		{"fe80::1", true},
+		{"::ffff:127.0.0.1", true},
+	}
+	for _, tt := range tests {
+		err := policy.CheckAddr(netip.MustParseAddr(tt.addr))
+		if (err != nil) != tt.blocked {
+			t.Errorf("CheckAddr(%s) = %v, want blocked=%v", tt.addr, err, tt.blocked)
+		}
+	}
+
+	domains := &NetworkPolicy{AllowDomains: []string{"*.example.com", "go.dev"}, DenyDomains: []string{"admin.example.com"}}
+	for host, allowed := range map[string]bool{
+		"example.com":         true,
+		"docs.example.com":    true,
+		"admin.example.com":   false,
+		"x.admin.example.com": false,
+		"go.dev":              true,
+		"notgo.dev":           false,
+		"evil.com":            false,
+	} {
+		if err := domains.CheckHost(host); (err == nil) != allowed {
+			t.Errorf("CheckHost(%s) = %v, want allowed=%v", host, err, allowed)
+		}
+	}
+}
@@ -0,0 +1,893 @@
+package tools
+
+import (
+	"bytes"
+	"compress/zlib"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"os/exec"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// webLink is a hyperlink found while extracting a page
+type webLink struct {
+	Text string
+	URL  string
+}
+
+// webPage is the readable content extracted from an HTML document
+type webPage struct {
+	Title    string
+	Markdown string
+	Links    []webLink
+}
+
+// skippedTags never contain readable content
+var skippedTags = map[string]bool{
+	"script": true, "style": true, "noscript": true, "template": true, "svg": true, "canvas": true,
+	"iframe": true, "object": true, "embed": true, "head": true, "select": true, "button": true,
+	"input": true, "textarea": true,
+}
+
+// chromeTags hold site navigation rather than the page's content
+var chromeTags = map[string]bool{"nav": true, "aside": true, "footer": true}
+
+// extractHTML converts an HTML document to Markdown. With a selector only the matching
+// elements are converted; otherwise the main content is detected and page chrome dropped.
+func extractHTML(body []byte, base *url.URL, selector string) (*webPage, error) {
+	doc, err := html.Parse(bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse HTML: %w", err)
+	}
+
+	page := &webPage{}
+	if title := findFirst(doc, func(n *html.Node) bool { return n.Data == "title" }); title != nil {
+		page.Title = collapseSpace(textContent(title))
+	}
+
+	r := &markdownRenderer{base: base, seen: make(map[string]bool)}
+	if selector != "" {
+		sel, err := parseSelector(selector)
+		if err != nil {
+			return nil, err
+		}
+		matches := sel.selectAll(doc)
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("selector %q matched nothing", selector)
+		}
+		parts := make([]string, 0, len(matches))
+		for _, n := range matches {
+			parts = append(parts, r.renderBlock(n))
+		}
+		page.Markdown = strings.Join(parts, "\n\n---\n\n")
+	} else {
+		r.skipChrome = true
+		page.Markdown = r.renderBlock(mainContent(doc))
+	}
+	page.Links = r.links
+	return page, nil
+}
+
+// mainContent picks the element holding the page's primary content: an <article> or <main>
+// when present, else the element whose paragraphs carry the most non-link text
+func mainContent(doc *html.Node) *html.Node {
+	var articles []*html.Node
+	walk(doc, func(n *html.Node) bool {
+		if n.Type == html.ElementNode && (n.Data == "article" || n.Data == "main" || attr(n, "role") == "main") {
+			articles = append(articles, n)
+			return false
+		}
+		return true
+	})
+	if len(articles) > 0 {
+		best := articles[0]
+		for _, n := range articles[1:] {
+			if len(textContent(n)) > len(textContent(best)) {
+				best = n
+			}
+		}
+		return best
+	}
+
+	// Score parents by the paragraphs they contain, crediting grandparents half
+	scores := make(map[*html.Node]float64)
+	var candidates []*html.Node
+	credit := func(n *html.Node, score float64) {
+		if _, ok := scores[n]; !ok {
+			candidates = append(candidates, n)
+		}
+		scores[n] += score
+	}
+	walk(doc, func(n *html.Node) bool {
+		if n.Type != html.ElementNode {
+			return true
+		}
+		if skippedTags[n.Data] || chromeTags[n.Data] {
+			return false
+		}
+		if n.Data != "p" && n.Data != "pre" && n.Data != "td" {
+			return true
+		}
+		text := collapseSpace(textContent(n))
+		if len(text) < 25 || n.Parent == nil {
+			return false
+		}
+		score := float64(len(text)) * (1 - linkDensity(n, len(text)))
+		credit(n.Parent, score)
+		if n.Parent.Parent != nil {
+			credit(n.Parent.Parent, score/2)
+		}
+		return false
+	})
+
+	var best *html.Node
+	for _, n := range candidates {
+		if best == nil || scores[n] > scores[best] {
+			best = n
+		}
+	}
+	if best != nil {
+		return best
+	}
+	if body := findFirst(doc, func(n *html.Node) bool { return n.Data == "body" }); body != nil {
+		return body
+	}
+	return doc
+}
+
+// linkDensity is the fraction of an element's text that sits inside links
+func linkDensity(n *html.Node, textLen int) float64 {
+	if textLen == 0 {
+		return 0
+	}
+	linkLen := 0
+	walk(n, func(c *html.Node) bool {
+		if c.Type == html.ElementNode && c.Data == "a" {
+			linkLen += len(collapseSpace(textContent(c)))
+			return false
+		}
+		return true
+	})
+	return float64(linkLen) / float64(textLen)
+}
+
+// markdownRenderer writes an HTML tree as Markdown, collecting the links it passes
+type markdownRenderer struct {
+	sb         strings.Builder
+	base       *url.URL
+	skipChrome bool // Drop navigation, sidebars and footers
+	lists      []listState
+	links      []webLink
+	seen       map[string]bool
+}
+
+// listState tracks the list being rendered
+type listState struct {
+	ordered bool
+	n       int
+}
+
+// blankLines matches runs of blank lines left by nested blocks
+var blankLines = regexp.MustCompile(`\n[ \t]*\n(?:[ \t]*\n)+`)
+
+// renderBlock renders a node and returns it as tidy Markdown
+func (r *markdownRenderer) renderBlock(n *html.Node) string {
+	saved := r.sb
+	r.sb = strings.Builder{}
+	r.render(n)
+	out := r.sb.String()
+	r.sb = saved
+	return tidyMarkdown(out)
+}
+
+// inner renders a node's children into a separate buffer
+func (r *markdownRenderer) inner(n *html.Node) string {
+	saved := r.sb
+	r.sb = strings.Builder{}
+	r.children(n)
+	out := r.sb.String()
+	r.sb = saved
+	return out
+}
+
+// block separates block-level content with a blank line
+func (r *markdownRenderer) block() {
+	if r.sb.Len() > 0 && !strings.HasSuffix(r.sb.String(), "\n\n") {
+		r.sb.WriteString("\n\n")
+	}
+}
+
+func (r *markdownRenderer) render(n *html.Node) {
+	switch n.Type {
+	case html.TextNode:
+		r.text(n.Data)
+		return
+	case html.ElementNode:
+	default:
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			r.render(c)
+		}
+		return
+	}
+
+	if skippedTags[n.Data] || (r.skipChrome && chromeTags[n.Data]) || hidden(n) {
+		return
+	}
+
+	switch n.Data {
+	case "h1", "h2", "h3", "h4", "h5", "h6":
+		level := int(n.Data[1] - '0')
+		if text := oneLine(r.inner(n)); text != "" {
+			r.block()
+			r.sb.WriteString(strings.Repeat("#", level) + " " + text)
+			r.block()
+		}
+	case "br":
+		r.sb.WriteString("\n")
+	case "hr":
+		r.block()
+		r.sb.WriteString("---")
+		r.block()
+	case "a":
+		r.link(n)
+	case "img":
+		if alt := collapseSpace(attr(n, "alt")); alt != "" {
+			r.sb.WriteString("![" + alt + "](" + r.resolve(attr(n, "src")) + ")")
+		}
+	case "strong", "b":
+		r.wrap(n, "**")
+	case "em", "i":
+		r.wrap(n, "_")
+	case "code", "kbd", "samp":
+		if text := oneLine(textContent(n)); text != "" {
+			r.sb.WriteString("`" + text + "`")
+		}
+	case "pre":
+		r.block()
+		lang := ""
+		if code := findFirst(n, func(c *html.Node) bool { return c.Data == "code" }); code != nil {
+			for _, class := range strings.Fields(attr(code, "class")) {
+				if strings.HasPrefix(class, "language-") {
+					lang = strings.TrimPrefix(class, "language-")
+				}
+			}
+		}
+		r.sb.WriteString("```" + lang + "\n" + strings.Trim(textContent(n), "\n") + "\n```")
+		r.block()
+	case "ul", "ol":
+		r.block()
+		r.lists = append(r.lists, listState{ordered: n.Data == "ol"})
+		r.children(n)
+		r.lists = r.lists[:len(r.lists)-1]
+		r.block()
+	case "li":
+		r.listItem(n)
+	case "blockquote":
+		text := tidyMarkdown(r.inner(n))
+		if text != "" {
+			r.block()
+			r.sb.WriteString("> " + strings.ReplaceAll(text, "\n", "\n> "))
+			r.block()
+		}
+	case "table":
+		r.table(n)
+	case "p", "div", "section", "article", "main", "header", "footer", "nav", "aside", "figure",
+		"figcaption", "form", "fieldset", "details", "summary", "address", "dl", "dt", "dd", "center":
+		r.block()
+		r.children(n)
+		r.block()
+	default:
+		r.children(n)
+	}
+}
+
+func (r *markdownRenderer) children(n *html.Node) {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		r.render(c)
+	}
+}
+
+// text writes a text node with its whitespace collapsed
+func (r *markdownRenderer) text(s string) {
+	s = collapseSpace(s)
+	if s == "" || s == " " {
+		if s == " " && r.sb.Len() > 0 && !strings.HasSuffix(r.sb.String(), " ") && !strings.HasSuffix(r.sb.String(), "\n") {
+			r.sb.WriteString(" ")
+		}
+		return
+	}
+	out := r.sb.String()
+	if out == "" || strings.HasSuffix(out, "\n") || strings.HasSuffix(out, " ") {
+		s = strings.TrimLeft(s, " ")
+	}
+	r.sb.WriteString(s)
+}
+
+// wrap renders inline children between a Markdown marker
+func (r *markdownRenderer) wrap(n *html.Node, marker string) {
+	text := strings.TrimSpace(r.inner(n))
+	if text == "" {
+		return
+	}
+	r.sb.WriteString(marker + text + marker)
+}
+
+// link renders an anchor inline and records it for the link list
+func (r *markdownRenderer) link(n *html.Node) {
+	text := oneLine(r.inner(n))
+	href := strings.TrimSpace(attr(n, "href"))
+	if href == "" || strings.HasPrefix(href, "#") || strings.HasPrefix(strings.ToLower(href), "javascript:") {
+		r.text(text)
+		return
+	}
+	target := r.resolve(href)
+	if text == "" {
+		text = target
+	}
+	r.sb.WriteString("[" + text + "](" + target + ")")
+	if !r.seen[target] {
+		r.seen[target] = true
+		r.links = append(r.links, webLink{Text: text, URL: target})
+	}
+}
+
+// listItem renders a list item with its marker, indenting continuation lines
+func (r *markdownRenderer) listItem(n *html.Node) {
+	marker := "- "
+	if len(r.lists) > 0 {
+		list := &r.lists[len(r.lists)-1]
+		list.n++
+		if list.ordered {
+			marker = strconv.Itoa(list.n) + ". "
+		}
+	}
+
+	// Nested lists come back already marked; indenting the item's lines nests them
+	text := strings.TrimSpace(blankLines.ReplaceAllString(r.inner(n), "\n"))
+	text = strings.ReplaceAll(text, "\n\n", "\n")
+	if !strings.HasSuffix(r.sb.String(), "\n") && r.sb.Len() > 0 {
+		r.sb.WriteString("\n")
+	}
+	r.sb.WriteString(marker + strings.ReplaceAll(text, "\n", "\n"+strings.Repeat(" ", len(marker))) + "\n")
+}
+
+// table renders rows as a Markdown pipe table with the first row as header
+func (r *markdownRenderer) table(n *html.Node) {
+	var rows [][]string
+	walk(n, func(c *html.Node) bool {
+		if c.Type != html.ElementNode {
+			return true
+		}
+		if c != n && c.Data == "table" {
+			return false // Nested tables are flattened into their cell
+		}
+		if c.Data != "tr" {
+			return true
+		}
+		var cells []string
+		for cell := c.FirstChild; cell != nil; cell = cell.NextSibling {
+			if cell.Type == html.ElementNode && (cell.Data == "td" || cell.Data == "th") {
+				cells = append(cells, strings.ReplaceAll(oneLine(r.inner(cell)), "|", `\|`))
+			}
+		}
+		if len(cells) > 0 {
+			rows = append(rows, cells)
+		}
+		return false
+	})
+	if len(rows) == 0 {
+		return
+	}
+
+	width := 0
+	for _, row := range rows {
+		width = max(width, len(row))
+	}
+	r.block()
+	for i, row := range rows {
+		for len(row) < width {
+			row = append(row, "")
+		}
+		r.sb.WriteString("| " + strings.Join(row, " | ") + " |\n")
+		if i == 0 {
+			r.sb.WriteString("|" + strings.Repeat(" --- |", width) + "\n")
+		}
+	}
+	r.block()
+}
+
+// resolve makes a link absolute against the page URL
+func (r *markdownRenderer) resolve(href string) string {
+	if r.base == nil {
+		return href
+	}
+	u, err := r.base.Parse(href)
+	if err != nil {
+		return href
+	}
+	return u.String()
+}
+
+// tidyMarkdown trims trailing spaces and collapses runs of blank lines
+func tidyMarkdown(s string) string {
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " \t")
+	}
+	return strings.TrimSpace(blankLines.ReplaceAllString(strings.Join(lines, "\n"), "\n\n"))
+}
+
+// oneLine flattens rendered content onto a single line
+func oneLine(s string) string {
+	return strings.TrimSpace(collapseSpace(s))
+}
+
+// collapseSpace replaces runs of whitespace with a single space
+func collapseSpace(s string) string {
+	var sb strings.Builder
+	space := false
+	for _, c := range s {
+		if c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f' || c == '\u00a0' {
+			space = true
+			continue
+		}
+		if space {
+			sb.WriteByte(' ')
+			space = false
+		}
+		sb.WriteRune(c)
+	}
+	if space {
+		sb.WriteByte(' ')
+	}
+	return sb.String()
+}
+
+// textContent returns all text beneath a node
+func textContent(n *html.Node) string {
+	var sb strings.Builder
+	walk(n, func(c *html.Node) bool {
+		if c.Type == html.ElementNode && skippedTags[c.Data] {
+			return false
+		}
+		if c.Type == html.TextNode {
+			sb.WriteString(c.Data)
+		}
+		return true
+	})
+	return sb.String()
+}
+
+// hidden reports whether an element is marked invisible
+func hidden(n *html.Node) bool {
+	if _, ok := lookupAttr(n, "hidden"); ok || attr(n, "aria-hidden") == "true" {
+		return true
+	}
+	style := strings.ReplaceAll(strings.ToLower(attr(n, "style")), " ", "")
+	return strings.Contains(style, "display:none") || strings.Contains(style, "visibility:hidden")
+}
+
+// walk visits n and its descendants depth-first; returning false skips a node's children
+func walk(n *html.Node, visit func(*html.Node) bool) {
+	if !visit(n) {
+		return
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		walk(c, visit)
+	}
+}
+
+// findFirst returns the first element beneath n that matches
+func findFirst(n *html.Node, match func(*html.Node) bool) *html.Node {
+	var found *html.Node
+	walk(n, func(c *html.Node) bool {
+		if found != nil {
+			return false
+		}
+		if c.Type == html.ElementNode && match(c) {
+			found = c
+			return false
+		}
+		return true
+	})
+	return found
+}
+
+func attr(n *html.Node, name string) string {
+	v, _ := lookupAttr(n, name)
+	return v
+}
+
+func lookupAttr(n *html.Node, name string) (string, bool) {
+	for _, a := range n.Attr {
+		if a.Key == name {
+			return a.Val, true
+		}
+	}
+	return "", false
+}
+
+// cssSelector is the subset of CSS selectors the web tool supports: type, #id, .class
+// and [attr], [attr=v], [attr^=v], [attr$=v], [attr*=v], [attr~=v] combined with
+// descendant and child combinators, in comma-separated groups
+type cssSelector struct {
+	groups [][]cssCompound
+}
+
+// cssCompound is one compound selector like div.post[data-id]
+type cssCompound struct {
+	tag     string
+	id      string
+	classes []string
+	attrs   []cssAttr
+	child   bool // Joined to the previous compound with '>' rather than a descendant space
+}
+
+// cssAttr is an attribute condition
+type cssAttr struct {
+	name  string
+	op    string
+	value string
+}
+
+// parseSelector parses a CSS selector
+func parseSelector(s string) (*cssSelector, error) {
+	sel := &cssSelector{}
+	for _, group := range strings.Split(s, ",") {
+		compounds, err := parseSelectorGroup(strings.TrimSpace(group))
+		if err != nil {
+			return nil, fmt.Errorf("invalid selector %q: %w", s, err)
+		}
+		sel.groups = append(sel.groups, compounds)
+	}
+	return sel, nil
+}
+
+func parseSelectorGroup(s string) ([]cssCompound, error) {
+	if s == "" {
+		return nil, fmt.Errorf("empty selector")
+	}
+	var compounds []cssCompound
+	child := false
+	for i := 0; i < len(s); {
+		switch s[i] {
+		case ' ', '\t', '\n':
+			i++
+			continue
+		case '>':
+			if len(compounds) == 0 || child {
+				return nil, fmt.Errorf("misplaced '>'")
+			}
+			child = true
+			i++
+			continue
+		}
+		compound, n, err := parseCompound(s[i:])
+		if err != nil {
+			return nil, err
+		}
+		compound.child = child
+		child = false
+		compounds = append(compounds, compound)
+		i += n
+	}
+	if child {
+		return nil, fmt.Errorf("selector ends with '>'")
+	}
+	return compounds, nil
+}
+
+func parseCompound(s string) (cssCompound, int, error) {
+	var c cssCompound
+	i := 0
+	if s[0] == '*' {
+		i = 1
+	} else {
+		i = identLen(s)
+		c.tag = strings.ToLower(s[:i])
+	}
+	for i < len(s) {
+		switch s[i] {
+		case '#', '.':
+			n := identLen(s[i+1:])
+			if n == 0 {
+				return c, 0, fmt.Errorf("expected name after %q", s[i])
+			}
+			if s[i] == '#' {
+				c.id = s[i+1 : i+1+n]
+			} else {
+				c.classes = append(c.classes, s[i+1:i+1+n])
+			}
+			i += 1 + n
+		case '[':
+			end := strings.IndexByte(s[i:], ']')
+			if end < 0 {
+				return c, 0, fmt.Errorf("unclosed '['")
+			}
+			c.attrs = append(c.attrs, parseAttrSelector(s[i+1:i+end]))
+			i += end + 1
+		case ' ', '\t', '\n', '>':
+			return c, i, nil
+		default:
+			return c, 0, fmt.Errorf("unsupported syntax at %q", s[i:])
+		}
+	}
+	if i == 0 {
+		return c, 0, fmt.Errorf("unsupported syntax at %q", s)
+	}
+	return c, i, nil
+}
+
+func parseAttrSelector(s string) cssAttr {
+	eq := strings.IndexByte(s, '=')
+	if eq < 0 {
+		return cssAttr{name: strings.ToLower(strings.TrimSpace(s))}
+	}
+	a := cssAttr{name: s[:eq], op: "="}
+	if eq > 0 && strings.IndexByte("^$*~", s[eq-1]) >= 0 {
+		a.name, a.op = s[:eq-1], s[eq-1:eq+1]
+	}
+	a.name = strings.ToLower(strings.TrimSpace(a.name))
+	a.value = strings.Trim(strings.TrimSpace(s[eq+1:]), `"'`)
+	return a
+}
+
+// identLen is the length of the CSS identifier at the start of s
+func identLen(s string) int {
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if !(c == '-' || c == '_' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= 0x80) {
+			return i
+		}
+	}
+	return len(s)
+}
+
+// selectAll returns the outermost matching elements in document order
+func (s *cssSelector) selectAll(doc *html.Node) []*html.Node {
+	var matches []*html.Node
+	walk(doc, func(n *html.Node) bool {
+		if n.Type != html.ElementNode {
+			return true
+		}
+		for _, group := range s.groups {
+			if matchCompounds(n, group, len(group)-1) {
+				matches = append(matches, n)
+				return false
+			}
+		}
+		return true
+	})
+	return matches
+}
+
+// matchCompounds reports whether n matches compounds[i] with its ancestors matching the rest
+func matchCompounds(n *html.Node, compounds []cssCompound, i int) bool {
+	if !compounds[i].matches(n) {
+		return false
+	}
+	if i == 0 {
+		return true
+	}
+	if compounds[i].child {
+		return n.Parent != nil && matchCompounds(n.Parent, compounds, i-1)
+	}
+	for p := n.Parent; p != nil; p = p.Parent {
+		if matchCompounds(p, compounds, i-1) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *cssCompound) matches(n *html.Node) bool {
+	if n.Type != html.ElementNode || (c.tag != "" && n.Data != c.tag) {
+		return false
+	}
+	if c.id != "" && attr(n, "id") != c.id {
+		return false
+	}
+	classes := strings.Fields(attr(n, "class"))
+	for _, class := range c.classes {
+		if !slices.Contains(classes, class) {
+			return false
+		}
+	}
+	for _, a := range c.attrs {
+		v, ok := lookupAttr(n, a.name)
+		if !ok {
+			return false
+		}
+		switch a.op {
+		case "=":
+			ok = v == a.value
+		case "^=":
+			ok = strings.HasPrefix(v, a.value)
+		case "$=":
+			ok = strings.HasSuffix(v, a.value)
+		case "*=":
+			ok = strings.Contains(v, a.value)
+		case "~=":
+			ok = slices.Contains(strings.Fields(v), a.value)
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// prettyJSON indents a JSON document, returning false if it isn't valid JSON
+func prettyJSON(body []byte) (string, bool) {
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, bytes.TrimSpace(body), "", "  "); err != nil {
+		return "", false
+	}
+	return buf.String(), true
+}
+
+// extractPDF returns the text of a PDF, using pdftotext when it is installed and
+// falling back to reading the text operators of the document's content streams
+func extractPDF(ctx context.Context, data []byte) string {
+	if path, err := exec.LookPath("pdftotext"); err == nil {
+		cmd := exec.CommandContext(ctx, path, "-layout", "-", "-")
+		cmd.Stdin = bytes.NewReader(data)
+		if out, err := cmd.Output(); err == nil && len(bytes.TrimSpace(out)) > 0 {
+			return string(out)
+		}
+	}
+	return pdfText(data)
+}
+
+// pdfStream matches the start of a stream body
+var pdfStream = regexp.MustCompile(`stream\r?\n`)
+
+// pdfText extracts text from the content streams of a PDF
+func pdfText(data []byte) string {
+	var sb strings.Builder
+	for _, loc := range pdfStream.FindAllIndex(data, -1) {
+		if loc[0] >= 3 && string(data[loc[0]-3:loc[0]]) == "end" {
+			continue
+		}
+		end := bytes.Index(data[loc[1]:], []byte("endstream"))
+		if end < 0 {
+			break
+		}
+		dict := data[max(0, loc[0]-512):loc[0]]
+		if i := bytes.LastIndex(dict, []byte("<<")); i >= 0 {
+			dict = dict[i:]
+		}
+		if bytes.Contains(dict, []byte("/Image")) {
+			continue
+		}
+
+		content := data[loc[1] : loc[1]+end]
+		if bytes.Contains(dict, []byte("/FlateDecode")) {
+			zr, err := zlib.NewReader(bytes.NewReader(content))
+			if err != nil {
+				continue
+			}
+			content, err = io.ReadAll(zr)
+			if err != nil && len(content) == 0 {
+				continue
+			}
+		}
+		if text := pdfContentText(content); strings.TrimSpace(text) != "" {
+			sb.WriteString(text)
+			sb.WriteString("\n")
+		}
+	}
+	return strings.TrimSpace(sb.String())
+}
+
+// pdfContentText reads the strings shown by the text operators of a content stream
+func pdfContentText(content []byte) string {
+	var sb strings.Builder
+	var operands []string
+	inArray := false
+	newline := func() {
+		if sb.Len() > 0 && !strings.HasSuffix(sb.String(), "\n") {
+			sb.WriteByte('\n')
+		}
+	}
+
+	for i := 0; i < len(content); i++ {
+		c := content[i]
+		switch {
+		case c == '(':
+			s, n := pdfLiteral(content[i:])
+			operands = append(operands, s)
+			i += n - 1
+		case c == '[':
+			inArray = true
+		case c == ']':
+			inArray = false
+		case c == '-' || c == '.' || (c >= '0' && c <= '9'):
+			j := i + 1
+			for j < len(content) && (content[j] == '.' || content[j] >= '0' && content[j] <= '9') {
+				j++
+			}
+			// Wide negative kerning inside a TJ array stands for a word space
+			if v, err := strconv.ParseFloat(string(content[i:j]), 64); err == nil && inArray && v < -150 {
+				operands = append(operands, " ")
+			}
+			i = j - 1
+		case c == '\'' || c == '"' || c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z':
+			j := i + 1
+			for j < len(content) && (content[j] == '*' || content[j] >= 'A' && content[j] <= 'Z' || content[j] >= 'a' && content[j] <= 'z') {
+				j++
+			}
+			switch op := string(content[i:j]); op {
+			case "Tj", "TJ", "'", `"`:
+				if op == "'" || op == `"` {
+					newline()
+				}
+				for _, s := range operands {
+					sb.WriteString(s)
+				}
+			case "Td", "TD", "T*", "ET":
+				newline()
+			}
+			operands = operands[:0]
+			i = j - 1
+		}
+	}
+	return sb.String()
+}
+
+// pdfLiteral decodes the PDF literal string at the start of s, returning it and its length
+func pdfLiteral(s []byte) (string, int) {
+	var sb strings.Builder
+	depth := 0
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch c {
+		case '(':
+			if depth > 0 {
+				sb.WriteByte(c)
+			}
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return sb.String(), i + 1
+			}
+			sb.WriteByte(c)
+		case '\\':
+			if i+1 >= len(s) {
+				return sb.String(), len(s)
+			}
+			i++
+			switch e := s[i]; e {
+			case 'n':
+				sb.WriteByte('\n')
+			case 'r':
+				sb.WriteByte('\r')
+			case 't':
+				sb.WriteByte('\t')
+			case 'b', 'f', '\n', '\r':
+			case '0', '1', '2', '3', '4', '5', '6', '7':
+				j := i
+				for j < len(s) && j < i+3 && s[j] >= '0' && s[j] <= '7' {
+					j++
+				}
+				v, _ := strconv.ParseUint(string(s[i:j]), 8, 8)
+				sb.WriteByte(byte(v))
+				i = j - 1
+			default:
+				sb.WriteByte(e)
+			}
+		default:
+			sb.WriteByte(c)
+		}
+	}
+	return sb.String(), len(s)
+}
@@ -0,0 +1,138 @@
+package tools
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// NetworkPolicy decides which hosts the web tool may reach
+type NetworkPolicy struct {
+	// AllowPrivate permits loopback, private, link-local and other non-public addresses
+	AllowPrivate bool
+
+	// AllowDomains, when set, limits requests to these domains and their subdomains
+	AllowDomains []string
+
+	// DenyDomains blocks these domains and their subdomains; it wins over AllowDomains
+	DenyDomains []string
+}
+
+// blockedPrefixes are non-public ranges not covered by the netip.Addr helpers
+var blockedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),     // "This" network
+	netip.MustParsePrefix("100.64.0.0/10"), // Carrier-grade NAT
+	netip.MustParsePrefix("192.0.0.0/24"),  // IETF protocol assignments
+	netip.MustParsePrefix("198.18.0.0/15"), // Benchmarking
+	netip.MustParsePrefix("240.0.0.0/4"),   // Reserved
+	netip.MustParsePrefix("64:ff9b::/96"),  // NAT64, can embed private IPv4
+}
+
+// CheckHost reports whether a URL host (without port) may be requested
+func (p *NetworkPolicy) CheckHost(host string) error {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	if host == "" {
+		return fmt.Errorf("missing host")
+	}
+	for _, domain := range p.DenyDomains {
+		if matchDomain(host, domain) {
+			return fmt.Errorf("host %s is blocked by web deny_domains", host)
+		}
+	}
+	if len(p.AllowDomains) > 0 {
+		allowed := false
+		for _, domain := range p.AllowDomains {
+			if matchDomain(host, domain) {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return fmt.Errorf("host %s is not in web allow_domains", host)
+		}
+	}
+	if addr, err := netip.ParseAddr(strings.Trim(host, "[]")); err == nil {
+		return p.CheckAddr(addr)
+	}
+	if !p.AllowPrivate && (host == "localhost" || strings.HasSuffix(host, ".localhost")) {
+		return fmt.Errorf("host %s is a private address", host)
+	}
+	return nil
+}
+
+// CheckAddr reports whether an IP address may be connected to
+func (p *NetworkPolicy) CheckAddr(addr netip.Addr) error {
+	if p.AllowPrivate {
+		return nil
+	}
+	addr = addr.Unmap()
+	if addr.IsLoopback() || addr.IsPrivate() || addr.IsLinkLocalUnicast() || addr.IsLinkLocalMulticast() ||
+		addr.IsInterfaceLocalMulticast() || addr.IsMulticast() || addr.IsUnspecified() {
+		return fmt.Errorf("address %s is a private address", addr)
+	}
+	for _, prefix := range blockedPrefixes {
+		if prefix.Contains(addr) {
+			return fmt.Errorf("address %s is a private address", addr)
+		}
+	}
+	return nil
+}
+
+// matchDomain reports whether host is domain or one of its subdomains
+func matchDomain(host, domain string) bool {
+	domain = strings.ToLower(strings.TrimPrefix(strings.TrimSuffix(domain, "."), "*."))
+	return host == domain || strings.HasSuffix(host, "."+domain)
+}
+
+// newPolicyClient returns an HTTP client that enforces the policy on every request,
+// redirect and dialed address, so DNS names that resolve to private ranges are refused too.
+// Proxies are not used because the policy could not see the address behind them.
+func newPolicyClient(policy *NetworkPolicy, timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{
+		Timeout:   10 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			addrPort, err := netip.ParseAddrPort(address)
+			if err != nil {
+				return err
+			}
+			return policy.CheckAddr(addrPort.Addr())
+		},
+	}
+
+	transport := &http.Transport{
+		Proxy:                 nil,
+		DialContext:           dialer.DialContext,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          20,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: time.Second,
+	}
+
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: transport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= 10 {
+				return fmt.Errorf("stopped after 10 redirects")
+			}
+			if err := checkURLScheme(req.URL.Scheme); err != nil {
+				return err
+			}
+			return policy.CheckHost(req.URL.Hostname())
+		},
+	}
+}
+
+// checkURLScheme allows only plain web URLs
+func checkURLScheme(scheme string) error {
+	if scheme != "http" && scheme != "https" {
+		return fmt.Errorf("unsupported URL scheme %q", scheme)
+	}
+	return nil
+}
@@ -123,7 +123,25 @@ func (t *WriteTool) Execute(ctx context.Context, input json.RawMessage) (*ToolRe
 	}, nil
 }
 
+// ModifiedPaths returns the file the write replaces, so it can be checkpointed first
+func (t *WriteTool) ModifiedPaths(input json.RawMessage) []string {
+	var in WriteInput
+	if err := json.Unmarshal(input, &in); err != nil || in.Path == "" {
+		return nil
+	}
+	if strings.HasPrefix(in.Path, "~/") {
+		home, _ := os.UserHomeDir()
+		in.Path = filepath.Join(home, in.Path[2:])
+	}
+	return []string{in.Path}
+}
+
 // RequiresApproval returns true - writing files needs approval
 func (t *WriteTool) RequiresApproval() bool {
 	return true
 }
+
+// SerialOnly returns true - writes must not race with other file operations
+func (t *WriteTool) SerialOnly() bool {
+	return true
+}
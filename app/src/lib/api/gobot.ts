@@ -9,6 +9,14 @@ export function healthCheck() {
 	return webapi.get<components.HealthResponse>(`/health`)
 }
 
+/**
+ * @description "List tool calls and approval decisions from the audit log"
+ * @param params
+ */
+export function listAgentAudit(params: components.ListAgentAuditRequestParams) {
+	return webapi.get<components.ListAgentAuditResponse>(`/api/v1/agent/audit`, params)
+}
+
 /**
  * @description "List agent sessions"
  */
@@ -32,6 +40,40 @@ export function getAgentSessionMessages(params: components.GetAgentSessionReques
 	return webapi.get<components.GetAgentSessionMessagesResponse>(`/api/v1/agent/sessions/${id}/messages`, params)
 }
 
+/**
+ * @description "Show sessions arranged by fork parentage"
+ */
+export function getAgentSessionTree() {
+	return webapi.get<components.GetAgentSessionTreeResponse>(`/api/v1/agent/sessions/tree`)
+}
+
+/**
+ * @description "Fork a session at a message into a new session"
+ * @param params
+ * @param req
+ */
+export function forkAgentSession(params: components.ForkAgentSessionRequestParams, req: components.ForkAgentSessionRequest, id: string) {
+	return webapi.post<components.AgentSession>(`/api/v1/agent/sessions/${id}/fork`, params, req)
+}
+
+/**
+ * @description "Rewind a session, removing messages after a message"
+ * @param params
+ * @param req
+ */
+export function rewindAgentSession(params: components.RewindAgentSessionRequestParams, req: components.RewindAgentSessionRequest, id: string) {
+	return webapi.post<components.RewindAgentSessionResponse>(`/api/v1/agent/sessions/${id}/rewind`, params, req)
+}
+
+/**
+ * @description "Restore files changed by write and edit calls after a message"
+ * @param params
+ * @param req
+ */
+export function undoAgentSession(params: components.UndoAgentSessionRequestParams, req: components.UndoAgentSessionRequest, id: string) {
+	return webapi.post<components.UndoAgentSessionResponse>(`/api/v1/agent/sessions/${id}/undo`, params, req)
+}
+
 /**
  * @description "Get agent settings"
  */
@@ -54,6 +96,38 @@ export function getSimpleAgentStatus() {
 	return webapi.get<components.SimpleAgentStatusResponse>(`/api/v1/agent/status`)
 }
 
+/**
+ * @description "Stop in-flight agent runs"
+ * @param req
+ */
+export function stopAgentRun(req: components.StopAgentRunRequest) {
+	return webapi.post<components.MessageResponse>(`/api/v1/agent/stop`, req)
+}
+
+/**
+ * @description "List orchestrator sub-agents, newest first"
+ * @param params
+ */
+export function listSubAgents(params: components.ListSubAgentsRequestParams) {
+	return webapi.get<components.ListSubAgentsResponse>(`/api/v1/agent/subagents`, params)
+}
+
+/**
+ * @description "Get a sub-agent with its conversation"
+ * @param params
+ */
+export function getSubAgent(params: components.SubAgentRequestParams, id: string) {
+	return webapi.get<components.GetSubAgentResponse>(`/api/v1/agent/subagents/${id}`, params)
+}
+
+/**
+ * @description "Cancel a running sub-agent"
+ * @param params
+ */
+export function cancelSubAgent(params: components.SubAgentRequestParams, id: string) {
+	return webapi.post<components.MessageResponse>(`/api/v1/agent/subagents/${id}/cancel`, params)
+}
+
 /**
  * @description "List connected agents"
  */
@@ -1,6 +1,21 @@
 // Code generated by goctl. DO NOT EDIT.
 // goctl 1.9.0
 
+export interface AgentAuditEntry {
+	id: number
+	createdAt: string
+	sessionKey?: string
+	toolCallId?: string
+	tool: string
+	input: string
+	output: string // Truncated
+	isError: boolean
+	approved: boolean // False when the call was denied
+	channel?: string // cli, web or auto
+	approver?: string
+	durationMs: number
+}
+
 export interface AgentConnectRequest {
 	agentId: string
 }
@@ -21,10 +36,17 @@ export interface AgentSession {
 	name?: string
 	summary?: string
 	messageCount: number
+	parentId?: string
+	forkMessageId?: number
 	createdAt: string
 	updatedAt: string
 }
 
+export interface AgentSessionNode {
+	session: AgentSession
+	children: Array<AgentSessionNode>
+}
+
 export interface AgentSettings {
 	autonomousMode: boolean
 	autoApproveRead: boolean
@@ -58,6 +80,10 @@ export interface AuthProfile {
 	isActive: boolean
 	createdAt: string
 	updatedAt: string
+	usageCount: number
+	errorCount: number // Errors since the last successful use
+	lastUsedAt?: string
+	cooldownUntil?: string // Set while the key is skipped after a 429/401
 }
 
 export interface CLIAvailability {
@@ -195,6 +221,13 @@ export interface ForgotPasswordRequest {
 	email: string
 }
 
+export interface ForkAgentSessionRequest {
+	messageId?: number // Fork at this message (default: latest)
+	key?: string // New session key (default: <key>-fork-N)
+}
+export interface ForkAgentSessionRequestParams {
+}
+
 export interface GetAgentSessionMessagesResponse {
 	messages: Array<SessionMessage>
 	total: number
@@ -205,6 +238,10 @@ export interface GetAgentSessionRequest {
 export interface GetAgentSessionRequestParams {
 }
 
+export interface GetAgentSessionTreeResponse {
+	sessions: Array<AgentSessionNode>
+}
+
 export interface GetAgentSettingsResponse {
 	settings: AgentSettings
 }
@@ -267,6 +304,11 @@ export interface GetSkillResponse {
 	skill: ExtensionSkill
 }
 
+export interface GetSubAgentResponse {
+	subAgent: SubAgentRun
+	messages: Array<SessionMessage> // The sub-agent's conversation
+}
+
 export interface GetUnreadCountResponse {
 	count: number
 }
@@ -281,6 +323,25 @@ export interface HealthResponse {
 	timestamp: string
 }
 
+export interface ListAgentAuditRequest {
+}
+export interface ListAgentAuditRequestParams {
+	session?: string // Session key filter
+	tool?: string // Tool name filter
+	channel?: string // cli, web or auto
+	approver?: string // Approver filter
+	errors?: boolean // Only calls that returned an error
+	denied?: boolean // Only denied calls
+	since?: string // Duration (24h), YYYY-MM-DD or RFC3339
+	until?: string // Duration (24h), YYYY-MM-DD or RFC3339 (exclusive)
+	limit?: number
+	offset?: number
+}
+
+export interface ListAgentAuditResponse {
+	entries: Array<AgentAuditEntry>
+}
+
 export interface ListAgentSessionsResponse {
 	sessions: Array<AgentSession>
 	total: number
@@ -351,6 +412,17 @@ export interface ListOAuthProvidersResponse {
 	providers: Array<OAuthProvider>
 }
 
+export interface ListSubAgentsRequest {
+}
+export interface ListSubAgentsRequestParams {
+	session?: string // Parent session key filter
+}
+
+export interface ListSubAgentsResponse {
+	subAgents: Array<SubAgentRun>
+	total: number
+}
+
 export interface LoginRequest {
 	email: string
 	password: string
@@ -384,6 +456,7 @@ export interface ModelPricing {
 	input?: number
 	output?: number
 	cachedInput?: number
+	cacheWrite?: number
 }
 
 export interface Notification {
@@ -442,6 +515,22 @@ export interface ResetPasswordRequest {
 	newPassword: string
 }
 
+export interface RestoredFile {
+	path: string
+	messageId: number // Earliest undone message that changed it
+	deleted: boolean // Created by the agent, so removed
+}
+
+export interface RewindAgentSessionRequest {
+	messageId: number // Keep messages up to and including this one
+}
+export interface RewindAgentSessionRequestParams {
+}
+
+export interface RewindAgentSessionResponse {
+	removed: number
+}
+
 export interface SearchChatMessagesRequest {
 }
 export interface SearchChatMessagesRequestParams {
@@ -470,6 +559,7 @@ export interface SessionMessage {
 	id: number
 	role: string
 	content?: string
+	archived?: boolean // Replaced by a compaction summary
 	createdAt: string
 }
 
@@ -485,6 +575,30 @@ export interface SimpleAgentStatusResponse {
 	uptime?: number
 }
 
+export interface StopAgentRunRequest {
+	sessionKey?: string // Stop runs in this session
+	requestId?: string // Stop this run (both empty: stop all runs)
+}
+
+export interface SubAgentRequest {
+}
+export interface SubAgentRequestParams {
+}
+
+export interface SubAgentRun {
+	id: string
+	parentSessionKey?: string // Session whose agent spawned it
+	sessionKey: string // The sub-agent's own session
+	type: string
+	description: string
+	task: string
+	status: string // pending, running, completed, failed, cancelled
+	result?: string
+	error?: string
+	startedAt: string
+	completedAt?: string
+}
+
 export interface TaskRouting {
 	vision?: string
 	reasoning?: string
@@ -520,6 +634,17 @@ export interface ToggleSkillResponse {
 	enabled: boolean
 }
 
+export interface UndoAgentSessionRequest {
+	messageId?: number // Restore files as they were at this message (default: before the last run)
+	dryRun?: boolean // Report the files without changing them
+}
+export interface UndoAgentSessionRequestParams {
+}
+
+export interface UndoAgentSessionResponse {
+	files: Array<RestoredFile>
+}
+
 export interface UpdateAgentSettingsRequest {
 	autonomousMode: boolean
 	autoApproveRead: boolean
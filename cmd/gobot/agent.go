@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"os/signal"
@@ -17,6 +18,7 @@ import (
 
 	"gobot/agent/ai"
 	agentcfg "gobot/agent/config"
+	"gobot/agent/orchestrator"
 	"gobot/agent/runner"
 	"gobot/agent/session"
 	"gobot/agent/tools"
@@ -28,22 +30,212 @@ import (
 type agentState struct {
 	conn            *websocket.Conn
 	connMu          sync.Mutex
-	pendingApproval map[string]chan bool
+	pendingApproval map[string]chan approvalDecision
 	approvalMu      sync.RWMutex
-	quiet           bool // Suppress console output for clean CLI
+	runs            activeRuns
+	orchestrator    *orchestrator.Orchestrator           // Sub-agents spawned by the task tool
+	jobs            *tools.JobManager                    // Background bash jobs, stopped when their session ends
+	policyRules     []*tools.PolicyRule                  // Config rules kept when settings replace the policy
+	policyExecutors map[tools.PolicyLevel]tools.Executor // Config sandbox kept when settings replace the policy
+	quiet           bool                                 // Suppress console output for clean CLI
+}
+
+// approvalDecision is a user's answer to an approval request
+type approvalDecision struct {
+	approved bool
+	approver string // User who answered, if the server reported one
+}
+
+// activeRuns tracks in-flight runs so cancel requests can stop them.
+// Runs execute off the read loop, so cancel, steer and approval frames are
+// still read while a run is busy; the runner queues runs on the same session.
+type activeRuns struct {
+	mu   sync.Mutex
+	runs map[string]activeRun // request ID -> run
+}
+
+// activeRun is a queued or running request
+type activeRun struct {
+	sessionKey string
+	cancel     context.CancelFunc
+}
+
+// start registers a run and executes fn in the background with a cancellable context
+func (a *activeRuns) start(ctx context.Context, requestID, sessionKey string, fn func(ctx context.Context)) {
+	runCtx, cancel := context.WithCancel(ctx)
+
+	a.mu.Lock()
+	if a.runs == nil {
+		a.runs = make(map[string]activeRun)
+	}
+	a.runs[requestID] = activeRun{sessionKey: sessionKey, cancel: cancel}
+	a.mu.Unlock()
+
+	go func() {
+		defer func() {
+			a.mu.Lock()
+			delete(a.runs, requestID)
+			a.mu.Unlock()
+			cancel()
+		}()
+		fn(runCtx)
+	}()
+}
+
+// cancel stops runs matching requestID and sessionKey (empty matches any) and returns how many it stopped
+func (a *activeRuns) cancel(requestID, sessionKey string) int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	cancelled := 0
+	for id, run := range a.runs {
+		if (requestID == "" || id == requestID) && (sessionKey == "" || run.sessionKey == sessionKey) {
+			run.cancel()
+			cancelled++
+		}
+	}
+	return cancelled
+}
+
+// handleCancel answers a cancel request from the server
+func (s *agentState) handleCancel(frameID, requestID, sessionKey string) {
+	cancelled := s.runs.cancel(requestID, sessionKey)
+	if !s.quiet {
+		fmt.Printf("\n\033[33m[Cancel]\033[0m stopped %d run(s)\n", cancelled)
+	}
+	s.sendFrame(map[string]any{
+		"type":    "res",
+		"id":      frameID,
+		"ok":      true,
+		"payload": map[string]any{"cancelled": cancelled},
+	})
+}
+
+// handleCancelSubAgent answers a request from the server to stop a sub-agent
+func (s *agentState) handleCancelSubAgent(frameID, agentID string) {
+	if s.orchestrator == nil {
+		s.sendFrame(map[string]any{"type": "res", "id": frameID, "ok": false, "error": "orchestrator not configured"})
+		return
+	}
+	if err := s.orchestrator.CancelAgent(agentID); err != nil {
+		s.sendFrame(map[string]any{"type": "res", "id": frameID, "ok": false, "error": err.Error()})
+		return
+	}
+	if !s.quiet {
+		fmt.Printf("\n\033[33m[Cancel]\033[0m stopped sub-agent %s\n", agentID)
+	}
+	s.sendFrame(map[string]any{
+		"type":    "res",
+		"id":      frameID,
+		"ok":      true,
+		"payload": map[string]any{"cancelled": agentID},
+	})
+}
+
+// forwardSubAgentEvent sends a sub-agent's stream event to the server as an event frame
+// whose ID is the sub-agent ID, so the UI can show its progress under the parent session
+func (s *agentState) forwardSubAgentEvent(e orchestrator.SubAgentEvent) {
+	payload := map[string]any{
+		"parent_session_key": e.ParentSessionKey,
+		"description":        e.Description,
+		"status":             e.Status,
+		"event":              e.Event.Type,
+	}
+	switch e.Event.Type {
+	case ai.EventTypeText:
+		payload["chunk"] = e.Event.Text
+	case ai.EventTypeToolCall:
+		payload["tool"] = e.Event.ToolCall.Name
+		payload["input"] = e.Event.ToolCall.Input
+	case ai.EventTypeToolResult:
+		payload["tool_result"] = e.Event.Text
+	case ai.EventTypeUsage:
+		payload["usage"] = e.Event.Usage
+	case ai.EventTypeDone:
+		payload["result"] = e.Event.Text
+	}
+	if e.Event.Error != nil {
+		payload["error"] = e.Event.Error.Error()
+	}
+
+	s.sendFrame(map[string]any{
+		"type":    "event",
+		"id":      e.AgentID,
+		"method":  "subagent",
+		"payload": payload,
+	})
+}
+
+// handleEndSession answers a request from the server to stop a deleted session's background jobs
+func (s *agentState) handleEndSession(frameID, sessionKey string) {
+	killed := 0
+	if s.jobs != nil && sessionKey != "" {
+		killed = s.jobs.EndSession(sessionKey)
+	}
+	if killed > 0 && !s.quiet {
+		fmt.Printf("\n\033[33m[Jobs]\033[0m stopped %d background job(s) in %s\n", killed, sessionKey)
+	}
+	s.sendFrame(map[string]any{
+		"type":    "res",
+		"id":      frameID,
+		"ok":      true,
+		"payload": map[string]any{"killed": killed},
+	})
+}
+
+// forwardJobEvent sends a background job's output or exit to the server as an event frame
+// whose ID is the job ID, so the UI can show it under the session that started the job
+func (s *agentState) forwardJobEvent(e tools.JobEvent) {
+	s.sendFrame(map[string]any{
+		"type":   "event",
+		"id":     e.JobID,
+		"method": "job",
+		"payload": map[string]any{
+			"session_key": e.SessionKey,
+			"command":     e.Command,
+			"event":       e.Type,
+			"output":      e.Output,
+			"status":      e.Status,
+			"exit_code":   e.ExitCode,
+		},
+	})
+}
+
+// settingsPolicy builds the policy for approval settings changed in the web UI, keeping the config rules and sandbox
+func (s *agentState) settingsPolicy(level, askMode string) *tools.Policy {
+	policy := tools.NewPolicyFromConfig(level, askMode, nil)
+	policy.Rules = s.policyRules
+	policy.Executors = s.policyExecutors
+	return policy
+}
+
+// runErrorPayload describes a run error for a stream frame, with a code clients can act on
+func runErrorPayload(err error) map[string]any {
+	payload := map[string]any{"error": err.Error()}
+	if runner.IsBudgetExceeded(err) {
+		payload["code"] = "budget_exceeded"
+	} else if errors.Is(err, runner.ErrCancelled) {
+		payload["code"] = "cancelled"
+	}
+	return payload
 }
 
 // sendFrame sends a JSON frame to the server
 func (s *agentState) sendFrame(frame map[string]any) error {
+	data, _ := json.Marshal(frame)
+	return s.send(data)
+}
+
+// send writes an encoded frame; runs and the read loop share the connection
+func (s *agentState) send(data []byte) error {
 	s.connMu.Lock()
 	defer s.connMu.Unlock()
-	data, _ := json.Marshal(frame)
 	return s.conn.WriteMessage(websocket.TextMessage, data)
 }
 
 // requestApproval sends an approval request and waits for response
 func (s *agentState) requestApproval(ctx context.Context, requestID, toolName string, input json.RawMessage) (bool, error) {
-	respCh := make(chan bool, 1)
+	respCh := make(chan approvalDecision, 1)
 	s.approvalMu.Lock()
 	s.pendingApproval[requestID] = respCh
 	s.approvalMu.Unlock()
@@ -67,21 +259,22 @@ func (s *agentState) requestApproval(ctx context.Context, requestID, toolName st
 	}
 
 	select {
-	case approved := <-respCh:
-		return approved, nil
+	case decision := <-respCh:
+		tools.SetApprover(ctx, decision.approver)
+		return decision.approved, nil
 	case <-ctx.Done():
 		return false, ctx.Err()
 	}
 }
 
 // handleApprovalResponse processes an approval response from the server
-func (s *agentState) handleApprovalResponse(requestID string, approved bool) {
+func (s *agentState) handleApprovalResponse(requestID string, approved bool, approver string) {
 	s.approvalMu.RLock()
 	ch, ok := s.pendingApproval[requestID]
 	s.approvalMu.RUnlock()
 	if ok {
 		select {
-		case ch <- approved:
+		case ch <- approvalDecision{approved: approved, approver: approver}:
 		default:
 		}
 	}
@@ -146,7 +339,7 @@ func runAgentLoopWithOptions(ctx context.Context, cfg *agentcfg.Config, serverUR
 
 	state := &agentState{
 		conn:            conn,
-		pendingApproval: make(map[string]chan bool),
+		pendingApproval: make(map[string]chan approvalDecision),
 		quiet:           opts.Quiet,
 	}
 
@@ -166,6 +359,14 @@ func runAgentLoopWithOptions(ctx context.Context, cfg *agentcfg.Config, serverUR
 		cfg.Policy.AskMode,
 		cfg.Policy.Allowlist,
 	)
+	if err := policy.SetRules(cfg.Policy.Rules); err != nil {
+		return fmt.Errorf("invalid policy rules: %w", err)
+	}
+	if err := policy.SetSandbox(cfg.Sandbox); err != nil {
+		return fmt.Errorf("invalid sandbox config: %w", err)
+	}
+	state.policyRules = policy.Rules
+	state.policyExecutors = policy.Executors
 
 	var approvalCounter int64
 	policy.ApprovalCallback = func(ctx context.Context, toolName string, input json.RawMessage) (bool, error) {
@@ -176,6 +377,19 @@ func runAgentLoopWithOptions(ctx context.Context, cfg *agentcfg.Config, serverUR
 
 	registry := tools.NewRegistry(policy)
 	registry.RegisterDefaults()
+	registerWebTool(cfg, registry)
+	registry.SetMaxParallel(cfg.MaxParallelTools)
+	registry.SetSerial(cfg.SerialTools...)
+	registry.SetCheckpoints(sessions) // Write and edit calls can be undone with "gobot undo"
+	state.jobs = registry.Jobs()
+	state.jobs.SetEventHandler(state.forwardJobEvent)
+	defer state.jobs.KillAll()
+	auditLog := openAuditLog(cfg, registry, false)
+	defer auditLog.Close()
+
+	// Mount tools from external MCP servers
+	mcpClients := mountMCPServers(ctx, cfg, registry)
+	defer mcpClients.Close()
 
 	// Create memory tool for auto-extraction (requires shared database)
 	var memoryTool *tools.MemoryTool
@@ -208,12 +422,17 @@ func runAgentLoopWithOptions(ctx context.Context, cfg *agentcfg.Config, serverUR
 	// We'll set this callback after creating the runner below
 	taskTool.CreateOrchestrator(cfg, sessions, providers, registry)
 	registry.Register(taskTool)
+	state.orchestrator = taskTool.GetOrchestrator()
+	state.orchestrator.SetEventHandler(state.forwardSubAgentEvent)
 
 	agentStatusTool := tools.NewAgentStatusTool()
 	agentStatusTool.SetOrchestrator(taskTool.GetOrchestrator())
 	registry.Register(agentStatusTool)
 
 	r := runner.New(cfg, sessions, providers, registry)
+	if profiles := useAuthProfiles(cfg, r); profiles != nil {
+		defer profiles.Close()
+	}
 
 	// Set up task-based model selector for intelligent model routing
 	modelsConfig := provider.GetModelsConfig()
@@ -221,6 +440,7 @@ func runAgentLoopWithOptions(ctx context.Context, cfg *agentcfg.Config, serverUR
 		if modelsConfig.TaskRouting != nil {
 			selector := ai.NewModelSelector(modelsConfig)
 			r.SetModelSelector(selector)
+			taskTool.GetOrchestrator().SetModelSelector(selector)
 		}
 		// Set up fuzzy matcher for user model switch requests
 		fuzzyMatcher := ai.NewFuzzyMatcher(modelsConfig)
@@ -247,12 +467,21 @@ func runAgentLoopWithOptions(ctx context.Context, cfg *agentcfg.Config, serverUR
 
 			// Collect result
 			var result strings.Builder
+			var runErr error
 			for event := range events {
-				if event.Type == ai.EventTypeText {
+				switch event.Type {
+				case ai.EventTypeText:
 					result.WriteString(event.Text)
+				case ai.EventTypeError:
+					runErr = event.Error
 				}
 			}
 
+			// Unattended jobs stop silently at a hard budget; surface it in cron history
+			if runner.IsBudgetExceeded(runErr) {
+				return runErr
+			}
+
 			// Optionally deliver result to channel
 			if deliver != nil && opts.ChannelManager != nil {
 				ch, ok := opts.ChannelManager.Get(deliver.Channel)
@@ -323,6 +552,11 @@ func runAgent(cfg *agentcfg.Config, serverURL string, dangerously bool) {
 	fmt.Println("\033[32m✓ Connected\033[0m")
 	fmt.Println("Waiting for tasks... (Ctrl+C to exit)")
 
+	state := &agentState{
+		conn:            conn,
+		pendingApproval: make(map[string]chan approvalDecision),
+	}
+
 	sessions, err := session.New(cfg.DBPath())
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error opening database: %v\n", err)
@@ -345,8 +579,31 @@ func runAgent(cfg *agentcfg.Config, serverURL string, dangerously bool) {
 			cfg.Policy.Allowlist,
 		)
 	}
+	if err := policy.SetRules(cfg.Policy.Rules); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: invalid policy rules: %v\n", err)
+		os.Exit(1)
+	}
+	if err := policy.SetSandbox(cfg.Sandbox); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: invalid sandbox config: %v\n", err)
+		os.Exit(1)
+	}
+	state.policyRules = policy.Rules
+	state.policyExecutors = policy.Executors
 	registry := tools.NewRegistry(policy)
 	registry.RegisterDefaults()
+	registerWebTool(cfg, registry)
+	registry.SetMaxParallel(cfg.MaxParallelTools)
+	registry.SetSerial(cfg.SerialTools...)
+	registry.SetCheckpoints(sessions) // Write and edit calls can be undone with "gobot undo"
+	state.jobs = registry.Jobs()
+	state.jobs.SetEventHandler(state.forwardJobEvent)
+	defer state.jobs.KillAll()
+	auditLog := openAuditLog(cfg, registry, dangerously)
+	defer auditLog.Close()
+
+	// Mount tools from external MCP servers
+	mcpClients := mountMCPServers(context.Background(), cfg, registry)
+	defer mcpClients.Close()
 
 	// Create memory tool for auto-extraction
 	memoryTool, memErr := tools.NewMemoryTool(tools.MemoryConfig{})
@@ -359,12 +616,17 @@ func runAgent(cfg *agentcfg.Config, serverURL string, dangerously bool) {
 	taskTool := tools.NewTaskTool()
 	taskTool.CreateOrchestrator(cfg, sessions, providers, registry)
 	registry.Register(taskTool)
+	state.orchestrator = taskTool.GetOrchestrator()
+	state.orchestrator.SetEventHandler(state.forwardSubAgentEvent)
 
 	agentStatusTool := tools.NewAgentStatusTool()
 	agentStatusTool.SetOrchestrator(taskTool.GetOrchestrator())
 	registry.Register(agentStatusTool)
 
 	r := runner.New(cfg, sessions, providers, registry)
+	if profiles := useAuthProfiles(cfg, r); profiles != nil {
+		defer profiles.Close()
+	}
 
 	// Set up task-based model selector for intelligent model routing
 	modelsConfig := provider.GetModelsConfig()
@@ -372,6 +634,7 @@ func runAgent(cfg *agentcfg.Config, serverURL string, dangerously bool) {
 		if modelsConfig.TaskRouting != nil {
 			selector := ai.NewModelSelector(modelsConfig)
 			r.SetModelSelector(selector)
+			taskTool.GetOrchestrator().SetModelSelector(selector)
 		}
 		// Set up fuzzy matcher for user model switch requests
 		fuzzyMatcher := ai.NewFuzzyMatcher(modelsConfig)
@@ -409,20 +672,23 @@ func runAgent(cfg *agentcfg.Config, serverURL string, dangerously bool) {
 				return
 			}
 
-			handleAgentMessage(ctx, conn, r, message)
+			handleAgentMessage(ctx, state, r, message)
 		}
 	}
 }
 
 // handleAgentMessage processes a message from the server
-func handleAgentMessage(ctx context.Context, conn *websocket.Conn, r *runner.Runner, message []byte) {
+func handleAgentMessage(ctx context.Context, state *agentState, r *runner.Runner, message []byte) {
 	var frame struct {
 		Type   string `json:"type"`
 		ID     string `json:"id"`
 		Method string `json:"method"`
 		Params struct {
-			Prompt     string `json:"prompt"`
-			SessionKey string `json:"session_key"`
+			Prompt      string                `json:"prompt"`
+			Attachments []session.ContentPart `json:"attachments"` // Files sent with the prompt
+			SessionKey  string                `json:"session_key"`
+			RequestID   string                `json:"request_id"` // For cancel
+			AgentID     string                `json:"agent_id"`   // For cancel_subagent
 		} `json:"params"`
 	}
 
@@ -442,7 +708,23 @@ func handleAgentMessage(ctx context.Context, conn *websocket.Conn, r *runner.Run
 				"payload": map[string]any{"pong": true},
 			}
 			data, _ := json.Marshal(response)
-			conn.WriteMessage(websocket.TextMessage, data)
+			state.send(data)
+
+		case "steer":
+			// Join the run in progress on the session, or start a run if it has finished.
+			// Steering carries text only, so messages with attachments queue a run instead.
+			if frame.Params.SessionKey != "" && len(frame.Params.Attachments) == 0 && r.Steer(frame.Params.SessionKey, frame.Params.Prompt) {
+				fmt.Printf("\n\033[36m[Steer %s]\033[0m %s\n", frame.ID, frame.Params.Prompt)
+				data, _ := json.Marshal(map[string]any{
+					"type":    "res",
+					"id":      frame.ID,
+					"ok":      true,
+					"payload": map[string]any{"steered": true},
+				})
+				state.send(data)
+				break
+			}
+			fallthrough
 
 		case "run", "generate_title":
 			sessionKey := frame.Params.SessionKey
@@ -458,82 +740,104 @@ func handleAgentMessage(ctx context.Context, conn *websocket.Conn, r *runner.Run
 				fmt.Printf("\n\033[36m[Task %s]\033[0m %s\n", frame.ID, frame.Params.Prompt)
 			}
 
-			events, err := r.Run(ctx, &runner.RunRequest{
-				SessionKey: sessionKey,
-				Prompt:     frame.Params.Prompt,
-			})
-			fmt.Printf("[Agent] Run started, events channel created, err=%v\n", err)
+			requestID, prompt, attachments := frame.ID, frame.Params.Prompt, frame.Params.Attachments
+			state.runs.start(ctx, requestID, sessionKey, func(ctx context.Context) {
+				events, err := r.Run(ctx, &runner.RunRequest{
+					SessionKey:  sessionKey,
+					Prompt:      prompt,
+					Attachments: attachments,
+				})
+				fmt.Printf("[Agent] Run started, events channel created, err=%v\n", err)
+
+				if err != nil {
+					response := map[string]any{
+						"type":  "res",
+						"id":    requestID,
+						"ok":    false,
+						"error": err.Error(),
+					}
+					data, _ := json.Marshal(response)
+					state.send(data)
+					return
+				}
 
-			if err != nil {
+				var result strings.Builder
+				eventCount := 0
+				for event := range events {
+					eventCount++
+					fmt.Printf("[Agent] Event %d: type=%s text_len=%d\n", eventCount, event.Type, len(event.Text))
+					switch event.Type {
+					case ai.EventTypeText:
+						result.WriteString(event.Text)
+						fmt.Print(event.Text)
+						chunk := map[string]any{
+							"type": "stream",
+							"id":   requestID,
+							"payload": map[string]any{
+								"chunk": event.Text,
+							},
+						}
+						chunkData, _ := json.Marshal(chunk)
+						fmt.Printf("[Agent] Sending stream frame: %s\n", string(chunkData))
+						state.send(chunkData)
+
+					case ai.EventTypeToolCall:
+						toolEvent := map[string]any{
+							"type": "stream",
+							"id":   requestID,
+							"payload": map[string]any{
+								"tool":  event.ToolCall.Name,
+								"input": event.ToolCall.Input,
+							},
+						}
+						toolData, _ := json.Marshal(toolEvent)
+						state.send(toolData)
+
+					case ai.EventTypeToolResult:
+						resultEvent := map[string]any{
+							"type": "stream",
+							"id":   requestID,
+							"payload": map[string]any{
+								"tool_result": event.Text,
+							},
+						}
+						resultData, _ := json.Marshal(resultEvent)
+						state.send(resultData)
+
+					case ai.EventTypeError:
+						fmt.Printf("\n\033[31m[Error]\033[0m %v\n", event.Error)
+						errorData, _ := json.Marshal(map[string]any{
+							"type":    "stream",
+							"id":      requestID,
+							"payload": runErrorPayload(event.Error),
+						})
+						state.send(errorData)
+					}
+				}
+				fmt.Println()
+
+				fmt.Printf("[Agent] Events complete, total events=%d, result_len=%d\n", eventCount, result.Len())
 				response := map[string]any{
-					"type":  "res",
-					"id":    frame.ID,
-					"ok":    false,
-					"error": err.Error(),
+					"type": "res",
+					"id":   requestID,
+					"ok":   true,
+					"payload": map[string]any{
+						"result": result.String(),
+					},
 				}
 				data, _ := json.Marshal(response)
-				conn.WriteMessage(websocket.TextMessage, data)
-				return
-			}
+				fmt.Printf("[Agent] Sending final response for %s\n", requestID)
+				state.send(data)
+			})
 
-			var result strings.Builder
-			eventCount := 0
-			for event := range events {
-				eventCount++
-				fmt.Printf("[Agent] Event %d: type=%s text_len=%d\n", eventCount, event.Type, len(event.Text))
-				switch event.Type {
-				case ai.EventTypeText:
-					result.WriteString(event.Text)
-					fmt.Print(event.Text)
-					chunk := map[string]any{
-						"type": "stream",
-						"id":   frame.ID,
-						"payload": map[string]any{
-							"chunk": event.Text,
-						},
-					}
-					chunkData, _ := json.Marshal(chunk)
-					fmt.Printf("[Agent] Sending stream frame: %s\n", string(chunkData))
-					conn.WriteMessage(websocket.TextMessage, chunkData)
-
-				case ai.EventTypeToolCall:
-					toolEvent := map[string]any{
-						"type": "stream",
-						"id":   frame.ID,
-						"payload": map[string]any{
-							"tool":  event.ToolCall.Name,
-							"input": event.ToolCall.Input,
-						},
-					}
-					toolData, _ := json.Marshal(toolEvent)
-					conn.WriteMessage(websocket.TextMessage, toolData)
-
-				case ai.EventTypeToolResult:
-					resultEvent := map[string]any{
-						"type": "stream",
-						"id":   frame.ID,
-						"payload": map[string]any{
-							"tool_result": event.Text,
-						},
-					}
-					resultData, _ := json.Marshal(resultEvent)
-					conn.WriteMessage(websocket.TextMessage, resultData)
-				}
-			}
-			fmt.Println()
+		case "cancel":
+			state.handleCancel(frame.ID, frame.Params.RequestID, frame.Params.SessionKey)
 
-			fmt.Printf("[Agent] Events complete, total events=%d, result_len=%d\n", eventCount, result.Len())
-			response := map[string]any{
-				"type": "res",
-				"id":   frame.ID,
-				"ok":   true,
-				"payload": map[string]any{
-					"result": result.String(),
-				},
-			}
-			data, _ := json.Marshal(response)
-			fmt.Printf("[Agent] Sending final response for %s\n", frame.ID)
-			conn.WriteMessage(websocket.TextMessage, data)
+		case "cancel_subagent":
+			state.handleCancelSubAgent(frame.ID, frame.Params.AgentID)
+
+		case "end_session":
+			state.handleEndSession(frame.ID, frame.Params.SessionKey)
 
 		default:
 			response := map[string]any{
@@ -543,7 +847,7 @@ func handleAgentMessage(ctx context.Context, conn *websocket.Conn, r *runner.Run
 				"error": "unknown method: " + frame.Method,
 			}
 			data, _ := json.Marshal(response)
-			conn.WriteMessage(websocket.TextMessage, data)
+			state.send(data)
 		}
 
 	case "event":
@@ -561,7 +865,7 @@ func handleAgentMessage(ctx context.Context, conn *websocket.Conn, r *runner.Run
 				p := eventFrame.Payload
 				if p.AutonomousMode {
 					fmt.Println("\033[33m[Settings] Autonomous mode ENABLED - all approvals bypassed\033[0m")
-					r.SetPolicy(tools.NewPolicyFromConfig("full", "off", nil))
+					r.SetPolicy(state.settingsPolicy("full", "off"))
 				} else {
 					askMode := "on-miss"
 					if p.AutoApproveRead && p.AutoApproveWrite && p.AutoApproveBash {
@@ -569,7 +873,7 @@ func handleAgentMessage(ctx context.Context, conn *websocket.Conn, r *runner.Run
 					}
 					fmt.Printf("\033[36m[Settings] Updated - read:%v write:%v bash:%v\033[0m\n",
 						p.AutoApproveRead, p.AutoApproveWrite, p.AutoApproveBash)
-					r.SetPolicy(tools.NewPolicyFromConfig("allowlist", askMode, nil))
+					r.SetPolicy(state.settingsPolicy("allowlist", askMode))
 				}
 			}
 		} else {
@@ -585,11 +889,15 @@ func handleAgentMessageWithState(ctx context.Context, state *agentState, r *runn
 		ID      string `json:"id"`
 		Method  string `json:"method"`
 		Payload struct {
-			Approved bool `json:"approved"`
+			Approved bool   `json:"approved"`
+			Approver string `json:"approver"`
 		} `json:"payload"`
 		Params struct {
-			Prompt     string `json:"prompt"`
-			SessionKey string `json:"session_key"`
+			Prompt      string                `json:"prompt"`
+			Attachments []session.ContentPart `json:"attachments"` // Files sent with the prompt
+			SessionKey  string                `json:"session_key"`
+			RequestID   string                `json:"request_id"` // For cancel
+			AgentID     string                `json:"agent_id"`   // For cancel_subagent
 		} `json:"params"`
 	}
 
@@ -600,7 +908,7 @@ func handleAgentMessageWithState(ctx context.Context, state *agentState, r *runn
 
 	switch frame.Type {
 	case "approval_response":
-		state.handleApprovalResponse(frame.ID, frame.Payload.Approved)
+		state.handleApprovalResponse(frame.ID, frame.Payload.Approved, frame.Payload.Approver)
 
 	case "req":
 		switch frame.Method {
@@ -612,69 +920,39 @@ func handleAgentMessageWithState(ctx context.Context, state *agentState, r *runn
 				"payload": map[string]any{"pong": true},
 			})
 
+		case "steer":
+			// Join the run in progress on the session, or start a run if it has finished.
+			// Steering carries text only, so messages with attachments queue a run instead.
+			if frame.Params.SessionKey != "" && len(frame.Params.Attachments) == 0 && r.Steer(frame.Params.SessionKey, frame.Params.Prompt) {
+				state.sendFrame(map[string]any{
+					"type":    "res",
+					"id":      frame.ID,
+					"ok":      true,
+					"payload": map[string]any{"steered": true},
+				})
+				break
+			}
+			fallthrough
+
 		case "run", "generate_title":
 			sessionKey := frame.Params.SessionKey
 			if sessionKey == "" {
 				sessionKey = "agent-" + frame.ID
 			}
 
-			events, err := r.Run(ctx, &runner.RunRequest{
-				SessionKey: sessionKey,
-				Prompt:     frame.Params.Prompt,
+			requestID, prompt, attachments := frame.ID, frame.Params.Prompt, frame.Params.Attachments
+			state.runs.start(ctx, requestID, sessionKey, func(ctx context.Context) {
+				state.executeRun(ctx, r, requestID, sessionKey, prompt, attachments)
 			})
 
-			if err != nil {
-				state.sendFrame(map[string]any{
-					"type":  "res",
-					"id":    frame.ID,
-					"ok":    false,
-					"error": err.Error(),
-				})
-				return
-			}
+		case "cancel":
+			state.handleCancel(frame.ID, frame.Params.RequestID, frame.Params.SessionKey)
 
-			var result strings.Builder
-			for event := range events {
-				switch event.Type {
-				case ai.EventTypeText:
-					result.WriteString(event.Text)
-					state.sendFrame(map[string]any{
-						"type": "stream",
-						"id":   frame.ID,
-						"payload": map[string]any{
-							"chunk": event.Text,
-						},
-					})
+		case "cancel_subagent":
+			state.handleCancelSubAgent(frame.ID, frame.Params.AgentID)
 
-				case ai.EventTypeToolCall:
-					state.sendFrame(map[string]any{
-						"type": "stream",
-						"id":   frame.ID,
-						"payload": map[string]any{
-							"tool":  event.ToolCall.Name,
-							"input": event.ToolCall.Input,
-						},
-					})
-
-				case ai.EventTypeToolResult:
-					state.sendFrame(map[string]any{
-						"type": "stream",
-						"id":   frame.ID,
-						"payload": map[string]any{
-							"tool_result": event.Text,
-						},
-					})
-				}
-			}
-
-			state.sendFrame(map[string]any{
-				"type": "res",
-				"id":   frame.ID,
-				"ok":   true,
-				"payload": map[string]any{
-					"result": result.String(),
-				},
-			})
+		case "end_session":
+			state.handleEndSession(frame.ID, frame.Params.SessionKey)
 
 		default:
 			state.sendFrame(map[string]any{
@@ -699,15 +977,96 @@ func handleAgentMessageWithState(ctx context.Context, state *agentState, r *runn
 			if eventFrame.Method == "settings_updated" {
 				p := eventFrame.Payload
 				if p.AutonomousMode {
-					r.SetPolicy(tools.NewPolicyFromConfig("full", "off", nil))
+					r.SetPolicy(state.settingsPolicy("full", "off"))
 				} else {
 					askMode := "on-miss"
 					if p.AutoApproveRead && p.AutoApproveWrite && p.AutoApproveBash {
 						askMode = "off"
 					}
-					r.SetPolicy(tools.NewPolicyFromConfig("allowlist", askMode, nil))
+					r.SetPolicy(state.settingsPolicy("allowlist", askMode))
 				}
 			}
 		}
 	}
 }
+
+// executeRun runs a prompt and streams its events back to the server as frames
+func (s *agentState) executeRun(ctx context.Context, r *runner.Runner, requestID, sessionKey, prompt string, attachments []session.ContentPart) {
+	events, err := r.Run(ctx, &runner.RunRequest{
+		SessionKey:  sessionKey,
+		Prompt:      prompt,
+		Attachments: attachments,
+	})
+
+	if err != nil {
+		s.sendFrame(map[string]any{
+			"type":  "res",
+			"id":    requestID,
+			"ok":    false,
+			"error": err.Error(),
+		})
+		return
+	}
+
+	var result strings.Builder
+	var runUsage ai.Usage
+	for event := range events {
+		switch event.Type {
+		case ai.EventTypeText:
+			result.WriteString(event.Text)
+			s.sendFrame(map[string]any{
+				"type": "stream",
+				"id":   requestID,
+				"payload": map[string]any{
+					"chunk": event.Text,
+				},
+			})
+
+		case ai.EventTypeToolCall:
+			s.sendFrame(map[string]any{
+				"type": "stream",
+				"id":   requestID,
+				"payload": map[string]any{
+					"tool":  event.ToolCall.Name,
+					"input": event.ToolCall.Input,
+				},
+			})
+
+		case ai.EventTypeToolResult:
+			s.sendFrame(map[string]any{
+				"type": "stream",
+				"id":   requestID,
+				"payload": map[string]any{
+					"tool_result": event.Text,
+				},
+			})
+
+		case ai.EventTypeError:
+			s.sendFrame(map[string]any{
+				"type":    "stream",
+				"id":      requestID,
+				"payload": runErrorPayload(event.Error),
+			})
+
+		case ai.EventTypeUsage:
+			runUsage.Add(event.Usage)
+			s.sendFrame(map[string]any{
+				"type": "stream",
+				"id":   requestID,
+				"payload": map[string]any{
+					"usage": event.Usage,
+				},
+			})
+		}
+	}
+
+	s.sendFrame(map[string]any{
+		"type": "res",
+		"id":   requestID,
+		"ok":   true,
+		"payload": map[string]any{
+			"result": result.String(),
+			"usage":  runUsage,
+		},
+	})
+}
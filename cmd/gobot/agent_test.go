@@ -0,0 +1,223 @@
+package cli
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"gobot/agent/ai"
+	agentcfg "gobot/agent/config"
+	"gobot/agent/runner"
+	"gobot/agent/session"
+	"gobot/agent/tools"
+	"gobot/internal/agenthub"
+	"gobot/internal/channels"
+	"gobot/internal/router"
+)
+
+// testChannel is a channel adapter whose inbound handler the test drives
+type testChannel struct {
+	mu      sync.Mutex
+	handler func(channels.InboundMessage)
+	sent    []channels.OutboundMessage
+}
+
+func (c *testChannel) ID() string                                                    { return "telegram" }
+func (c *testChannel) Connect(ctx context.Context, cfg channels.ChannelConfig) error { return nil }
+func (c *testChannel) Disconnect() error                                             { return nil }
+func (c *testChannel) SetHandler(fn func(channels.InboundMessage))                   { c.handler = fn }
+func (c *testChannel) Send(ctx context.Context, msg channels.OutboundMessage) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.sent = append(c.sent, msg)
+	return nil
+}
+
+// replyTo returns the text sent in reply to a message, if any
+func (c *testChannel) replyTo(messageID string) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, msg := range c.sent {
+		if msg.ReplyToID == messageID {
+			return msg.Text, true
+		}
+	}
+	return "", false
+}
+
+// slowProvider streams a first chunk, then blocks until the run is cancelled
+type slowProvider struct {
+	started chan struct{}
+	once    sync.Once
+}
+
+func (p *slowProvider) ID() string {
+	return "slow"
+}
+
+func (p *slowProvider) Stream(ctx context.Context, req *ai.ChatRequest) (<-chan ai.StreamEvent, error) {
+	ch := make(chan ai.StreamEvent)
+	go func() {
+		defer close(ch)
+		ch <- ai.StreamEvent{Type: ai.EventTypeText, Text: "Thinking"}
+		p.once.Do(func() { close(p.started) })
+		<-ctx.Done()
+		ch <- ai.StreamEvent{Type: ai.EventTypeError, Error: ctx.Err()}
+	}()
+	return ch, nil
+}
+
+// recordingProvider replies at once and keeps the requests it was sent
+type recordingProvider struct {
+	mu       sync.Mutex
+	requests []*ai.ChatRequest
+}
+
+func (p *recordingProvider) ID() string {
+	return "recording"
+}
+
+func (p *recordingProvider) Stream(ctx context.Context, req *ai.ChatRequest) (<-chan ai.StreamEvent, error) {
+	p.mu.Lock()
+	p.requests = append(p.requests, req)
+	p.mu.Unlock()
+
+	ch := make(chan ai.StreamEvent, 2)
+	ch <- ai.StreamEvent{Type: ai.EventTypeText, Text: "A cat"}
+	ch <- ai.StreamEvent{Type: ai.EventTypeDone}
+	close(ch)
+	return ch, nil
+}
+
+// connectAgent wires a channel through the router and hub to an agent running the real frame
+// handler over a websocket, as the server and agent loop do
+func connectAgent(t *testing.T, ctx context.Context, provider ai.Provider) (*testChannel, *session.Manager) {
+	t.Helper()
+
+	hub := agenthub.NewHub()
+	go hub.Run(ctx)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hub.HandleWebSocket(w, r, "agent-1")
+	}))
+	t.Cleanup(srv.Close)
+
+	channel := &testChannel{}
+	channelMgr := channels.NewManager()
+	channelMgr.Register(channel)
+	msgRouter := router.NewRouter(channelMgr, hub)
+	msgRouter.GetBindings().Add(&router.Binding{ID: "b1", ChannelType: "telegram", ChannelID: "42", Enabled: true})
+	msgRouter.SetupChannelHandlers(ctx)
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("failed to connect agent: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	sessions, err := session.New(t.TempDir() + "/agent.db")
+	if err != nil {
+		t.Fatalf("failed to open sessions: %v", err)
+	}
+	t.Cleanup(func() { sessions.Close() })
+
+	r := runner.New(agentcfg.DefaultConfig(), sessions, []ai.Provider{provider}, tools.NewRegistry(nil))
+	state := &agentState{conn: conn, pendingApproval: make(map[string]chan approvalDecision), quiet: true}
+	go func() {
+		for {
+			_, message, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			handleAgentMessageWithState(ctx, state, r, message)
+		}
+	}()
+
+	for deadline := time.Now().Add(5 * time.Second); !hub.IsConnected(); time.Sleep(10 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("agent never connected to the hub")
+		}
+	}
+	return channel, sessions
+}
+
+func TestChannelStopCancelsAgentRun(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	provider := &slowProvider{started: make(chan struct{})}
+	channel, sessions := connectAgent(t, ctx, provider)
+
+	routed := make(chan struct{})
+	go func() {
+		defer close(routed)
+		channel.handler(channels.InboundMessage{ChannelType: "telegram", ChannelID: "42", MessageID: "1", Text: "Think hard"})
+	}()
+
+	select {
+	case <-provider.started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("channel message never started an agent run")
+	}
+
+	channel.handler(channels.InboundMessage{ChannelType: "telegram", ChannelID: "42", MessageID: "2", Text: "/stop"})
+
+	select {
+	case <-routed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("run was not stopped")
+	}
+
+	if text, _ := channel.replyTo("2"); text != "Stopping." {
+		t.Errorf("expected /stop to be acknowledged, got %q", text)
+	}
+	if text, _ := channel.replyTo("1"); text != "Thinking" {
+		t.Errorf("expected the partial reply to be sent, got %q", text)
+	}
+
+	sess, err := sessions.GetByKey("telegram:42")
+	if err != nil {
+		t.Fatalf("expected a session for the conversation: %v", err)
+	}
+	messages, _ := sessions.GetMessages(sess.ID, 0)
+	if last := messages[len(messages)-1]; !strings.Contains(last.Content, runner.CancelledMarker) {
+		t.Errorf("expected the run to be cancelled, last message %q", last.Content)
+	}
+}
+
+func TestChannelAttachmentsReachAgentRun(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	provider := &recordingProvider{}
+	channel, _ := connectAgent(t, ctx, provider)
+
+	png := []byte("\x89PNG\r\n\x1a\nimage")
+	channel.handler(channels.InboundMessage{
+		ChannelType: "telegram",
+		ChannelID:   "42",
+		MessageID:   "1",
+		Text:        "What is this?",
+		Attachments: []channels.Attachment{{Name: "photo.png", MediaType: "image/png", Data: png}},
+	})
+
+	if text, _ := channel.replyTo("1"); text != "A cat" {
+		t.Errorf("expected the agent reply, got %q", text)
+	}
+
+	provider.mu.Lock()
+	defer provider.mu.Unlock()
+	if len(provider.requests) == 0 {
+		t.Fatal("channel message never reached the provider")
+	}
+	messages := provider.requests[0].Messages
+	last := messages[len(messages)-1]
+	if len(last.Parts) != 1 || last.Parts[0].Type != session.PartImage || string(last.Parts[0].Data) != string(png) {
+		t.Errorf("expected the photo to be sent with the prompt, got %+v", last.Parts)
+	}
+}
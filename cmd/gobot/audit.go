@@ -0,0 +1,128 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"gobot/agent/audit"
+	agentcfg "gobot/agent/config"
+)
+
+// AuditCmd creates the audit command
+func AuditCmd() *cobra.Command {
+	var filter audit.Filter
+	var since, until string
+	var asJSON, full bool
+
+	cmd := &cobra.Command{
+		Use:   "audit",
+		Short: "Show the tool execution and approval audit log",
+		Long: `Show every tool call the agent made, newest first: input, truncated output, errors,
+duration, session, and who approved it through which channel (cli, web or auto).
+Use --session to show a single session.
+
+The log is append-only and stored in ~/.gobot/gobot.db.`,
+		Example: `  gobot audit --since 24h
+  gobot audit --session default --tool bash
+  gobot audit --denied --channel web
+  gobot audit --errors --json`,
+		Run: func(cmd *cobra.Command, args []string) {
+			cfg := loadAgentConfig()
+			if cmd.Flags().Changed("session") {
+				filter.SessionKey = sessionKey
+			}
+			var err error
+			if since != "" {
+				if filter.Since, err = audit.ParseTime(since); err != nil {
+					fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+					os.Exit(1)
+				}
+			}
+			if until != "" {
+				if filter.Until, err = audit.ParseTime(until); err != nil {
+					fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+					os.Exit(1)
+				}
+			}
+			showAudit(cfg, filter, asJSON, full)
+		},
+	}
+
+	cmd.Flags().StringVar(&filter.Tool, "tool", "", "only calls to this tool")
+	cmd.Flags().StringVar(&filter.Channel, "channel", "", "only decisions from this channel (cli, web, auto)")
+	cmd.Flags().StringVar(&filter.Approver, "approver", "", "only decisions by this approver")
+	cmd.Flags().BoolVar(&filter.ErrorsOnly, "errors", false, "only calls that returned an error")
+	cmd.Flags().BoolVar(&filter.DeniedOnly, "denied", false, "only calls that were denied")
+	cmd.Flags().StringVar(&since, "since", "", "only calls after this time (e.g. 24h, 2026-01-02)")
+	cmd.Flags().StringVar(&until, "until", "", "only calls before this time")
+	cmd.Flags().IntVarP(&filter.Limit, "limit", "n", 50, "maximum entries to show")
+	cmd.Flags().BoolVar(&full, "full", false, "show full input and output")
+	cmd.Flags().BoolVar(&asJSON, "json", false, "output as JSON")
+
+	return cmd
+}
+
+// showAudit prints audit entries matching a filter
+func showAudit(cfg *agentcfg.Config, filter audit.Filter, asJSON, full bool) {
+	auditLog, err := audit.Open(cfg.DBPath())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	defer auditLog.Close()
+
+	entries, err := auditLog.Query(filter)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if asJSON {
+		data, _ := json.MarshalIndent(entries, "", "  ")
+		fmt.Println(string(data))
+		return
+	}
+
+	if len(entries) == 0 {
+		fmt.Println("No audit entries.")
+		return
+	}
+
+	for _, e := range entries {
+		status := "\033[32mok\033[0m"
+		switch {
+		case !e.Approved:
+			status = "\033[33mdenied\033[0m"
+		case e.IsError:
+			status = "\033[31merror\033[0m"
+		}
+		approver := e.Channel
+		if e.Approver != "" {
+			approver += ":" + e.Approver
+		}
+		fmt.Printf("%s  %-10s %-12s %s  %dms  %s  [%s]\n",
+			e.CreatedAt.Format("2006-01-02 15:04:05"), e.Tool, e.SessionKey, status, e.DurationMS, approver, e.ToolCallID)
+
+		input, output := e.Input, e.Output
+		if !full {
+			input, output = oneLine(input, 100), oneLine(output, 100)
+		}
+		fmt.Printf("    in:  %s\n", input)
+		if output != "" {
+			fmt.Printf("    out: %s\n", output)
+		}
+	}
+}
+
+// oneLine flattens s to a single line of at most max bytes
+func oneLine(s string, max int) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if len(s) > max {
+		s = s[:max-3] + "..."
+	}
+	return s
+}
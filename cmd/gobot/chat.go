@@ -3,10 +3,12 @@ package cli
 import (
 	"bufio"
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"os/signal"
 	"strings"
+	"sync"
 	"syscall"
 
 	"github.com/spf13/cobra"
@@ -25,6 +27,7 @@ func ChatCmd() *cobra.Command {
 	var interactive bool
 	var dangerously bool
 	var voiceMode bool
+	var attach []string
 
 	cmd := &cobra.Command{
 		Use:   "chat [prompt]",
@@ -37,22 +40,38 @@ Examples:
   gobot chat "List all Go files in this directory"
   gobot chat --interactive
   gobot chat --voice              # Record voice input
+  gobot chat --attach screenshot.png "What's wrong in this UI?"
   gobot chat --dangerously "deploy to production"`,
 		Run: func(cmd *cobra.Command, args []string) {
 			cfg := loadAgentConfig()
-			runChat(cfg, args, interactive, dangerously, voiceMode)
+			runChat(cfg, args, interactive, dangerously, voiceMode, loadAttachments(attach))
 		},
 	}
 
 	cmd.Flags().BoolVarP(&interactive, "interactive", "i", false, "start interactive chat session")
 	cmd.Flags().BoolVar(&dangerously, "dangerously", false, "100% autonomous mode - bypass ALL tool approval prompts (use with caution!)")
 	cmd.Flags().BoolVar(&voiceMode, "voice", false, "use voice input (requires microphone and OPENAI_API_KEY)")
+	cmd.Flags().StringArrayVar(&attach, "attach", nil, "attach an image, PDF, text or audio file to the (first) message; repeatable")
 
 	return cmd
 }
 
+// loadAttachments reads files given with --attach
+func loadAttachments(paths []string) []session.ContentPart {
+	parts := make([]session.ContentPart, 0, len(paths))
+	for _, path := range paths {
+		part, err := session.ReadPart(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		parts = append(parts, part)
+	}
+	return parts
+}
+
 // runChat runs the chat command
-func runChat(cfg *agentcfg.Config, args []string, interactive bool, dangerously bool, voiceMode bool) {
+func runChat(cfg *agentcfg.Config, args []string, interactive bool, dangerously bool, voiceMode bool, attachments []session.ContentPart) {
 	if dangerously {
 		if !confirmDangerousMode() {
 			fmt.Println("Aborted.")
@@ -98,8 +117,27 @@ func runChat(cfg *agentcfg.Config, args []string, interactive bool, dangerously
 			cfg.Policy.Allowlist,
 		)
 	}
+	if err := policy.SetRules(cfg.Policy.Rules); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: invalid policy rules: %v\n", err)
+		os.Exit(1)
+	}
+	if err := policy.SetSandbox(cfg.Sandbox); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: invalid sandbox config: %v\n", err)
+		os.Exit(1)
+	}
 	registry := tools.NewRegistry(policy)
 	registry.RegisterDefaults()
+	registerWebTool(cfg, registry)
+	registry.SetMaxParallel(cfg.MaxParallelTools)
+	registry.SetSerial(cfg.SerialTools...)
+	registry.SetCheckpoints(sessions) // Write and edit calls can be undone with "gobot undo"
+	defer registry.Jobs().KillAll()   // Background jobs end with the chat
+	auditLog := openAuditLog(cfg, registry, dangerously)
+	defer auditLog.Close()
+
+	// Mount tools from external MCP servers
+	mcpClients := mountMCPServers(context.Background(), cfg, registry)
+	defer mcpClients.Close()
 
 	taskTool := tools.NewTaskTool()
 	taskTool.CreateOrchestrator(cfg, sessions, providers, registry)
@@ -110,6 +148,9 @@ func runChat(cfg *agentcfg.Config, args []string, interactive bool, dangerously
 	registry.Register(agentStatusTool)
 
 	r := runner.New(cfg, sessions, providers, registry)
+	if profiles := useAuthProfiles(cfg, r); profiles != nil {
+		defer profiles.Close()
+	}
 
 	// Set up task-based model selector for intelligent model routing
 	modelsConfig := provider.GetModelsConfig()
@@ -117,6 +158,7 @@ func runChat(cfg *agentcfg.Config, args []string, interactive bool, dangerously
 		if modelsConfig.TaskRouting != nil {
 			selector := ai.NewModelSelector(modelsConfig)
 			r.SetModelSelector(selector)
+			taskTool.GetOrchestrator().SetModelSelector(selector)
 		}
 		// Set up fuzzy matcher for user model switch requests
 		fuzzyMatcher := ai.NewFuzzyMatcher(modelsConfig)
@@ -128,25 +170,26 @@ func runChat(cfg *agentcfg.Config, args []string, interactive bool, dangerously
 
 	sigCh := make(chan os.Signal, 1)
 	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
-	go func() {
-		<-sigCh
-		fmt.Println("\n\033[33mInterrupted\033[0m")
-		cancel()
-	}()
 
 	if interactive || len(args) == 0 {
-		runInteractive(ctx, r, sessions)
+		runInteractive(ctx, r, sessions, registry.Jobs(), sigCh, attachments)
 	} else {
+		go func() {
+			<-sigCh
+			fmt.Println("\n\033[33mInterrupted\033[0m")
+			cancel()
+		}()
 		prompt := strings.Join(args, " ")
-		runOnce(ctx, r, prompt)
+		runOnce(ctx, r, prompt, attachments)
 	}
 }
 
 // runOnce runs a single prompt
-func runOnce(ctx context.Context, r *runner.Runner, prompt string) {
+func runOnce(ctx context.Context, r *runner.Runner, prompt string, attachments []session.ContentPart) {
 	events, err := r.Run(ctx, &runner.RunRequest{
-		SessionKey: sessionKey,
-		Prompt:     prompt,
+		SessionKey:  sessionKey,
+		Prompt:      prompt,
+		Attachments: attachments,
 	})
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
@@ -159,12 +202,31 @@ func runOnce(ctx context.Context, r *runner.Runner, prompt string) {
 	fmt.Println()
 }
 
-// runInteractive runs an interactive chat session
-func runInteractive(ctx context.Context, r *runner.Runner, sessions *session.Manager) {
+// runInteractive runs an interactive chat session. Attachments are sent with the next message.
+// Ctrl+C stops the reply in progress; at the prompt it exits, stopping background jobs.
+func runInteractive(ctx context.Context, r *runner.Runner, sessions *session.Manager, jobs *tools.JobManager, sigCh <-chan os.Signal, attachments []session.ContentPart) {
 	fmt.Println("\033[1mGoBot Interactive Mode\033[0m")
-	fmt.Println("Type your message and press Enter. Use /help for commands, Ctrl+C to exit.")
+	fmt.Println("Type your message and press Enter. Use /help for commands, Ctrl+C to stop a reply or exit.")
 	fmt.Println()
 
+	var runMu sync.Mutex
+	var stopRun context.CancelFunc
+	go func() {
+		for sig := range sigCh {
+			runMu.Lock()
+			stop := stopRun
+			runMu.Unlock()
+			if stop != nil && sig == syscall.SIGINT {
+				fmt.Println("\n\033[33mStopping...\033[0m")
+				stop()
+				continue
+			}
+			fmt.Println("\n\033[33mInterrupted\033[0m")
+			jobs.KillAll()
+			os.Exit(0)
+		}
+	}()
+
 	reader := bufio.NewReader(os.Stdin)
 
 	for {
@@ -180,36 +242,59 @@ func runInteractive(ctx context.Context, r *runner.Runner, sessions *session.Man
 			continue
 		}
 
+		if path, ok := strings.CutPrefix(line, "/attach "); ok {
+			part, err := session.ReadPart(strings.TrimSpace(path))
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "\033[31mError: %v\033[0m\n", err)
+				continue
+			}
+			attachments = append(attachments, part)
+			fmt.Printf("Attached %s (%s); it will be sent with your next message.\n", part.Name, part.MediaType)
+			continue
+		}
+
 		if strings.HasPrefix(line, "/") {
-			if handleCommand(line, sessions) {
+			if handleCommand(line, sessions, jobs) {
 				continue
 			}
 		}
 
-		events, err := r.Run(ctx, &runner.RunRequest{
-			SessionKey: sessionKey,
-			Prompt:     line,
+		runCtx, cancelRun := context.WithCancel(ctx)
+		runMu.Lock()
+		stopRun = cancelRun
+		runMu.Unlock()
+
+		events, err := r.Run(runCtx, &runner.RunRequest{
+			SessionKey:  sessionKey,
+			Prompt:      line,
+			Attachments: attachments,
 		})
+		attachments = nil
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "\033[31mError: %v\033[0m\n", err)
-			continue
+		} else {
+			fmt.Print("\033[32m")
+			for event := range events {
+				handleEvent(event)
+			}
+			fmt.Print("\033[0m\n\n")
 		}
 
-		fmt.Print("\033[32m")
-		for event := range events {
-			handleEvent(event)
-		}
-		fmt.Print("\033[0m\n\n")
+		runMu.Lock()
+		stopRun = nil
+		runMu.Unlock()
+		cancelRun()
 	}
 }
 
 // handleCommand handles interactive commands
-func handleCommand(cmd string, sessions *session.Manager) bool {
+func handleCommand(cmd string, sessions *session.Manager, jobs *tools.JobManager) bool {
 	switch {
 	case cmd == "/help":
 		fmt.Println(`Commands:
   /help     - Show this help
-  /clear    - Clear current session
+  /attach   - Attach a file to the next message (/attach <path>)
+  /clear    - Clear current session and stop its background jobs
   /sessions - List all sessions
   /quit     - Exit`)
 		return true
@@ -218,6 +303,7 @@ func handleCommand(cmd string, sessions *session.Manager) bool {
 		sess, err := sessions.GetOrCreate(sessionKey)
 		if err == nil {
 			sessions.Reset(sess.ID)
+			jobs.EndSession(sessionKey)
 			fmt.Println("Session cleared.")
 		}
 		return true
@@ -235,6 +321,7 @@ func handleCommand(cmd string, sessions *session.Manager) bool {
 		return true
 
 	case cmd == "/quit" || cmd == "/exit":
+		jobs.KillAll()
 		os.Exit(0)
 		return true
 	}
@@ -267,7 +354,17 @@ func handleEvent(event ai.StreamEvent) {
 			fmt.Printf("\033[90m%s\033[0m\n", preview)
 		}
 
+	case ai.EventTypeUsage:
+		if verbose && event.Usage != nil {
+			fmt.Printf("\n\033[90m[usage: %s, %d in / %d out, $%.4f]\033[0m\n",
+				event.Usage.Model, event.Usage.InputTokens, event.Usage.OutputTokens, event.Usage.CostUSD)
+		}
+
 	case ai.EventTypeError:
+		if errors.Is(event.Error, runner.ErrCancelled) {
+			fmt.Printf("\n\033[33m[stopped]\033[0m\n")
+			return
+		}
 		fmt.Printf("\n\033[31mError: %v\033[0m\n", event.Error)
 
 	case ai.EventTypeDone:
@@ -1,13 +1,17 @@
 package cli
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"slices"
+	"time"
 
 	"github.com/spf13/cobra"
 
 	"gobot/agent/ai"
 	agentcfg "gobot/agent/config"
+	"gobot/internal/provider"
 )
 
 // configCmd creates the config command
@@ -29,9 +33,63 @@ func ConfigCmd() *cobra.Command {
 		},
 	})
 
+	var save bool
+	modelsCmd := &cobra.Command{
+		Use:   "models <provider>",
+		Short: "List the models an openai-compatible provider serves",
+		Long: `Queries the provider's /models endpoint. With --save, models not yet in models.yaml
+are added under the provider so they can be routed to as "<provider>/<model>".`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			discoverModels(loadAgentConfig(), args[0], save)
+		},
+	}
+	modelsCmd.Flags().BoolVar(&save, "save", false, "add discovered models to models.yaml")
+	cmd.AddCommand(modelsCmd)
+
 	return cmd
 }
 
+// discoverModels lists the models an openai-compatible provider serves, optionally saving them
+func discoverModels(cfg *agentcfg.Config, name string, save bool) {
+	idx := slices.IndexFunc(cfg.Providers, func(p agentcfg.ProviderConfig) bool { return p.Name == name })
+	if idx < 0 || cfg.Providers[idx].Type != provider.ProviderTypeOpenAICompatible {
+		fmt.Fprintf(os.Stderr, "Error: %s is not an openai-compatible provider in models.yaml\n", name)
+		os.Exit(1)
+	}
+	pcfg := cfg.Providers[idx]
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+	models, err := ai.NewOpenAICompatibleProvider(pcfg.Name, pcfg.BaseURL, pcfg.APIKey, pcfg.Model, pcfg.Headers).ListModels(ctx)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	known := provider.GetProviderModels(name)
+	added := 0
+	for _, id := range models {
+		marker := " "
+		if !slices.ContainsFunc(known, func(m provider.ModelInfo) bool { return m.ID == id }) {
+			marker = "+"
+			known = append(known, provider.ModelInfo{ID: id, DisplayName: id})
+			added++
+		}
+		fmt.Printf("  %s %s/%s\n", marker, name, id)
+	}
+
+	if !save {
+		fmt.Printf("%d models, %d not in models.yaml (add them with --save)\n", len(models), added)
+		return
+	}
+	if err := provider.SetProviderModels(name, known); err != nil {
+		fmt.Fprintf(os.Stderr, "Error saving models: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Added %d models to %s\n", added, provider.GetModelsFilePath())
+}
+
 // loadAgentConfig loads the agent configuration
 func loadAgentConfig() *agentcfg.Config {
 	var cfg *agentcfg.Config
@@ -64,6 +122,7 @@ func showConfig(cfg *agentcfg.Config) {
 	fmt.Printf("Database: %s\n", cfg.DBPath())
 	fmt.Printf("Max Context: %d messages\n", cfg.MaxContext)
 	fmt.Printf("Max Iterations: %d\n", cfg.MaxIterations)
+	fmt.Printf("Max Parallel Tools: %d\n", cfg.MaxParallelTools)
 	fmt.Println()
 
 	fmt.Println("Providers:")
@@ -78,6 +137,13 @@ func showConfig(cfg *agentcfg.Config) {
 				status = "\033[31m✗\033[0m"
 				statusInfo = fmt.Sprintf(" (command '%s' not found)", p.Command)
 			}
+		} else if p.Type == provider.ProviderTypeOpenAICompatible {
+			if p.BaseURL != "" {
+				status = "\033[32m✓\033[0m"
+				statusInfo = fmt.Sprintf(" (%s)", p.BaseURL)
+			} else {
+				statusInfo = " (no base_url)"
+			}
 		} else if p.APIKey != "" {
 			status = "\033[32m✓\033[0m"
 		}
@@ -13,6 +13,8 @@ import (
 	"time"
 
 	"github.com/spf13/cobra"
+
+	"gobot/internal/provider"
 )
 
 // doctorCmd creates the doctor command for health checks
@@ -197,6 +199,19 @@ func checkAPIKeys() []checkResult {
 	}
 
 	for _, p := range cfg.Providers {
+		// Local OpenAI-compatible servers usually don't need a key
+		if p.Type == provider.ProviderTypeOpenAICompatible && p.APIKey == "" {
+			status, message := "ok", fmt.Sprintf("URL: %s, Model: %s", p.BaseURL, p.Model)
+			if p.BaseURL == "" {
+				status, message = "error", "No base_url configured"
+			}
+			results = append(results, checkResult{
+				name:    fmt.Sprintf("Provider: %s", p.Name),
+				status:  status,
+				message: message,
+			})
+			continue
+		}
 		if p.APIKey == "" {
 			results = append(results, checkResult{
 				name:    fmt.Sprintf("Provider: %s", p.Name),
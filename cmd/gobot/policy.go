@@ -0,0 +1,89 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	agentcfg "gobot/agent/config"
+	"gobot/agent/tools"
+)
+
+// PolicyCmd creates the policy command
+func PolicyCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "policy",
+		Short: "Inspect the tool approval policy",
+		Long: `The tool policy in ~/.gobot/config.yaml decides whether each tool call runs, asks for
+approval or is refused. Rules under policy.rules match a tool name and argument patterns;
+deny wins over ask and ask over allow. Calls no rule matches fall back to the policy level
+and the bash allowlist.`,
+	}
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "test <tool> [json]",
+		Short: "Show whether a tool call would be allowed, asked or denied",
+		Example: `  gobot policy test write '{"path":"src/main.go","content":""}'
+  gobot policy test web '{"url":"http://wiki.corp.internal/"}'
+  gobot policy test process '{"action":"kill","pid":1234}'
+  gobot policy test bash '{"command":"git status"}'`,
+		Args: cobra.RangeArgs(1, 2),
+		Run: func(cmd *cobra.Command, args []string) {
+			cfg := loadAgentConfig()
+			input := "{}"
+			if len(args) > 1 {
+				input = args[1]
+			}
+			testPolicy(cfg, args[0], input)
+		},
+	})
+
+	return cmd
+}
+
+// testPolicy prints the policy's verdict for a tool call without running it
+func testPolicy(cfg *agentcfg.Config, toolName, input string) {
+	if !json.Valid([]byte(input)) {
+		fmt.Fprintf(os.Stderr, "Error: tool input must be a JSON object, got %s\n", input)
+		os.Exit(1)
+	}
+
+	policy := tools.NewPolicyFromConfig(cfg.Policy.Level, cfg.Policy.AskMode, cfg.Policy.Allowlist)
+	if err := policy.SetRules(cfg.Policy.Rules); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: invalid policy rules: %v\n", err)
+		os.Exit(1)
+	}
+	if err := policy.SetSandbox(cfg.Sandbox); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: invalid sandbox config: %v\n", err)
+		os.Exit(1)
+	}
+
+	registry := tools.NewRegistry(policy)
+	registry.RegisterDefaults()
+	requiresApproval := true
+	if tool, ok := registry.Get(toolName); ok {
+		requiresApproval = tools.RequiresApproval(tool, json.RawMessage(input))
+	} else {
+		fmt.Printf("\033[90mNote: %s is not a built-in tool; assuming it requires approval\033[0m\n", toolName)
+	}
+
+	verdict := policy.Evaluate(toolName, json.RawMessage(input), requiresApproval)
+	color := "\033[32m"
+	switch verdict.Action {
+	case tools.RuleAsk:
+		color = "\033[33m"
+	case tools.RuleDeny:
+		color = "\033[31m"
+	}
+
+	fmt.Printf("Decision: %s%s\033[0m\n", color, verdict.Action)
+	fmt.Printf("Reason:   %s\n", verdict.Reason)
+	if verdict.Rule != nil {
+		fmt.Printf("Rule:     %s\n", verdict.Rule)
+	}
+	if toolName == "bash" {
+		fmt.Printf("Runs in:  %s\n", policy.Executor().Name())
+	}
+}
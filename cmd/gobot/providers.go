@@ -5,16 +5,63 @@ import (
 	"fmt"
 	"os"
 	"strings"
+	"time"
 
 	"gobot/agent/ai"
 	agentcfg "gobot/agent/config"
+	"gobot/agent/runner"
 	"gobot/internal/provider"
 )
 
 var _ = agentcfg.Config{} // silence unused import if needed
 
-// createProviders creates AI providers from config and database
+// createProviders creates AI providers from config and database.
+// GOBOT_REPLAY=<cassette> serves recorded responses instead of calling any provider;
+// GOBOT_RECORD=<cassette> records every provider response for later replay.
 func createProviders(cfg *agentcfg.Config) []ai.Provider {
+	if path := os.Getenv("GOBOT_REPLAY"); path != "" {
+		return replayProviders(path)
+	}
+
+	providers := configuredProviders(cfg)
+
+	if path := os.Getenv("GOBOT_RECORD"); path != "" {
+		cassette, err := ai.OpenCassette(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		for i, p := range providers {
+			providers[i] = cassette.Record(p)
+		}
+		if verbose {
+			fmt.Printf("Recording provider responses to %s\n", path)
+		}
+	}
+
+	return providers
+}
+
+// replayProviders creates offline providers that replay a recorded cassette
+func replayProviders(path string) []ai.Provider {
+	cassette, err := ai.OpenCassette(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	var providers []ai.Provider
+	for _, id := range cassette.ProviderIDs() {
+		providers = append(providers, cassette.Replay(id))
+	}
+	if verbose {
+		fmt.Printf("Replaying provider responses from %s\n", path)
+	}
+	return providers
+}
+
+// configuredProviders creates the live providers from the database and config
+func configuredProviders(cfg *agentcfg.Config) []ai.Provider {
 	var providers []ai.Provider
 
 	// First, try to load from database auth profiles (UI-configured keys)
@@ -52,6 +99,11 @@ func createProviders(cfg *agentcfg.Config) []ai.Provider {
 				fmt.Fprintf(os.Stderr, "Ollama provider %s: not available at %s\n", pcfg.Name, baseURL)
 			}
 
+		case provider.ProviderTypeOpenAICompatible:
+			if p := openAICompatibleProvider(pcfg); p != nil {
+				providers = append(providers, p)
+			}
+
 		case "cli":
 			if pcfg.Command == "" {
 				continue
@@ -90,6 +142,33 @@ func createProviders(cfg *agentcfg.Config) []ai.Provider {
 	return providers
 }
 
+// openAICompatibleProvider creates a provider for an OpenAI-compatible API. Without a model in
+// models.yaml, the first model the server lists is used; nil if the server can't be used.
+func openAICompatibleProvider(pcfg agentcfg.ProviderConfig) *ai.OpenAIProvider {
+	if pcfg.BaseURL == "" {
+		fmt.Fprintf(os.Stderr, "Warning: provider %s is openai-compatible but has no base_url\n", pcfg.Name)
+		return nil
+	}
+
+	p := ai.NewOpenAICompatibleProvider(pcfg.Name, pcfg.BaseURL, pcfg.APIKey, pcfg.Model, pcfg.Headers)
+	if p.Model() == "" {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		models, err := p.ListModels(ctx)
+		if err != nil || len(models) == 0 {
+			if verbose {
+				fmt.Fprintf(os.Stderr, "Provider %s: no model configured and none listed at %s (%v)\n", pcfg.Name, pcfg.BaseURL, err)
+			}
+			return nil
+		}
+		p.SetModel(models[0])
+	}
+	if verbose {
+		fmt.Printf("Loaded OpenAI-compatible provider %s at %s (model: %s)\n", pcfg.Name, pcfg.BaseURL, p.Model())
+	}
+	return p
+}
+
 // loadProvidersFromDB loads API providers from database auth profiles
 // These are configured via the UI in Settings > Providers
 func loadProvidersFromDB(dbPath string) []ai.Provider {
@@ -105,76 +184,73 @@ func loadProvidersFromDB(dbPath string) []ai.Provider {
 	defer mgr.Close()
 
 	ctx := context.Background()
-
-	// Load anthropic profiles
-	if profiles, err := mgr.ListActiveProfiles(ctx, "anthropic"); err == nil {
-		for _, p := range profiles {
-			if p.APIKey != "" {
-				model := p.Model
-				if model == "" {
-					model = provider.GetDefaultModel("anthropic")
-				}
-				providers = append(providers, ai.NewAnthropicProvider(p.APIKey, model))
+	for _, name := range []string{"anthropic", "openai", "google", "ollama"} {
+		profiles, err := mgr.ListActiveProfiles(ctx, name)
+		if err != nil {
+			continue
+		}
+		for i := range profiles {
+			if p := profileProvider(&profiles[i]); p != nil {
+				providers = append(providers, p)
 				if verbose {
-					fmt.Printf("Loaded Anthropic provider from DB: %s (model: %s)\n", p.Name, model)
+					fmt.Printf("Loaded %s provider from DB: %s\n", name, profiles[i].Name)
 				}
 			}
 		}
 	}
 
-	// Load openai profiles
-	if profiles, err := mgr.ListActiveProfiles(ctx, "openai"); err == nil {
-		for _, p := range profiles {
-			if p.APIKey != "" {
-				model := p.Model
-				if model == "" {
-					model = provider.GetDefaultModel("openai")
-				}
-				providers = append(providers, ai.NewOpenAIProvider(p.APIKey, model))
-				if verbose {
-					fmt.Printf("Loaded OpenAI provider from DB: %s (model: %s)\n", p.Name, model)
-				}
-			}
-		}
+	return providers
+}
+
+// profileProvider creates a provider using an auth profile's key and model; nil if the
+// profile has no key or its Ollama server isn't reachable
+func profileProvider(p *agentcfg.AuthProfile) ai.Provider {
+	model := p.Model
+	if model == "" {
+		model = provider.GetDefaultModel(p.Provider)
 	}
 
-	// Load google/gemini profiles
-	if profiles, err := mgr.ListActiveProfiles(ctx, "google"); err == nil {
-		for _, p := range profiles {
-			if p.APIKey != "" {
-				model := p.Model
-				if model == "" {
-					model = provider.GetDefaultModel("google")
-				}
-				providers = append(providers, ai.NewGeminiProvider(p.APIKey, model))
-				if verbose {
-					fmt.Printf("Loaded Gemini provider from DB: %s (model: %s)\n", p.Name, model)
-				}
-			}
+	switch p.Provider {
+	case "anthropic":
+		if p.APIKey != "" {
+			return ai.NewAnthropicProvider(p.APIKey, model)
+		}
+	case "openai":
+		if p.APIKey != "" {
+			return ai.NewOpenAIProvider(p.APIKey, model)
+		}
+	case "google":
+		if p.APIKey != "" {
+			return ai.NewGeminiProvider(p.APIKey, model)
+		}
+	case "ollama":
+		baseURL := p.BaseURL
+		if baseURL == "" {
+			baseURL = "http://localhost:11434"
+		}
+		if ai.CheckOllamaAvailable(baseURL) {
+			return ai.NewOllamaProvider(baseURL, model)
 		}
 	}
+	return nil
+}
 
-	// Load ollama profiles
-	if profiles, err := mgr.ListActiveProfiles(ctx, "ollama"); err == nil {
-		for _, p := range profiles {
-			baseURL := p.BaseURL
-			if baseURL == "" {
-				baseURL = "http://localhost:11434"
-			}
-			if ai.CheckOllamaAvailable(baseURL) {
-				model := p.Model
-				if model == "" {
-					model = provider.GetDefaultModel("ollama")
-				}
-				providers = append(providers, ai.NewOllamaProvider(baseURL, model))
-				if verbose {
-					fmt.Printf("Loaded Ollama provider from DB: %s (model: %s)\n", p.Name, model)
-				}
-			}
+// useAuthProfiles has the runner pick API keys per request from the database auth profiles,
+// so keys are rotated and cooled down as they hit limits. The returned manager must stay open
+// while the runner is used; nil when recording or replaying, which use fixed providers.
+func useAuthProfiles(cfg *agentcfg.Config, r *runner.Runner) *agentcfg.AuthProfileManager {
+	if os.Getenv("GOBOT_REPLAY") != "" || os.Getenv("GOBOT_RECORD") != "" {
+		return nil
+	}
+	mgr, err := agentcfg.NewAuthProfileManager(cfg.DBPath())
+	if err != nil {
+		if verbose {
+			fmt.Fprintf(os.Stderr, "Warning: Could not open auth profiles: %v\n", err)
 		}
+		return nil
 	}
-
-	return providers
+	r.SetAuthProfiles(mgr, profileProvider)
+	return mgr
 }
 
 // CLIAvailability represents which CLI tools are installed on the system
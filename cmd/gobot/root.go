@@ -16,6 +16,7 @@ import (
 	"github.com/zeromicro/go-zero/core/logx"
 
 	"gobot/app"
+	"gobot/agent/audit"
 	agentcfg "gobot/agent/config"
 	agentmcp "gobot/agent/mcp"
 	"gobot/agent/tools"
@@ -332,11 +333,58 @@ func createMCPRegistry(cfg *agentcfg.Config) *tools.Registry {
 		cfg.Policy.AskMode,
 		cfg.Policy.Allowlist,
 	)
+	if err := policy.SetRules(cfg.Policy.Rules); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: invalid policy rules: %v\n", err)
+		os.Exit(1)
+	}
+	if err := policy.SetSandbox(cfg.Sandbox); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: invalid sandbox config: %v\n", err)
+		os.Exit(1)
+	}
 	registry := tools.NewRegistry(policy)
 	registry.RegisterDefaults()
+	registerWebTool(cfg, registry)
+	openAuditLog(cfg, registry, false) // Stays open for the life of the server
 	return registry
 }
 
+// openAuditLog records the registry's tool calls and approvals in the agent database.
+// When required (autonomous mode), running without an audit trail is refused.
+func openAuditLog(cfg *agentcfg.Config, registry *tools.Registry, required bool) *audit.Log {
+	auditLog, err := audit.Open(cfg.DBPath())
+	if err != nil {
+		if required {
+			fmt.Fprintf(os.Stderr, "Error: audit log unavailable, refusing to run autonomously: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Fprintf(os.Stderr, "Warning: audit log unavailable: %v\n", err)
+		return nil
+	}
+	registry.SetAuditLog(auditLog)
+	return auditLog
+}
+
+// registerWebTool replaces the default web tool with one using the configured network policy
+func registerWebTool(cfg *agentcfg.Config, registry *tools.Registry) {
+	registry.Register(tools.NewWebTool(tools.WebConfig{
+		Policy: tools.NetworkPolicy{
+			AllowPrivate: cfg.Web.AllowPrivate,
+			AllowDomains: cfg.Web.AllowDomains,
+			DenyDomains:  cfg.Web.DenyDomains,
+		},
+		CacheTTL: cfg.Web.CacheTTL,
+	}))
+}
+
+// mountMCPServers connects to the configured external MCP servers and registers their tools
+func mountMCPServers(ctx context.Context, cfg *agentcfg.Config, registry *tools.Registry) *agentmcp.ClientManager {
+	clients := agentmcp.NewClientManager(registry)
+	if len(cfg.MCPServers) > 0 {
+		clients.Mount(ctx, cfg.MCPServers)
+	}
+	return clients
+}
+
 // runMCPServerDaemon runs the MCP server in daemon mode
 func runMCPServerDaemon(ctx context.Context, registry *tools.Registry, port int, quiet bool) error {
 	mcpServer := agentmcp.NewServer(registry)
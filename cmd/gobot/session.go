@@ -1,8 +1,12 @@
 package cli
 
 import (
+	"encoding/json"
 	"fmt"
+	"io"
 	"os"
+	"strconv"
+	"strings"
 
 	"github.com/spf13/cobra"
 
@@ -39,9 +43,282 @@ func SessionCmd() *cobra.Command {
 		},
 	})
 
+	cmd.AddCommand(&cobra.Command{
+		Use:   "history [session-key]",
+		Short: "Show a session's messages with their IDs",
+		Run: func(cmd *cobra.Command, args []string) {
+			cfg := loadAgentConfig()
+			key := sessionKey
+			if len(args) > 0 {
+				key = args[0]
+			}
+			showSessionHistory(cfg, key)
+		},
+	})
+
+	var forkAt int64
+	var forkAs string
+	forkCmd := &cobra.Command{
+		Use:   "fork <session-key>",
+		Short: "Copy a session up to a message into a new session",
+		Long: `Copy a session's messages up to and including a message into a new session key.
+The original session is left untouched, so a bad run can be retried from the point it went wrong.`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			cfg := loadAgentConfig()
+			forkSession(cfg, args[0], forkAt, forkAs)
+		},
+	}
+	forkCmd.Flags().Int64Var(&forkAt, "at", 0, "message ID to fork at (default: latest message)")
+	forkCmd.Flags().StringVar(&forkAs, "as", "", "key for the new session (default: <key>-fork-N)")
+	cmd.AddCommand(forkCmd)
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "rewind <session-key> <message-id>",
+		Short: "Remove all messages after a message",
+		Args:  cobra.ExactArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			cfg := loadAgentConfig()
+			messageID, err := strconv.ParseInt(args[1], 10, 64)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: invalid message ID: %s\n", args[1])
+				os.Exit(1)
+			}
+			rewindSession(cfg, args[0], messageID)
+		},
+	})
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "tree",
+		Short: "Show sessions and their forks",
+		Run: func(cmd *cobra.Command, args []string) {
+			cfg := loadAgentConfig()
+			showSessionTree(cfg)
+		},
+	})
+
+	var exportFormat, exportOutput string
+	exportCmd := &cobra.Command{
+		Use:   "export <session-key>",
+		Short: "Export a session transcript",
+		Long: `Export a session's full history, including tool calls, tool results and timestamps.
+JSONL exports can be loaded again with "gobot session import"; Markdown and HTML are for reading.`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			cfg := loadAgentConfig()
+			exportSession(cfg, args[0], exportFormat, exportOutput)
+		},
+	}
+	exportCmd.Flags().StringVarP(&exportFormat, "format", "f", session.FormatJSONL, "output format: jsonl, markdown or html")
+	exportCmd.Flags().StringVarP(&exportOutput, "output", "o", "", "write to a file instead of stdout")
+	cmd.AddCommand(exportCmd)
+
+	var importAs string
+	importCmd := &cobra.Command{
+		Use:   "import <file.jsonl>",
+		Short: "Import a session from a JSONL export",
+		Long:  `Import a session exported with "gobot session export --format jsonl". Use "-" to read from stdin.`,
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			cfg := loadAgentConfig()
+			importSession(cfg, args[0], importAs)
+		},
+	}
+	importCmd.Flags().StringVar(&importAs, "as", "", "session key to import into (default: the exported key)")
+	cmd.AddCommand(importCmd)
+
 	return cmd
 }
 
+// openSessions opens the agent session store or exits
+func openSessions(cfg *agentcfg.Config) *session.Manager {
+	sessions, err := session.New(cfg.DBPath())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	return sessions
+}
+
+// lookupSession resolves a session key or exits
+func lookupSession(sessions *session.Manager, key string) *session.Session {
+	sess, err := sessions.GetByKey(key)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	return sess
+}
+
+// showSessionHistory prints a session's active messages with their IDs
+func showSessionHistory(cfg *agentcfg.Config, key string) {
+	sessions := openSessions(cfg)
+	defer sessions.Close()
+
+	sess := lookupSession(sessions, key)
+	messages, err := sessions.GetMessages(sess.ID, 0)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if len(messages) == 0 {
+		fmt.Println("No messages.")
+		return
+	}
+
+	for _, msg := range messages {
+		summary := strings.ReplaceAll(msg.Content, "\n", " ")
+		if summary == "" {
+			switch {
+			case len(msg.ToolCalls) > 0:
+				var calls []session.ToolCall
+				json.Unmarshal(msg.ToolCalls, &calls)
+				names := make([]string, 0, len(calls))
+				for _, c := range calls {
+					names = append(names, c.Name)
+				}
+				summary = "→ " + strings.Join(names, ", ")
+			case len(msg.ToolResults) > 0:
+				summary = "(tool results)"
+			}
+		}
+		if len(summary) > 80 {
+			summary = summary[:77] + "..."
+		}
+		fmt.Printf("  %6d  %-9s %s  %s\n", msg.ID, msg.Role, msg.CreatedAt.Format("2006-01-02 15:04"), summary)
+	}
+}
+
+// forkSession copies a session into a new session key
+func forkSession(cfg *agentcfg.Config, key string, at int64, newKey string) {
+	sessions := openSessions(cfg)
+	defer sessions.Close()
+
+	sess := lookupSession(sessions, key)
+	fork, err := sessions.Fork(sess.ID, at, newKey)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Forked %s at message %d -> %s\n", key, fork.ForkMessageID, fork.SessionKey)
+	fmt.Printf("Continue with: gobot chat -s %s\n", fork.SessionKey)
+}
+
+// rewindSession removes all messages after a message
+func rewindSession(cfg *agentcfg.Config, key string, messageID int64) {
+	sessions := openSessions(cfg)
+	defer sessions.Close()
+
+	sess := lookupSession(sessions, key)
+	removed, err := sessions.Rewind(sess.ID, messageID)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Rewound %s to message %d (%d messages removed)\n", key, messageID, removed)
+}
+
+// showSessionTree prints sessions with their forks indented beneath them
+func showSessionTree(cfg *agentcfg.Config) {
+	sessions := openSessions(cfg)
+	defer sessions.Close()
+
+	roots, err := sessions.Tree()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if len(roots) == 0 {
+		fmt.Println("No sessions found.")
+		return
+	}
+
+	var printNodes func(nodes []*session.SessionNode, indent string)
+	printNodes = func(nodes []*session.SessionNode, indent string) {
+		for _, n := range nodes {
+			fork := ""
+			if n.ParentID != "" {
+				fork = fmt.Sprintf(" (forked at %d)", n.ForkMessageID)
+			}
+			fmt.Printf("%s%s%s - %d messages\n", indent, n.SessionKey, fork, n.MessageCount)
+			printNodes(n.Children, indent+"  ")
+		}
+	}
+	printNodes(roots, "")
+}
+
+// exportSession writes a session transcript to stdout or a file
+func exportSession(cfg *agentcfg.Config, key, format, output string) {
+	sessions := openSessions(cfg)
+	defer sessions.Close()
+
+	sess := lookupSession(sessions, key)
+
+	var w io.Writer = os.Stdout
+	if output != "" {
+		f, err := os.Create(output)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		defer f.Close()
+		w = f
+	}
+
+	if err := sessions.Export(w, sess.ID, format); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if output != "" {
+		fmt.Printf("Exported %s to %s\n", key, output)
+	}
+}
+
+// importSession loads a JSONL export into a new session
+func importSession(cfg *agentcfg.Config, path, key string) {
+	var r io.Reader = os.Stdin
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		defer f.Close()
+		r = f
+	}
+
+	exported, messages, err := session.ReadJSONL(r)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to read %s: %v\n", path, err)
+		os.Exit(1)
+	}
+
+	if key == "" && exported != nil {
+		key = exported.SessionKey
+	}
+	if key == "" {
+		fmt.Fprintln(os.Stderr, "Error: the file has no session header; choose a key with --as")
+		os.Exit(1)
+	}
+
+	sessions := openSessions(cfg)
+	defer sessions.Close()
+
+	sess, err := sessions.Import(key, messages)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Imported %d messages into %s\n", len(messages), sess.SessionKey)
+	fmt.Printf("Continue with: gobot chat -s %s\n", sess.SessionKey)
+}
+
 // listSessions lists all sessions
 func listSessions(cfg *agentcfg.Config) {
 	sessions, err := session.New(cfg.DBPath())
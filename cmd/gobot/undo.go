@@ -0,0 +1,119 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	agentcfg "gobot/agent/config"
+	"gobot/agent/session"
+)
+
+// UndoCmd creates the undo command
+func UndoCmd() *cobra.Command {
+	var to int64
+	var list, dryRun bool
+
+	cmd := &cobra.Command{
+		Use:   "undo",
+		Short: "Restore files changed by the agent's write and edit calls",
+		Long: `Every write and edit call snapshots the file first, keyed by session and the assistant
+message that made the call. Undo puts files back the way they were: by default before the
+last run in the session, or with --to as they were at a message (see "gobot session history").
+Files the agent created are deleted. Works outside git repositories.`,
+		Example: `  gobot undo
+  gobot undo -s work --to 42
+  gobot undo --list`,
+		Run: func(cmd *cobra.Command, args []string) {
+			cfg := loadAgentConfig()
+			if list {
+				listCheckpoints(cfg, sessionKey)
+				return
+			}
+			undoFiles(cfg, sessionKey, to, dryRun)
+		},
+	}
+
+	cmd.Flags().Int64Var(&to, "to", 0, "restore files as they were at this message ID (default: before the last run)")
+	cmd.Flags().BoolVar(&list, "list", false, "list the session's file checkpoints instead")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "show which files would be restored without changing them")
+
+	return cmd
+}
+
+// undoFiles restores the files changed after a message
+func undoFiles(cfg *agentcfg.Config, key string, to int64, dryRun bool) {
+	sessions := openSessions(cfg)
+	defer sessions.Close()
+
+	sess := lookupSession(sessions, key)
+	if dryRun {
+		pending, err := sessions.PendingCheckpoints(sess.ID, to)
+		if errors.Is(err, session.ErrNoCheckpoints) {
+			fmt.Println("No file changes to undo.")
+			return
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		for _, c := range pending {
+			if c.Existed {
+				fmt.Printf("  restore  %s (as before message %d)\n", c.Path, c.MessageID)
+			} else {
+				fmt.Printf("  delete   %s (created at message %d)\n", c.Path, c.MessageID)
+			}
+		}
+		return
+	}
+
+	restored, err := sessions.RestoreCheckpoints(sess.ID, to)
+	if errors.Is(err, session.ErrNoCheckpoints) {
+		fmt.Println("No file changes to undo.")
+		return
+	}
+	for _, f := range restored {
+		if f.Deleted {
+			fmt.Printf("  \033[31mdeleted\033[0m   %s\n", f.Path)
+		} else {
+			fmt.Printf("  \033[32mrestored\033[0m  %s\n", f.Path)
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Restored %d files in %s\n", len(restored), key)
+}
+
+// listCheckpoints prints a session's file checkpoints
+func listCheckpoints(cfg *agentcfg.Config, key string) {
+	sessions := openSessions(cfg)
+	defer sessions.Close()
+
+	sess := lookupSession(sessions, key)
+	checkpoints, err := sessions.ListCheckpoints(sess.ID)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if len(checkpoints) == 0 {
+		fmt.Println("No file checkpoints in this session.")
+		return
+	}
+
+	fmt.Printf("%-8s %-17s %-9s %s\n", "MESSAGE", "TIME", "STATE", "PATH")
+	for _, c := range checkpoints {
+		state := "pending"
+		if !c.RestoredAt.IsZero() {
+			state = "undone"
+		}
+		path := c.Path
+		if !c.Existed {
+			path += " (new)"
+		}
+		fmt.Printf("%-8d %-17s %-9s %s\n", c.MessageID, c.CreatedAt.Format("2006-01-02 15:04"), state, path)
+	}
+}
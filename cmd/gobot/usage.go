@@ -0,0 +1,103 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	agentcfg "gobot/agent/config"
+	"gobot/agent/session"
+)
+
+// UsageCmd creates the usage command
+func UsageCmd() *cobra.Command {
+	var days int
+	var asJSON bool
+
+	cmd := &cobra.Command{
+		Use:   "usage [session-key]",
+		Short: "Show token usage and cost",
+		Long: `Show token usage and estimated cost grouped by session, day and model.
+
+Costs are computed from the pricing in ~/.gobot/models.yaml at the time of each call.`,
+		Args: cobra.MaximumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			cfg := loadAgentConfig()
+			filter := session.UsageFilter{}
+			if len(args) > 0 {
+				filter.SessionKey = args[0]
+			}
+			if days > 0 {
+				now := time.Now()
+				start := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+				filter.Since = start.AddDate(0, 0, -(days - 1))
+			}
+			showUsage(cfg, filter, asJSON)
+		},
+	}
+
+	cmd.Flags().IntVar(&days, "days", 0, "only include the last N days (0 = all time)")
+	cmd.Flags().BoolVar(&asJSON, "json", false, "output as JSON")
+
+	return cmd
+}
+
+// showUsage prints a usage report
+func showUsage(cfg *agentcfg.Config, filter session.UsageFilter, asJSON bool) {
+	sessions, err := session.New(cfg.DBPath())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	defer sessions.Close()
+
+	report, err := sessions.Usage(filter)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if asJSON {
+		data, _ := json.MarshalIndent(report, "", "  ")
+		fmt.Println(string(data))
+		return
+	}
+
+	if report.Total.Calls == 0 {
+		fmt.Println("No usage recorded.")
+		return
+	}
+
+	printUsageTable("By model", report.ByModel)
+	printUsageTable("By day", report.ByDay)
+	printUsageTable("By session", report.BySession)
+
+	t := report.Total
+	fmt.Printf("Total: %d calls, %s in (%s cached) / %s out tokens, $%.4f\n",
+		t.Calls, formatTokens(t.InputTokens), formatTokens(t.CacheReadTokens), formatTokens(t.OutputTokens), t.CostUSD)
+}
+
+// printUsageTable prints one grouping of a usage report
+func printUsageTable(title string, rows []session.UsageTotals) {
+	fmt.Printf("%s:\n", title)
+	for _, r := range rows {
+		fmt.Printf("  %-40s %6d calls  %10s in  %10s out  $%.4f\n",
+			r.Key, r.Calls, formatTokens(r.InputTokens), formatTokens(r.OutputTokens), r.CostUSD)
+	}
+	fmt.Println()
+}
+
+// formatTokens formats a token count compactly (e.g. 12.3k, 1.2M)
+func formatTokens(n int64) string {
+	switch {
+	case n >= 1_000_000:
+		return fmt.Sprintf("%.1fM", float64(n)/1_000_000)
+	case n >= 1_000:
+		return fmt.Sprintf("%.1fk", float64(n)/1_000)
+	default:
+		return fmt.Sprintf("%d", n)
+	}
+}
@@ -44,6 +44,10 @@ Just type 'gobot' to start both the server and agent together.`,
 	rootCmd.AddCommand(ChatCmd())
 	rootCmd.AddCommand(ConfigCmd())
 	rootCmd.AddCommand(SessionCmd())
+	rootCmd.AddCommand(UndoCmd())
+	rootCmd.AddCommand(UsageCmd())
+	rootCmd.AddCommand(AuditCmd())
+	rootCmd.AddCommand(PolicyCmd())
 	rootCmd.AddCommand(SkillsCmd())
 	rootCmd.AddCommand(PluginsCmd())
 	rootCmd.AddCommand(MessageCmd())
@@ -40,6 +40,9 @@ type ResponseHandler func(agentID string, frame *Frame)
 // ApprovalRequestHandler is called when an agent requests approval
 type ApprovalRequestHandler func(agentID string, requestID string, toolName string, input json.RawMessage)
 
+// EventHandler is called when an agent sends an event frame (e.g. sub-agent progress)
+type EventHandler func(agentID string, frame *Frame)
+
 // Hub manages THE agent connection (single-bot paradigm)
 type Hub struct {
 	// Single Bot Paradigm: ONE agent connection
@@ -52,14 +55,19 @@ type Hub struct {
 	// Unregister channel
 	unregister chan *AgentConnection
 
-	// Response handler for routing agent responses
+	// Response handler for routing agent responses, plus any added alongside it
 	responseHandler   ResponseHandler
+	extraHandlers     []ResponseHandler
 	responseHandlerMu sync.RWMutex
 
 	// Approval request handler
 	approvalHandler   ApprovalRequestHandler
 	approvalHandlerMu sync.RWMutex
 
+	// Event handler
+	eventHandler   EventHandler
+	eventHandlerMu sync.RWMutex
+
 	upgrader websocket.Upgrader
 }
 
@@ -199,6 +207,25 @@ func (h *Hub) SetResponseHandler(handler ResponseHandler) {
 	fmt.Printf("[AgentHub] Response handler registered (handler=%v)\n", handler != nil)
 }
 
+// AddResponseHandler adds a handler that receives agent responses alongside the one set by
+// SetResponseHandler, e.g. for requests the channel router sends
+func (h *Hub) AddResponseHandler(handler ResponseHandler) {
+	h.responseHandlerMu.Lock()
+	defer h.responseHandlerMu.Unlock()
+	h.extraHandlers = append(h.extraHandlers, handler)
+}
+
+// responseHandlers returns every handler that should see an agent response
+func (h *Hub) responseHandlers() []ResponseHandler {
+	h.responseHandlerMu.RLock()
+	defer h.responseHandlerMu.RUnlock()
+	handlers := append([]ResponseHandler(nil), h.extraHandlers...)
+	if h.responseHandler != nil {
+		handlers = append(handlers, h.responseHandler)
+	}
+	return handlers
+}
+
 // SetApprovalHandler sets the handler for approval requests
 func (h *Hub) SetApprovalHandler(handler ApprovalRequestHandler) {
 	h.approvalHandlerMu.Lock()
@@ -206,12 +233,54 @@ func (h *Hub) SetApprovalHandler(handler ApprovalRequestHandler) {
 	h.approvalHandler = handler
 }
 
-// SendApprovalResponse sends an approval response back to THE agent
-func (h *Hub) SendApprovalResponse(agentID, requestID string, approved bool) error {
+// SetEventHandler sets the handler for agent events
+func (h *Hub) SetEventHandler(handler EventHandler) {
+	h.eventHandlerMu.Lock()
+	defer h.eventHandlerMu.Unlock()
+	h.eventHandler = handler
+}
+
+// SendApprovalResponse sends an approval response back to THE agent.
+// approver identifies the user who answered and is recorded in the agent's audit log.
+func (h *Hub) SendApprovalResponse(agentID, requestID string, approved bool, approver string) error {
 	frame := &Frame{
 		Type:    "approval_response",
 		ID:      requestID,
-		Payload: map[string]any{"approved": approved},
+		Payload: map[string]any{"approved": approved, "approver": approver},
+	}
+	return h.Send(frame)
+}
+
+// CancelRun asks THE agent to stop in-flight runs. A requestID stops that run, a sessionKey
+// stops every run in the session, and leaving both empty stops all runs.
+func (h *Hub) CancelRun(requestID, sessionKey string) error {
+	frame := &Frame{
+		Type:   "req",
+		ID:     fmt.Sprintf("cancel-%d", time.Now().UnixNano()),
+		Method: "cancel",
+		Params: map[string]any{"request_id": requestID, "session_key": sessionKey},
+	}
+	return h.Send(frame)
+}
+
+// CancelSubAgent asks THE agent to stop a running orchestrator sub-agent
+func (h *Hub) CancelSubAgent(subAgentID string) error {
+	frame := &Frame{
+		Type:   "req",
+		ID:     fmt.Sprintf("cancel-subagent-%d", time.Now().UnixNano()),
+		Method: "cancel_subagent",
+		Params: map[string]any{"agent_id": subAgentID},
+	}
+	return h.Send(frame)
+}
+
+// EndSession asks THE agent to stop the background jobs of a session that was deleted
+func (h *Hub) EndSession(sessionKey string) error {
+	frame := &Frame{
+		Type:   "req",
+		ID:     fmt.Sprintf("end-session-%d", time.Now().UnixNano()),
+		Method: "end_session",
+		Params: map[string]any{"session_key": sessionKey},
 	}
 	return h.Send(frame)
 }
@@ -319,21 +388,13 @@ func (h *Hub) writePump(agent *AgentConnection) {
 func (h *Hub) handleFrame(agent *AgentConnection, frame *Frame) {
 	switch frame.Type {
 	case "res":
-		// Response to a request we sent - route to handler
-		h.responseHandlerMu.RLock()
-		handler := h.responseHandler
-		h.responseHandlerMu.RUnlock()
-
-		if handler != nil {
+		// Response to a request we sent - route to handlers
+		for _, handler := range h.responseHandlers() {
 			handler(agent.ID, frame)
 		}
 	case "stream":
-		// Streaming chunk from agent - route to same handler as responses
-		h.responseHandlerMu.RLock()
-		handler := h.responseHandler
-		h.responseHandlerMu.RUnlock()
-
-		if handler != nil {
+		// Streaming chunk from agent - route to same handlers as responses
+		for _, handler := range h.responseHandlers() {
 			handler(agent.ID, frame)
 		}
 	case "approval_request":
@@ -353,7 +414,14 @@ func (h *Hub) handleFrame(agent *AgentConnection, frame *Frame) {
 			}
 		}
 	case "event":
-		// Event from agent - could be broadcast to other systems
+		// Event from agent (Method names the event) - route to handler
+		h.eventHandlerMu.RLock()
+		handler := h.eventHandler
+		h.eventHandlerMu.RUnlock()
+
+		if handler != nil {
+			handler(agent.ID, frame)
+		}
 	case "req":
 		// Request from agent - handle and respond
 		h.handleRequest(agent, frame)
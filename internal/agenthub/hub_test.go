@@ -146,6 +146,101 @@ func TestSendToAgent(t *testing.T) {
 	}
 }
 
+func TestCancelRun(t *testing.T) {
+	hub := NewHub()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go hub.Run(ctx)
+	time.Sleep(10 * time.Millisecond)
+
+	if err := hub.CancelRun("", "chat-1"); err == nil {
+		t.Error("expected error with no agent connected")
+	}
+
+	agent := &AgentConnection{
+		ID: "agent-1", Send: make(chan []byte, 256), CreatedAt: time.Now(),
+	}
+	hub.register <- agent
+	time.Sleep(10 * time.Millisecond)
+
+	if err := hub.CancelRun("req-1", "chat-1"); err != nil {
+		t.Fatalf("CancelRun failed: %v", err)
+	}
+
+	select {
+	case msg := <-agent.Send:
+		var received struct {
+			Type   string            `json:"type"`
+			Method string            `json:"method"`
+			Params map[string]string `json:"params"`
+		}
+		if err := json.Unmarshal(msg, &received); err != nil {
+			t.Fatalf("failed to unmarshal frame: %v", err)
+		}
+		if received.Type != "req" || received.Method != "cancel" {
+			t.Errorf("expected cancel request, got %s %s", received.Type, received.Method)
+		}
+		if received.Params["request_id"] != "req-1" || received.Params["session_key"] != "chat-1" {
+			t.Errorf("unexpected params: %v", received.Params)
+		}
+	case <-time.After(100 * time.Millisecond):
+		t.Error("no message received")
+	}
+}
+
+func TestCancelSubAgent(t *testing.T) {
+	hub := NewHub()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go hub.Run(ctx)
+	time.Sleep(10 * time.Millisecond)
+
+	agent := &AgentConnection{
+		ID: "agent-1", Send: make(chan []byte, 256), CreatedAt: time.Now(),
+	}
+	hub.register <- agent
+	time.Sleep(10 * time.Millisecond)
+
+	if err := hub.CancelSubAgent("agent-123-0"); err != nil {
+		t.Fatalf("CancelSubAgent failed: %v", err)
+	}
+
+	select {
+	case msg := <-agent.Send:
+		var received struct {
+			Type   string            `json:"type"`
+			Method string            `json:"method"`
+			Params map[string]string `json:"params"`
+		}
+		if err := json.Unmarshal(msg, &received); err != nil {
+			t.Fatalf("failed to unmarshal frame: %v", err)
+		}
+		if received.Type != "req" || received.Method != "cancel_subagent" || received.Params["agent_id"] != "agent-123-0" {
+			t.Errorf("unexpected frame: %+v", received)
+		}
+	case <-time.After(100 * time.Millisecond):
+		t.Error("no message received")
+	}
+}
+
+func TestEventHandler(t *testing.T) {
+	hub := NewHub()
+
+	var got *Frame
+	hub.SetEventHandler(func(agentID string, frame *Frame) {
+		got = frame
+	})
+
+	agent := &AgentConnection{ID: "agent-1", Send: make(chan []byte, 1), CreatedAt: time.Now()}
+	hub.handleFrame(agent, &Frame{Type: "event", ID: "agent-123-0", Method: "subagent"})
+
+	if got == nil || got.Method != "subagent" || got.ID != "agent-123-0" {
+		t.Errorf("expected subagent event to reach handler, got %+v", got)
+	}
+}
+
 func TestBroadcast(t *testing.T) {
 	hub := NewHub()
 
@@ -2,6 +2,9 @@ package channels
 
 import (
 	"context"
+	"fmt"
+	"io"
+	"net/http"
 )
 
 // Channel represents a messaging channel adapter
@@ -46,8 +49,9 @@ type InboundMessage struct {
 	ChannelID   string `json:"channel_id"`   // Chat ID, channel ID, etc.
 
 	// Message content
-	MessageID string `json:"message_id"`
-	Text      string `json:"text"`
+	MessageID   string       `json:"message_id"`
+	Text        string       `json:"text"`
+	Attachments []Attachment `json:"attachments,omitempty"` // Images, documents, voice notes
 
 	// Sender info
 	SenderID   string `json:"sender_id"`
@@ -61,6 +65,45 @@ type InboundMessage struct {
 	Raw any `json:"-"`
 }
 
+// MaxAttachmentSize is the largest file downloaded from a channel message
+const MaxAttachmentSize = 10 << 20
+
+// Attachment is a file sent with an inbound message
+type Attachment struct {
+	Name      string `json:"name"`
+	MediaType string `json:"media_type,omitempty"` // Empty if the platform doesn't say
+	Data      []byte `json:"data"`
+}
+
+// Download fetches a file attached to a message, with optional auth headers
+func Download(ctx context.Context, url string, header http.Header) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("download failed: %s", resp.Status)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, MaxAttachmentSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > MaxAttachmentSize {
+		return nil, fmt.Errorf("file is larger than %d bytes", MaxAttachmentSize)
+	}
+	return data, nil
+}
+
 // OutboundMessage represents a message to send to a channel
 type OutboundMessage struct {
 	// Target
@@ -120,6 +120,20 @@ func (a *Adapter) messageHandler(s *discordgo.Session, m *discordgo.MessageCreat
 		inbound.ThreadID = m.Thread.ID
 	}
 
+	// Download attachments
+	for _, att := range m.Attachments {
+		if att.Size > channels.MaxAttachmentSize {
+			fmt.Printf("[Discord] Skipping attachment %s: %d bytes\n", att.Filename, att.Size)
+			continue
+		}
+		data, err := channels.Download(context.Background(), att.URL, nil)
+		if err != nil {
+			fmt.Printf("[Discord] Failed to download attachment %s: %v\n", att.Filename, err)
+			continue
+		}
+		inbound.Attachments = append(inbound.Attachments, channels.Attachment{Name: att.Filename, MediaType: att.ContentType, Data: data})
+	}
+
 	// Call handler
 	a.mu.RLock()
 	handler := a.handler
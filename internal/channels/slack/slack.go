@@ -1,6 +1,7 @@
 package slack
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"sync"
@@ -141,8 +142,8 @@ func (a *Adapter) handleMessage(msg *slackevents.MessageEvent) {
 		return
 	}
 
-	// Ignore message updates/deletes
-	if msg.SubType != "" {
+	// Ignore message updates/deletes; file uploads come as file_share
+	if msg.SubType != "" && msg.SubType != "file_share" {
 		return
 	}
 
@@ -165,6 +166,22 @@ func (a *Adapter) handleMessage(msg *slackevents.MessageEvent) {
 		Raw:         msg,
 	}
 
+	// Download shared files
+	if msg.Message != nil {
+		for _, f := range msg.Message.Files {
+			if f.Size > channels.MaxAttachmentSize {
+				fmt.Printf("[Slack] Skipping attachment %s: %d bytes\n", f.Name, f.Size)
+				continue
+			}
+			var buf bytes.Buffer
+			if err := a.client.GetFileContext(context.Background(), f.URLPrivateDownload, &buf); err != nil {
+				fmt.Printf("[Slack] Failed to download attachment %s: %v\n", f.Name, err)
+				continue
+			}
+			inbound.Attachments = append(inbound.Attachments, channels.Attachment{Name: f.Name, MediaType: f.Mimetype, Data: buf.Bytes()})
+		}
+	}
+
 	// Call handler
 	a.mu.RLock()
 	handler := a.handler
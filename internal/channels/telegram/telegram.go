@@ -143,6 +143,12 @@ func (a *Adapter) defaultHandler(ctx context.Context, b *bot.Bot, update *models
 		inbound.ThreadID = strconv.Itoa(msg.MessageThreadID)
 	}
 
+	// Media messages carry their text as a caption
+	if inbound.Text == "" {
+		inbound.Text = msg.Caption
+	}
+	inbound.Attachments = a.attachments(ctx, b, msg)
+
 	// Call handler
 	a.mu.RLock()
 	handler := a.handler
@@ -152,3 +158,47 @@ func (a *Adapter) defaultHandler(ctx context.Context, b *bot.Bot, update *models
 		handler(inbound)
 	}
 }
+
+// attachments downloads the photo, document, audio or voice note sent with a message
+func (a *Adapter) attachments(ctx context.Context, b *bot.Bot, msg *models.Message) []channels.Attachment {
+	type file struct {
+		id, name, mediaType string
+		size                int64
+	}
+
+	var files []file
+	if len(msg.Photo) > 0 {
+		// Photos come in several sizes, largest last
+		p := msg.Photo[len(msg.Photo)-1]
+		files = append(files, file{p.FileID, "photo.jpg", "image/jpeg", int64(p.FileSize)})
+	}
+	if d := msg.Document; d != nil {
+		files = append(files, file{d.FileID, d.FileName, d.MimeType, d.FileSize})
+	}
+	if au := msg.Audio; au != nil {
+		files = append(files, file{au.FileID, au.FileName, au.MimeType, au.FileSize})
+	}
+	if v := msg.Voice; v != nil {
+		files = append(files, file{v.FileID, "voice.ogg", v.MimeType, v.FileSize})
+	}
+
+	var attachments []channels.Attachment
+	for _, f := range files {
+		if f.size > channels.MaxAttachmentSize {
+			fmt.Printf("[Telegram] Skipping attachment %s: %d bytes\n", f.name, f.size)
+			continue
+		}
+		info, err := b.GetFile(ctx, &bot.GetFileParams{FileID: f.id})
+		if err != nil {
+			fmt.Printf("[Telegram] Failed to get attachment %s: %v\n", f.name, err)
+			continue
+		}
+		data, err := channels.Download(ctx, b.FileDownloadLink(info), nil)
+		if err != nil {
+			fmt.Printf("[Telegram] Failed to download attachment %s: %v\n", f.name, err)
+			continue
+		}
+		attachments = append(attachments, channels.Attachment{Name: f.name, MediaType: f.mediaType, Data: data})
+	}
+	return attachments
+}
@@ -0,0 +1,29 @@
+package agent
+
+import (
+	"net/http"
+
+	"github.com/zeromicro/go-zero/rest/httpx"
+	"gobot/internal/logic/agent"
+	"gobot/internal/svc"
+	"gobot/internal/types"
+)
+
+// Cancel a running sub-agent
+func CancelSubAgentHandler(svcCtx *svc.ServiceContext) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req types.SubAgentRequest
+		if err := httpx.Parse(r, &req); err != nil {
+			httpx.ErrorCtx(r.Context(), w, err)
+			return
+		}
+
+		l := agent.NewCancelSubAgentLogic(r.Context(), svcCtx)
+		resp, err := l.CancelSubAgent(&req)
+		if err != nil {
+			httpx.ErrorCtx(r.Context(), w, err)
+		} else {
+			httpx.OkJsonCtx(r.Context(), w, resp)
+		}
+	}
+}
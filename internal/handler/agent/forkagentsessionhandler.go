@@ -0,0 +1,29 @@
+package agent
+
+import (
+	"net/http"
+
+	"github.com/zeromicro/go-zero/rest/httpx"
+	"gobot/internal/logic/agent"
+	"gobot/internal/svc"
+	"gobot/internal/types"
+)
+
+// Fork a session at a message into a new session
+func ForkAgentSessionHandler(svcCtx *svc.ServiceContext) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req types.ForkAgentSessionRequest
+		if err := httpx.Parse(r, &req); err != nil {
+			httpx.ErrorCtx(r.Context(), w, err)
+			return
+		}
+
+		l := agent.NewForkAgentSessionLogic(r.Context(), svcCtx)
+		resp, err := l.ForkAgentSession(&req)
+		if err != nil {
+			httpx.ErrorCtx(r.Context(), w, err)
+		} else {
+			httpx.OkJsonCtx(r.Context(), w, resp)
+		}
+	}
+}
@@ -0,0 +1,22 @@
+package agent
+
+import (
+	"net/http"
+
+	"github.com/zeromicro/go-zero/rest/httpx"
+	"gobot/internal/logic/agent"
+	"gobot/internal/svc"
+)
+
+// Show sessions arranged by fork parentage
+func GetAgentSessionTreeHandler(svcCtx *svc.ServiceContext) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		l := agent.NewGetAgentSessionTreeLogic(r.Context(), svcCtx)
+		resp, err := l.GetAgentSessionTree()
+		if err != nil {
+			httpx.ErrorCtx(r.Context(), w, err)
+		} else {
+			httpx.OkJsonCtx(r.Context(), w, resp)
+		}
+	}
+}
@@ -34,6 +34,12 @@ func RegisterHandlers(server *rest.Server, serverCtx *svc.ServiceContext) {
 
 	server.AddRoutes(
 		[]rest.Route{
+			{
+				// List tool calls and approval decisions from the audit log
+				Method:  http.MethodGet,
+				Path:    "/agent/audit",
+				Handler: agent.ListAgentAuditHandler(serverCtx),
+			},
 			{
 				// List agent sessions
 				Method:  http.MethodGet,
@@ -46,12 +52,36 @@ func RegisterHandlers(server *rest.Server, serverCtx *svc.ServiceContext) {
 				Path:    "/agent/sessions/:id",
 				Handler: agent.DeleteAgentSessionHandler(serverCtx),
 			},
+			{
+				// Fork a session at a message into a new session
+				Method:  http.MethodPost,
+				Path:    "/agent/sessions/:id/fork",
+				Handler: agent.ForkAgentSessionHandler(serverCtx),
+			},
 			{
 				// Get session messages
 				Method:  http.MethodGet,
 				Path:    "/agent/sessions/:id/messages",
 				Handler: agent.GetAgentSessionMessagesHandler(serverCtx),
 			},
+			{
+				// Rewind a session, removing messages after a message
+				Method:  http.MethodPost,
+				Path:    "/agent/sessions/:id/rewind",
+				Handler: agent.RewindAgentSessionHandler(serverCtx),
+			},
+			{
+				// Restore files changed by write and edit calls after a message
+				Method:  http.MethodPost,
+				Path:    "/agent/sessions/:id/undo",
+				Handler: agent.UndoAgentSessionHandler(serverCtx),
+			},
+			{
+				// Show sessions arranged by fork parentage
+				Method:  http.MethodGet,
+				Path:    "/agent/sessions/tree",
+				Handler: agent.GetAgentSessionTreeHandler(serverCtx),
+			},
 			{
 				// Get agent settings
 				Method:  http.MethodGet,
@@ -70,6 +100,36 @@ func RegisterHandlers(server *rest.Server, serverCtx *svc.ServiceContext) {
 				Path:    "/agent/status",
 				Handler: agent.GetSimpleAgentStatusHandler(serverCtx),
 			},
+			{
+				// Stop in-flight agent runs
+				Method:  http.MethodPost,
+				Path:    "/agent/stop",
+				Handler: agent.StopAgentRunHandler(serverCtx),
+			},
+			{
+				// List orchestrator sub-agents, newest first
+				Method:  http.MethodGet,
+				Path:    "/agent/subagents",
+				Handler: agent.ListSubAgentsHandler(serverCtx),
+			},
+			{
+				// Get a sub-agent with its conversation
+				Method:  http.MethodGet,
+				Path:    "/agent/subagents/:id",
+				Handler: agent.GetSubAgentHandler(serverCtx),
+			},
+			{
+				// Cancel a running sub-agent
+				Method:  http.MethodPost,
+				Path:    "/agent/subagents/:id/cancel",
+				Handler: agent.CancelSubAgentHandler(serverCtx),
+			},
+			{
+				// Get token usage and cost totals by session, day and model
+				Method:  http.MethodGet,
+				Path:    "/agent/usage",
+				Handler: agent.GetAgentUsageHandler(serverCtx),
+			},
 			{
 				// List connected agents
 				Method:  http.MethodGet,
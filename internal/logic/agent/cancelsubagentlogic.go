@@ -0,0 +1,49 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+
+	"gobot/internal/svc"
+	"gobot/internal/types"
+
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+type CancelSubAgentLogic struct {
+	logx.Logger
+	ctx    context.Context
+	svcCtx *svc.ServiceContext
+}
+
+// Cancel a running sub-agent
+func NewCancelSubAgentLogic(ctx context.Context, svcCtx *svc.ServiceContext) *CancelSubAgentLogic {
+	return &CancelSubAgentLogic{
+		Logger: logx.WithContext(ctx),
+		ctx:    ctx,
+		svcCtx: svcCtx,
+	}
+}
+
+func (l *CancelSubAgentLogic) CancelSubAgent(req *types.SubAgentRequest) (resp *types.MessageResponse, err error) {
+	sessions, err := l.svcCtx.AgentSessions()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open agent sessions: %w", err)
+	}
+
+	run, err := sessions.GetSubAgentRun(req.Id)
+	if err != nil {
+		return nil, err
+	}
+	if run.Status != "pending" && run.Status != "running" {
+		return nil, fmt.Errorf("sub-agent is not running: %s", run.Status)
+	}
+
+	if err := l.svcCtx.AgentHub.CancelSubAgent(req.Id); err != nil {
+		return nil, fmt.Errorf("failed to cancel sub-agent: %w", err)
+	}
+
+	return &types.MessageResponse{
+		Message: "Cancel requested",
+	}, nil
+}
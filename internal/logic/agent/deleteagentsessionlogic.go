@@ -2,6 +2,7 @@ package agent
 
 import (
 	"context"
+	"fmt"
 
 	"gobot/internal/svc"
 	"gobot/internal/types"
@@ -25,11 +26,23 @@ func NewDeleteAgentSessionLogic(ctx context.Context, svcCtx *svc.ServiceContext)
 }
 
 func (l *DeleteAgentSessionLogic) DeleteAgentSession(req *types.DeleteAgentSessionRequest) (resp *types.MessageResponse, err error) {
-	err = l.svcCtx.DB.DeleteSession(l.ctx, req.Id)
+	sessions, err := l.svcCtx.AgentSessions()
 	if err != nil {
+		return nil, fmt.Errorf("failed to open agent sessions: %w", err)
+	}
+
+	sess, getErr := sessions.Get(req.Id)
+	if err := sessions.DeleteSession(req.Id); err != nil {
 		return nil, err
 	}
 
+	// Background jobs end with their session; the agent may not be connected
+	if getErr == nil {
+		if err := l.svcCtx.AgentHub.EndSession(sess.SessionKey); err != nil {
+			l.Infof("Could not stop background jobs for session %s: %v", sess.SessionKey, err)
+		}
+	}
+
 	return &types.MessageResponse{
 		Message: "Session deleted successfully",
 	}, nil
@@ -0,0 +1,41 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+
+	"gobot/internal/svc"
+	"gobot/internal/types"
+
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+type ForkAgentSessionLogic struct {
+	logx.Logger
+	ctx    context.Context
+	svcCtx *svc.ServiceContext
+}
+
+// Fork a session at a message into a new session
+func NewForkAgentSessionLogic(ctx context.Context, svcCtx *svc.ServiceContext) *ForkAgentSessionLogic {
+	return &ForkAgentSessionLogic{
+		Logger: logx.WithContext(ctx),
+		ctx:    ctx,
+		svcCtx: svcCtx,
+	}
+}
+
+func (l *ForkAgentSessionLogic) ForkAgentSession(req *types.ForkAgentSessionRequest) (resp *types.AgentSession, err error) {
+	sessions, err := l.svcCtx.AgentSessions()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open agent sessions: %w", err)
+	}
+
+	fork, err := sessions.Fork(req.Id, req.MessageId, req.Key)
+	if err != nil {
+		return nil, err
+	}
+
+	result := toAgentSession(fork)
+	return &result, nil
+}
@@ -2,6 +2,7 @@ package agent
 
 import (
 	"context"
+	"fmt"
 	"time"
 
 	"gobot/internal/svc"
@@ -26,7 +27,13 @@ func NewGetAgentSessionMessagesLogic(ctx context.Context, svcCtx *svc.ServiceCon
 }
 
 func (l *GetAgentSessionMessagesLogic) GetAgentSessionMessages(req *types.GetAgentSessionRequest) (resp *types.GetAgentSessionMessagesResponse, err error) {
-	messages, err := l.svcCtx.DB.GetSessionMessages(l.ctx, req.Id)
+	sessions, err := l.svcCtx.AgentSessions()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open agent sessions: %w", err)
+	}
+
+	// Full history, including messages archived by compaction
+	messages, err := sessions.GetHistory(req.Id)
 	if err != nil {
 		return nil, err
 	}
@@ -36,12 +43,14 @@ func (l *GetAgentSessionMessagesLogic) GetAgentSessionMessages(req *types.GetAge
 		result = append(result, types.SessionMessage{
 			Id:        int(m.ID),
 			Role:      m.Role,
-			Content:   m.Content.String,
-			CreatedAt: time.Unix(m.CreatedAt, 0).Format(time.RFC3339),
+			Content:   m.Content,
+			Archived:  m.Archived,
+			CreatedAt: m.CreatedAt.Format(time.RFC3339),
 		})
 	}
 
 	return &types.GetAgentSessionMessagesResponse{
 		Messages: result,
+		Total:    len(result),
 	}, nil
 }
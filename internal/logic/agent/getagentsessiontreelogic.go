@@ -0,0 +1,55 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+
+	"gobot/agent/session"
+	"gobot/internal/svc"
+	"gobot/internal/types"
+
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+type GetAgentSessionTreeLogic struct {
+	logx.Logger
+	ctx    context.Context
+	svcCtx *svc.ServiceContext
+}
+
+// Show sessions arranged by fork parentage
+func NewGetAgentSessionTreeLogic(ctx context.Context, svcCtx *svc.ServiceContext) *GetAgentSessionTreeLogic {
+	return &GetAgentSessionTreeLogic{
+		Logger: logx.WithContext(ctx),
+		ctx:    ctx,
+		svcCtx: svcCtx,
+	}
+}
+
+func (l *GetAgentSessionTreeLogic) GetAgentSessionTree() (resp *types.GetAgentSessionTreeResponse, err error) {
+	sessions, err := l.svcCtx.AgentSessions()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open agent sessions: %w", err)
+	}
+
+	roots, err := sessions.Tree()
+	if err != nil {
+		return nil, err
+	}
+
+	return &types.GetAgentSessionTreeResponse{
+		Sessions: toAgentSessionNodes(roots),
+	}, nil
+}
+
+// toAgentSessionNodes converts a session tree to its API form
+func toAgentSessionNodes(nodes []*session.SessionNode) []types.AgentSessionNode {
+	result := make([]types.AgentSessionNode, 0, len(nodes))
+	for _, n := range nodes {
+		result = append(result, types.AgentSessionNode{
+			Session:  toAgentSession(&n.Session),
+			Children: toAgentSessionNodes(n.Children),
+		})
+	}
+	return result
+}
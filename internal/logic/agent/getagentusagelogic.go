@@ -0,0 +1,85 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"gobot/agent/session"
+	"gobot/internal/svc"
+	"gobot/internal/types"
+
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+type GetAgentUsageLogic struct {
+	logx.Logger
+	ctx    context.Context
+	svcCtx *svc.ServiceContext
+}
+
+// Get token usage and cost totals by session, day and model
+func NewGetAgentUsageLogic(ctx context.Context, svcCtx *svc.ServiceContext) *GetAgentUsageLogic {
+	return &GetAgentUsageLogic{
+		Logger: logx.WithContext(ctx),
+		ctx:    ctx,
+		svcCtx: svcCtx,
+	}
+}
+
+func (l *GetAgentUsageLogic) GetAgentUsage(req *types.GetAgentUsageRequest) (resp *types.GetAgentUsageResponse, err error) {
+	sessions, err := l.svcCtx.AgentSessions()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open agent sessions: %w", err)
+	}
+
+	filter := session.UsageFilter{SessionKey: req.Session}
+	if filter.Since, err = parseUsageTime(req.Since); err != nil {
+		return nil, fmt.Errorf("invalid since: %w", err)
+	}
+	if filter.Until, err = parseUsageTime(req.Until); err != nil {
+		return nil, fmt.Errorf("invalid until: %w", err)
+	}
+
+	report, err := sessions.Usage(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	return &types.GetAgentUsageResponse{
+		Total:     toUsageTotals(report.Total),
+		BySession: toUsageTotalsList(report.BySession),
+		ByDay:     toUsageTotalsList(report.ByDay),
+		ByModel:   toUsageTotalsList(report.ByModel),
+	}, nil
+}
+
+// parseUsageTime accepts YYYY-MM-DD (local time) or RFC3339; empty means no bound
+func parseUsageTime(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", s, time.Local); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, s)
+}
+
+func toUsageTotals(t session.UsageTotals) types.AgentUsageTotals {
+	return types.AgentUsageTotals{
+		Key:             t.Key,
+		Calls:           t.Calls,
+		InputTokens:     t.InputTokens,
+		OutputTokens:    t.OutputTokens,
+		CacheReadTokens: t.CacheReadTokens,
+		CostUsd:         t.CostUSD,
+	}
+}
+
+func toUsageTotalsList(list []session.UsageTotals) []types.AgentUsageTotals {
+	result := make([]types.AgentUsageTotals, 0, len(list))
+	for _, t := range list {
+		result = append(result, toUsageTotals(t))
+	}
+	return result
+}
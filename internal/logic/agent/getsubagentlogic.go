@@ -0,0 +1,68 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"gobot/agent/session"
+	"gobot/internal/svc"
+	"gobot/internal/types"
+
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+type GetSubAgentLogic struct {
+	logx.Logger
+	ctx    context.Context
+	svcCtx *svc.ServiceContext
+}
+
+// Get a sub-agent with its conversation
+func NewGetSubAgentLogic(ctx context.Context, svcCtx *svc.ServiceContext) *GetSubAgentLogic {
+	return &GetSubAgentLogic{
+		Logger: logx.WithContext(ctx),
+		ctx:    ctx,
+		svcCtx: svcCtx,
+	}
+}
+
+func (l *GetSubAgentLogic) GetSubAgent(req *types.SubAgentRequest) (resp *types.GetSubAgentResponse, err error) {
+	sessions, err := l.svcCtx.AgentSessions()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open agent sessions: %w", err)
+	}
+
+	run, err := sessions.GetSubAgentRun(req.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	// The session is created when the sub-agent starts, so a pending one has no messages yet
+	messages := []types.SessionMessage{}
+	sess, err := sessions.GetByKey(run.SessionKey)
+	if err != nil && !errors.Is(err, session.ErrSessionNotFound) {
+		return nil, err
+	}
+	if sess != nil {
+		history, err := sessions.GetHistory(sess.ID)
+		if err != nil {
+			return nil, err
+		}
+		for _, m := range history {
+			messages = append(messages, types.SessionMessage{
+				Id:        int(m.ID),
+				Role:      m.Role,
+				Content:   m.Content,
+				Archived:  m.Archived,
+				CreatedAt: m.CreatedAt.Format(time.RFC3339),
+			})
+		}
+	}
+
+	return &types.GetSubAgentResponse{
+		SubAgent: toSubAgentRun(run),
+		Messages: messages,
+	}, nil
+}
@@ -0,0 +1,83 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"gobot/agent/audit"
+	"gobot/internal/svc"
+	"gobot/internal/types"
+
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+type ListAgentAuditLogic struct {
+	logx.Logger
+	ctx    context.Context
+	svcCtx *svc.ServiceContext
+}
+
+// List tool calls and approval decisions from the audit log
+func NewListAgentAuditLogic(ctx context.Context, svcCtx *svc.ServiceContext) *ListAgentAuditLogic {
+	return &ListAgentAuditLogic{
+		Logger: logx.WithContext(ctx),
+		ctx:    ctx,
+		svcCtx: svcCtx,
+	}
+}
+
+func (l *ListAgentAuditLogic) ListAgentAudit(req *types.ListAgentAuditRequest) (resp *types.ListAgentAuditResponse, err error) {
+	auditLog, err := l.svcCtx.AgentAudit()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log: %w", err)
+	}
+
+	filter := audit.Filter{
+		SessionKey: req.Session,
+		Tool:       req.Tool,
+		Channel:    req.Channel,
+		Approver:   req.Approver,
+		ErrorsOnly: req.Errors,
+		DeniedOnly: req.Denied,
+		Limit:      req.Limit,
+		Offset:     req.Offset,
+	}
+	if req.Since != "" {
+		if filter.Since, err = audit.ParseTime(req.Since); err != nil {
+			return nil, fmt.Errorf("invalid since: %w", err)
+		}
+	}
+	if req.Until != "" {
+		if filter.Until, err = audit.ParseTime(req.Until); err != nil {
+			return nil, fmt.Errorf("invalid until: %w", err)
+		}
+	}
+
+	entries, err := auditLog.Query(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]types.AgentAuditEntry, 0, len(entries))
+	for _, e := range entries {
+		result = append(result, types.AgentAuditEntry{
+			Id:         e.ID,
+			CreatedAt:  e.CreatedAt.Format(time.RFC3339),
+			SessionKey: e.SessionKey,
+			ToolCallId: e.ToolCallID,
+			Tool:       e.Tool,
+			Input:      e.Input,
+			Output:     e.Output,
+			IsError:    e.IsError,
+			Approved:   e.Approved,
+			Channel:    e.Channel,
+			Approver:   e.Approver,
+			DurationMs: e.DurationMS,
+		})
+	}
+
+	return &types.ListAgentAuditResponse{
+		Entries: result,
+	}, nil
+}
@@ -2,9 +2,10 @@ package agent
 
 import (
 	"context"
+	"fmt"
 	"time"
 
-	"gobot/internal/db"
+	"gobot/agent/session"
 	"gobot/internal/svc"
 	"gobot/internal/types"
 
@@ -27,24 +28,19 @@ func NewListAgentSessionsLogic(ctx context.Context, svcCtx *svc.ServiceContext)
 }
 
 func (l *ListAgentSessionsLogic) ListAgentSessions() (resp *types.ListAgentSessionsResponse, err error) {
-	sessions, err := l.svcCtx.DB.ListSessions(l.ctx, db.ListSessionsParams{
-		Limit:  100,
-		Offset: 0,
-	})
+	sessions, err := l.svcCtx.AgentSessions()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open agent sessions: %w", err)
+	}
+
+	list, err := sessions.ListSessions()
 	if err != nil {
 		return nil, err
 	}
 
-	result := make([]types.AgentSession, 0, len(sessions))
-	for _, s := range sessions {
-		result = append(result, types.AgentSession{
-			Id:           s.ID,
-			Name:         s.Name.String,
-			Summary:      s.Summary.String,
-			MessageCount: int(s.MessageCount.Int64),
-			CreatedAt:    time.Unix(s.CreatedAt, 0).Format(time.RFC3339),
-			UpdatedAt:    time.Unix(s.UpdatedAt, 0).Format(time.RFC3339),
-		})
+	result := make([]types.AgentSession, 0, len(list))
+	for _, s := range list {
+		result = append(result, toAgentSession(&s))
 	}
 
 	return &types.ListAgentSessionsResponse{
@@ -52,3 +48,16 @@ func (l *ListAgentSessionsLogic) ListAgentSessions() (resp *types.ListAgentSessi
 		Total:    len(result),
 	}, nil
 }
+
+// toAgentSession converts a stored agent session to its API form
+func toAgentSession(s *session.Session) types.AgentSession {
+	return types.AgentSession{
+		Id:            s.ID,
+		Name:          s.SessionKey,
+		MessageCount:  s.MessageCount,
+		ParentId:      s.ParentID,
+		ForkMessageId: s.ForkMessageID,
+		CreatedAt:     s.CreatedAt.Format(time.RFC3339),
+		UpdatedAt:     s.UpdatedAt.Format(time.RFC3339),
+	}
+}
@@ -0,0 +1,70 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"gobot/agent/session"
+	"gobot/internal/svc"
+	"gobot/internal/types"
+
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+type ListSubAgentsLogic struct {
+	logx.Logger
+	ctx    context.Context
+	svcCtx *svc.ServiceContext
+}
+
+// List orchestrator sub-agents, newest first
+func NewListSubAgentsLogic(ctx context.Context, svcCtx *svc.ServiceContext) *ListSubAgentsLogic {
+	return &ListSubAgentsLogic{
+		Logger: logx.WithContext(ctx),
+		ctx:    ctx,
+		svcCtx: svcCtx,
+	}
+}
+
+func (l *ListSubAgentsLogic) ListSubAgents(req *types.ListSubAgentsRequest) (resp *types.ListSubAgentsResponse, err error) {
+	sessions, err := l.svcCtx.AgentSessions()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open agent sessions: %w", err)
+	}
+
+	runs, err := sessions.ListSubAgentRuns(req.Session)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]types.SubAgentRun, 0, len(runs))
+	for _, run := range runs {
+		result = append(result, toSubAgentRun(&run))
+	}
+
+	return &types.ListSubAgentsResponse{
+		SubAgents: result,
+		Total:     len(result),
+	}, nil
+}
+
+// toSubAgentRun converts a stored sub-agent run to its API form
+func toSubAgentRun(run *session.SubAgentRun) types.SubAgentRun {
+	result := types.SubAgentRun{
+		Id:               run.ID,
+		ParentSessionKey: run.ParentSessionKey,
+		SessionKey:       run.SessionKey,
+		Type:             run.Type,
+		Description:      run.Description,
+		Task:             run.Task,
+		Status:           run.Status,
+		Result:           run.Result,
+		Error:            run.Error,
+		StartedAt:        run.StartedAt.Format(time.RFC3339),
+	}
+	if !run.CompletedAt.IsZero() {
+		result.CompletedAt = run.CompletedAt.Format(time.RFC3339)
+	}
+	return result
+}
@@ -0,0 +1,46 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+
+	"gobot/internal/svc"
+	"gobot/internal/types"
+
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+type RewindAgentSessionLogic struct {
+	logx.Logger
+	ctx    context.Context
+	svcCtx *svc.ServiceContext
+}
+
+// Rewind a session, removing messages after a message
+func NewRewindAgentSessionLogic(ctx context.Context, svcCtx *svc.ServiceContext) *RewindAgentSessionLogic {
+	return &RewindAgentSessionLogic{
+		Logger: logx.WithContext(ctx),
+		ctx:    ctx,
+		svcCtx: svcCtx,
+	}
+}
+
+func (l *RewindAgentSessionLogic) RewindAgentSession(req *types.RewindAgentSessionRequest) (resp *types.RewindAgentSessionResponse, err error) {
+	sessions, err := l.svcCtx.AgentSessions()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open agent sessions: %w", err)
+	}
+
+	if req.MessageId <= 0 {
+		return nil, fmt.Errorf("messageId is required")
+	}
+
+	removed, err := sessions.Rewind(req.Id, req.MessageId)
+	if err != nil {
+		return nil, err
+	}
+
+	return &types.RewindAgentSessionResponse{
+		Removed: removed,
+	}, nil
+}
@@ -0,0 +1,36 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+
+	"gobot/internal/svc"
+	"gobot/internal/types"
+
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+type StopAgentRunLogic struct {
+	logx.Logger
+	ctx    context.Context
+	svcCtx *svc.ServiceContext
+}
+
+// Stop in-flight agent runs
+func NewStopAgentRunLogic(ctx context.Context, svcCtx *svc.ServiceContext) *StopAgentRunLogic {
+	return &StopAgentRunLogic{
+		Logger: logx.WithContext(ctx),
+		ctx:    ctx,
+		svcCtx: svcCtx,
+	}
+}
+
+func (l *StopAgentRunLogic) StopAgentRun(req *types.StopAgentRunRequest) (resp *types.MessageResponse, err error) {
+	if err := l.svcCtx.AgentHub.CancelRun(req.RequestId, req.SessionKey); err != nil {
+		return nil, fmt.Errorf("failed to stop run: %w", err)
+	}
+
+	return &types.MessageResponse{
+		Message: "Stop requested",
+	}, nil
+}
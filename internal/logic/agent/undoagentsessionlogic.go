@@ -0,0 +1,62 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"gobot/agent/session"
+	"gobot/internal/svc"
+	"gobot/internal/types"
+
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+type UndoAgentSessionLogic struct {
+	logx.Logger
+	ctx    context.Context
+	svcCtx *svc.ServiceContext
+}
+
+// Restore files changed by write and edit calls after a message
+func NewUndoAgentSessionLogic(ctx context.Context, svcCtx *svc.ServiceContext) *UndoAgentSessionLogic {
+	return &UndoAgentSessionLogic{
+		Logger: logx.WithContext(ctx),
+		ctx:    ctx,
+		svcCtx: svcCtx,
+	}
+}
+
+func (l *UndoAgentSessionLogic) UndoAgentSession(req *types.UndoAgentSessionRequest) (resp *types.UndoAgentSessionResponse, err error) {
+	sessions, err := l.svcCtx.AgentSessions()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open agent sessions: %w", err)
+	}
+
+	resp = &types.UndoAgentSessionResponse{Files: []types.RestoredFile{}}
+	if req.DryRun {
+		pending, err := sessions.PendingCheckpoints(req.Id, req.MessageId)
+		if errors.Is(err, session.ErrNoCheckpoints) {
+			return resp, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, c := range pending {
+			resp.Files = append(resp.Files, types.RestoredFile{Path: c.Path, MessageId: c.MessageID, Deleted: !c.Existed})
+		}
+		return resp, nil
+	}
+
+	restored, err := sessions.RestoreCheckpoints(req.Id, req.MessageId)
+	if errors.Is(err, session.ErrNoCheckpoints) {
+		return resp, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range restored {
+		resp.Files = append(resp.Files, types.RestoredFile{Path: f.Path, MessageId: f.MessageID, Deleted: f.Deleted})
+	}
+	return resp, nil
+}
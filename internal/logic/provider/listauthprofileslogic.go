@@ -2,6 +2,7 @@ package provider
 
 import (
 	"context"
+	"database/sql"
 	"time"
 
 	"gobot/internal/svc"
@@ -34,15 +35,19 @@ func (l *ListAuthProfilesLogic) ListAuthProfiles() (resp *types.ListAuthProfiles
 	result := make([]types.AuthProfile, len(profiles))
 	for i, p := range profiles {
 		result[i] = types.AuthProfile{
-			Id:        p.ID,
-			Name:      p.Name,
-			Provider:  p.Provider,
-			Model:     p.Model.String,
-			BaseUrl:   p.BaseUrl.String,
-			Priority:  int(p.Priority.Int64),
-			IsActive:  p.IsActive.Int64 == 1,
-			CreatedAt: time.Unix(p.CreatedAt, 0).Format(time.RFC3339),
-			UpdatedAt: time.Unix(p.UpdatedAt, 0).Format(time.RFC3339),
+			Id:            p.ID,
+			Name:          p.Name,
+			Provider:      p.Provider,
+			Model:         p.Model.String,
+			BaseUrl:       p.BaseUrl.String,
+			Priority:      int(p.Priority.Int64),
+			IsActive:      p.IsActive.Int64 == 1,
+			CreatedAt:     time.Unix(p.CreatedAt, 0).Format(time.RFC3339),
+			UpdatedAt:     time.Unix(p.UpdatedAt, 0).Format(time.RFC3339),
+			UsageCount:    int(p.UsageCount.Int64),
+			ErrorCount:    int(p.ErrorCount.Int64),
+			LastUsedAt:    optionalTime(p.LastUsedAt),
+			CooldownUntil: activeCooldown(p.CooldownUntil),
 		}
 	}
 
@@ -50,3 +55,19 @@ func (l *ListAuthProfilesLogic) ListAuthProfiles() (resp *types.ListAuthProfiles
 		Profiles: result,
 	}, nil
 }
+
+// optionalTime formats a nullable Unix time, or "" when it is unset
+func optionalTime(t sql.NullInt64) string {
+	if !t.Valid || t.Int64 == 0 {
+		return ""
+	}
+	return time.Unix(t.Int64, 0).Format(time.RFC3339)
+}
+
+// activeCooldown formats the end of a profile's cooldown, or "" once it has passed
+func activeCooldown(until sql.NullInt64) string {
+	if !until.Valid || until.Int64 <= time.Now().Unix() {
+		return ""
+	}
+	return optionalTime(until)
+}
@@ -45,6 +45,7 @@ func (l *ListModelsLogic) ListModels() (resp *types.ListModelsResponse, err erro
 					Input:       m.Pricing.Input,
 					Output:      m.Pricing.Output,
 					CachedInput: m.Pricing.CachedInput,
+					CacheWrite:  m.Pricing.CacheWrite,
 				}
 			}
 			modelList[i] = info
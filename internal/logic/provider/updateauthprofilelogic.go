@@ -96,15 +96,19 @@ func (l *UpdateAuthProfileLogic) UpdateAuthProfile(req *types.UpdateAuthProfileR
 
 	return &types.GetAuthProfileResponse{
 		Profile: types.AuthProfile{
-			Id:        profile.ID,
-			Name:      profile.Name,
-			Provider:  profile.Provider,
-			Model:     profile.Model.String,
-			BaseUrl:   profile.BaseUrl.String,
-			Priority:  int(profile.Priority.Int64),
-			IsActive:  profile.IsActive.Int64 == 1,
-			CreatedAt: time.Unix(profile.CreatedAt, 0).Format(time.RFC3339),
-			UpdatedAt: time.Unix(profile.UpdatedAt, 0).Format(time.RFC3339),
+			Id:            profile.ID,
+			Name:          profile.Name,
+			Provider:      profile.Provider,
+			Model:         profile.Model.String,
+			BaseUrl:       profile.BaseUrl.String,
+			Priority:      int(profile.Priority.Int64),
+			IsActive:      profile.IsActive.Int64 == 1,
+			CreatedAt:     time.Unix(profile.CreatedAt, 0).Format(time.RFC3339),
+			UpdatedAt:     time.Unix(profile.UpdatedAt, 0).Format(time.RFC3339),
+			UsageCount:    int(profile.UsageCount.Int64),
+			ErrorCount:    int(profile.ErrorCount.Int64),
+			LastUsedAt:    optionalTime(profile.LastUsedAt),
+			CooldownUntil: activeCooldown(profile.CooldownUntil),
 		},
 	}, nil
 }
@@ -15,6 +15,7 @@ type ModelPricing struct {
 	Input       float64 `json:"input,omitempty" yaml:"input,omitempty"`             // $ per 1M input tokens
 	Output      float64 `json:"output,omitempty" yaml:"output,omitempty"`           // $ per 1M output tokens
 	CachedInput float64 `json:"cachedInput,omitempty" yaml:"cachedInput,omitempty"` // $ per 1M cached input tokens
+	CacheWrite  float64 `json:"cacheWrite,omitempty" yaml:"cacheWrite,omitempty"`   // $ per 1M tokens written to the prompt cache
 }
 
 // ModelInfo describes an AI model
@@ -35,12 +36,17 @@ func (m *ModelInfo) IsActive() bool {
 	return *m.Active
 }
 
+// ProviderTypeOpenAICompatible declares a provider that speaks the OpenAI chat completions API at BaseURL
+const ProviderTypeOpenAICompatible = "openai-compatible"
+
 // ProviderCredentials holds API credentials for a provider
 type ProviderCredentials struct {
-	APIKey  string `json:"apiKey,omitempty" yaml:"api_key,omitempty"`
-	BaseURL string `json:"baseUrl,omitempty" yaml:"base_url,omitempty"` // For Ollama or custom endpoints
-	Command string `json:"command,omitempty" yaml:"command,omitempty"` // For CLI providers
-	Args    string `json:"args,omitempty" yaml:"args,omitempty"`       // CLI args
+	Type    string            `json:"type,omitempty" yaml:"type,omitempty"` // "openai-compatible"; otherwise inferred from the name
+	APIKey  string            `json:"apiKey,omitempty" yaml:"api_key,omitempty"`
+	BaseURL string            `json:"baseUrl,omitempty" yaml:"base_url,omitempty"` // For Ollama or custom endpoints
+	Headers map[string]string `json:"headers,omitempty" yaml:"headers,omitempty"`  // Extra HTTP headers (openai-compatible)
+	Command string            `json:"command,omitempty" yaml:"command,omitempty"`  // For CLI providers
+	Args    string            `json:"args,omitempty" yaml:"args,omitempty"`        // CLI args
 }
 
 // TaskRouting defines which models to use for different task types
@@ -3,12 +3,14 @@ package realtime
 import (
 	"context"
 	"database/sql"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"strings"
 	"sync"
 	"time"
 
+	"gobot/agent/session"
 	"gobot/internal/agenthub"
 	"gobot/internal/db"
 	"gobot/internal/svc"
@@ -61,6 +63,8 @@ func (c *ChatContext) SetHub(hub *agenthub.Hub) {
 	hub.SetResponseHandler(c.handleAgentResponse)
 	// Register to receive approval requests
 	hub.SetApprovalHandler(c.handleApprovalRequest)
+	// Register to receive sub-agent progress
+	hub.SetEventHandler(c.handleAgentEvent)
 }
 
 // RegisterChatHandler sets up the chat handler
@@ -69,7 +73,7 @@ func RegisterChatHandler(chatCtx *ChatContext) {
 		go handleChatMessage(c, msg, chatCtx)
 	})
 	SetApprovalResponseHandler(func(c *Client, msg *Message) {
-		go chatCtx.handleApprovalResponse(msg)
+		go chatCtx.handleApprovalResponse(msg, c.UserID)
 	})
 }
 
@@ -97,12 +101,49 @@ func (c *ChatContext) handleApprovalRequest(agentID string, requestID string, to
 	}
 }
 
+// handleAgentEvent forwards sub-agent progress and background job output from the agent to
+// all connected clients. session_id is the session that started them, so a chat can show them.
+func (c *ChatContext) handleAgentEvent(agentID string, frame *agenthub.Frame) {
+	if c.clientHub == nil {
+		return
+	}
+	payload, ok := frame.Payload.(map[string]any)
+	if !ok {
+		return
+	}
+
+	var msgType, idKey, sessionKey string
+	switch frame.Method {
+	case "subagent":
+		msgType, idKey, sessionKey = "subagent_event", "agent_id", "parent_session_key"
+	case "job":
+		msgType, idKey, sessionKey = "job_event", "job_id", "session_key"
+	default:
+		return
+	}
+
+	data := map[string]interface{}{
+		idKey:        frame.ID,
+		"session_id": payload[sessionKey],
+	}
+	for k, v := range payload {
+		if k != sessionKey {
+			data[k] = v
+		}
+	}
+	c.clientHub.Broadcast(&Message{
+		Type:      msgType,
+		Data:      data,
+		Timestamp: time.Now(),
+	})
+}
+
 // handleApprovalResponse processes an approval response from a client
-func (c *ChatContext) handleApprovalResponse(msg *Message) {
+func (c *ChatContext) handleApprovalResponse(msg *Message, userID string) {
 	requestID, _ := msg.Data["request_id"].(string)
 	approved, _ := msg.Data["approved"].(bool)
 
-	logx.Infof("[Chat] Approval response: id=%s approved=%v", requestID, approved)
+	logx.Infof("[Chat] Approval response: id=%s approved=%v user=%s", requestID, approved, userID)
 
 	// Find the agent that requested this approval
 	c.pendingApprovalsMu.Lock()
@@ -119,7 +160,7 @@ func (c *ChatContext) handleApprovalResponse(msg *Message) {
 
 	// Send response back to agent via hub
 	if c.hub != nil {
-		if err := c.hub.SendApprovalResponse(agentID, requestID, approved); err != nil {
+		if err := c.hub.SendApprovalResponse(agentID, requestID, approved, userID); err != nil {
 			logx.Errorf("[Chat] Failed to send approval response: %v", err)
 		}
 	}
@@ -165,6 +206,10 @@ func (c *ChatContext) handleAgentResponse(agentID string, frame *agenthub.Frame)
 		if toolResult, ok := payload["tool_result"].(string); ok {
 			sendToolResult(req.client, req.sessionID, toolResult)
 		}
+		if errStr, ok := payload["error"].(string); ok && req.client != nil {
+			// Run errors such as a hard budget stop
+			sendChatError(req.client, req.sessionID, errStr)
+		}
 		return
 	}
 
@@ -194,6 +239,14 @@ func (c *ChatContext) handleAgentResponse(agentID string, frame *agenthub.Frame)
 		return
 	}
 
+	// A steering message joined a run in progress; that run's request streams the reply
+	if payload, ok := frame.Payload.(map[string]any); ok {
+		if steered, _ := payload["steered"].(bool); steered {
+			logx.Infof("[Chat] Request %s steered the run in progress for session %s", frame.ID, req.sessionID)
+			return
+		}
+	}
+
 	// Check if this is a title generation response (prompt is empty)
 	if req.prompt == "" && req.streamedContent != "" {
 		// This is a title response - update the chat title
@@ -257,9 +310,16 @@ func handleChatMessage(c *Client, msg *Message, chatCtx *ChatContext) {
 	sessionID, _ := msg.Data["session_id"].(string)
 	prompt, _ := msg.Data["prompt"].(string)
 	useCompanion, _ := msg.Data["companion"].(bool)
+	steer, _ := msg.Data["steer"].(bool) // Add to the run in progress instead of queueing a new one
 
 	logx.Infof("[Chat] Processing message for session %s: %s", sessionID, prompt)
 
+	attachments, err := chatAttachments(msg.Data["attachments"])
+	if err != nil {
+		sendChatError(c, sessionID, err.Error())
+		return
+	}
+
 	if chatCtx.hub == nil {
 		sendChatError(c, sessionID, "Agent hub not initialized")
 		return
@@ -332,7 +392,7 @@ func handleChatMessage(c *Client, msg *Message, chatCtx *ChatContext) {
 			ChatID:   sessionID,
 			Role:     "user",
 			Content:  prompt,
-			Metadata: sql.NullString{},
+			Metadata: attachmentMetadata(attachments),
 		})
 		if err != nil {
 			logx.Errorf("[Chat] Failed to save user message: %v", err)
@@ -359,15 +419,24 @@ func handleChatMessage(c *Client, msg *Message, chatCtx *ChatContext) {
 	logx.Infof("[Chat] Registered pending request: %s for session %s", requestID, sessionID)
 	chatCtx.pendingMu.Unlock()
 
-	// Send "run" request to the agent (agent handles "run" not "chat")
+	// Send "run" request to the agent (agent handles "run" not "chat").
+	// "steer" joins the session's run in progress, or starts a run if there is none.
+	method := "run"
+	if steer && sessionID != "" {
+		method = "steer"
+	}
+	params := map[string]any{
+		"session_key": sessionID,
+		"prompt":      prompt,
+	}
+	if len(attachments) > 0 {
+		params["attachments"] = attachments
+	}
 	frame := &agenthub.Frame{
 		Type:   "req",
 		ID:     requestID,
-		Method: "run",
-		Params: map[string]any{
-			"session_key": sessionID,
-			"prompt":      prompt,
-		},
+		Method: method,
+		Params: params,
 	}
 
 	if err := chatCtx.hub.SendToAgent(agent.ID, frame); err != nil {
@@ -384,6 +453,52 @@ func handleChatMessage(c *Client, msg *Message, chatCtx *ChatContext) {
 	logx.Infof("[Chat] Routed message to agent %s (request: %s)", agent.ID, requestID)
 }
 
+// chatAttachments reads the files sent with a chat message, each {name, media_type, data}
+// with base64 data
+func chatAttachments(raw any) ([]session.ContentPart, error) {
+	items, _ := raw.([]any)
+	parts := make([]session.ContentPart, 0, len(items))
+	for _, item := range items {
+		fields, _ := item.(map[string]any)
+		name, _ := fields["name"].(string)
+		mediaType, _ := fields["media_type"].(string)
+		encoded, _ := fields["data"].(string)
+
+		data, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("attachment %s is not valid base64: %w", name, err)
+		}
+		part, err := session.NewPart(name, mediaType, data)
+		if err != nil {
+			return nil, err
+		}
+		parts = append(parts, part)
+	}
+	return parts, nil
+}
+
+// attachmentMetadata describes attachments for the stored chat message, so the history can
+// show them without keeping the bytes twice
+func attachmentMetadata(parts []session.ContentPart) sql.NullString {
+	if len(parts) == 0 {
+		return sql.NullString{}
+	}
+	type attachment struct {
+		Name      string `json:"name"`
+		Type      string `json:"type"`
+		MediaType string `json:"media_type"`
+		Size      int    `json:"size"`
+	}
+	meta := struct {
+		Attachments []attachment `json:"attachments"`
+	}{}
+	for _, p := range parts {
+		meta.Attachments = append(meta.Attachments, attachment{Name: p.Name, Type: p.Type, MediaType: p.MediaType, Size: len(p.Data)})
+	}
+	data, _ := json.Marshal(meta)
+	return sql.NullString{String: string(data), Valid: true}
+}
+
 // requestTitleGeneration asks the agent to generate a title for the chat
 func (c *ChatContext) requestTitleGeneration(agentID, sessionID, userPrompt, assistantResponse string) {
 	if c.hub == nil || c.svcCtx == nil {
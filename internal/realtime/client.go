@@ -21,8 +21,8 @@ const (
 	// Send pings to peer with this period. Must be less than pongWait.
 	pingPeriod = (pongWait * 9) / 10
 
-	// Maximum message size allowed from peer.
-	maxMessageSize = 32768 // 32KB
+	// Maximum message size allowed from peer, with room for base64 chat attachments.
+	maxMessageSize = 32 << 20 // 32MB
 )
 
 // Error types
@@ -6,9 +6,11 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"strings"
 	"sync"
 	"time"
 
+	"gobot/agent/session"
 	"gobot/internal/agenthub"
 	"gobot/internal/channels"
 
@@ -26,6 +28,9 @@ type Router struct {
 	// Pending requests waiting for agent responses
 	pending sync.Map // map[requestID]chan *agenthub.Frame
 
+	// Request in progress per conversation, for /stop
+	active sync.Map // map[conversation key]requestID
+
 	// Request timeout
 	timeout time.Duration
 }
@@ -53,28 +58,38 @@ func (r *Router) Route(ctx context.Context, msg channels.InboundMessage) error {
 		return fmt.Errorf("no agent bound to channel %s:%s", msg.ChannelType, msg.ChannelID)
 	}
 
+	if isStopCommand(msg.Text) {
+		return r.stop(ctx, msg)
+	}
+
 	// Check if any agent is connected
 	agents := r.agents.GetAllAgents()
 	if len(agents) == 0 {
 		return fmt.Errorf("no agents connected")
 	}
 
-	// Create request frame
+	// Create a run request; each conversation is its own agent session
 	requestID := uuid.New().String()
+	conversation := conversationKey(msg)
+	params := map[string]any{
+		"prompt":       msg.Text,
+		"session_key":  conversation,
+		"channel_type": msg.ChannelType,
+		"channel_id":   msg.ChannelID,
+		"sender_id":    msg.SenderID,
+		"sender_name":  msg.SenderName,
+		"message_id":   msg.MessageID,
+		"reply_to_id":  msg.ReplyToID,
+		"thread_id":    msg.ThreadID,
+	}
+	if parts := attachmentParts(msg.Attachments); len(parts) > 0 {
+		params["attachments"] = parts
+	}
 	frame := &agenthub.Frame{
 		Type:   "req",
 		ID:     requestID,
-		Method: "chat",
-		Params: map[string]any{
-			"message":      msg.Text,
-			"channel_type": msg.ChannelType,
-			"channel_id":   msg.ChannelID,
-			"sender_id":    msg.SenderID,
-			"sender_name":  msg.SenderName,
-			"message_id":   msg.MessageID,
-			"reply_to_id":  msg.ReplyToID,
-			"thread_id":    msg.ThreadID,
-		},
+		Method: "run",
+		Params: params,
 	}
 
 	// Create response channel
@@ -82,6 +97,9 @@ func (r *Router) Route(ctx context.Context, msg channels.InboundMessage) error {
 	r.pending.Store(requestID, respCh)
 	defer r.pending.Delete(requestID)
 
+	r.active.Store(conversation, requestID)
+	defer r.active.CompareAndDelete(conversation, requestID)
+
 	// Send to first available agent (could implement load balancing)
 	targetAgent := agents[0]
 	if binding.AgentID != "" {
@@ -108,8 +126,59 @@ func (r *Router) Route(ctx context.Context, msg channels.InboundMessage) error {
 	}
 }
 
-// HandleAgentResponse processes a response from an agent (called by agenthub)
+// stop cancels the request in progress for the conversation a /stop command came from
+func (r *Router) stop(ctx context.Context, msg channels.InboundMessage) error {
+	requestID, ok := r.active.Load(conversationKey(msg))
+	if !ok {
+		return r.reply(ctx, msg, "Nothing to stop.")
+	}
+
+	if err := r.agents.CancelRun(requestID.(string), ""); err != nil {
+		return fmt.Errorf("failed to stop run: %w", err)
+	}
+	logx.Infof("[router] Stop requested for %s by %s", requestID, msg.SenderID)
+	return r.reply(ctx, msg, "Stopping.")
+}
+
+// reply sends a short notice back to the conversation a message came from
+func (r *Router) reply(ctx context.Context, original channels.InboundMessage, text string) error {
+	channel, ok := r.channels.Get(original.ChannelType)
+	if !ok {
+		return fmt.Errorf("channel not found: %s", original.ChannelType)
+	}
+	return channel.Send(ctx, channels.OutboundMessage{
+		ChannelID: original.ChannelID,
+		Text:      text,
+		ReplyToID: original.MessageID,
+		ThreadID:  original.ThreadID,
+	})
+}
+
+// isStopCommand reports whether text is the /stop command, including Telegram's /stop@botname form
+func isStopCommand(text string) bool {
+	fields := strings.Fields(text)
+	if len(fields) != 1 {
+		return false
+	}
+	command, _, _ := strings.Cut(fields[0], "@")
+	return strings.EqualFold(command, "/stop")
+}
+
+// conversationKey identifies a channel conversation (thread-aware) and is its agent session key
+func conversationKey(msg channels.InboundMessage) string {
+	key := msg.ChannelType + ":" + msg.ChannelID
+	if msg.ThreadID != "" {
+		key += ":" + msg.ThreadID
+	}
+	return key
+}
+
+// HandleAgentResponse processes a response from an agent (called by agenthub).
+// Only the final response is delivered; streamed chunks are ignored.
 func (r *Router) HandleAgentResponse(requestID string, frame *agenthub.Frame) {
+	if frame.Type != "res" {
+		return
+	}
 	if respChI, ok := r.pending.Load(requestID); ok {
 		respCh := respChI.(chan *agenthub.Frame)
 		select {
@@ -123,8 +192,7 @@ func (r *Router) HandleAgentResponse(requestID string, frame *agenthub.Frame) {
 // handleAgentResponse sends the agent's response back to the channel
 func (r *Router) handleAgentResponse(ctx context.Context, original channels.InboundMessage, resp *agenthub.Frame) error {
 	if !resp.OK {
-		logx.Errorf("[router] Agent error: %s", resp.Error)
-		// Optionally send error message to channel
+		return fmt.Errorf("agent error: %s", resp.Error)
 	}
 
 	// Extract response text
@@ -133,7 +201,9 @@ func (r *Router) handleAgentResponse(ctx context.Context, original channels.Inbo
 	case string:
 		responseText = payload
 	case map[string]any:
-		if text, ok := payload["text"].(string); ok {
+		if result, ok := payload["result"].(string); ok {
+			responseText = result
+		} else if text, ok := payload["text"].(string); ok {
 			responseText = text
 		} else if content, ok := payload["content"].(string); ok {
 			responseText = content
@@ -168,8 +238,12 @@ func (r *Router) handleAgentResponse(ctx context.Context, original channels.Inbo
 	return channel.Send(ctx, outMsg)
 }
 
-// SetupChannelHandlers sets up message handlers for all channels
+// SetupChannelHandlers sets up message handlers for all channels and starts receiving
+// the agent's responses to routed messages
 func (r *Router) SetupChannelHandlers(ctx context.Context) {
+	r.agents.AddResponseHandler(func(agentID string, frame *agenthub.Frame) {
+		r.HandleAgentResponse(frame.ID, frame)
+	})
 	for _, channelID := range r.channels.List() {
 		channel, _ := r.channels.Get(channelID)
 		channel.SetHandler(func(msg channels.InboundMessage) {
@@ -192,3 +266,17 @@ func truncate(s string, max int) string {
 	}
 	return s[:max] + "..."
 }
+
+// attachmentParts converts channel attachments to message parts, dropping unsupported files
+func attachmentParts(attachments []channels.Attachment) []session.ContentPart {
+	var parts []session.ContentPart
+	for _, a := range attachments {
+		part, err := session.NewPart(a.Name, a.MediaType, a.Data)
+		if err != nil {
+			logx.Infof("[router] Dropping attachment: %v", err)
+			continue
+		}
+		parts = append(parts, part)
+	}
+	return parts
+}
@@ -1,10 +1,14 @@
 package router
 
 import (
+	"context"
 	"os"
 	"path/filepath"
 	"testing"
 	"time"
+
+	"gobot/internal/agenthub"
+	"gobot/internal/channels"
 )
 
 func TestBindingStore(t *testing.T) {
@@ -207,3 +211,63 @@ func TestBindingStorePersistence(t *testing.T) {
 		t.Errorf("Loaded binding Name = %q, want %q", got.Name, "Test")
 	}
 }
+
+// recordingChannel is a channel adapter that keeps sent messages
+type recordingChannel struct {
+	sent []channels.OutboundMessage
+}
+
+func (c *recordingChannel) ID() string                                                    { return "telegram" }
+func (c *recordingChannel) Connect(ctx context.Context, cfg channels.ChannelConfig) error { return nil }
+func (c *recordingChannel) Disconnect() error                                             { return nil }
+func (c *recordingChannel) SetHandler(fn func(channels.InboundMessage))                   {}
+func (c *recordingChannel) Send(ctx context.Context, msg channels.OutboundMessage) error {
+	c.sent = append(c.sent, msg)
+	return nil
+}
+
+func TestIsStopCommand(t *testing.T) {
+	tests := []struct {
+		text string
+		want bool
+	}{
+		{"/stop", true},
+		{"  /stop  ", true},
+		{"/STOP", true},
+		{"/stop@gobot_bot", true},
+		{"/stopwatch", false},
+		{"/stop now please", false},
+		{"please /stop", false},
+		{"stop", false},
+	}
+	for _, tt := range tests {
+		if got := isStopCommand(tt.text); got != tt.want {
+			t.Errorf("isStopCommand(%q) = %v, want %v", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestRouteStop(t *testing.T) {
+	channel := &recordingChannel{}
+	mgr := channels.NewManager()
+	mgr.Register(channel)
+
+	r := NewRouter(mgr, agenthub.NewHub())
+	r.bindings.Add(&Binding{ID: "b1", ChannelType: "telegram", ChannelID: "42", Enabled: true})
+
+	msg := channels.InboundMessage{ChannelType: "telegram", ChannelID: "42", MessageID: "7", Text: "/stop"}
+
+	// Nothing running: the router answers without involving the agent
+	if err := r.Route(context.Background(), msg); err != nil {
+		t.Fatalf("Route() error = %v", err)
+	}
+	if len(channel.sent) != 1 || channel.sent[0].Text != "Nothing to stop." || channel.sent[0].ReplyToID != "7" {
+		t.Fatalf("unexpected replies: %+v", channel.sent)
+	}
+
+	// A run in progress is cancelled through the hub, which fails here with no agent connected
+	r.active.Store(conversationKey(msg), "req-1")
+	if err := r.Route(context.Background(), msg); err == nil {
+		t.Error("expected error cancelling without a connected agent")
+	}
+}
@@ -164,7 +164,7 @@ func RunWithOptions(ctx context.Context, c config.Config, opts ServerOptions) er
 		channelMgr = channels.NewManager()
 	}
 	msgRouter := router.NewRouter(channelMgr, svcCtx.AgentHub)
-	_ = msgRouter
+	msgRouter.SetupChannelHandlers(ctx)
 
 	rewriteHandler := realtime.NewRewriteHandler(svcCtx)
 	rewriteHandler.Register()
@@ -3,7 +3,11 @@ package svc
 import (
 	"os"
 	"path/filepath"
+	"sync"
 
+	"gobot/agent/audit"
+	agentcfg "gobot/agent/config"
+	"gobot/agent/session"
 	"gobot/internal/agenthub"
 	"gobot/internal/config"
 	"gobot/internal/db"
@@ -25,6 +29,16 @@ type ServiceContext struct {
 	SkillSettings  *local.SkillSettingsStore
 
 	AgentHub *agenthub.Hub
+
+	// Agent session store (~/.gobot/gobot.db), opened on first use
+	agentSessionsOnce sync.Once
+	agentSessions     *session.Manager
+	agentSessionsErr  error
+
+	// Agent tool audit log (same database), opened on first use
+	agentAuditOnce sync.Once
+	agentAudit     *audit.Log
+	agentAuditErr  error
 }
 
 // NewServiceContext creates a new service context, initializing database if not provided
@@ -88,11 +102,43 @@ func NewServiceContextWithDB(c config.Config, database *db.Store) *ServiceContex
 	return svc
 }
 
+// AgentSessions returns the agent's session store, opening it on first use
+func (svc *ServiceContext) AgentSessions() (*session.Manager, error) {
+	svc.agentSessionsOnce.Do(func() {
+		cfg, err := agentcfg.Load()
+		if err != nil {
+			svc.agentSessionsErr = err
+			return
+		}
+		svc.agentSessions, svc.agentSessionsErr = session.New(cfg.DBPath())
+	})
+	return svc.agentSessions, svc.agentSessionsErr
+}
+
+// AgentAudit returns the agent's tool audit log, opening it on first use
+func (svc *ServiceContext) AgentAudit() (*audit.Log, error) {
+	svc.agentAuditOnce.Do(func() {
+		cfg, err := agentcfg.Load()
+		if err != nil {
+			svc.agentAuditErr = err
+			return
+		}
+		svc.agentAudit, svc.agentAuditErr = audit.Open(cfg.DBPath())
+	})
+	return svc.agentAudit, svc.agentAuditErr
+}
+
 func (svc *ServiceContext) Close() {
 	if svc.DB != nil {
 		svc.DB.Close()
 		logx.Info("SQLite database connection closed")
 	}
+	if svc.agentSessions != nil {
+		svc.agentSessions.Close()
+	}
+	if svc.agentAudit != nil {
+		svc.agentAudit.Close()
+	}
 	logx.Info("Service context closed")
 }
 
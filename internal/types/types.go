@@ -3,6 +3,21 @@
 
 package types
 
+type AgentAuditEntry struct {
+	Id         int64  `json:"id"`
+	CreatedAt  string `json:"createdAt"`
+	SessionKey string `json:"sessionKey,omitempty"`
+	ToolCallId string `json:"toolCallId,omitempty"`
+	Tool       string `json:"tool"`
+	Input      string `json:"input"`
+	Output     string `json:"output"` // Truncated
+	IsError    bool   `json:"isError"`
+	Approved   bool   `json:"approved"`          // False when the call was denied
+	Channel    string `json:"channel,omitempty"` // cli, web or auto
+	Approver   string `json:"approver,omitempty"`
+	DurationMs int64  `json:"durationMs"`
+}
+
 type AgentConnectRequest struct {
 	AgentId string `json:"agentId"`
 }
@@ -19,12 +34,19 @@ type AgentInfo struct {
 }
 
 type AgentSession struct {
-	Id           string `json:"id"`
-	Name         string `json:"name,omitempty"`
-	Summary      string `json:"summary,omitempty"`
-	MessageCount int    `json:"messageCount"`
-	CreatedAt    string `json:"createdAt"`
-	UpdatedAt    string `json:"updatedAt"`
+	Id            string `json:"id"`
+	Name          string `json:"name,omitempty"`
+	Summary       string `json:"summary,omitempty"`
+	MessageCount  int    `json:"messageCount"`
+	ParentId      string `json:"parentId,omitempty"`      // Session this one was forked from
+	ForkMessageId int64  `json:"forkMessageId,omitempty"` // Last parent message copied into the fork
+	CreatedAt     string `json:"createdAt"`
+	UpdatedAt     string `json:"updatedAt"`
+}
+
+type AgentSessionNode struct {
+	Session  AgentSession       `json:"session"`
+	Children []AgentSessionNode `json:"children"`
 }
 
 type AgentSettings struct {
@@ -44,21 +66,34 @@ type AgentStatusResponse struct {
 	Uptime    int64  `json:"uptime"`
 }
 
+type AgentUsageTotals struct {
+	Key             string  `json:"key"`
+	Calls           int     `json:"calls"`
+	InputTokens     int64   `json:"inputTokens"`
+	OutputTokens    int64   `json:"outputTokens"`
+	CacheReadTokens int64   `json:"cacheReadTokens"`
+	CostUsd         float64 `json:"costUsd"`
+}
+
 type AuthConfigResponse struct {
 	GoogleEnabled bool `json:"googleEnabled"`
 	GitHubEnabled bool `json:"githubEnabled"`
 }
 
 type AuthProfile struct {
-	Id        string `json:"id"`
-	Name      string `json:"name"`
-	Provider  string `json:"provider"` // anthropic, openai, google, ollama
-	Model     string `json:"model,omitempty"`
-	BaseUrl   string `json:"baseUrl,omitempty"`
-	Priority  int    `json:"priority"`
-	IsActive  bool   `json:"isActive"`
-	CreatedAt string `json:"createdAt"`
-	UpdatedAt string `json:"updatedAt"`
+	Id            string `json:"id"`
+	Name          string `json:"name"`
+	Provider      string `json:"provider"` // anthropic, openai, google, ollama
+	Model         string `json:"model,omitempty"`
+	BaseUrl       string `json:"baseUrl,omitempty"`
+	Priority      int    `json:"priority"`
+	IsActive      bool   `json:"isActive"`
+	CreatedAt     string `json:"createdAt"`
+	UpdatedAt     string `json:"updatedAt"`
+	UsageCount    int    `json:"usageCount"`
+	ErrorCount    int    `json:"errorCount"` // Errors since the last successful use
+	LastUsedAt    string `json:"lastUsedAt,omitempty"`
+	CooldownUntil string `json:"cooldownUntil,omitempty"` // Set while the key is skipped after a 429/401
 }
 
 type CLIAvailability struct {
@@ -191,6 +226,12 @@ type ForgotPasswordRequest struct {
 	Email string `json:"email"`
 }
 
+type ForkAgentSessionRequest struct {
+	Id        string `path:"id"`
+	MessageId int64  `json:"messageId,optional"` // Fork at this message (default: latest)
+	Key       string `json:"key,optional"`       // New session key (default: <key>-fork-N)
+}
+
 type GetAgentSessionMessagesResponse struct {
 	Messages []SessionMessage `json:"messages"`
 	Total    int              `json:"total"`
@@ -200,10 +241,27 @@ type GetAgentSessionRequest struct {
 	Id string `path:"id"`
 }
 
+type GetAgentSessionTreeResponse struct {
+	Sessions []AgentSessionNode `json:"sessions"`
+}
+
 type GetAgentSettingsResponse struct {
 	Settings AgentSettings `json:"settings"`
 }
 
+type GetAgentUsageRequest struct {
+	Session string `form:"session,optional"` // Session key filter
+	Since   string `form:"since,optional"`   // YYYY-MM-DD or RFC3339
+	Until   string `form:"until,optional"`   // YYYY-MM-DD or RFC3339 (exclusive)
+}
+
+type GetAgentUsageResponse struct {
+	Total     AgentUsageTotals   `json:"total"`
+	BySession []AgentUsageTotals `json:"bySession"`
+	ByDay     []AgentUsageTotals `json:"byDay"`
+	ByModel   []AgentUsageTotals `json:"byModel"`
+}
+
 type GetAuthProfileRequest struct {
 	Id string `path:"id"`
 }
@@ -257,6 +315,11 @@ type GetSkillResponse struct {
 	Skill ExtensionSkill `json:"skill"`
 }
 
+type GetSubAgentResponse struct {
+	SubAgent SubAgentRun      `json:"subAgent"`
+	Messages []SessionMessage `json:"messages"` // The sub-agent's conversation
+}
+
 type GetUnreadCountResponse struct {
 	Count int `json:"count"`
 }
@@ -271,6 +334,23 @@ type HealthResponse struct {
 	Timestamp string `json:"timestamp"`
 }
 
+type ListAgentAuditRequest struct {
+	Session  string `form:"session,optional"`  // Session key filter
+	Tool     string `form:"tool,optional"`     // Tool name filter
+	Channel  string `form:"channel,optional"`  // cli, web or auto
+	Approver string `form:"approver,optional"` // Approver filter
+	Errors   bool   `form:"errors,optional"`   // Only calls that returned an error
+	Denied   bool   `form:"denied,optional"`   // Only denied calls
+	Since    string `form:"since,optional"`    // Duration (24h), YYYY-MM-DD or RFC3339
+	Until    string `form:"until,optional"`    // Duration (24h), YYYY-MM-DD or RFC3339 (exclusive)
+	Limit    int    `form:"limit,default=100"`
+	Offset   int    `form:"offset,optional"`
+}
+
+type ListAgentAuditResponse struct {
+	Entries []AgentAuditEntry `json:"entries"`
+}
+
 type ListAgentSessionsResponse struct {
 	Sessions []AgentSession `json:"sessions"`
 	Total    int            `json:"total"`
@@ -335,6 +415,15 @@ type ListOAuthProvidersResponse struct {
 	Providers []OAuthProvider `json:"providers"`
 }
 
+type ListSubAgentsRequest struct {
+	Session string `form:"session,optional"` // Parent session key filter
+}
+
+type ListSubAgentsResponse struct {
+	SubAgents []SubAgentRun `json:"subAgents"`
+	Total     int           `json:"total"`
+}
+
 type LoginRequest struct {
 	Email    string `json:"email"`
 	Password string `json:"password"`
@@ -367,6 +456,7 @@ type ModelPricing struct {
 	Input       float64 `json:"input,omitempty"`
 	Output      float64 `json:"output,omitempty"`
 	CachedInput float64 `json:"cachedInput,omitempty"`
+	CacheWrite  float64 `json:"cacheWrite,omitempty"`
 }
 
 type Notification struct {
@@ -424,6 +514,21 @@ type ResetPasswordRequest struct {
 	NewPassword string `json:"newPassword"`
 }
 
+type RestoredFile struct {
+	Path      string `json:"path"`
+	MessageId int64  `json:"messageId"` // Earliest undone message that changed it
+	Deleted   bool   `json:"deleted"`   // Created by the agent, so removed
+}
+
+type RewindAgentSessionRequest struct {
+	Id        string `path:"id"`
+	MessageId int64  `json:"messageId"` // Keep messages up to and including this one
+}
+
+type RewindAgentSessionResponse struct {
+	Removed int `json:"removed"`
+}
+
 type SearchChatMessagesRequest struct {
 	Query    string `form:"query"`
 	Page     int    `form:"page,optional"`
@@ -450,6 +555,7 @@ type SessionMessage struct {
 	Id        int    `json:"id"`
 	Role      string `json:"role"`
 	Content   string `json:"content,omitempty"`
+	Archived  bool   `json:"archived,omitempty"` // Replaced by a compaction summary
 	CreatedAt string `json:"createdAt"`
 }
 
@@ -465,6 +571,29 @@ type SimpleAgentStatusResponse struct {
 	Uptime    int64  `json:"uptime,omitempty"`
 }
 
+type StopAgentRunRequest struct {
+	SessionKey string `json:"sessionKey,optional"` // Stop runs in this session
+	RequestId  string `json:"requestId,optional"`  // Stop this run (both empty: stop all runs)
+}
+
+type SubAgentRequest struct {
+	Id string `path:"id"`
+}
+
+type SubAgentRun struct {
+	Id               string `json:"id"`
+	ParentSessionKey string `json:"parentSessionKey,omitempty"` // Session whose agent spawned it
+	SessionKey       string `json:"sessionKey"`                 // The sub-agent's own session
+	Type             string `json:"type"`
+	Description      string `json:"description"`
+	Task             string `json:"task"`
+	Status           string `json:"status"` // pending, running, completed, failed, cancelled
+	Result           string `json:"result,omitempty"`
+	Error            string `json:"error,omitempty"`
+	StartedAt        string `json:"startedAt"`
+	CompletedAt      string `json:"completedAt,omitempty"`
+}
+
 type TaskRouting struct {
 	Vision    string              `json:"vision,omitempty"`
 	Reasoning string              `json:"reasoning,omitempty"`
@@ -498,6 +627,16 @@ type ToggleSkillResponse struct {
 	Enabled bool   `json:"enabled"`
 }
 
+type UndoAgentSessionRequest struct {
+	Id        string `path:"id"`
+	MessageId int64  `json:"messageId,optional"` // Restore files as they were at this message (default: before the last run)
+	DryRun    bool   `json:"dryRun,optional"`    // Report the files without changing them
+}
+
+type UndoAgentSessionResponse struct {
+	Files []RestoredFile `json:"files"`
+}
+
 type UpdateAgentSettingsRequest struct {
 	AutonomousMode   bool `json:"autonomousMode"`
 	AutoApproveRead  bool `json:"autoApproveRead"`